@@ -0,0 +1,250 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend forwarding the signing
+// requests to an external signer, e.g. wtcsigner, over JSON-RPC.
+package external
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	wtc "github.com/wtc/go-wtc"
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/event"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/rlp"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/signer"
+)
+
+// Scheme is the URL scheme of the wallets of external signers.
+const Scheme = "extapi"
+
+// Backend is an accounts.Backend providing the single wallet of an external
+// signer.
+type Backend struct {
+	signers []accounts.Wallet
+}
+
+// NewBackend connects to the external signer at the given IPC path or HTTP URL.
+func NewBackend(endpoint string) (*Backend, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer %s: %v", endpoint, err)
+	}
+	return &Backend{signers: []accounts.Wallet{NewSigner(client, endpoint)}}, nil
+}
+
+// Wallets implements accounts.Backend.
+func (b *Backend) Wallets() []accounts.Wallet {
+	cpy := make([]accounts.Wallet, len(b.signers))
+	copy(cpy, b.signers)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend. The wallet of an external signer never
+// changes, no events are sent.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Signer is an accounts.Wallet forwarding signing requests to an external
+// signer, which approves them. Passphrases are never forwarded and hashes
+// can't be signed, since they can't be reviewed by the user of the signer.
+// Messages are signed through SignText instead.
+type Signer struct {
+	client *rpc.Client
+	url    accounts.URL
+
+	lock     sync.RWMutex
+	accounts []accounts.Account // Accounts last listed by the signer
+}
+
+// NewSigner creates a wallet of the external signer reachable by the client.
+func NewSigner(client *rpc.Client, endpoint string) *Signer {
+	return &Signer{client: client, url: accounts.URL{Scheme: Scheme, Path: endpoint}}
+}
+
+// URL implements accounts.Wallet.
+func (s *Signer) URL() accounts.URL {
+	return s.url
+}
+
+// Status implements accounts.Wallet, checking the signer is reachable.
+func (s *Signer) Status() (string, error) {
+	if _, err := s.list(); err != nil {
+		return "Unreachable", err
+	}
+	return "Ok", nil
+}
+
+// Open implements accounts.Wallet, the connection is established on creation.
+func (s *Signer) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet.
+func (s *Signer) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// Accounts implements accounts.Wallet, retrieving the accounts of the signer.
+// If the signer is unreachable, the accounts last listed are returned.
+func (s *Signer) Accounts() []accounts.Account {
+	list, err := s.list()
+	if err != nil {
+		log.Warn("Failed to list external signer accounts", "url", s.url, "err", err)
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return append([]accounts.Account{}, s.accounts...)
+	}
+	return list
+}
+
+// list retrieves the accounts of the signer, caching them.
+func (s *Signer) list() ([]accounts.Account, error) {
+	var addresses []common.Address
+	if err := s.client.Call(&addresses, signer.Namespace+"_list"); err != nil {
+		return nil, err
+	}
+	list := make([]accounts.Account, len(addresses))
+	for i, addr := range addresses {
+		list[i] = accounts.Account{Address: addr, URL: s.url}
+	}
+	s.lock.Lock()
+	s.accounts = list
+	s.lock.Unlock()
+
+	return append([]accounts.Account{}, list...), nil
+}
+
+// Contains implements accounts.Wallet, checking the cached accounts first and
+// asking the signer only if not found.
+func (s *Signer) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != s.url {
+		return false
+	}
+	s.lock.RLock()
+	for _, a := range s.accounts {
+		if a.Address == account.Address {
+			s.lock.RUnlock()
+			return true
+		}
+	}
+	s.lock.RUnlock()
+
+	list, err := s.list()
+	if err != nil {
+		return false
+	}
+	for _, a := range list {
+		if a.Address == account.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported.
+func (s *Signer) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop.
+func (s *Signer) SelfDerive(base accounts.DerivationPath, chain wtc.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, but is not supported.
+func (s *Signer) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but is not supported.
+func (s *Signer) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignText requests the signer to sign the hash of the given message, prefixed
+// like eth_sign and personal_sign do, and verifies the signature. The message
+// itself is forwarded so the user of the signer can review it. The V value of
+// the returned signature is 0 or 1, like the ones of SignHash.
+func (s *Signer) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	var signature hexutil.Bytes
+	if err := s.client.Call(&signature, signer.Namespace+"_signData", account.Address, hexutil.Bytes(text)); err != nil {
+		return nil, err
+	}
+	if len(signature) != 65 || (signature[64] != 27 && signature[64] != 28) {
+		return nil, fmt.Errorf("invalid signature from external signer")
+	}
+	signature[64] -= 27 // Transform V from 27/28 back to 0/1
+
+	// Ensure the signer signed the requested message with the right key
+	pubkey, err := crypto.SigToPub(signer.SignHash(text), signature)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != account.Address {
+		return nil, fmt.Errorf("external signer signed with the wrong key")
+	}
+	return signature, nil
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported, the
+// external signer decides on the keys itself.
+func (s *Signer) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTx implements accounts.Wallet, requesting the signer to sign the
+// transaction and verifying the signature.
+func (s *Signer) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &signer.SignTxArgs{
+		From:     account.Address,
+		To:       tx.To(),
+		Gas:      hexutil.Big(*tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+		ChainID:  (*hexutil.Big)(chainID),
+	}
+	var res signer.SignTxResult
+	if err := s.client.Call(&res, signer.Namespace+"_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %v", err)
+	}
+	// Ensure the signer signed the requested transaction with the right key
+	var txSigner types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		txSigner = types.NewEIP155Signer(chainID)
+	}
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, fmt.Errorf("external signer modified the transaction")
+	}
+	if from, err := types.Sender(txSigner, signed); err != nil || from != account.Address {
+		return nil, fmt.Errorf("external signer signed with the wrong key")
+	}
+	return signed, nil
+}
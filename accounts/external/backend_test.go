@@ -0,0 +1,154 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/accounts/keystore"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/signer"
+)
+
+// testUI approves the requests as configured, counting them.
+type testUI struct {
+	approve  bool
+	requests int
+}
+
+func (ui *testUI) ApproveTx(req *signer.TxRequest) (signer.Approval, error) {
+	ui.requests++
+	return signer.Approval{Approved: ui.approve, Passphrase: "secret"}, nil
+}
+
+func (ui *testUI) ApproveData(req *signer.DataRequest) (signer.Approval, error) {
+	ui.requests++
+	return signer.Approval{Approved: ui.approve, Passphrase: "secret"}, nil
+}
+
+// newTestSigner creates an external signer wallet connected to an in-process
+// signer with a single keystore account.
+func newTestSigner(t *testing.T) (*Signer, accounts.Account, *testUI, func()) {
+	dir, err := ioutil.TempDir("", "external-signer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := accounts.NewManager(ks)
+	ui := &testUI{approve: true}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName(signer.Namespace, signer.NewAPI(am, ui)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	return NewSigner(client, "test"), account, ui, func() {
+		client.Close()
+		server.Stop()
+		am.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSignerAccounts(t *testing.T) {
+	wallet, account, _, teardown := newTestSigner(t)
+	defer teardown()
+
+	list := wallet.Accounts()
+	if len(list) != 1 || list[0].Address != account.Address || list[0].URL != wallet.URL() {
+		t.Fatalf("account list mismatch: have %v, want %x", list, account.Address)
+	}
+	if !wallet.Contains(accounts.Account{Address: account.Address}) {
+		t.Errorf("signer account not contained")
+	}
+	if wallet.Contains(accounts.Account{Address: common.Address{1}}) {
+		t.Errorf("unknown account contained")
+	}
+	if status, err := wallet.Status(); err != nil {
+		t.Errorf("unexpected status %q: %v", status, err)
+	}
+}
+
+func TestSignerSignTx(t *testing.T) {
+	wallet, account, ui, teardown := newTestSigner(t)
+	defer teardown()
+
+	chainID := big.NewInt(15)
+	tx := types.NewTransaction(3, common.Address{0xaa}, big.NewInt(100), big.NewInt(21000), big.NewInt(1), []byte{1, 2})
+
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(chainID), signed); err != nil || from != account.Address {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, account.Address)
+	}
+	if signed.Nonce() != 3 || signed.Value().Cmp(tx.Value()) != 0 {
+		t.Errorf("signed transaction mismatch: %v", signed)
+	}
+	// Denied requests and unsupported operations fail
+	ui.approve = false
+	if _, err := wallet.SignTx(account, tx, chainID); err == nil || !strings.Contains(err.Error(), signer.ErrRequestDenied.Error()) {
+		t.Errorf("denied request: have error %v, want %v", err, signer.ErrRequestDenied)
+	}
+	if _, err := wallet.SignTxWithPassphrase(account, "secret", tx, chainID); err != accounts.ErrNotSupported {
+		t.Errorf("passphrase signing: have error %v, want %v", err, accounts.ErrNotSupported)
+	}
+	if _, err := wallet.SignHash(account, make([]byte, 32)); err != accounts.ErrNotSupported {
+		t.Errorf("hash signing: have error %v, want %v", err, accounts.ErrNotSupported)
+	}
+	if ui.requests != 2 {
+		t.Errorf("approval request count mismatch: have %d, want 2", ui.requests)
+	}
+}
+
+func TestSignerSignText(t *testing.T) {
+	wallet, account, ui, teardown := newTestSigner(t)
+	defer teardown()
+
+	text := []byte("hello wtc")
+	signature, err := wallet.SignText(account, text)
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	if v := signature[64]; v != 0 && v != 1 {
+		t.Errorf("signature V mismatch: have %d, want 0 or 1", v)
+	}
+	pubkey, err := crypto.SigToPub(signer.SignHash(text), signature)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != account.Address {
+		t.Errorf("signature not made by the account: %v", err)
+	}
+	ui.approve = false
+	if _, err := wallet.SignText(account, text); err == nil || !strings.Contains(err.Error(), signer.ErrRequestDenied.Error()) {
+		t.Errorf("denied request: have error %v, want %v", err, signer.ErrRequestDenied)
+	}
+	if ui.requests != 2 {
+		t.Errorf("approval request count mismatch: have %d, want 2", ui.requests)
+	}
+}
@@ -34,6 +34,8 @@ type Manager struct {
 
 	feed event.Feed // Wallet feed notifying of arrivals/departures
 
+	policy TxPolicy // Policy vetting the transactions to sign, if any
+
 	quit chan chan error
 	lock sync.RWMutex
 }
@@ -126,7 +128,9 @@ func (am *Manager) Wallets() []Wallet {
 	defer am.lock.RUnlock()
 
 	cpy := make([]Wallet, len(am.wallets))
-	copy(cpy, am.wallets)
+	for i, wallet := range am.wallets {
+		cpy[i] = am.guard(wallet)
+	}
 	return cpy
 }
 
@@ -156,7 +160,7 @@ func (am *Manager) Find(account Account) (Wallet, error) {
 
 	for _, wallet := range am.wallets {
 		if wallet.Contains(account) {
-			return wallet, nil
+			return am.guard(wallet), nil
 		}
 	}
 	return nil, ErrUnknownAccount
@@ -0,0 +1,76 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"math/big"
+
+	"github.com/wtc/go-wtc/core/types"
+)
+
+// TxPolicy vets the transactions of accounts before they are signed by any of
+// the wallets of the account manager.
+type TxPolicy interface {
+	// Authorize checks a transaction an account is about to sign against the
+	// policy, calling sign if it's permitted. An error is returned if either the
+	// policy rejects the transaction or signing fails.
+	Authorize(account Account, tx *types.Transaction, sign func() error) error
+}
+
+// policyWallet is a wallet signing transactions only if permitted by a policy.
+type policyWallet struct {
+	Wallet
+	policy TxPolicy
+}
+
+// SignTx implements Wallet, vetting the transaction before signing it.
+func (w *policyWallet) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var signed *types.Transaction
+	err := w.policy.Authorize(account, tx, func() (err error) {
+		signed, err = w.Wallet.SignTx(account, tx, chainID)
+		return err
+	})
+	return signed, err
+}
+
+// SignTxWithPassphrase implements Wallet, vetting the transaction before signing
+// it.
+func (w *policyWallet) SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var signed *types.Transaction
+	err := w.policy.Authorize(account, tx, func() (err error) {
+		signed, err = w.Wallet.SignTxWithPassphrase(account, passphrase, tx, chainID)
+		return err
+	})
+	return signed, err
+}
+
+// SetTxPolicy installs a policy vetting all transactions signed by the wallets
+// retrieved from the account manager afterwards. A nil policy removes it.
+func (am *Manager) SetTxPolicy(policy TxPolicy) {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	am.policy = policy
+}
+
+// guard wraps a wallet to enforce the transaction policy, if any.
+func (am *Manager) guard(wallet Wallet) Wallet {
+	if am.policy == nil {
+		return wallet
+	}
+	return &policyWallet{Wallet: wallet, policy: am.policy}
+}
@@ -0,0 +1,496 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package policy implements a rules based transaction signing policy, vetting
+// the transactions of unlocked accounts before any key is used to sign them.
+//
+// The rules are read from a JSON file of the form:
+//
+//   {
+//     "audit": "signing-audit.jsonl",
+//     "default": {"maxGasPrice": "100000000000"},
+//     "accounts": {
+//       "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8": {
+//         "valueCap":   {"amount": "10000000000000000000", "period": "24h"},
+//         "recipients": ["0x8a5ae5ad8b5e4ed0d47e2c1bee1fe1b3ee1a56f8"],
+//         "methods":    ["0xa9059cbb"],
+//         "windows":    [{"days": ["Mon", "Tue", "Wed", "Thu", "Fri"], "from": "08:00", "to": "18:00"}]
+//       }
+//     }
+//   }
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/common/math"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/log"
+)
+
+// Config is the contents of a policy rules file.
+type Config struct {
+	// Audit is the file the audit records of all signing requests are appended
+	// to as JSON lines. Relative paths are resolved against the rules file. If
+	// empty, the records are only logged and value caps restart from zero with
+	// the policy.
+	Audit string `json:"audit,omitempty"`
+
+	// Default are the rules of the accounts without rules of their own. If nil,
+	// those accounts are unrestricted.
+	Default *Rules `json:"default,omitempty"`
+
+	// Accounts are the rules of individual accounts.
+	Accounts map[common.Address]*Rules `json:"accounts,omitempty"`
+}
+
+// Rules restrict the transactions an account may sign. Unset rules don't
+// restrict anything.
+type Rules struct {
+	// ValueCap limits the total value the account may transfer in a period.
+	ValueCap *ValueCap `json:"valueCap,omitempty"`
+
+	// Recipients are the addresses the account may send transactions to. If set,
+	// contract creations are only permitted if AllowCreate is set too.
+	Recipients  []common.Address `json:"recipients,omitempty"`
+	AllowCreate bool             `json:"allowCreate,omitempty"`
+
+	// Methods are the 4 byte selectors of the contract methods the account may
+	// call. Transactions without any data, i.e. plain transfers, are permitted.
+	Methods []hexutil.Bytes `json:"methods,omitempty"`
+
+	// MaxGasPrice is the highest gas price the account may pay.
+	MaxGasPrice *math.HexOrDecimal256 `json:"maxGasPrice,omitempty"`
+
+	// Windows are the times of day the account may sign in. Signing at any time
+	// is permitted if empty.
+	Windows []Window `json:"windows,omitempty"`
+}
+
+// ValueCap limits the value transferred by the transactions signed in a period.
+// The transactions signed within the preceding period are counted, replacement
+// transactions included. A zero period caps the value of single transactions.
+type ValueCap struct {
+	Amount *math.HexOrDecimal256 `json:"amount"`
+	Period Duration              `json:"period,omitempty"`
+}
+
+// Duration is a time.Duration in the notation of time.ParseDuration.
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(input []byte) error {
+	v, err := time.ParseDuration(string(input))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Window is a range of the time of day on some days of the week, in UTC. Windows
+// ending before they start span midnight.
+type Window struct {
+	Days []time.Weekday // Days the window is open on, every day if empty
+	From time.Duration  // Offset of the opening time since midnight
+	To   time.Duration  // Offset of the closing time since midnight
+}
+
+// window is the JSON representation of a Window.
+type window struct {
+	Days []string `json:"days,omitempty"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// UnmarshalJSON implements json.Unmarshaler, parsing days like "Mon" and times
+// of day like "17:30".
+func (w *Window) UnmarshalJSON(input []byte) error {
+	var dec window
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	var days []time.Weekday
+	for _, name := range dec.Days {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("invalid day %q", name)
+		}
+		days = append(days, day)
+	}
+	from, err := parseTimeOfDay(dec.From)
+	if err != nil {
+		return err
+	}
+	to, err := parseTimeOfDay(dec.To)
+	if err != nil {
+		return err
+	}
+	*w = Window{Days: days, From: from, To: to}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w Window) MarshalJSON() ([]byte, error) {
+	enc := window{From: formatTimeOfDay(w.From), To: formatTimeOfDay(w.To)}
+	for _, day := range w.Days {
+		enc.Days = append(enc.Days, day.String()[:3])
+	}
+	return json.Marshal(enc)
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want hh:mm", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// contains reports whether the window is open at the given time.
+func (w *Window) contains(t time.Time) bool {
+	t = t.UTC()
+	if len(w.Days) > 0 {
+		open := false
+		for _, day := range w.Days {
+			open = open || day == t.Weekday()
+		}
+		if !open {
+			return false
+		}
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.From <= w.To {
+		return offset >= w.From && offset < w.To
+	}
+	return offset >= w.From || offset < w.To
+}
+
+// ViolationError is returned for transactions rejected by the policy.
+type ViolationError struct {
+	Account common.Address // Account requesting the signature
+	Rule    string         // Name of the violated rule, e.g. valueCap
+	Reason  string         // Description of the violation
+}
+
+// Error implements error.
+func (err *ViolationError) Error() string {
+	return fmt.Sprintf("signing policy violation (%s) for %s: %s", err.Rule, err.Account.Hex(), err.Reason)
+}
+
+// Record is the audit record of a signing request.
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Account   common.Address  `json:"account"`
+	To        *common.Address `json:"to"`
+	Value     *big.Int        `json:"value"`
+	GasPrice  *big.Int        `json:"gasPrice"`
+	Nonce     uint64          `json:"nonce"`
+	Selector  hexutil.Bytes   `json:"selector,omitempty"`
+	Permitted bool            `json:"permitted"`
+	Violation string          `json:"violation,omitempty"` // Violated rule if rejected
+	Error     string          `json:"error,omitempty"`     // Reason of the rejection or signing failure
+}
+
+// spend is the value of a transaction signed at some time.
+type spend struct {
+	time  time.Time
+	value *big.Int
+}
+
+// Policy is an accounts.TxPolicy enforcing the rules of a Config.
+type Policy struct {
+	config Config
+	audit  string // Path of the audit file, if any
+
+	lock  sync.Mutex
+	spent map[common.Address][]spend // Transfers counting against value caps
+	now   func() time.Time
+}
+
+// Load reads a policy rules file.
+func Load(file string) (*Policy, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := new(Config)
+	if err := json.Unmarshal(blob, config); err != nil {
+		return nil, fmt.Errorf("invalid signing policy %s: %v", file, err)
+	}
+	if config.Audit != "" && !filepath.IsAbs(config.Audit) {
+		config.Audit = filepath.Join(filepath.Dir(file), config.Audit)
+	}
+	return New(config)
+}
+
+// New creates a policy enforcing the given rules.
+func New(config *Config) (*Policy, error) {
+	rules := []*Rules{config.Default}
+	for _, r := range config.Accounts {
+		rules = append(rules, r)
+	}
+	for _, r := range rules {
+		if r == nil {
+			continue
+		}
+		if r.ValueCap != nil && r.ValueCap.Amount == nil {
+			return nil, fmt.Errorf("value cap without amount")
+		}
+		for _, method := range r.Methods {
+			if len(method) != 4 {
+				return nil, fmt.Errorf("invalid method selector %s, want 4 bytes", method)
+			}
+		}
+	}
+	p := &Policy{
+		config: *config,
+		audit:  config.Audit,
+		spent:  make(map[common.Address][]spend),
+		now:    time.Now,
+	}
+	if err := p.replay(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// replay restores the transfers counting against the value caps from the audit
+// file, so they aren't reset by restarting the signer.
+func (p *Policy) replay() error {
+	if p.audit == "" {
+		return nil
+	}
+	f, err := os.Open(p.audit)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("invalid signing audit record %s:%d: %v", p.audit, line, err)
+		}
+		if !r.Permitted || r.Error != "" || r.Value == nil || r.Value.Sign() <= 0 {
+			continue
+		}
+		if rules := p.rules(r.Account); rules != nil && rules.ValueCap != nil && rules.ValueCap.Period > 0 {
+			p.spent[r.Account] = append(p.spent[r.Account], spend{r.Time, r.Value})
+		}
+	}
+	return scanner.Err()
+}
+
+// rules returns the rules applying to an account, nil if unrestricted.
+func (p *Policy) rules(account common.Address) *Rules {
+	if r, ok := p.config.Accounts[account]; ok {
+		return r
+	}
+	return p.config.Default
+}
+
+// Authorize implements accounts.TxPolicy, signing the transaction if permitted
+// by the rules of the account and recording the outcome in the audit log. The
+// requests are serialised to enforce the value caps.
+func (p *Policy) Authorize(account accounts.Account, tx *types.Transaction, sign func() error) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	record := &Record{
+		Time:     now.UTC(),
+		Account:  account.Address,
+		To:       tx.To(),
+		Value:    tx.Value(),
+		GasPrice: tx.GasPrice(),
+		Nonce:    tx.Nonce(),
+	}
+	if data := tx.Data(); len(data) >= 4 && tx.To() != nil {
+		record.Selector = common.CopyBytes(data[:4])
+	}
+	if err := p.check(account.Address, tx, now); err != nil {
+		record.Violation, record.Error = err.Rule, err.Reason
+		p.record(record)
+		return err
+	}
+	record.Permitted = true
+	if err := sign(); err != nil {
+		record.Error = err.Error()
+		p.record(record)
+		return err
+	}
+	if r := p.rules(account.Address); r != nil && r.ValueCap != nil && r.ValueCap.Period > 0 && tx.Value().Sign() > 0 {
+		p.spent[account.Address] = append(p.spent[account.Address], spend{now, tx.Value()})
+	}
+	p.record(record)
+	return nil
+}
+
+// check evaluates the rules of an account against a transaction.
+func (p *Policy) check(account common.Address, tx *types.Transaction, now time.Time) *ViolationError {
+	r := p.rules(account)
+	if r == nil {
+		return nil
+	}
+	violation := func(rule, format string, args ...interface{}) *ViolationError {
+		return &ViolationError{Account: account, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+	}
+	if len(r.Windows) > 0 {
+		open := false
+		for i := range r.Windows {
+			open = open || r.Windows[i].contains(now)
+		}
+		if !open {
+			return violation("windows", "signing not permitted at %s", now.UTC().Format("Mon 15:04 MST"))
+		}
+	}
+	if r.MaxGasPrice != nil && tx.GasPrice().Cmp((*big.Int)(r.MaxGasPrice)) > 0 {
+		return violation("maxGasPrice", "gas price %v above ceiling %v", tx.GasPrice(), (*big.Int)(r.MaxGasPrice))
+	}
+	if len(r.Recipients) > 0 {
+		if to := tx.To(); to == nil {
+			if !r.AllowCreate {
+				return violation("recipients", "contract creation not permitted")
+			}
+		} else if !containsAddress(r.Recipients, *to) {
+			return violation("recipients", "recipient %s not permitted", to.Hex())
+		}
+	}
+	if data := tx.Data(); len(r.Methods) > 0 && tx.To() != nil && len(data) > 0 {
+		if len(data) < 4 || !containsSelector(r.Methods, data[:4]) {
+			return violation("methods", "contract method %s not permitted", hexutil.Bytes(data[:minInt(len(data), 4)]))
+		}
+	}
+	if r.ValueCap != nil {
+		limit := (*big.Int)(r.ValueCap.Amount)
+		spent := p.spending(account, time.Duration(r.ValueCap.Period), now)
+		if total := new(big.Int).Add(spent, tx.Value()); total.Cmp(limit) > 0 {
+			if r.ValueCap.Period == 0 {
+				return violation("valueCap", "value %v above cap %v", tx.Value(), limit)
+			}
+			return violation("valueCap", "value %v exceeds cap %v per %v, %v already signed", tx.Value(), limit, time.Duration(r.ValueCap.Period), spent)
+		}
+	}
+	return nil
+}
+
+// spending sums the value an account signed within the period preceding now,
+// dropping older transfers.
+func (p *Policy) spending(account common.Address, period time.Duration, now time.Time) *big.Int {
+	total := new(big.Int)
+	if period == 0 {
+		return total
+	}
+	spends := p.spent[account]
+	for len(spends) > 0 && now.Sub(spends[0].time) >= period {
+		spends = spends[1:]
+	}
+	for _, s := range spends {
+		total.Add(total, s.value)
+	}
+	if len(spends) == 0 {
+		delete(p.spent, account)
+	} else {
+		p.spent[account] = spends
+	}
+	return total
+}
+
+// record logs an audit record and appends it to the audit file if configured.
+func (p *Policy) record(r *Record) {
+	ctx := []interface{}{"account", r.Account, "to", r.To, "value", r.Value, "gasprice", r.GasPrice, "nonce", r.Nonce}
+	switch {
+	case r.Violation != "":
+		log.Warn("Signing policy rejected transaction", append(ctx, "rule", r.Violation, "reason", r.Error)...)
+	case r.Error != "":
+		log.Warn("Policy permitted transaction failed to sign", append(ctx, "err", r.Error)...)
+	default:
+		log.Info("Signing policy permitted transaction", ctx...)
+	}
+	if p.audit == "" {
+		return
+	}
+	blob, err := json.Marshal(r)
+	if err != nil {
+		log.Error("Failed to encode signing audit record", "err", err)
+		return
+	}
+	f, err := os.OpenFile(p.audit, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		log.Error("Failed to open signing audit log", "file", p.audit, "err", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(blob, '\n')); err != nil {
+		log.Error("Failed to write signing audit log", "file", p.audit, "err", err)
+	}
+}
+
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, a := range list {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSelector(list []hexutil.Bytes, selector []byte) bool {
+	for _, s := range list {
+		if string(s) == string(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+)
+
+var (
+	payer     = common.HexToAddress("0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8")
+	recipient = common.HexToAddress("0x8a5ae5ad8b5e4ed0d47e2c1bee1fe1b3ee1a56f8")
+	stranger  = common.HexToAddress("0x1111111111111111111111111111111111111111")
+)
+
+const testRules = `{
+	"audit": "audit.jsonl",
+	"default": {"maxGasPrice": "100"},
+	"accounts": {
+		"0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8": {
+			"valueCap":   {"amount": "1000", "period": "1h"},
+			"recipients": ["0x8a5ae5ad8b5e4ed0d47e2c1bee1fe1b3ee1a56f8"],
+			"methods":    ["0xa9059cbb"],
+			"windows":    [{"days": ["Mon", "Tue"], "from": "22:00", "to": "02:00"}]
+		}
+	}
+}`
+
+// monday is a time within the signing window of the payer.
+var monday = time.Date(2017, time.October, 2, 23, 0, 0, 0, time.UTC)
+
+func loadTestPolicy(t *testing.T) (*Policy, string) {
+	dir, err := ioutil.TempDir("", "policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "rules.json")
+	if err := ioutil.WriteFile(file, []byte(testRules), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(file)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	p.now = func() time.Time { return monday }
+	return p, dir
+}
+
+func transfer(to *common.Address, value, gasPrice int64, data []byte) *types.Transaction {
+	if to == nil {
+		return types.NewContractCreation(0, big.NewInt(value), big.NewInt(21000), big.NewInt(gasPrice), data)
+	}
+	return types.NewTransaction(0, *to, big.NewInt(value), big.NewInt(21000), big.NewInt(gasPrice), data)
+}
+
+func authorize(p *Policy, from common.Address, tx *types.Transaction) (bool, error) {
+	signed := false
+	err := p.Authorize(accounts.Account{Address: from}, tx, func() error {
+		signed = true
+		return nil
+	})
+	return signed, err
+}
+
+func TestPolicyRules(t *testing.T) {
+	p, dir := loadTestPolicy(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		from common.Address
+		tx   *types.Transaction
+		rule string // Violated rule, empty if permitted
+	}{
+		{payer, transfer(&recipient, 400, 1000, nil), ""},
+		{payer, transfer(&recipient, 0, 1, []byte{0xa9, 0x05, 0x9c, 0xbb, 0x01}), ""},
+		{payer, transfer(&recipient, 0, 1, []byte{0x09, 0x5e, 0xa7, 0xb3}), "methods"},
+		{payer, transfer(&recipient, 0, 1, []byte{0xa9}), "methods"},
+		{payer, transfer(&stranger, 1, 1, nil), "recipients"},
+		{payer, transfer(nil, 0, 1, []byte{0x60}), "recipients"},
+		{payer, transfer(&recipient, 500, 1, nil), ""},
+		{payer, transfer(&recipient, 101, 1, nil), "valueCap"},
+		{stranger, transfer(&payer, 1000000, 100, nil), ""},
+		{stranger, transfer(&payer, 1, 101, nil), "maxGasPrice"},
+	}
+	for i, tt := range tests {
+		signed, err := authorize(p, tt.from, tt.tx)
+		if tt.rule == "" {
+			if err != nil || !signed {
+				t.Errorf("test %d: have signed %v, err %v, want permitted", i, signed, err)
+			}
+			continue
+		}
+		violation, ok := err.(*ViolationError)
+		if !ok || violation.Rule != tt.rule || signed {
+			t.Errorf("test %d: have signed %v, err %v, want %s violation", i, signed, err, tt.rule)
+		}
+	}
+	// Transfers leave the value cap period after an hour
+	p.now = func() time.Time { return monday.Add(time.Hour) }
+	if _, err := authorize(p, payer, transfer(&recipient, 1000, 1, nil)); err != nil {
+		t.Errorf("transfer after cap period: %v", err)
+	}
+	// Signing is only permitted within the windows, Tuesday 02:00 is past it
+	p.now = func() time.Time { return monday.Add(3 * time.Hour) }
+	if _, err := authorize(p, payer, transfer(&recipient, 0, 1, nil)); err == nil || err.(*ViolationError).Rule != "windows" {
+		t.Errorf("transfer outside window: have %v, want windows violation", err)
+	}
+}
+
+func TestPolicyAudit(t *testing.T) {
+	p, dir := loadTestPolicy(t)
+	defer os.RemoveAll(dir)
+
+	authorize(p, payer, transfer(&recipient, 1, 1, nil))
+	authorize(p, payer, transfer(&stranger, 1, 1, nil))
+
+	// Failed signatures don't count against the value cap
+	fail := errors.New("invalid passphrase")
+	if err := p.Authorize(accounts.Account{Address: payer}, transfer(&recipient, 999, 1, nil), func() error { return fail }); err != fail {
+		t.Fatalf("signing error mismatch: have %v, want %v", err, fail)
+	}
+	if _, err := authorize(p, payer, transfer(&recipient, 999, 1, nil)); err != nil {
+		t.Fatalf("transfer within cap rejected: %v", err)
+	}
+	f, err := os.Open(filepath.Join(dir, "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid audit record %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 4 {
+		t.Fatalf("audit record count mismatch: have %d, want 4", len(records))
+	}
+	if r := records[0]; !r.Permitted || r.Error != "" || *r.To != recipient || r.Value.Int64() != 1 {
+		t.Errorf("permitted record mismatch: %+v", r)
+	}
+	if r := records[1]; r.Permitted || r.Violation != "recipients" || r.Error == "" {
+		t.Errorf("rejected record mismatch: %+v", r)
+	}
+	if r := records[2]; !r.Permitted || r.Error != fail.Error() {
+		t.Errorf("failed record mismatch: %+v", r)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	tests := []string{
+		`{"default": {"methods": ["0xa9059c"]}}`,
+		`{"default": {"valueCap": {"period": "1h"}}}`,
+		`{"default": {"valueCap": {"amount": "1", "period": "1 hour"}}}`,
+		`{"default": {"windows": [{"days": ["Someday"], "from": "08:00", "to": "09:00"}]}}`,
+		`{"default": {"windows": [{"from": "8am", "to": "09:00"}]}}`,
+		`{"accounts": {"0x01": {}}}`,
+	}
+	for i, rules := range tests {
+		config := new(Config)
+		err := json.Unmarshal([]byte(rules), config)
+		if err == nil {
+			_, err = New(config)
+		}
+		if err == nil {
+			t.Errorf("test %d: invalid rules accepted: %s", i, rules)
+		}
+	}
+}
+
+// Tests that transfers counting against the value caps survive restarts by
+// being replayed from the audit log.
+func TestPolicyRestart(t *testing.T) {
+	p, dir := loadTestPolicy(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := authorize(p, payer, transfer(&recipient, 600, 1, nil)); err != nil {
+		t.Fatalf("transfer within cap rejected: %v", err)
+	}
+	fail := errors.New("invalid passphrase")
+	p.Authorize(accounts.Account{Address: payer}, transfer(&recipient, 300, 1, nil), func() error { return fail })
+	authorize(p, payer, transfer(&stranger, 300, 1, nil))
+
+	// Reload the policy and ensure only the signed transfer counts
+	restarted, err := Load(filepath.Join(dir, "rules.json"))
+	if err != nil {
+		t.Fatalf("failed to reload policy: %v", err)
+	}
+	restarted.now = func() time.Time { return monday.Add(30 * time.Minute) }
+	if _, err := authorize(restarted, payer, transfer(&recipient, 401, 1, nil)); err == nil || err.(*ViolationError).Rule != "valueCap" {
+		t.Errorf("transfer above cap after restart: have %v, want valueCap violation", err)
+	}
+	if _, err := authorize(restarted, payer, transfer(&recipient, 400, 1, nil)); err != nil {
+		t.Errorf("transfer within cap after restart rejected: %v", err)
+	}
+	// Corrupt audit logs are refused rather than resetting the caps
+	f, err := os.OpenFile(filepath.Join(dir, "audit.jsonl"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	f.WriteString("{\"time\":\n")
+	f.Close()
+
+	if _, err := Load(filepath.Join(dir, "rules.json")); err == nil {
+		t.Errorf("corrupt audit log accepted")
+	}
+}
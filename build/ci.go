@@ -79,6 +79,7 @@ var (
 		executablePath("rlpdump"),
 		executablePath("swarm"),
 		executablePath("wnode"),
+		executablePath("wtcsigner"),
 	}
 
 	// A debian package is created for all executables listed here.
@@ -115,6 +116,10 @@ var (
 			Name:        "wnode",
 			Description: "Wtc Whisper diagnostic tool",
 		},
+		{
+			Name:        "wtcsigner",
+			Description: "Standalone signer keeping the account keys out of the gwtc process.",
+		},
 	}
 
 	// Distros for which packages are created.
@@ -0,0 +1,399 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtc/go-wtc/cmd/utils"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/forkid"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/wtc"
+)
+
+var (
+	errHandshakeTimeout = errors.New("handshake timeout")
+	errStatusReceived   = errors.New("status received")
+	errUnexpectedPeer   = errors.New("unexpected peer")
+)
+
+// crawlMain implements the "bootnode crawl" subcommand, which enumerates the
+// nodes of the network via discovery and runs the RLPx and wtc handshakes with
+// each of them to find out their client version and chain.
+func crawlMain(args []string) {
+	var (
+		fs = flag.NewFlagSet("crawl", flag.ExitOnError)
+
+		nodesFile = fs.String("nodes", "", "JSON node set of a previous crawl to continue from")
+		out       = fs.String("out", "-", "file to write the crawled node set to as JSON")
+		crawlTime = fs.Duration("crawltime", time.Minute, "duration of the discovery crawl")
+		bootnodes = fs.String("bootnodes", "", "comma separated enode URLs to start the crawl from")
+		listen    = fs.String("addr", ":0", "listen address of the discovery protocol")
+		workers   = fs.Int("workers", 16, "number of nodes to contact concurrently")
+		timeout   = fs.Duration("timeout", 10*time.Second, "timeout of the handshakes with a single node")
+		verbosity = fs.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+	)
+	fs.Parse(args)
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	ns := make(nodeSet)
+	if *nodesFile != "" {
+		var err error
+		if ns, err = loadNodeSet(*nodesFile); err != nil {
+			utils.Fatalf("-nodes: %v", err)
+		}
+	}
+	urls := params.MainnetBootnodes
+	if *bootnodes != "" {
+		urls = strings.Split(*bootnodes, ",")
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate key: %v", err)
+	}
+	tab, err := discover.ListenUDP(key, *listen, nil, "", nil)
+	if err != nil {
+		utils.Fatalf("-addr: %v", err)
+	}
+	defer tab.Close()
+
+	c, err := newCrawler(tab, ns, key, *workers, *timeout)
+	if err != nil {
+		utils.Fatalf("Failed to start crawler: %v", err)
+	}
+	defer c.close()
+
+	if err := c.run(append(parseBootnodes(urls), ns.nodes()...), *crawlTime); err != nil {
+		utils.Fatalf("Crawl failed: %v", err)
+	}
+	if err := ns.write(*out); err != nil {
+		utils.Fatalf("-out: %v", err)
+	}
+	var handshakes int
+	for _, n := range ns {
+		if n.Status != nil {
+			handshakes++
+		}
+	}
+	log.Info("Crawl finished", "nodes", len(ns), "handshakes", handshakes)
+}
+
+// crawlRecords walks the discovery network for the given duration, requesting
+// the node record of every node found.
+func crawlRecords(addr string, bootnodes []*discover.Node, duration time.Duration) (nodeSet, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	tab, err := discover.ListenUDP(key, addr, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tab.Close()
+
+	ns := make(nodeSet)
+	c, err := newCrawler(tab, ns, nil, 16, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.run(bootnodes, duration); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// statusData and statusData64 mirror the wtc status messages before and from
+// protocol version 64 on.
+type (
+	statusData struct {
+		ProtocolVersion uint32
+		NetworkId       uint64
+		TD              *big.Int
+		CurrentBlock    common.Hash
+		GenesisBlock    common.Hash
+	}
+	statusData64 struct {
+		ProtocolVersion uint32
+		NetworkId       uint64
+		TD              *big.Int
+		CurrentBlock    common.Hash
+		GenesisBlock    common.Hash
+		ForkID          forkid.ID
+	}
+)
+
+func (s *statusData) toJSON() *nodeStatus {
+	return &nodeStatus{
+		ProtocolVersion: s.ProtocolVersion,
+		NetworkID:       s.NetworkId,
+		TD:              s.TD,
+		Head:            s.CurrentBlock,
+		Genesis:         s.GenesisBlock,
+	}
+}
+
+// handshakeResult is the outcome of the wtc handshake with a node.
+type handshakeResult struct {
+	client string
+	status *nodeStatus
+	err    error
+}
+
+// crawler enumerates the nodes of the network using random discovery lookups,
+// requesting the record of every node found and, if a key is configured, also
+// running the RLPx and wtc status handshakes with it.
+type crawler struct {
+	tab     *discover.Table
+	srv     *p2p.Server // Server running the handshakes, nil if disabled
+	ns      nodeSet
+	workers int
+	timeout time.Duration
+
+	pending map[discover.NodeID]chan handshakeResult // Handshakes in progress
+	lock    sync.Mutex                               // Protects ns and pending
+}
+
+// newCrawler creates a crawler adding the nodes it finds to the given set. The
+// handshakes are only run if a key is given.
+func newCrawler(tab *discover.Table, ns nodeSet, key *ecdsa.PrivateKey, workers int, timeout time.Duration) (*crawler, error) {
+	c := &crawler{
+		tab:     tab,
+		ns:      ns,
+		workers: workers,
+		timeout: timeout,
+		pending: make(map[discover.NodeID]chan handshakeResult),
+	}
+	if key == nil {
+		return c, nil
+	}
+	// Advertise all wtc protocol versions, only reading the remote status
+	protocols := make([]p2p.Protocol, len(eth.ProtocolVersions))
+	for i, version := range eth.ProtocolVersions {
+		version := version
+		protocols[i] = p2p.Protocol{
+			Name:    eth.ProtocolName,
+			Version: version,
+			Length:  eth.ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return c.readStatus(p, rw, version)
+			},
+		}
+	}
+	c.srv = &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    2 * workers,
+		Name:        common.MakeName("bootnode-crawler", params.Version),
+		NoDiscovery: true,
+		Protocols:   protocols,
+	}}
+	if err := c.srv.Start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// close stops the handshake server of the crawler.
+func (c *crawler) close() {
+	if c.srv != nil {
+		c.srv.Stop()
+	}
+}
+
+// run crawls the network for the given duration, starting out from the given
+// seed nodes. Nodes already found when the time is up are still contacted.
+func (c *crawler) run(seeds []*discover.Node, duration time.Duration) error {
+	if err := c.tab.SetFallbackNodes(seeds); err != nil {
+		return err
+	}
+	var (
+		queue    = make(chan *discover.Node)
+		tried    = make(map[discover.NodeID]bool)
+		deadline = time.Now().Add(duration)
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range queue {
+				c.visit(n)
+			}
+		}()
+	}
+	enqueue := func(nodes []*discover.Node) {
+		for _, n := range nodes {
+			if !tried[n.ID] {
+				tried[n.ID] = true
+				queue <- n
+			}
+		}
+	}
+	enqueue(seeds)
+	for time.Now().Before(deadline) {
+		var target discover.NodeID
+		rand.Read(target[:])
+
+		found := c.tab.Lookup(target)
+		if len(found) == 0 {
+			time.Sleep(time.Second) // Avoid spinning while the table is empty
+		}
+		enqueue(found)
+
+		c.lock.Lock()
+		log.Info("Crawling discovery network", "tried", len(tried), "nodes", len(c.ns))
+		c.lock.Unlock()
+	}
+	close(queue)
+	wg.Wait()
+	return nil
+}
+
+// visit requests the record of a node and runs the handshakes with it.
+func (c *crawler) visit(n *discover.Node) {
+	r, err := c.tab.RequestENR(n)
+	if err != nil {
+		log.Debug("Failed to request node record", "id", n.ID, "err", err)
+	}
+	c.lock.Lock()
+	switch {
+	case err == nil:
+		c.ns.add(r, time.Now())
+	case c.srv != nil:
+		// Nodes predating node records may still be reachable via RLPx
+		c.ns.addNode(n, time.Now())
+	}
+	c.lock.Unlock()
+
+	if c.srv == nil {
+		return
+	}
+	client, status, err := c.handshake(n)
+	if err != nil {
+		log.Debug("Failed to handshake with node", "id", n.ID, "err", err)
+	} else {
+		log.Debug("Handshake with node succeeded", "id", n.ID, "client", client, "network", status.NetworkID)
+	}
+	c.lock.Lock()
+	c.ns.setHandshake(n.ID, client, status, err)
+	c.lock.Unlock()
+}
+
+// handshake dials the node and waits for the status it sends in the wtc
+// handshake.
+func (c *crawler) handshake(n *discover.Node) (string, *nodeStatus, error) {
+	result := make(chan handshakeResult, 1)
+
+	c.lock.Lock()
+	c.pending[n.ID] = result
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, n.ID)
+		c.lock.Unlock()
+	}()
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", n.IP, n.TCP), c.timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	defer fd.Close()
+
+	go c.srv.SetupConn(fd, 0, n)
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+
+	select {
+	case res := <-result:
+		return res.client, res.status, res.err
+	case <-timeout.C:
+		return "", nil, errHandshakeTimeout
+	}
+}
+
+// readStatus reads the wtc status message of a connected peer and delivers it
+// to the pending handshake. The peer is disconnected afterwards.
+func (c *crawler) readStatus(p *p2p.Peer, rw p2p.MsgReadWriter, version uint) error {
+	c.lock.Lock()
+	result := c.pending[p.ID()]
+	c.lock.Unlock()
+
+	if result == nil {
+		return errUnexpectedPeer
+	}
+	res := handshakeResult{client: p.Name()}
+	res.status, res.err = decodeStatus(rw, version)
+	result <- res
+
+	return errStatusReceived
+}
+
+// decodeStatus reads the first message of a wtc protocol connection, which has
+// to be the status of the peer.
+func decodeStatus(rw p2p.MsgReader, version uint) (*nodeStatus, error) {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	defer msg.Discard()
+
+	if msg.Code != eth.StatusMsg {
+		return nil, fmt.Errorf("first message has code %d, want status", msg.Code)
+	}
+	if msg.Size > eth.ProtocolMaxMsgSize {
+		return nil, fmt.Errorf("status message too large: %d bytes", msg.Size)
+	}
+	// The status carries the fork identifier from protocol version 64 on
+	if version < 64 {
+		var status statusData
+		if err := msg.Decode(&status); err != nil {
+			return nil, fmt.Errorf("invalid status message: %v", err)
+		}
+		return status.toJSON(), nil
+	}
+	var status statusData64
+	if err := msg.Decode(&status); err != nil {
+		return nil, fmt.Errorf("invalid status message: %v", err)
+	}
+	res := &nodeStatus{
+		ProtocolVersion: status.ProtocolVersion,
+		NetworkID:       status.NetworkId,
+		TD:              status.TD,
+		Head:            status.CurrentBlock,
+		Genesis:         status.GenesisBlock,
+		ForkID:          &status.ForkID,
+	}
+	return res, nil
+}
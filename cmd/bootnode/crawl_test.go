@@ -0,0 +1,151 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+// startTestServer starts a p2p server on the loopback interface, running the
+// discovery protocol and a wtc protocol that only sends its status.
+func startTestServer(t *testing.T, name string, network uint64, bootnodes []*discover.Node) *p2p.Server {
+	// Reserve a port usable for both TCP and UDP, so the advertised endpoint is valid
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:     key,
+		MaxPeers:       10,
+		Name:           name,
+		ListenAddr:     addr,
+		BootstrapNodes: bootnodes,
+		NoDial:         true,
+		Protocols: []p2p.Protocol{{
+			Name:    "eth",
+			Version: 63,
+			Length:  17,
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				status := &statusData{
+					ProtocolVersion: 63,
+					NetworkId:       network,
+					TD:              big.NewInt(131072),
+					CurrentBlock:    common.HexToHash("0x01"),
+					GenesisBlock:    common.HexToHash("0x02"),
+				}
+				if err := p2p.Send(rw, 0, status); err != nil {
+					return err
+				}
+				for {
+					if _, err := rw.ReadMsg(); err != nil {
+						return err
+					}
+				}
+			},
+		}},
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	return srv
+}
+
+// Tests that the crawler finds all nodes of a small network, records their
+// handshake status, and that its output can be fed back into later crawls.
+func TestCrawl(t *testing.T) {
+	boot := startTestServer(t, "node-0", 77, nil)
+	defer boot.Stop()
+
+	servers := []*p2p.Server{boot}
+	for i := 1; i < 3; i++ {
+		srv := startTestServer(t, fmt.Sprintf("node-%d", i), 77, []*discover.Node{boot.Self()})
+		defer srv.Stop()
+		servers = append(servers, srv)
+	}
+	time.Sleep(500 * time.Millisecond) // Let the nodes bond with the bootnode
+
+	key, _ := crypto.GenerateKey()
+	tab, err := discover.ListenUDP(key, "127.0.0.1:0", nil, "", nil)
+	if err != nil {
+		t.Fatalf("failed to start discovery: %v", err)
+	}
+	defer tab.Close()
+
+	ns := make(nodeSet)
+	c, err := newCrawler(tab, ns, key, 4, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create crawler: %v", err)
+	}
+	defer c.close()
+
+	if err := c.run([]*discover.Node{boot.Self()}, 2*time.Second); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	for i, srv := range servers {
+		n, ok := ns[srv.Self().ID]
+		if !ok {
+			t.Errorf("node %d: not found", i)
+			continue
+		}
+		if n.Record == "" {
+			t.Errorf("node %d: missing record", i)
+		}
+		if n.Client != srv.Name {
+			t.Errorf("node %d: client mismatch: have %q, want %q", i, n.Client, srv.Name)
+		}
+		if n.Status == nil || n.Status.NetworkID != 77 || n.Status.TD.Int64() != 131072 {
+			t.Errorf("node %d: status mismatch: have %+v (error %q)", i, n.Status, n.Error)
+		}
+	}
+	// Write out the crawl and ensure the nodes can be loaded back
+	dir, err := ioutil.TempDir("", "crawl-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "nodes.json")
+	if err := ns.write(file); err != nil {
+		t.Fatalf("failed to write node set: %v", err)
+	}
+	loaded, err := loadNodeSet(file)
+	if err != nil {
+		t.Fatalf("failed to load node set: %v", err)
+	}
+	if have, want := len(loaded.nodes()), len(ns); have != want {
+		t.Errorf("loaded node count mismatch: have %d, want %d", have, want)
+	}
+	if have, want := len(loaded.records()), len(ns); have != want {
+		t.Errorf("loaded record count mismatch: have %d, want %d", have, want)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wtc/go-wtc/cmd/utils"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/p2p/dnsdisc"
+	"github.com/wtc/go-wtc/params"
+)
+
+// dnsMain implements the "bootnode dns" subcommand, which creates a signed DNS
+// node list tree from the nodes of a previous crawl, or crawls the discovery
+// network itself if no node set is given.
+func dnsMain(args []string) {
+	var (
+		fs = flag.NewFlagSet("dns", flag.ExitOnError)
+
+		keyFile   = fs.String("key", "", "private key file signing the tree")
+		domain    = fs.String("domain", "", "domain name the tree is published under")
+		seq       = fs.Uint("seq", uint(time.Now().Unix()), "sequence number of the tree")
+		links     = fs.String("links", "", "comma separated enrtree:// URLs of trees to link to")
+		nodesFile = fs.String("nodes", "", "JSON node set to build the tree from (crawls the network if not set)")
+		crawlTime = fs.Duration("crawltime", 30*time.Second, "duration of the discovery crawl")
+		bootnodes = fs.String("bootnodes", "", "comma separated enode URLs to start the crawl from")
+		listen    = fs.String("addr", ":0", "listen address of the crawler")
+		nodesOut  = fs.String("nodesout", "", "file to write the crawled node set to")
+		out       = fs.String("out", "-", "file to write the TXT records of the tree to as JSON")
+		verbosity = fs.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+	)
+	fs.Parse(args)
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	if *keyFile == "" {
+		utils.Fatalf("Use -key to specify the tree signing key")
+	}
+	if *domain == "" {
+		utils.Fatalf("Use -domain to specify the domain of the tree")
+	}
+	keyhex, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		utils.Fatalf("-key: %v", err)
+	}
+	key, err := crypto.LoadECDSA(strings.TrimSpace(string(keyhex)))
+	if err != nil {
+		utils.Fatalf("-key: %v", err)
+	}
+	// Gather the nodes to publish, either from file or a fresh crawl
+	var ns nodeSet
+	if *nodesFile != "" {
+		if ns, err = loadNodeSet(*nodesFile); err != nil {
+			utils.Fatalf("-nodes: %v", err)
+		}
+	} else {
+		urls := params.MainnetBootnodes
+		if *bootnodes != "" {
+			urls = strings.Split(*bootnodes, ",")
+		}
+		if ns, err = crawlRecords(*listen, parseBootnodes(urls), *crawlTime); err != nil {
+			utils.Fatalf("Crawl failed: %v", err)
+		}
+		if *nodesOut != "" {
+			if err := ns.write(*nodesOut); err != nil {
+				utils.Fatalf("-nodesout: %v", err)
+			}
+		}
+	}
+	var linkURLs []string
+	if *links != "" {
+		linkURLs = strings.Split(*links, ",")
+	}
+	// Create and sign the tree, then output its TXT records
+	tree, err := dnsdisc.MakeTree(*seq, ns.records(), linkURLs)
+	if err != nil {
+		utils.Fatalf("Failed to create tree: %v", err)
+	}
+	url, err := tree.Sign(key, *domain)
+	if err != nil {
+		utils.Fatalf("Failed to sign tree: %v", err)
+	}
+	if err := writeJSON(*out, tree.ToTXT(*domain)); err != nil {
+		utils.Fatalf("-out: %v", err)
+	}
+	log.Info("Created DNS node tree", "records", len(tree.Records()), "links", len(linkURLs), "seq", tree.Seq())
+	fmt.Fprintln(os.Stderr, url)
+}
+
+// parseBootnodes parses a list of enode URLs, aborting on invalid ones.
+func parseBootnodes(urls []string) []*discover.Node {
+	nodes := make([]*discover.Node, 0, len(urls))
+	for _, url := range urls {
+		n, err := discover.ParseNode(url)
+		if err != nil {
+			utils.Fatalf("Invalid bootnode %q: %v", url, err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
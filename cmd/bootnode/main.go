@@ -15,6 +15,9 @@
 // along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
 
 // bootnode runs a bootstrap node for the Wtc Discovery Protocol.
+//
+// Invoked as "bootnode dns", it creates signed DNS node lists instead, and as
+// "bootnode crawl" it enumerates the nodes of the network.
 package main
 
 import (
@@ -33,6 +36,16 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dns":
+			dnsMain(os.Args[2:])
+			return
+		case "crawl":
+			crawlMain(os.Args[2:])
+			return
+		}
+	}
 	var (
 		listenAddr  = flag.String("addr", ":10101", "listen address")
 		genKey      = flag.String("genkey", "", "generate a node key")
@@ -0,0 +1,175 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/forkid"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+// nodeJSON is the persisted form of a node found by a crawl.
+type nodeJSON struct {
+	Seq           uint64    `json:"seq"`
+	Record        string    `json:"record,omitempty"`
+	Enode         string    `json:"enode,omitempty"`
+	FirstResponse time.Time `json:"firstResponse,omitempty"`
+	LastResponse  time.Time `json:"lastResponse,omitempty"`
+
+	// Results of the last RLPx and wtc handshake, if any
+	Client string      `json:"client,omitempty"`
+	Status *nodeStatus `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// nodeStatus is the chain status a node reported in its wtc handshake.
+type nodeStatus struct {
+	ProtocolVersion uint32      `json:"protocolVersion"`
+	NetworkID       uint64      `json:"networkId"`
+	TD              *big.Int    `json:"td"`
+	Head            common.Hash `json:"head"`
+	Genesis         common.Hash `json:"genesis"`
+	ForkID          *forkid.ID  `json:"forkId,omitempty"`
+}
+
+// nodeSet is a set of crawled nodes keyed by their ID, stored as a JSON file
+// which can be fed back into later crawls and tree generation.
+type nodeSet map[discover.NodeID]nodeJSON
+
+// loadNodeSet reads a node set from a JSON file.
+func loadNodeSet(file string) (nodeSet, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ns nodeSet
+	if err := json.Unmarshal(blob, &ns); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// write stores the node set as indented JSON into the given file, or to the
+// standard output if the file name is "-".
+func (ns nodeSet) write(file string) error {
+	return writeJSON(file, ns)
+}
+
+// add inserts or refreshes a node in the set, keeping the most recent record.
+func (ns nodeSet) add(r *discover.Record, seen time.Time) {
+	id, err := r.NodeID()
+	if err != nil {
+		return
+	}
+	n, ok := ns[id]
+	if !ok {
+		n.FirstResponse = seen
+	}
+	if !ok || r.Seq() >= n.Seq {
+		n.Seq, n.Record = r.Seq(), r.String()
+		if node, err := r.Node(); err == nil && !node.Incomplete() {
+			n.Enode = node.String()
+		}
+	}
+	n.LastResponse = seen
+	ns[id] = n
+}
+
+// addNode inserts or refreshes a node which didn't provide a record.
+func (ns nodeSet) addNode(node *discover.Node, seen time.Time) {
+	n, ok := ns[node.ID]
+	if !ok {
+		n.FirstResponse = seen
+	}
+	n.Enode, n.LastResponse = node.String(), seen
+	ns[node.ID] = n
+}
+
+// setHandshake stores the outcome of a handshake with a node of the set.
+func (ns nodeSet) setHandshake(id discover.NodeID, client string, status *nodeStatus, err error) {
+	n, ok := ns[id]
+	if !ok {
+		return
+	}
+	n.Client, n.Status, n.Error = client, status, ""
+	if err != nil {
+		n.Error = err.Error()
+	}
+	ns[id] = n
+}
+
+// records parses the node records of the set, skipping invalid ones. The result
+// is sorted by node ID.
+func (ns nodeSet) records() []*discover.Record {
+	ids := make([]discover.NodeID, 0, len(ns))
+	for id := range ns {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	records := make([]*discover.Record, 0, len(ids))
+	for _, id := range ids {
+		r, err := discover.ParseRecord(ns[id].Record)
+		if err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// nodes returns the complete nodes of the set, as described by their records or
+// enode URLs.
+func (ns nodeSet) nodes() []*discover.Node {
+	var nodes []*discover.Node
+	for _, n := range ns {
+		if r, err := discover.ParseRecord(n.Record); err == nil {
+			if node, err := r.Node(); err == nil && !node.Incomplete() {
+				nodes = append(nodes, node)
+				continue
+			}
+		}
+		if node, err := discover.ParseNode(n.Enode); err == nil && !node.Incomplete() {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return bytes.Compare(nodes[i].ID[:], nodes[j].ID[:]) < 0 })
+	return nodes
+}
+
+// writeJSON stores a value as indented JSON into the given file, or to the
+// standard output if the file name is "-".
+func writeJSON(file string, value interface{}) error {
+	blob, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	blob = append(blob, '\n')
+	if file == "-" {
+		_, err := os.Stdout.Write(blob)
+		return err
+	}
+	return ioutil.WriteFile(file, blob, 0644)
+}
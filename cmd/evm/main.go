@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/wtc/go-wtc/cmd/utils"
+	"github.com/wtc/go-wtc/core/vm/profiler"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -114,6 +115,33 @@ var (
 		Name:  "nostack",
 		Usage: "disable stack output",
 	}
+	ProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: "aggregates gas and time per opcode, contract and pc range, writing the profile to the given path",
+	}
+	ProfileFormatFlag = cli.StringFlag{
+		Name:  "profile.format",
+		Usage: "profile output format (pprof, folded)",
+		Value: "pprof",
+	}
+	ProfileMetricFlag = cli.StringFlag{
+		Name:  "profile.metric",
+		Usage: "metric reported by folded profiles (gas, time)",
+		Value: "gas",
+	}
+	ProfileRangeFlag = cli.Uint64Flag{
+		Name:  "profile.range",
+		Usage: "size of the pc ranges the profile aggregates over",
+		Value: profiler.DefaultRangeSize,
+	}
+	ProfileSrcMapFlag = cli.StringFlag{
+		Name:  "profile.srcmap",
+		Usage: "file containing the solc source map of the executed code",
+	}
+	ProfileSourcesFlag = cli.StringFlag{
+		Name:  "profile.sources",
+		Usage: "comma separated list of the source files of the source map, in solc index order",
+	}
 )
 
 func init() {
@@ -138,6 +166,12 @@ func init() {
 		ReceiverFlag,
 		DisableMemoryFlag,
 		DisableStackFlag,
+		ProfileFlag,
+		ProfileFormatFlag,
+		ProfileMetricFlag,
+		ProfileRangeFlag,
+		ProfileSrcMapFlag,
+		ProfileSourcesFlag,
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
@@ -23,6 +23,8 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime/pprof"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	goruntime "runtime"
@@ -33,7 +35,9 @@ import (
 	"github.com/wtc/go-wtc/core"
 	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/core/vm/profiler"
 	"github.com/wtc/go-wtc/core/vm/runtime"
+	"github.com/wtc/go-wtc/crypto"
 	"github.com/wtc/go-wtc/wtcdb"
 	"github.com/wtc/go-wtc/log"
 	"github.com/wtc/go-wtc/params"
@@ -86,7 +90,14 @@ func runCmd(ctx *cli.Context) error {
 		sender      = common.StringToAddress("sender")
 		receiver    = common.StringToAddress("receiver")
 	)
-	if ctx.GlobalBool(MachineFlag.Name) {
+	var prof *profiler.Profiler
+	if ctx.GlobalString(ProfileFlag.Name) != "" {
+		if ctx.GlobalBool(MachineFlag.Name) || ctx.GlobalBool(DebugFlag.Name) {
+			utils.Fatalf("--%s can't be combined with --%s or --%s", ProfileFlag.Name, MachineFlag.Name, DebugFlag.Name)
+		}
+		prof = profiler.New(&profiler.Config{RangeSize: ctx.GlobalUint64(ProfileRangeFlag.Name)})
+		tracer = prof
+	} else if ctx.GlobalBool(MachineFlag.Name) {
 		tracer = NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.GlobalBool(DebugFlag.Name) {
 		debugLogger = vm.NewStructLogger(logconfig)
@@ -160,7 +171,7 @@ func runCmd(ctx *cli.Context) error {
 		Value:    utils.GlobalBig(ctx, ValueFlag.Name),
 		EVMConfig: vm.Config{
 			Tracer:             tracer,
-			Debug:              ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
+			Debug:              ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || prof != nil,
 			DisableGasMetering: ctx.GlobalBool(DisableGasMeteringFlag.Name),
 		},
 	}
@@ -181,6 +192,18 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
+	if prof != nil && ctx.GlobalString(ProfileSrcMapFlag.Name) != "" {
+		// Init code is executed together with its constructor arguments
+		profiled := code
+		if ctx.GlobalBool(CreateFlag.Name) {
+			profiled = append(common.CopyBytes(code), common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
+		}
+		sourceMap, err := loadSourceMap(ctx, code)
+		if err != nil {
+			utils.Fatalf("Failed to load source map: %v", err)
+		}
+		prof.AddSourceMap(crypto.Keccak256Hash(profiled), sourceMap)
+	}
 	tstart := time.Now()
 	var leftOverGas uint64
 	if ctx.GlobalBool(CreateFlag.Name) {
@@ -194,6 +217,14 @@ func runCmd(ctx *cli.Context) error {
 	}
 	execTime := time.Since(tstart)
 
+	if prof != nil {
+		prof.CaptureEnd(ret, initialGas-leftOverGas, execTime, err)
+		if err := writeProfile(ctx, prof); err != nil {
+			utils.Fatalf("Failed to write profile: %v", err)
+		}
+		tracer = nil
+	}
+
 	if ctx.GlobalBool(DumpFlag.Name) {
 		statedb.IntermediateRoot(true)
 		fmt.Println(string(statedb.Dump()))
@@ -244,3 +275,54 @@ Gas used:           %d
 
 	return nil
 }
+
+// loadSourceMap reads the solc source map and the source files given on the
+// command line and decodes them for the given code.
+func loadSourceMap(ctx *cli.Context, code []byte) (*profiler.SourceMap, error) {
+	srcmap, err := ioutil.ReadFile(ctx.GlobalString(ProfileSrcMapFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	if list := ctx.GlobalString(ProfileSourcesFlag.Name); list != "" {
+		files = strings.Split(list, ",")
+	}
+	sources := make([][]byte, len(files))
+	for i, file := range files {
+		if sources[i], err = ioutil.ReadFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return profiler.NewSourceMap(code, string(bytes.TrimSpace(srcmap)), files, sources)
+}
+
+// writeProfile writes the collected profile in the requested format and prints
+// a summary of the most expensive opcodes.
+func writeProfile(ctx *cli.Context, prof *profiler.Profiler) error {
+	f, err := os.Create(ctx.GlobalString(ProfileFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format := ctx.GlobalString(ProfileFormatFlag.Name); format {
+	case "pprof":
+		err = prof.WritePprof(f)
+	case "folded":
+		err = prof.WriteFolded(f, profiler.Metric(ctx.GlobalString(ProfileMetricFlag.Name)))
+	default:
+		err = fmt.Errorf("unknown profile format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "OPCODE\tCOUNT\tGAS\tTIME")
+	for i, stat := range prof.Opcodes() {
+		if i == 10 {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\n", stat.Op, stat.Count, stat.Gas, stat.Time)
+	}
+	return w.Flush()
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/wtc/go-wtc/cmd/utils"
 	"github.com/wtc/go-wtc/contracts/release"
+	"github.com/wtc/go-wtc/internal/debug"
 	"github.com/wtc/go-wtc/wtc"
 	"github.com/wtc/go-wtc/node"
 	"github.com/wtc/go-wtc/params"
@@ -75,11 +76,19 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+// logConfig holds the logging settings, overridden by the command line flags.
+// Zero values keep the settings of the flags.
+type logConfig struct {
+	Verbosity int    `toml:",omitempty"`
+	Vmodule   string `toml:",omitempty"`
+}
+
 type gwtcConfig struct {
 	Eth      eth.Config
 	Shh      whisper.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
+	Log      logConfig
 }
 
 func loadConfig(file string, cfg *gwtcConfig) error {
@@ -97,6 +106,17 @@ func loadConfig(file string, cfg *gwtcConfig) error {
 	return err
 }
 
+// readConfigFile loads a config file over the default config.
+func readConfigFile(file string) (gwtcConfig, error) {
+	cfg := gwtcConfig{
+		Eth:  eth.DefaultConfig,
+		Shh:  whisper.DefaultConfig,
+		Node: defaultNodeConfig(),
+	}
+	err := loadConfig(file, &cfg)
+	return cfg, err
+}
+
 func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
@@ -123,6 +143,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gwtcConfig) {
 	}
 
 	// Apply flags.
+	setLogConfig(ctx, &cfg.Log)
 	utils.SetNodeConfig(ctx, &cfg.Node)
 	stack, err := node.New(&cfg.Node)
 	if err != nil {
@@ -138,6 +159,23 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gwtcConfig) {
 	return stack, cfg
 }
 
+// setLogConfig applies the logging settings of the config file unless overridden
+// by the command line flags, and records the flags in the config otherwise.
+func setLogConfig(ctx *cli.Context, cfg *logConfig) {
+	if ctx.GlobalIsSet("verbosity") {
+		cfg.Verbosity = ctx.GlobalInt("verbosity")
+	} else if cfg.Verbosity != 0 {
+		debug.Handler.Verbosity(cfg.Verbosity)
+	}
+	if ctx.GlobalIsSet("vmodule") {
+		cfg.Vmodule = ctx.GlobalString("vmodule")
+	} else if cfg.Vmodule != "" {
+		if err := debug.Handler.Vmodule(cfg.Vmodule); err != nil {
+			utils.Fatalf("Invalid vmodule in config file: %v", err)
+		}
+	}
+}
+
 // enableWhisper returns true in case one of the whisper flags is set.
 func enableWhisper(ctx *cli.Context) bool {
 	for _, flag := range whisperFlags {
@@ -166,11 +204,19 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Add the GraphQL endpoint if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts)
+	}
+
 	// Add the Wtc Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
 	}
 
+	// Add the config reloader, it updates the services registered before.
+	registerConfigReloader(stack, ctx.GlobalString(configFileFlag.Name))
+
 	// Add the release oracle service so it boots along with node.
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		config := release.Config{
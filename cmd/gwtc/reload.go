@@ -0,0 +1,290 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/wtc/go-wtc/cmd/utils"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/internal/debug"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/wtc"
+)
+
+// errNoConfigFile is returned when reloading the config of a node started
+// without a config file.
+var errNoConfigFile = errors.New("node was started without a config file")
+
+// liveTxPoolLimits are the transaction pool settings applied by SetLimits.
+var liveTxPoolLimits = map[string]bool{
+	"PriceBump":    true,
+	"AccountSlots": true,
+	"GlobalSlots":  true,
+	"AccountQueue": true,
+	"GlobalQueue":  true,
+	"Lifetime":     true,
+}
+
+// ConfigChange is a single setting changed in the config file.
+type ConfigChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReloadResult reports the settings changed in the config file since it was
+// last loaded, split into those applied to the running node, those failing to
+// apply and those taking effect only after a restart.
+type ReloadResult struct {
+	Applied         []ConfigChange `json:"applied"`
+	Failed          []ConfigChange `json:"failed"`
+	RestartRequired []ConfigChange `json:"restartRequired"`
+}
+
+// configReloader is a service re-reading the config file of the node, applying
+// the settings that are safe to change while it's running.
+type configReloader struct {
+	file  string
+	stack *node.Node
+	eth   *eth.Wtc // nil for light clients
+
+	lock sync.Mutex
+	last gwtcConfig // Config file contents as last applied, over the defaults
+}
+
+// registerConfigReloader adds the service providing admin_reloadConfig. It must
+// be registered after the Wtc service to be able to update it.
+func registerConfigReloader(stack *node.Node, file string) {
+	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		r := &configReloader{file: file, stack: stack}
+		if file != "" {
+			cfg, err := readConfigFile(file)
+			if err != nil {
+				return nil, err
+			}
+			r.last = cfg
+		}
+		var ethereum *eth.Wtc
+		if err := ctx.Service(&ethereum); err == nil {
+			r.eth = ethereum
+		}
+		return r, nil
+	})
+	if err != nil {
+		utils.Fatalf("Failed to register the config reloader: %v", err)
+	}
+}
+
+// Protocols implements node.Service.
+func (r *configReloader) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the admin API of the reloader.
+func (r *configReloader) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   &PrivateConfigAPI{r},
+	}}
+}
+
+// Start implements node.Service.
+func (r *configReloader) Start(*p2p.Server) error { return nil }
+
+// Stop implements node.Service.
+func (r *configReloader) Stop() error { return nil }
+
+// PrivateConfigAPI is the admin API reloading the config file of the node.
+type PrivateConfigAPI struct {
+	r *configReloader
+}
+
+// ReloadConfig re-reads the config file and applies the settings changed since
+// it was last loaded that can be changed without a restart. A setting changed
+// in the file overrides the command line flag it was set by.
+func (api *PrivateConfigAPI) ReloadConfig() (*ReloadResult, error) {
+	return api.r.reload()
+}
+
+// reload diffs the config file against the last applied contents and applies
+// the changes. Settings requiring a restart or failing to apply are reported
+// again on subsequent reloads.
+func (r *configReloader) reload() (*ReloadResult, error) {
+	if r.file == "" {
+		return nil, errNoConfigFile
+	}
+	cfg, err := readConfigFile(r.file)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result := &ReloadResult{
+		Applied:         []ConfigChange{},
+		Failed:          []ConfigChange{},
+		RestartRequired: []ConfigChange{},
+	}
+	for _, change := range diffConfig("", reflect.ValueOf(r.last), reflect.ValueOf(cfg)) {
+		live, err := r.apply(change.Field, &cfg)
+		switch {
+		case !live:
+			result.RestartRequired = append(result.RestartRequired, change)
+		case err != nil:
+			change.Error = err.Error()
+			result.Failed = append(result.Failed, change)
+		default:
+			copyField(reflect.ValueOf(&r.last).Elem(), reflect.ValueOf(cfg), change.Field)
+			result.Applied = append(result.Applied, change)
+		}
+	}
+	log.Info("Reloaded config file", "file", r.file, "applied", len(result.Applied),
+		"failed", len(result.Failed), "restart", len(result.RestartRequired))
+	return result, nil
+}
+
+// apply updates the running node with a changed setting of the config, and
+// reports whether the setting can be changed live at all.
+func (r *configReloader) apply(field string, cfg *gwtcConfig) (bool, error) {
+	switch field {
+	case "Log.Verbosity":
+		// Removing the setting restores the flag, only known at startup
+		if cfg.Log.Verbosity == 0 {
+			return false, nil
+		}
+		debug.Handler.Verbosity(cfg.Log.Verbosity)
+		return true, nil
+
+	case "Log.Vmodule":
+		if cfg.Log.Vmodule == "" {
+			return false, nil
+		}
+		return true, debug.Handler.Vmodule(cfg.Log.Vmodule)
+
+	case "Node.HTTPCors":
+		return true, r.stack.SetHTTPCors(cfg.Node.HTTPCors)
+
+	case "Node.HTTPVirtualHosts":
+		return true, r.stack.SetHTTPVirtualHosts(cfg.Node.HTTPVirtualHosts)
+
+	case "Node.WSOrigins":
+		return true, r.stack.SetWSOrigins(cfg.Node.WSOrigins)
+
+	case "Node.P2P.MaxPeers":
+		server := r.stack.Server()
+		if server == nil {
+			return true, node.ErrNodeStopped
+		}
+		server.SetMaxPeers(cfg.Node.P2P.MaxPeers)
+		if r.eth != nil {
+			r.eth.SetMaxPeers(cfg.Node.P2P.MaxPeers)
+		}
+		return true, nil
+	}
+	if r.eth == nil {
+		return false, nil
+	}
+	switch field {
+	case "Eth.Etherbase":
+		r.eth.SetEtherbase(cfg.Eth.Etherbase)
+		return true, nil
+
+	case "Eth.ExtraData":
+		return true, r.eth.Miner().SetExtra(cfg.Eth.ExtraData)
+
+	case "Eth.GasPrice":
+		if cfg.Eth.GasPrice == nil {
+			return true, errors.New("gas price not set")
+		}
+		r.eth.SetGasPrice(cfg.Eth.GasPrice)
+		return true, nil
+
+	case "Eth.TxPool.PriceLimit":
+		r.eth.TxPool().SetGasPrice(new(big.Int).SetUint64(cfg.Eth.TxPool.PriceLimit))
+		return true, nil
+	}
+	if name := strings.TrimPrefix(field, "Eth.TxPool."); liveTxPoolLimits[name] {
+		pool := r.eth.TxPool()
+		limits := pool.Limits()
+		copyField(reflect.ValueOf(&limits).Elem(), reflect.ValueOf(cfg.Eth.TxPool), name)
+		pool.SetLimits(limits)
+		return true, nil
+	}
+	return false, nil
+}
+
+// diffConfig returns the settings differing between two configs, descending
+// into nested structs. Fields not stored in the config file are ignored.
+func diffConfig(path string, old, new reflect.Value) []ConfigChange {
+	if old.Kind() != reflect.Struct || !hasSettings(old.Type()) {
+		if reflect.DeepEqual(old.Interface(), new.Interface()) {
+			return nil
+		}
+		return []ConfigChange{{Field: path, Old: formatSetting(old), New: formatSetting(new)}}
+	}
+	var changes []ConfigChange
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if field.PkgPath != "" || field.Tag.Get("toml") == "-" {
+			continue
+		}
+		name := field.Name
+		if path != "" {
+			name = path + "." + name
+		}
+		changes = append(changes, diffConfig(name, old.Field(i), new.Field(i))...)
+	}
+	return changes
+}
+
+// hasSettings reports whether a struct has any fields stored in the config file.
+func hasSettings(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.PkgPath == "" && field.Tag.Get("toml") != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// copyField sets the field at the dotted path of a struct to its value in src.
+func copyField(dst, src reflect.Value, path string) {
+	for _, name := range strings.Split(path, ".") {
+		dst, src = dst.FieldByName(name), src.FieldByName(name)
+	}
+	dst.Set(src)
+}
+
+// formatSetting renders the value of a setting for the reload report.
+func formatSetting(v reflect.Value) string {
+	switch {
+	case (v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil():
+		return ""
+	case v.Type() == reflect.TypeOf([]byte(nil)):
+		return hexutil.Encode(v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/p2p"
+)
+
+func changedFields(changes []ConfigChange) []string {
+	fields := []string{}
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	return fields
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir := tmpdir(t)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.toml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("[Node.P2P]\nMaxPeers = 10\n")
+
+	stack, err := node.New(&node.Config{DataDir: dir, P2P: p2p.Config{MaxPeers: 10, NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	registerConfigReloader(stack, file)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	var reloader *configReloader
+	if err := stack.Service(&reloader); err != nil {
+		t.Fatalf("config reloader not registered: %v", err)
+	}
+	// Change live and restart-only settings, the latter including those of the
+	// Wtc service missing from the stack
+	write("[Eth]\nNetworkId = 5\n[Node]\nHTTPCors = [\"localhost\"]\n[Node.P2P]\nMaxPeers = 20\nListenAddr = \":1234\"\n")
+
+	for i := 0; i < 2; i++ {
+		result, err := reloader.reload()
+		if err != nil {
+			t.Fatalf("reload %d: failed: %v", i, err)
+		}
+		wantApplied := []string{"Node.P2P.MaxPeers", "Node.HTTPCors"}
+		if i > 0 {
+			wantApplied = []string{}
+		}
+		if fields := changedFields(result.Applied); !reflect.DeepEqual(fields, wantApplied) {
+			t.Errorf("reload %d: applied mismatch: have %v, want %v", i, fields, wantApplied)
+		}
+		wantRestart := []string{"Eth.NetworkId", "Node.P2P.ListenAddr"}
+		if fields := changedFields(result.RestartRequired); !reflect.DeepEqual(fields, wantRestart) {
+			t.Errorf("reload %d: restart mismatch: have %v, want %v", i, fields, wantRestart)
+		}
+		if len(result.Failed) != 0 {
+			t.Errorf("reload %d: unexpected failures: %+v", i, result.Failed)
+		}
+		if i == 0 && len(result.Applied) == 2 {
+			want := ConfigChange{Field: "Node.P2P.MaxPeers", Old: "10", New: "20"}
+			if result.Applied[0] != want {
+				t.Errorf("change mismatch: have %+v, want %+v", result.Applied[0], want)
+			}
+		}
+	}
+}
+
+func TestReloadConfigWithoutFile(t *testing.T) {
+	reloader := &configReloader{}
+	if _, err := reloader.reload(); err != errNoConfigFile {
+		t.Errorf("error mismatch: have %v, want %v", err, errNoConfigFile)
+	}
+}
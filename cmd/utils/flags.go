@@ -19,13 +19,17 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wtc/go-wtc/accounts"
 	"github.com/wtc/go-wtc/accounts/keystore"
@@ -35,6 +39,7 @@ import (
 	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/vm"
 	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/graphql"
 	"github.com/wtc/go-wtc/wtc"
 	"github.com/wtc/go-wtc/wtc/downloader"
 	"github.com/wtc/go-wtc/wtc/gasprice"
@@ -43,6 +48,8 @@ import (
 	"github.com/wtc/go-wtc/les"
 	"github.com/wtc/go-wtc/log"
 	"github.com/wtc/go-wtc/metrics"
+	"github.com/wtc/go-wtc/metrics/influxdb"
+	"github.com/wtc/go-wtc/metrics/prometheus"
 	"github.com/wtc/go-wtc/node"
 	"github.com/wtc/go-wtc/p2p"
 	"github.com/wtc/go-wtc/p2p/discover"
@@ -50,7 +57,9 @@ import (
 	"github.com/wtc/go-wtc/p2p/nat"
 	"github.com/wtc/go-wtc/p2p/netutil"
 	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rpc"
 	whisper "github.com/wtc/go-wtc/whisper/whisperv5"
+	gometrics "github.com/rcrowley/go-metrics"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -307,11 +316,23 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer to forward signing requests to (IPC path or HTTP URL)",
+	}
+	SigningPolicyFlag = cli.StringFlag{
+		Name:  "signer.policy",
+		Usage: "Rules file of the policy vetting transactions before they are signed",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMExternalFlag = cli.StringFlag{
+		Name:  "vm.external",
+		Usage: "Go plugin implementing an external EVM interpreter",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -321,6 +342,36 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Serve the metrics in the Prometheus format on the given HTTP listening address (e.g. 127.0.0.1:6061)",
+	}
+	MetricsInfluxDBFlag = cli.BoolFlag{
+		Name:  "metrics.influxdb",
+		Usage: "Push the metrics to an InfluxDB server",
+	}
+	MetricsInfluxDBEndpointFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.endpoint",
+		Usage: "InfluxDB API endpoint to push the metrics to",
+		Value: "http://localhost:8086",
+	}
+	MetricsInfluxDBDatabaseFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.database",
+		Usage: "InfluxDB database name to push the metrics to",
+		Value: "gwtc",
+	}
+	MetricsInfluxDBUsernameFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.username",
+		Usage: "Username to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBPasswordFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.password",
+		Usage: "Password to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBTagsFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.tags",
+		Usage: "Comma separated list of key=value tags added to all pushed metrics (e.g. host=node1)",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -361,6 +412,72 @@ var (
 		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
 		Value: "",
 	}
+	RPCVirtualHostsFlag = cli.StringFlag{
+		Name:  "rpcvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpcbatchlimit",
+		Usage: "Maximum number of requests in an RPC batch (0 = unlimited)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpcresponselimit",
+		Usage: "Maximum size in bytes of an RPC response or batch response (0 = unlimited)",
+	}
+	RPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rpctimeout",
+		Usage: "Execution deadline of RPC method calls (0 = unlimited)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Maximum number of RPC requests per second per remote client (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpcrateburst",
+		Usage: "Number of RPC requests a remote client may burst above the rate limit",
+	}
+	HealthEnabledFlag = cli.BoolFlag{
+		Name:  "health",
+		Usage: "Enable the /health and /ready endpoints on the HTTP-RPC server",
+	}
+	HealthMinPeersFlag = cli.IntFlag{
+		Name:  "health.minpeers",
+		Usage: "Minimum number of peers of a ready node",
+		Value: node.DefaultHealthConfig.MinPeers,
+	}
+	HealthMaxHeadAgeFlag = cli.DurationFlag{
+		Name:  "health.maxheadage",
+		Usage: "Maximum age of the head block of a ready node (0 = unchecked)",
+		Value: node.DefaultHealthConfig.MaxHeadAge,
+	}
+	HealthMaxBlockLagFlag = cli.Uint64Flag{
+		Name:  "health.maxblocklag",
+		Usage: "Maximum number of blocks a ready node may lag behind its best peer",
+		Value: node.DefaultHealthConfig.MaxBlockLag,
+	}
+	RPCAccessLogFlag = cli.Float64Flag{
+		Name:  "rpcaccesslog",
+		Usage: "Fraction of RPC calls to write to the access log, between 0 and 1 (0 = disabled)",
+	}
+	RPCAccessLogSlowFlag = cli.DurationFlag{
+		Name:  "rpcaccesslogslow",
+		Usage: "Duration above which RPC calls are always written to the access log (0 = disabled)",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL query endpoint on the HTTP-RPC server at /graphql",
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept GraphQL cross origin requests (browser enforced)",
+		Value: "",
+	}
+	GraphQLVirtualHostsFlag = cli.StringFlag{
+		Name:  "graphql.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept GraphQL requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
@@ -438,6 +555,11 @@ var (
 		Usage: "Comma separated enode URLs for P2P v5 discovery bootstrap (light server, light nodes)",
 		Value: "",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS published node lists for P2P discovery bootstrap",
+		Value: "",
+	}
 	NodeKeyFileFlag = cli.StringFlag{
 		Name:  "nodekey",
 		Usage: "P2P node key file",
@@ -604,6 +726,20 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// setDNSDiscovery sets the DNS node lists to bootstrap discovery from, if any
+// have been specified on the command line.
+func setDNSDiscovery(ctx *cli.Context, cfg *p2p.Config) {
+	if !ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		return
+	}
+	cfg.DNSDiscovery = nil
+	for _, url := range strings.Split(ctx.GlobalString(DNSDiscoveryFlag.Name), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			cfg.DNSDiscovery = append(cfg.DNSDiscovery, url)
+		}
+	}
+}
+
 // setListenAddress creates a TCP listening address string from set command
 // line flags.
 func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
@@ -660,6 +796,84 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
+		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
+		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
+	}
+}
+
+// setRPCLimits applies the RPC resource limits from the command line flags,
+// keeping any configured ones not overridden.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	flags := []string{RPCBatchLimitFlag.Name, RPCResponseLimitFlag.Name, RPCTimeoutFlag.Name, RPCRateLimitFlag.Name, RPCRateBurstFlag.Name}
+	set := false
+	for _, name := range flags {
+		set = set || ctx.GlobalIsSet(name)
+	}
+	if !set {
+		return
+	}
+	if cfg.RPCLimits == nil {
+		cfg.RPCLimits = new(rpc.Limits)
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCLimits.ResponseBytes = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		cfg.RPCLimits.ExecutionTime = ctx.GlobalDuration(RPCTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.RateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCLimits.RateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+}
+
+// setRPCAccessLog applies the RPC access log settings from the command line
+// flags, keeping any configured ones not overridden.
+func setRPCAccessLog(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCAccessLogFlag.Name) && !ctx.GlobalIsSet(RPCAccessLogSlowFlag.Name) {
+		return
+	}
+	if cfg.RPCAccessLog == nil {
+		cfg.RPCAccessLog = new(rpc.AccessLog)
+	}
+	if ctx.GlobalIsSet(RPCAccessLogFlag.Name) {
+		cfg.RPCAccessLog.SampleRate = ctx.GlobalFloat64(RPCAccessLogFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAccessLogSlowFlag.Name) {
+		cfg.RPCAccessLog.SlowThreshold = ctx.GlobalDuration(RPCAccessLogSlowFlag.Name)
+	}
+}
+
+// setHealth enables the health and readiness endpoints if requested by the
+// command line flags, keeping any configured thresholds not overridden.
+func setHealth(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalBool(HealthEnabledFlag.Name) {
+		return
+	}
+	if cfg.Health == nil {
+		config := node.DefaultHealthConfig
+		cfg.Health = &config
+	}
+	if ctx.GlobalIsSet(HealthMinPeersFlag.Name) {
+		cfg.Health.MinPeers = ctx.GlobalInt(HealthMinPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxHeadAgeFlag.Name) {
+		cfg.Health.MaxHeadAge = ctx.GlobalDuration(HealthMaxHeadAgeFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxBlockLagFlag.Name) {
+		cfg.Health.MaxBlockLag = ctx.GlobalUint64(HealthMaxBlockLagFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -778,6 +992,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setDiscoveryV5Address(ctx, cfg)
 	setBootstrapNodes(ctx, cfg)
 	setBootstrapNodesV5(ctx, cfg)
+	setDNSDiscovery(ctx, cfg)
 
 	if ctx.GlobalIsSet(MaxPeersFlag.Name) {
 		cfg.MaxPeers = ctx.GlobalInt(MaxPeersFlag.Name)
@@ -823,6 +1038,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCAccessLog(ctx, cfg)
+	setHealth(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -845,6 +1063,12 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(SigningPolicyFlag.Name) {
+		cfg.SigningPolicy = ctx.GlobalString(SigningPolicyFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -992,6 +1216,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMExternalFlag.Name) {
+		cfg.EVMInterpreter = ctx.GlobalString(VMExternalFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1067,6 +1294,67 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL query endpoint to the HTTP-RPC server
+// of the given node, serving the data of its full or light client.
+func RegisterGraphQLService(stack *node.Node, cors, vhosts []string) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		// Retrieve both eth and les services
+		var ethServ *eth.Wtc
+		ctx.Service(&ethServ)
+
+		var lesServ *les.LightWtc
+		ctx.Service(&lesServ)
+
+		switch {
+		case ethServ != nil:
+			return graphql.New(ethServ.ApiBackend, cors, vhosts)
+		case lesServ != nil:
+			return graphql.New(lesServ.ApiBackend, cors, vhosts)
+		}
+		return nil, errors.New("GraphQL needs a full or light client service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
+// SetupMetrics starts the metrics exporters requested on the command line: the
+// Prometheus HTTP endpoint and the InfluxDB push reporter.
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled {
+		return
+	}
+	if addr := ctx.GlobalString(MetricsHTTPFlag.Name); addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			Fatalf("Failed to start the metrics HTTP server: %v", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle(prometheus.Path, prometheus.Handler(gometrics.DefaultRegistry))
+		go http.Serve(listener, mux)
+		log.Info("Metrics HTTP server started", "url", "http://"+listener.Addr().String()+prometheus.Path)
+	}
+	if ctx.GlobalBool(MetricsInfluxDBFlag.Name) {
+		tags := make(map[string]string)
+		for _, tag := range splitAndTrim(ctx.GlobalString(MetricsInfluxDBTagsFlag.Name)) {
+			kv := strings.SplitN(tag, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				Fatalf("Invalid InfluxDB tag %q, expected key=value", tag)
+			}
+			tags[kv[0]] = kv[1]
+		}
+		config := influxdb.Config{
+			Endpoint:  ctx.GlobalString(MetricsInfluxDBEndpointFlag.Name),
+			Database:  ctx.GlobalString(MetricsInfluxDBDatabaseFlag.Name),
+			Username:  ctx.GlobalString(MetricsInfluxDBUsernameFlag.Name),
+			Password:  ctx.GlobalString(MetricsInfluxDBPasswordFlag.Name),
+			Namespace: "gwtc/",
+			Tags:      tags,
+		}
+		go influxdb.Report(gometrics.DefaultRegistry, 10*time.Second, config)
+		log.Info("Pushing metrics to InfluxDB", "endpoint", config.Endpoint, "database", config.Database)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1121,6 +1409,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Fatalf("%v", err)
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	if path := ctx.GlobalString(VMExternalFlag.Name); path != "" {
+		if vmcfg.External, err = vm.LoadExternalInterpreter(path); err != nil {
+			Fatalf("Failed to load external EVM interpreter: %v", err)
+		}
+	}
 	chain, err = core.NewBlockChain(chainDb, config, engine, vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
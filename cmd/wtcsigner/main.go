@@ -0,0 +1,194 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+// wtcsigner is a standalone signer owning the keystore and hardware wallets,
+// signing the requests of gwtc nodes started with --signer once approved.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/accounts/keystore"
+	"github.com/wtc/go-wtc/accounts/policy"
+	"github.com/wtc/go-wtc/accounts/usbwallet"
+	"github.com/wtc/go-wtc/cmd/utils"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/signer"
+)
+
+func main() {
+	var (
+		keystoreDir = flag.String("keystore", filepath.Join(node.DefaultDataDir(), "keystore"), "directory of the keystore")
+		lightKDF    = flag.Bool("lightkdf", false, "reduce key-derivation RAM & CPU usage at some expense of KDF strength")
+		noUSB       = flag.Bool("nousb", false, "disable monitoring for and managing USB hardware wallets")
+		ipcPath     = flag.String("ipcpath", filepath.Join(node.DefaultDataDir(), "wtcsigner.ipc"), "IPC endpoint to serve the signer API on, empty to disable")
+		httpEnabled = flag.Bool("http", false, "serve the signer API over HTTP too (unauthenticated, not allowed with -auto)")
+		httpAddr    = flag.String("http.addr", "localhost", "HTTP listening interface")
+		httpPort    = flag.Int("http.port", 8550, "HTTP listening port")
+		httpVHosts  = flag.String("http.vhosts", "localhost", "comma separated list of virtual hostnames to accept HTTP requests from")
+		rulesFile   = flag.String("rules", "", "signing policy rules file vetting all transactions")
+		auto        = flag.Bool("auto", false, "approve all transactions permitted by the rules without prompting, reject data signing")
+		unlock      = flag.String("unlock", "", "comma separated list of accounts to unlock for automatic approval")
+		password    = flag.String("password", "", "password file to unlock the accounts with, one password per line")
+		verbosity   = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-9)")
+		vmodule     = flag.String("vmodule", "", "log verbosity pattern")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	glogger.Vmodule(*vmodule)
+	log.Root().SetHandler(glogger)
+
+	if *auto && *rulesFile == "" {
+		utils.Fatalf("Automatic approval requires a -rules file")
+	}
+	// The HTTP endpoint is unauthenticated, anyone reaching it could get their
+	// transactions approved without a user confirming them.
+	if *auto && *httpEnabled {
+		utils.Fatalf("Automatic approval is not allowed over the unauthenticated -http endpoint")
+	}
+	if *ipcPath == "" && !*httpEnabled {
+		utils.Fatalf("No endpoint enabled, use -ipcpath or -http")
+	}
+	// Assemble the wallets, vetting all transactions by the rules if given
+	am, ks := makeAccountManager(*keystoreDir, *lightKDF, *noUSB)
+	defer am.Close()
+
+	if *rulesFile != "" {
+		p, err := policy.Load(*rulesFile)
+		if err != nil {
+			utils.Fatalf("Failed to load signing rules: %v", err)
+		}
+		am.SetTxPolicy(p)
+	}
+	if *unlock != "" {
+		unlockAccounts(ks, strings.Split(*unlock, ","), *password)
+	}
+	var ui signer.UI = &commandlineUI{}
+	if *auto {
+		ui = &autoUI{}
+	}
+	// Serve the signer API on the requested endpoints
+	server := rpc.NewServer()
+	if err := server.RegisterName(signer.Namespace, signer.NewAPI(am, ui)); err != nil {
+		utils.Fatalf("Failed to register the signer API: %v", err)
+	}
+	defer server.Stop()
+
+	if *ipcPath != "" {
+		listener, err := rpc.CreateIPCListener(*ipcPath)
+		if err != nil {
+			utils.Fatalf("Failed to open IPC endpoint: %v", err)
+		}
+		defer listener.Close()
+		go server.ServeListener(listener)
+		log.Info("IPC endpoint opened", "path", *ipcPath)
+	}
+	if *httpEnabled {
+		endpoint := fmt.Sprintf("%s:%d", *httpAddr, *httpPort)
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			utils.Fatalf("Failed to open HTTP endpoint: %v", err)
+		}
+		defer listener.Close()
+		go rpc.NewHTTPServer(nil, splitList(*httpVHosts), server).Serve(listener)
+		log.Info("HTTP endpoint opened", "url", "http://"+endpoint)
+	}
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	<-sigc
+	log.Info("Got interrupt, shutting down...")
+}
+
+// makeAccountManager creates an account manager of the keystore and, unless
+// disabled, the USB hardware wallets.
+func makeAccountManager(keydir string, lightKDF, noUSB bool) (*accounts.Manager, *keystore.KeyStore) {
+	scryptN, scryptP := keystore.StandardScryptN, keystore.StandardScryptP
+	if lightKDF {
+		scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	}
+	if err := os.MkdirAll(keydir, 0700); err != nil {
+		utils.Fatalf("Failed to create keystore: %v", err)
+	}
+	ks := keystore.NewKeyStore(keydir, scryptN, scryptP)
+	backends := []accounts.Backend{ks}
+	if !noUSB {
+		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
+			log.Warn("Failed to start Ledger hub, disabling", "err", err)
+		} else {
+			backends = append(backends, ledgerhub)
+		}
+		if trezorhub, err := usbwallet.NewTrezorHub(); err != nil {
+			log.Warn("Failed to start Trezor hub, disabling", "err", err)
+		} else {
+			backends = append(backends, trezorhub)
+		}
+	}
+	return accounts.NewManager(backends...), ks
+}
+
+// unlockAccounts unlocks keystore accounts indefinitely with the passwords of
+// a password file, the last password being used for any remaining accounts.
+func unlockAccounts(ks *keystore.KeyStore, addresses []string, file string) {
+	if file == "" {
+		utils.Fatalf("Unlocking accounts requires a -password file")
+	}
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		utils.Fatalf("Failed to read password file: %v", err)
+	}
+	passwords := strings.Split(string(blob), "\n")
+	for i := range passwords {
+		passwords[i] = strings.TrimRight(passwords[i], "\r")
+	}
+	for i, addr := range addresses {
+		addr = strings.TrimSpace(addr)
+		if !common.IsHexAddress(addr) {
+			utils.Fatalf("Invalid account address %q", addr)
+		}
+		password := passwords[len(passwords)-1]
+		if i < len(passwords) {
+			password = passwords[i]
+		}
+		account := accounts.Account{Address: common.HexToAddress(addr)}
+		if err := ks.Unlock(account, password); err != nil {
+			utils.Fatalf("Failed to unlock account %s: %v", addr, err)
+		}
+		log.Info("Unlocked account", "address", account.Address.Hex())
+	}
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-wtc.
+//
+// go-wtc is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-wtc is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-wtc. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/console"
+	"github.com/wtc/go-wtc/signer"
+)
+
+// commandlineUI approves signing requests by prompting the user on the terminal.
+type commandlineUI struct{}
+
+// ApproveTx implements signer.UI, showing the transaction and asking for the
+// passphrase of the account.
+func (ui *commandlineUI) ApproveTx(req *signer.TxRequest) (signer.Approval, error) {
+	tx := req.Tx
+	fmt.Println("-------- Transaction signing request --------")
+	fmt.Printf("From:      %s\n", req.Account.Address.Hex())
+	if to := tx.To(); to != nil {
+		fmt.Printf("To:        %s\n", to.Hex())
+	} else {
+		fmt.Printf("To:        <contract creation>\n")
+	}
+	fmt.Printf("Value:     %v wei\n", tx.Value())
+	fmt.Printf("Gas:       %v\n", tx.Gas())
+	fmt.Printf("Gas price: %v wei\n", tx.GasPrice())
+	fmt.Printf("Nonce:     %d\n", tx.Nonce())
+	if req.ChainID != nil {
+		fmt.Printf("Chain ID:  %v\n", req.ChainID)
+	}
+	if data := tx.Data(); len(data) > 0 {
+		fmt.Printf("Data:      %s\n", hexutil.Bytes(data))
+	}
+	return ui.approve()
+}
+
+// ApproveData implements signer.UI, showing the data and asking for the
+// passphrase of the account.
+func (ui *commandlineUI) ApproveData(req *signer.DataRequest) (signer.Approval, error) {
+	fmt.Println("-------- Data signing request --------")
+	fmt.Printf("Account:   %s\n", req.Account.Address.Hex())
+	if utf8.Valid(req.Data) {
+		fmt.Printf("Message:   %q\n", req.Data)
+	} else {
+		fmt.Printf("Data:      %s\n", hexutil.Bytes(req.Data))
+	}
+	return ui.approve()
+}
+
+func (ui *commandlineUI) approve() (signer.Approval, error) {
+	ok, err := console.Stdin.PromptConfirm("Approve?")
+	if err != nil || !ok {
+		return signer.Approval{}, err
+	}
+	passphrase, err := console.Stdin.PromptPassword("Passphrase (empty if unlocked): ")
+	if err != nil {
+		return signer.Approval{}, err
+	}
+	return signer.Approval{Approved: true, Passphrase: passphrase}, nil
+}
+
+// autoUI approves all transaction signing requests, leaving the decision to
+// the signing policy. The policy doesn't cover data, which is never signed.
+type autoUI struct{}
+
+// ApproveTx implements signer.UI.
+func (ui *autoUI) ApproveTx(req *signer.TxRequest) (signer.Approval, error) {
+	return signer.Approval{Approved: true}, nil
+}
+
+// ApproveData implements signer.UI.
+func (ui *autoUI) ApproveData(req *signer.DataRequest) (signer.Approval, error) {
+	return signer.Approval{}, nil
+}
@@ -60,6 +60,10 @@ var (
 	errInvalidPoW        = errors.New("invalid proof-of-work")
 )
 
+// ErrTargetOverflow is returned by VerifyX11Seal if the weighted target of a
+// header exceeds 256 bits, so the seal can't be compared against it.
+var ErrTargetOverflow = errors.New("proof-of-work target exceeds 256 bits")
+
 // Author implements consensus.Engine, returning the header's coinbase as the
 // proof-of-work verified author of the block.
 func (ethash *Ethash) Author(header *types.Header) (common.Address, error) {
@@ -541,30 +545,6 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 			return errInvalidDifficulty
 		}
 	}
-
-	var orderHash []byte
-	if header.Number.Cmp(params.HardForkV1) >= 0 {
-		if header.Number.Cmp(params.HardForkV3) >= 0 {
-			set := header.Number.Bytes()
-			origin := sha256.New()
-			origin.Write(set)
-			origin.Write([]byte("HardForkV3"))
-			orderHash = origin.Sum(nil)
-		} else if header.Number.Cmp(params.HardForkV2) >= 0 {
-			set := header.Number.Bytes()
-			origin := sha256.New()
-			origin.Write(set)
-			orderHash = origin.Sum([]byte("HardForkV2"))
-		} else {
-			set := header.Number.Bytes()
-			origin := sha256.New()
-			origin.Write(set)
-			orderHash = origin.Sum(nil)
-		}
-	} else {
-		orderHash = header.HashNoNonce().Bytes()
-	}
-
 	// If we're running a fake PoW, accept any seal as valid
 	if ethash.fakeMode {
 		time.Sleep(ethash.fakeDelay)
@@ -591,32 +571,102 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 		fmt.Printf("YWQ:errInvalidDifficulty\n")
 		return errInvalidDifficulty
 	}
-	// Recompute the digest and PoW value and verify against the header
-	/*cache := ethash.cache(number)
-
-	size := datasetSize(number)
-	if ethash.tester {
-		size = 32 * 1024
-	}*/
-	//digest, result := hashimotoLight(size, cache, header.HashNoNonce().Bytes(), header.Nonce.Uint64())
-	//-----------------------------------------------
-
-	order := getX11Order(orderHash, 11)
-	digest, result := myx11(header.HashNoNonce().Bytes(), header.Nonce.Uint64(), order)
-	if !bytes.Equal(header.MixDigest[:], digest) {
+	if !posShareCheck {
+		difficulty = header.Difficulty
+	}
+	// Post HardForkV2 the target is weighted by the current coinbase balance
+	var balance *big.Int
+	if header.Number.Cmp(params.HardForkV2) >= 0 {
+		balance, _, _, _ = chain.GetBalanceAndCoinAgeByHeaderHash(header.Coinbase)
+	}
+	err := verifyX11PoW(header, difficulty, balance)
+	if err == errInvalidMixDigest {
 		fmt.Printf("YWQ:errInvalidMixDigest\n")
-		return errInvalidMixDigest
 	}
-	// target := new(big.Int).Div(maxUint256, header.Difficulty)
+	return err
+}
+
+// VerifyX11Seal checks whether the given header satisfies the X11 proof-of-work
+// requirements without needing access to a chain. The balance is the coinbase
+// balance used to weight the target from HardForkV2 onwards and is ignored for
+// headers before that fork.
+func VerifyX11Seal(header *types.Header, balance *big.Int) error {
+	if header.Number == nil || header.Difficulty == nil {
+		return errInvalidDifficulty
+	}
+	if header.Number.Cmp(params.HardForkV2) == 0 && header.Difficulty.Cmp(params.HardForkV2diff) != 0 {
+		return errInvalidDifficulty
+	}
+	if header.Number.Uint64()/epochLength >= uint64(len(cacheSizes)) {
+		return errNonceOutOfRange
+	}
+	if header.Difficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	result, target, err := x11PoW(header, header.Difficulty, balance)
+	if err != nil {
+		return err
+	}
+	if target.BitLen() > 256 {
+		return ErrTargetOverflow
+	}
+	if Compare(result, FullTo32(target.Bytes()), 32) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
 
-	target := new(big.Int)
-	if posShareCheck {
-		target = new(big.Int).Div(maxUint256, difficulty)
+// X11Order returns the sequence of X11 hash functions used to seal the given
+// header, encoded as one letter ('A'-'K') per round.
+func X11Order(header *types.Header) []byte {
+	var orderHash []byte
+	if header.Number.Cmp(params.HardForkV1) >= 0 {
+		if header.Number.Cmp(params.HardForkV3) >= 0 {
+			set := header.Number.Bytes()
+			origin := sha256.New()
+			origin.Write(set)
+			origin.Write([]byte("HardForkV3"))
+			orderHash = origin.Sum(nil)
+		} else if header.Number.Cmp(params.HardForkV2) >= 0 {
+			set := header.Number.Bytes()
+			origin := sha256.New()
+			origin.Write(set)
+			orderHash = origin.Sum([]byte("HardForkV2"))
+		} else {
+			set := header.Number.Bytes()
+			origin := sha256.New()
+			origin.Write(set)
+			orderHash = origin.Sum(nil)
+		}
 	} else {
-		target = new(big.Int).Div(maxUint256, header.Difficulty)
+		orderHash = header.HashNoNonce().Bytes()
+	}
+	return getX11Order(orderHash, 11)
+}
+
+// verifyX11PoW recomputes the X11 digest of the header and checks it against the
+// target derived from the given difficulty, weighted by the coin age before
+// HardForkV2 and by the coinbase balance afterwards.
+func verifyX11PoW(header *types.Header, difficulty *big.Int, balance *big.Int) error {
+	result, target, err := x11PoW(header, difficulty, balance)
+	if err != nil {
+		return err
 	}
+	if Compare(result, FullTo32(target.Bytes()), 32) > 0 {
+		return errInvalidPoW
+	}
+	return nil
+}
 
-	// targetOrigin := target
+// x11PoW recomputes the X11 digest of the header, returning the PoW value along
+// with the weighted target it needs to meet.
+func x11PoW(header *types.Header, difficulty *big.Int, balance *big.Int) ([]byte, *big.Int, error) {
+	order := X11Order(header)
+	digest, result := myx11(header.HashNoNonce().Bytes(), header.Nonce.Uint64(), order)
+	if !bytes.Equal(header.MixDigest[:], digest) {
+		return nil, nil, errInvalidMixDigest
+	}
+	target := new(big.Int).Div(maxUint256, difficulty)
 
 	var bn_txnumber *big.Int
 	if header.Number.Cmp(params.HardForkV1) >= 0 {
@@ -626,17 +676,20 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 	}
 
 	if header.Number.Cmp(params.HardForkV2) >= 0 {
-		balance, _, _, _ := chain.GetBalanceAndCoinAgeByHeaderHash(header.Coinbase)
+		if balance == nil {
+			balance = new(big.Int)
+		}
 		target = TargetDiff(balance, target)
 	} else {
 		coinage := header.CoinAge
+		if coinage == nil {
+			coinage = new(big.Int)
+		}
 		bn_coinage := new(big.Int).Mul(coinage, big.NewInt(1))
 		bn_coinage = Sqrt(bn_coinage, 6)
 		if bn_coinage.Cmp(big.NewInt(0)) > 0 {
 			target.Mul(bn_coinage, target)
 		}
-		// fmt.Printf("X11 coinage: %d\n", coinage)
-		// fmt.Printf("X11 bn_coinage: %d\n", bn_coinage)
 	}
 
 	if header.Number.Cmp(params.HardForkV1) >= 0 {
@@ -646,25 +699,7 @@ func (ethash *Ethash) VerifySeal(chain consensus.ChainReader, header *types.Head
 		}
 	}
 
-	// fmt.Printf("X11 order    : %s\n", order)
-	// fmt.Printf("X11 targeto  : %x\n", FullTo32(targetOrigin.Bytes()))
-	// fmt.Printf("X11 targetd  : %x\n", FullTo32(target.Bytes()))
-	// fmt.Printf("X11 targetdiv: %d\n", new(big.Int).Div(target, targetOrigin))
-	// fmt.Printf("X11 result   : %x\n", result)
-	// fmt.Printf("X11 miner    : %x\n", header.Coinbase)
-
-	// fmt.Printf("X11 order    : %s\n", order)
-	// fmt.Printf("X11 targeto  : %x\n", FullTo32(new(big.Int).Div(maxUint256, header.Difficulty).Bytes()))
-	// fmt.Printf("X11 targetd  : %x\n", FullTo32(target.Bytes()))
-	// fmt.Printf("X11 targetdiv: %d\n", new(big.Int).Div(target, new(big.Int).Div(maxUint256, header.Difficulty)))
-	// fmt.Printf("X11 result   : %x\n", result)
-	// fmt.Printf("X11 miner    : %x\n", header.Coinbase)
-
-	if Compare(result, FullTo32(target.Bytes()), 32) > 0 {
-		// fmt.Printf("YWQ:FullTo32  errInvalidPoW\n")
-		return errInvalidPoW
-	}
-	return nil
+	return result, target, nil
 }
 
 // Prepare implements consensus.Engine, initializing the difficulty field of a
@@ -0,0 +1,238 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package checkpointoracle
+
+import (
+	"strings"
+
+	"github.com/wtc/go-wtc/accounts/abi"
+	"github.com/wtc/go-wtc/accounts/abi/bind"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+)
+
+// CheckpointOracleABI is the input ABI used to generate the binding from.
+const CheckpointOracleABI = "[{\"constant\":true,\"inputs\":[],\"name\":\"admins\",\"outputs\":[{\"name\":\"\",\"type\":\"address[]\"}],\"type\":\"function\"},{\"constant\":true,\"inputs\":[],\"name\":\"latestCheckpoint\",\"outputs\":[{\"name\":\"index\",\"type\":\"uint64\"},{\"name\":\"sectionHead\",\"type\":\"bytes32\"},{\"name\":\"chtRoot\",\"type\":\"bytes32\"},{\"name\":\"bloomRoot\",\"type\":\"bytes32\"},{\"name\":\"sigs\",\"type\":\"bytes\"}],\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"name\":\"index\",\"type\":\"uint64\"},{\"name\":\"sectionHead\",\"type\":\"bytes32\"},{\"name\":\"chtRoot\",\"type\":\"bytes32\"},{\"name\":\"bloomRoot\",\"type\":\"bytes32\"},{\"name\":\"v\",\"type\":\"uint8[]\"},{\"name\":\"r\",\"type\":\"bytes32[]\"},{\"name\":\"s\",\"type\":\"bytes32[]\"}],\"name\":\"setCheckpoint\",\"outputs\":[{\"name\":\"\",\"type\":\"bool\"}],\"type\":\"function\"},{\"inputs\":[{\"name\":\"admins\",\"type\":\"address[]\"},{\"name\":\"sigThreshold\",\"type\":\"uint256\"}],\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"name\":\"index\",\"type\":\"uint64\"},{\"indexed\":false,\"name\":\"checkpointHash\",\"type\":\"bytes32\"}],\"name\":\"NewCheckpoint\",\"type\":\"event\"}]"
+
+// CheckpointOracle is an auto generated Go binding around an Wtc contract.
+type CheckpointOracle struct {
+	CheckpointOracleCaller     // Read-only binding to the contract
+	CheckpointOracleTransactor // Write-only binding to the contract
+}
+
+// CheckpointOracleCaller is an auto generated read-only Go binding around an Wtc contract.
+type CheckpointOracleCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// CheckpointOracleTransactor is an auto generated write-only Go binding around an Wtc contract.
+type CheckpointOracleTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// CheckpointOracleSession is an auto generated Go binding around an Wtc contract,
+// with pre-set call and transact options.
+type CheckpointOracleSession struct {
+	Contract     *CheckpointOracle // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// CheckpointOracleCallerSession is an auto generated read-only Go binding around an Wtc contract,
+// with pre-set call options.
+type CheckpointOracleCallerSession struct {
+	Contract *CheckpointOracleCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts           // Call options to use throughout this session
+}
+
+// CheckpointOracleTransactorSession is an auto generated write-only Go binding around an Wtc contract,
+// with pre-set transact options.
+type CheckpointOracleTransactorSession struct {
+	Contract     *CheckpointOracleTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts           // Transaction auth options to use throughout this session
+}
+
+// CheckpointOracleRaw is an auto generated low-level Go binding around an Wtc contract.
+type CheckpointOracleRaw struct {
+	Contract *CheckpointOracle // Generic contract binding to access the raw methods on
+}
+
+// CheckpointOracleCallerRaw is an auto generated low-level read-only Go binding around an Wtc contract.
+type CheckpointOracleCallerRaw struct {
+	Contract *CheckpointOracleCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// CheckpointOracleTransactorRaw is an auto generated low-level write-only Go binding around an Wtc contract.
+type CheckpointOracleTransactorRaw struct {
+	Contract *CheckpointOracleTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewCheckpointOracle creates a new instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracle(address common.Address, backend bind.ContractBackend) (*CheckpointOracle, error) {
+	contract, err := bindCheckpointOracle(address, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracle{CheckpointOracleCaller: CheckpointOracleCaller{contract: contract}, CheckpointOracleTransactor: CheckpointOracleTransactor{contract: contract}}, nil
+}
+
+// NewCheckpointOracleCaller creates a new read-only instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracleCaller(address common.Address, caller bind.ContractCaller) (*CheckpointOracleCaller, error) {
+	contract, err := bindCheckpointOracle(address, caller, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleCaller{contract: contract}, nil
+}
+
+// NewCheckpointOracleTransactor creates a new write-only instance of CheckpointOracle, bound to a specific deployed contract.
+func NewCheckpointOracleTransactor(address common.Address, transactor bind.ContractTransactor) (*CheckpointOracleTransactor, error) {
+	contract, err := bindCheckpointOracle(address, nil, transactor)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointOracleTransactor{contract: contract}, nil
+}
+
+// bindCheckpointOracle binds a generic wrapper to an already deployed contract.
+func bindCheckpointOracle(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(CheckpointOracleABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_CheckpointOracle *CheckpointOracleRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _CheckpointOracle.Contract.CheckpointOracleCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_CheckpointOracle *CheckpointOracleRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.CheckpointOracleTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_CheckpointOracle *CheckpointOracleRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.CheckpointOracleTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_CheckpointOracle *CheckpointOracleCallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _CheckpointOracle.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_CheckpointOracle *CheckpointOracleTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_CheckpointOracle *CheckpointOracleTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.contract.Transact(opts, method, params...)
+}
+
+// Admins is a free data retrieval call binding the contract method 0xa5de3619.
+//
+// Solidity: function admins() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleCaller) Admins(opts *bind.CallOpts) ([]common.Address, error) {
+	var (
+		ret0 = new([]common.Address)
+	)
+	out := ret0
+	err := _CheckpointOracle.contract.Call(opts, out, "admins")
+	return *ret0, err
+}
+
+// Admins is a free data retrieval call binding the contract method 0xa5de3619.
+//
+// Solidity: function admins() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleSession) Admins() ([]common.Address, error) {
+	return _CheckpointOracle.Contract.Admins(&_CheckpointOracle.CallOpts)
+}
+
+// Admins is a free data retrieval call binding the contract method 0xa5de3619.
+//
+// Solidity: function admins() constant returns(address[])
+func (_CheckpointOracle *CheckpointOracleCallerSession) Admins() ([]common.Address, error) {
+	return _CheckpointOracle.Contract.Admins(&_CheckpointOracle.CallOpts)
+}
+
+// LatestCheckpoint is a free data retrieval call binding the contract method 0x907c0f92.
+//
+// Solidity: function latestCheckpoint() constant returns(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, sigs bytes)
+func (_CheckpointOracle *CheckpointOracleCaller) LatestCheckpoint(opts *bind.CallOpts) (struct {
+	Index       uint64
+	SectionHead [32]byte
+	ChtRoot     [32]byte
+	BloomRoot   [32]byte
+	Sigs        []byte
+}, error) {
+	ret := new(struct {
+		Index       uint64
+		SectionHead [32]byte
+		ChtRoot     [32]byte
+		BloomRoot   [32]byte
+		Sigs        []byte
+	})
+	out := ret
+	err := _CheckpointOracle.contract.Call(opts, out, "latestCheckpoint")
+	return *ret, err
+}
+
+// LatestCheckpoint is a free data retrieval call binding the contract method 0x907c0f92.
+//
+// Solidity: function latestCheckpoint() constant returns(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, sigs bytes)
+func (_CheckpointOracle *CheckpointOracleSession) LatestCheckpoint() (struct {
+	Index       uint64
+	SectionHead [32]byte
+	ChtRoot     [32]byte
+	BloomRoot   [32]byte
+	Sigs        []byte
+}, error) {
+	return _CheckpointOracle.Contract.LatestCheckpoint(&_CheckpointOracle.CallOpts)
+}
+
+// LatestCheckpoint is a free data retrieval call binding the contract method 0x907c0f92.
+//
+// Solidity: function latestCheckpoint() constant returns(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, sigs bytes)
+func (_CheckpointOracle *CheckpointOracleCallerSession) LatestCheckpoint() (struct {
+	Index       uint64
+	SectionHead [32]byte
+	ChtRoot     [32]byte
+	BloomRoot   [32]byte
+	Sigs        []byte
+}, error) {
+	return _CheckpointOracle.Contract.LatestCheckpoint(&_CheckpointOracle.CallOpts)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x4dc58fbc.
+//
+// Solidity: function setCheckpoint(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, v uint8[], r bytes32[], s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleTransactor) SetCheckpoint(opts *bind.TransactOpts, index uint64, sectionHead [32]byte, chtRoot [32]byte, bloomRoot [32]byte, v []uint8, r [][32]byte, s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.contract.Transact(opts, "setCheckpoint", index, sectionHead, chtRoot, bloomRoot, v, r, s)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x4dc58fbc.
+//
+// Solidity: function setCheckpoint(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, v uint8[], r bytes32[], s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleSession) SetCheckpoint(index uint64, sectionHead [32]byte, chtRoot [32]byte, bloomRoot [32]byte, v []uint8, r [][32]byte, s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.SetCheckpoint(&_CheckpointOracle.TransactOpts, index, sectionHead, chtRoot, bloomRoot, v, r, s)
+}
+
+// SetCheckpoint is a paid mutator transaction binding the contract method 0x4dc58fbc.
+//
+// Solidity: function setCheckpoint(index uint64, sectionHead bytes32, chtRoot bytes32, bloomRoot bytes32, v uint8[], r bytes32[], s bytes32[]) returns(bool)
+func (_CheckpointOracle *CheckpointOracleTransactorSession) SetCheckpoint(index uint64, sectionHead [32]byte, chtRoot [32]byte, bloomRoot [32]byte, v []uint8, r [][32]byte, s [][32]byte) (*types.Transaction, error) {
+	return _CheckpointOracle.Contract.SetCheckpoint(&_CheckpointOracle.TransactOpts, index, sectionHead, chtRoot, bloomRoot, v, r, s)
+}
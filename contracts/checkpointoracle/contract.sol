@@ -0,0 +1,113 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// CheckpointOracle is a Wtc contract registering the trusted checkpoints of the
+// chain it's deployed on, allowing nodes to pick up newer checkpoints than the
+// ones hard coded into their releases.
+//
+// A new checkpoint is only accepted if signed by at least threshold distinct
+// admins. The signatures are stored along with the checkpoint so clients can
+// verify them against their own list of signers instead of trusting the contract.
+contract CheckpointOracle {
+  // Checkpoint is the set of trie roots of a CHT section, see params.TrustedCheckpoint
+  struct Checkpoint {
+    uint64  index;       // Index of the section covered by the checkpoint
+    bytes32 sectionHead; // Hash of the last block of the section
+    bytes32 chtRoot;     // Root of the canonical hash trie of the section
+    bytes32 bloomRoot;   // Root of the bloom trie of the section
+    bytes   sigs;        // Concatenated [R || S || V] signatures of the admins signing off
+  }
+
+  // Map of admins allowed to sign checkpoints and their list for enumeration
+  mapping(address => bool) authorised;
+  address[]                voters;
+
+  // Number of admin signatures needed to accept a checkpoint
+  uint threshold;
+
+  // Latest checkpoint accepted by the admins
+  Checkpoint latest;
+
+  // Fired whenever a new checkpoint is accepted
+  event NewCheckpoint(uint64 indexed index, bytes32 checkpointHash);
+
+  // isAdmin is a modifier to accept only transactions from admins.
+  modifier isAdmin() {
+    if (authorised[msg.sender]) {
+      _;
+    }
+  }
+
+  // Constructor to assign the initial set of admins and the signature threshold.
+  function CheckpointOracle(address[] admins, uint sigThreshold) {
+    for (uint i = 0; i < admins.length; i++) {
+      authorised[admins[i]] = true;
+      voters.push(admins[i]);
+    }
+    threshold = sigThreshold;
+  }
+
+  // admins returns the list of accounts allowed to sign checkpoints.
+  function admins() constant returns(address[]) {
+    return voters;
+  }
+
+  // latestCheckpoint returns the latest accepted checkpoint along with the admin
+  // signatures signing it off.
+  function latestCheckpoint() constant returns (uint64 index, bytes32 sectionHead, bytes32 chtRoot, bytes32 bloomRoot, bytes sigs) {
+    return (latest.index, latest.sectionHead, latest.chtRoot, latest.bloomRoot, latest.sigs);
+  }
+
+  // setCheckpoint registers a new checkpoint if it's newer than the current one
+  // and is signed by at least threshold distinct admins. Each admin signs the
+  // hash keccak256(0x19 || 0x00 || oracle || index || checkpointHash), where the
+  // checkpoint hash is keccak256(index || sectionHead || chtRoot || bloomRoot).
+  // The signatures must be ordered by ascending signer address.
+  function setCheckpoint(uint64 index, bytes32 sectionHead, bytes32 chtRoot, bytes32 bloomRoot, uint8[] v, bytes32[] r, bytes32[] s) isAdmin returns (bool) {
+    // Discard stale checkpoints and incomplete signature sets
+    if (latest.sigs.length > 0 && index <= latest.index) {
+      return false;
+    }
+    if (v.length != r.length || v.length != s.length || v.length < threshold) {
+      return false;
+    }
+    // Ensure all signatures originate from distinct admins
+    bytes32 hash = sha3(index, sectionHead, chtRoot, bloomRoot);
+    bytes32 signed = sha3(byte(0x19), byte(0), this, index, hash);
+
+    bytes memory sigs = new bytes(65 * v.length);
+
+    address last = 0;
+    for (uint i = 0; i < v.length; i++) {
+      address signer = ecrecover(signed, v[i], r[i], s[i]);
+      if (!authorised[signer] || signer <= last) {
+        return false;
+      }
+      last = signer;
+
+      for (uint j = 0; j < 32; j++) {
+        sigs[65*i+j] = r[i][j];
+        sigs[65*i+32+j] = s[i][j];
+      }
+      sigs[65*i+64] = byte(v[i]);
+    }
+    // Signatures verified, store the new checkpoint
+    latest = Checkpoint({index: index, sectionHead: sectionHead, chtRoot: chtRoot, bloomRoot: bloomRoot, sigs: sigs});
+    NewCheckpoint(index, hash);
+
+    return true;
+  }
+}
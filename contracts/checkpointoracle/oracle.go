@@ -0,0 +1,257 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package checkpointoracle contains the node service that tracks the trusted
+// checkpoints registered in the on-chain checkpoint oracle.
+package checkpointoracle
+
+//go:generate abigen --sol ./contract.sol --pkg checkpointoracle --out ./contract.go
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wtc/go-wtc/accounts/abi/bind"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/internal/ethapi"
+	"github.com/wtc/go-wtc/les"
+	"github.com/wtc/go-wtc/light"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/wtc"
+	"github.com/wtc/go-wtc/wtc/downloader"
+)
+
+// Interval to check for new checkpoints
+const oracleRecheckInterval = 10 * time.Minute
+
+var (
+	errNoCheckpoint       = errors.New("no checkpoint registered")
+	errSignatureMalformed = errors.New("malformed checkpoint signature")
+)
+
+// SignedHash returns the hash the admins of the oracle at the given address need
+// to sign to register a checkpoint, following EIP-191 with the oracle address as
+// the intended validator: keccak256(0x19 || 0x00 || oracle || index || hash).
+func SignedHash(oracle common.Address, checkpoint *params.TrustedCheckpoint) []byte {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], checkpoint.SectionIndex)
+
+	hash := checkpoint.Hash()
+	return crypto.Keccak256([]byte{0x19, 0x00}, oracle[:], index[:], hash[:])
+}
+
+// SignCheckpoint signs a checkpoint for registration in the oracle at the given
+// address, returning the signature in the [R || S || V] format with V being 27
+// or 28, as expected by the contract.
+func SignCheckpoint(oracle common.Address, checkpoint *params.TrustedCheckpoint, key *ecdsa.PrivateKey) ([]byte, error) {
+	sig, err := crypto.Sign(SignedHash(oracle, checkpoint), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// VerifySignatures checks that a checkpoint is signed off by at least as many
+// distinct signers authorised by the oracle config as its threshold requires.
+// Signatures of unknown accounts are ignored.
+func VerifySignatures(config *params.CheckpointOracleConfig, checkpoint *params.TrustedCheckpoint, sigs [][]byte) error {
+	authorised := make(map[common.Address]bool)
+	for _, signer := range config.Signers {
+		authorised[signer] = true
+	}
+	hash := SignedHash(config.Address, checkpoint)
+
+	signed := make(map[common.Address]bool)
+	for _, sig := range sigs {
+		if len(sig) != 65 || (sig[64] != 27 && sig[64] != 28) {
+			return errSignatureMalformed
+		}
+		sig = common.CopyBytes(sig)
+		sig[64] -= 27
+
+		pubkey, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			return err
+		}
+		if signer := crypto.PubkeyToAddress(*pubkey); authorised[signer] {
+			signed[signer] = true
+		}
+	}
+	if uint64(len(signed)) < config.Threshold {
+		return fmt.Errorf("checkpoint signed by %d authorised signers, need %d", len(signed), config.Threshold)
+	}
+	return nil
+}
+
+// CheckpointService is a node service that periodically checks the checkpoint
+// oracle of the chain for newly registered checkpoints, feeding them to the
+// downloader and the light chain after verifying their signatures.
+type CheckpointService struct {
+	config     *params.CheckpointOracleConfig // Oracle config of the chain, nil if none known
+	oracle     *CheckpointOracle              // Native binding to the checkpoint oracle contract
+	downloader *downloader.Downloader         // Downloader to challenge sync peers with the checkpoints
+	lightchain *light.LightChain              // Light chain to sync from the checkpoints, nil for full nodes
+	quit       chan chan error                // Quit channel to terminate the checkpoint checker
+}
+
+// NewCheckpointService creates a new service to periodically check for newly
+// registered checkpoints. If no oracle is known for the chain the node runs on,
+// the service does nothing.
+func NewCheckpointService(ctx *node.ServiceContext) (node.Service, error) {
+	// Retrieve the Wtc service dependency to access the blockchain
+	service := &CheckpointService{quit: make(chan chan error)}
+
+	var (
+		apiBackend ethapi.Backend
+		genesis    common.Hash
+	)
+	var wtc *eth.Wtc
+	if err := ctx.Service(&wtc); err == nil {
+		apiBackend, genesis = wtc.ApiBackend, wtc.BlockChain().Genesis().Hash()
+		service.downloader = wtc.Downloader()
+	} else {
+		var wtc *les.LightWtc
+		if err := ctx.Service(&wtc); err == nil {
+			apiBackend, genesis = wtc.ApiBackend, wtc.BlockChain().Genesis().Hash()
+			service.downloader, service.lightchain = wtc.Downloader(), wtc.BlockChain()
+		} else {
+			return nil, err
+		}
+	}
+	// Bind the oracle of the chain, if any
+	service.config = params.CheckpointOracles[genesis]
+	if service.config != nil {
+		contract, err := NewCheckpointOracle(service.config.Address, eth.NewContractBackend(apiBackend))
+		if err != nil {
+			return nil, err
+		}
+		service.oracle = contract
+	}
+	return service, nil
+}
+
+// Protocols returns an empty list of P2P protocols as the checkpoint service
+// does not have a networking component.
+func (s *CheckpointService) Protocols() []p2p.Protocol { return nil }
+
+// APIs returns an empty list of RPC descriptors as the checkpoint service does
+// not expose any functionality to the outside world.
+func (s *CheckpointService) APIs() []rpc.API { return nil }
+
+// Start spawns the periodic checkpoint checker goroutine, if an oracle is known.
+func (s *CheckpointService) Start(server *p2p.Server) error {
+	if s.oracle != nil {
+		go s.checker()
+	}
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *CheckpointService) Stop() error {
+	if s.oracle == nil {
+		return nil
+	}
+	errc := make(chan error)
+	s.quit <- errc
+	return <-errc
+}
+
+// checker runs indefinitely in the background, periodically checking for new
+// checkpoints.
+func (s *CheckpointService) checker() {
+	timer := time.NewTimer(0) // Immediately fire a checkpoint check
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(oracleRecheckInterval)
+			s.checkCheckpoint()
+
+		case errc := <-s.quit:
+			errc <- nil
+			return
+		}
+	}
+}
+
+// checkCheckpoint retrieves the latest checkpoint from the oracle and, if its
+// signatures check out, injects it into the local chain.
+func (s *CheckpointService) checkCheckpoint() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	checkpoint, err := s.latestCheckpoint(&bind.CallOpts{Context: ctx})
+	switch {
+	case err == bind.ErrNoCode:
+		log.Debug("Checkpoint oracle not found", "contract", s.config.Address)
+		return
+	case err == errNoCheckpoint:
+		log.Debug("No checkpoint registered in oracle", "contract", s.config.Address)
+		return
+	case err != nil:
+		log.Warn("Failed to retrieve trusted checkpoint", "contract", s.config.Address, "err", err)
+		return
+	}
+	if current := s.downloader.Checkpoint(); current != nil && current.SectionIndex >= checkpoint.SectionIndex {
+		return
+	}
+	s.downloader.SetCheckpoint(checkpoint)
+	if s.lightchain != nil {
+		s.lightchain.AddTrustedCheckpoint(checkpoint)
+	}
+	log.Info("Updated trusted checkpoint from oracle", "section", checkpoint.SectionIndex, "head", checkpoint.SectionHead)
+}
+
+// latestCheckpoint retrieves the latest checkpoint registered in the oracle,
+// verifying it against the locally configured signers.
+func (s *CheckpointService) latestCheckpoint(opts *bind.CallOpts) (*params.TrustedCheckpoint, error) {
+	latest, err := s.oracle.LatestCheckpoint(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest.Sigs) == 0 {
+		return nil, errNoCheckpoint
+	}
+	if len(latest.Sigs)%65 != 0 {
+		return nil, errSignatureMalformed
+	}
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: latest.Index,
+		SectionHead:  latest.SectionHead,
+		CHTRoot:      latest.ChtRoot,
+		BloomRoot:    latest.BloomRoot,
+	}
+	sigs := make([][]byte, len(latest.Sigs)/65)
+	for i := range sigs {
+		sigs[i] = latest.Sigs[i*65 : (i+1)*65]
+	}
+	if err := VerifySignatures(s.config, checkpoint, sigs); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
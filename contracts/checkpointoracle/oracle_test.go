@@ -0,0 +1,177 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package checkpointoracle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	wtc "github.com/wtc/go-wtc"
+	"github.com/wtc/go-wtc/accounts/abi"
+	"github.com/wtc/go-wtc/accounts/abi/bind"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/params"
+)
+
+// testCheckpoint is the checkpoint signed in the tests below.
+var testCheckpoint = &params.TrustedCheckpoint{
+	SectionIndex: 7,
+	SectionHead:  common.HexToHash("0x01"),
+	CHTRoot:      common.HexToHash("0x02"),
+	BloomRoot:    common.HexToHash("0x03"),
+}
+
+// makeSigners creates a number of signer keys and an oracle config authorising
+// them with the given threshold.
+func makeSigners(n int, threshold uint64) ([]*ecdsa.PrivateKey, *params.CheckpointOracleConfig) {
+	config := &params.CheckpointOracleConfig{
+		Address:   common.HexToAddress("0x0123456789abcdef"),
+		Threshold: threshold,
+	}
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		config.Signers = append(config.Signers, crypto.PubkeyToAddress(keys[i].PublicKey))
+	}
+	return keys, config
+}
+
+// signAll signs the checkpoint with all the given keys.
+func signAll(t *testing.T, config *params.CheckpointOracleConfig, checkpoint *params.TrustedCheckpoint, keys []*ecdsa.PrivateKey) [][]byte {
+	sigs := make([][]byte, len(keys))
+	for i, key := range keys {
+		sig, err := SignCheckpoint(config.Address, checkpoint, key)
+		if err != nil {
+			t.Fatalf("failed to sign checkpoint: %v", err)
+		}
+		sigs[i] = sig
+	}
+	return sigs
+}
+
+// Tests that checkpoints are only accepted if signed by enough distinct
+// authorised signers.
+func TestSignatureVerification(t *testing.T) {
+	keys, config := makeSigners(3, 2)
+	outsider, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		keys []*ecdsa.PrivateKey
+		ok   bool
+	}{
+		{keys[:2], true},  // Exactly the threshold
+		{keys, true},      // All signers
+		{keys[:1], false}, // Below the threshold
+		{[]*ecdsa.PrivateKey{keys[0], keys[0]}, false},          // Duplicate signer
+		{[]*ecdsa.PrivateKey{keys[0], outsider}, false},         // Unauthorised signer
+		{[]*ecdsa.PrivateKey{keys[0], outsider, keys[2]}, true}, // Unauthorised signer ignored
+	}
+	for i, tt := range tests {
+		err := VerifySignatures(config, testCheckpoint, signAll(t, config, testCheckpoint, tt.keys))
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+	// Signatures over a different checkpoint or oracle must be rejected
+	sigs := signAll(t, config, testCheckpoint, keys)
+
+	other := *testCheckpoint
+	other.SectionIndex++
+	if err := VerifySignatures(config, &other, sigs); err == nil {
+		t.Errorf("signatures accepted for different checkpoint")
+	}
+	moved := *config
+	moved.Address = common.HexToAddress("0xfedcba9876543210")
+	if err := VerifySignatures(&moved, testCheckpoint, sigs); err == nil {
+		t.Errorf("signatures accepted for different oracle")
+	}
+	// Malformed signatures must be rejected
+	sigs[0] = sigs[0][:64]
+	if err := VerifySignatures(config, testCheckpoint, sigs); err != errSignatureMalformed {
+		t.Errorf("malformed signature error mismatch: have %v, want %v", err, errSignatureMalformed)
+	}
+}
+
+// oracleTester is a contract caller mocking the oracle, returning a canned
+// encoded checkpoint for every call.
+type oracleTester struct {
+	output []byte
+}
+
+func (o *oracleTester) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x00}, nil
+}
+
+func (o *oracleTester) CallContract(ctx context.Context, call wtc.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return o.output, nil
+}
+
+// newOracleTester creates a checkpoint service on top of a mocked oracle
+// returning the given checkpoint and signatures.
+func newOracleTester(t *testing.T, config *params.CheckpointOracleConfig, checkpoint *params.TrustedCheckpoint, sigs [][]byte) *CheckpointService {
+	// Encode the oracle output reusing the ABI packer on a matching input signature
+	parsed, err := abi.JSON(strings.NewReader(`[{"inputs":[{"name":"index","type":"uint64"},{"name":"sectionHead","type":"bytes32"},{"name":"chtRoot","type":"bytes32"},{"name":"bloomRoot","type":"bytes32"},{"name":"sigs","type":"bytes"}],"name":"latestCheckpoint","type":"function"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse output ABI: %v", err)
+	}
+	var blob []byte
+	for _, sig := range sigs {
+		blob = append(blob, sig...)
+	}
+	output, err := parsed.Pack("latestCheckpoint", checkpoint.SectionIndex, [32]byte(checkpoint.SectionHead), [32]byte(checkpoint.CHTRoot), [32]byte(checkpoint.BloomRoot), blob)
+	if err != nil {
+		t.Fatalf("failed to encode oracle output: %v", err)
+	}
+	caller, err := NewCheckpointOracleCaller(config.Address, &oracleTester{output: output[4:]})
+	if err != nil {
+		t.Fatalf("failed to bind oracle: %v", err)
+	}
+	return &CheckpointService{
+		config: config,
+		oracle: &CheckpointOracle{CheckpointOracleCaller: *caller},
+	}
+}
+
+// Tests that checkpoints retrieved from the oracle are decoded and verified
+// against the local signer configuration.
+func TestOracleRetrieval(t *testing.T) {
+	keys, config := makeSigners(3, 2)
+
+	// A properly signed checkpoint should be accepted
+	service := newOracleTester(t, config, testCheckpoint, signAll(t, config, testCheckpoint, keys[:2]))
+	checkpoint, err := service.latestCheckpoint(&bind.CallOpts{})
+	if err != nil {
+		t.Fatalf("failed to retrieve checkpoint: %v", err)
+	}
+	if *checkpoint != *testCheckpoint {
+		t.Fatalf("checkpoint mismatch: have %+v, want %+v", checkpoint, testCheckpoint)
+	}
+	// A checkpoint signed by too few signers should be rejected
+	service = newOracleTester(t, config, testCheckpoint, signAll(t, config, testCheckpoint, keys[:1]))
+	if _, err := service.latestCheckpoint(&bind.CallOpts{}); err == nil {
+		t.Fatalf("under-signed checkpoint accepted")
+	}
+	// An empty oracle should report no checkpoint
+	service = newOracleTester(t, config, new(params.TrustedCheckpoint), nil)
+	if _, err := service.latestCheckpoint(&bind.CallOpts{}); err != errNoCheckpoint {
+		t.Fatalf("empty oracle error mismatch: have %v, want %v", err, errNoCheckpoint)
+	}
+}
@@ -46,6 +46,8 @@ import (
 
 var (
 	blockInsertTimer = metrics.NewTimer("chain/inserts")
+	headBlockGauge   = metrics.NewGauge("chain/head/block")
+	headTdGauge      = metrics.NewGaugeFloat64("chain/head/td")
 
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 )
@@ -201,6 +203,7 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
+	bc.updateHeadMetrics(currentBlock)
 
 	// Restore the last known head header
 	currentHeader := bc.currentBlock.Header()
@@ -465,6 +468,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 		log.Crit("Failed to insert head block hash", "err", err)
 	}
 	bc.currentBlock = block
+	bc.updateHeadMetrics(block)
 
 	// If the block is better than out head or is on a different chain, force update heads
 	if updateHeads {
@@ -477,6 +481,15 @@ func (bc *BlockChain) insert(block *types.Block) {
 	}
 }
 
+// updateHeadMetrics reports the number and total difficulty of a new head block.
+func (bc *BlockChain) updateHeadMetrics(block *types.Block) {
+	headBlockGauge.Update(int64(block.NumberU64()))
+	if td := bc.GetTd(block.Hash(), block.NumberU64()); td != nil {
+		f, _ := new(big.Float).SetInt(td).Float64()
+		headTdGauge.Update(f)
+	}
+}
+
 // Genesis retrieves the chain's genesis block.
 func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -857,7 +870,21 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 //
 // After insertion is done, all accumulated events will be fired.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
-	n, events, logs, err := bc.insertChain(chain)
+	n, events, logs, err := bc.insertChain(chain, nil)
+	bc.PostChainEvents(events, logs)
+	return n, err
+}
+
+// InsertTrustedChain inserts a batch of blocks like InsertChain, but skips the
+// seal checks of the blocks at or below the given trusted checkpoint header,
+// saving the costly checks of the ancient chain.
+//
+// The caller must ensure the batch is linked to the checkpoint by hashes, i.e.
+// the checkpoint is a descendant of every block in the batch at or below it. A
+// batch containing a block at the checkpoint height with another hash is
+// rejected.
+func (bc *BlockChain) InsertTrustedChain(chain types.Blocks, checkpoint *types.Header) (int, error) {
+	n, events, logs, err := bc.insertChain(chain, checkpoint)
 	bc.PostChainEvents(events, logs)
 	return n, err
 }
@@ -865,7 +892,7 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 // insertChain will execute the actual chain insertion and event aggregation. The
 // only reason this method exists as a separate one is to make locking cleaner
 // with deferred statements.
-func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+func (bc *BlockChain) insertChain(chain types.Blocks, checkpoint *types.Header) (int, []interface{}, []*types.Log, error) {
 	// Do a sanity check that the provided chain is actually ordered and linked
 	for i := 1; i < len(chain); i++ {
 		if chain[i].NumberU64() != chain[i-1].NumberU64()+1 || chain[i].ParentHash() != chain[i-1].Hash() {
@@ -877,6 +904,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 				chain[i-1].Hash().Bytes()[:4], i, chain[i].NumberU64(), chain[i].Hash().Bytes()[:4], chain[i].ParentHash().Bytes()[:4])
 		}
 	}
+	// If a checkpoint is trusted, make sure the batch doesn't contradict it
+	if checkpoint != nil && len(chain) > 0 {
+		if first, number := chain[0].NumberU64(), checkpoint.Number.Uint64(); first <= number && number-first < uint64(len(chain)) {
+			if block := chain[number-first]; block.Hash() != checkpoint.Hash() {
+				return int(number - first), nil, nil, fmt.Errorf("checkpoint mismatch: #%d [%x…], want [%x…]", number, block.Hash().Bytes()[:4], checkpoint.Hash().Bytes()[:4])
+			}
+		}
+	}
 	// Pre-checks passed, start the full block imports
 	bc.wg.Add(1)
 	defer bc.wg.Done()
@@ -899,7 +934,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 	for i, block := range chain {
 		headers[i] = block.Header()
-		seals[i] = true
+		seals[i] = checkpoint == nil || block.NumberU64() > checkpoint.Number.Uint64()
 	}
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
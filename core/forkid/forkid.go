@@ -0,0 +1,225 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkid implements fork identifiers summarising the chain rules a node
+// runs with, allowing peers on incompatible forks to be told apart early.
+//
+// The identifier follows the scheme of EIP-2124: a CRC32 checksum of the
+// genesis hash and the fork blocks already passed, plus the next upcoming fork
+// block (or 0 if none is known).
+package forkid
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/params"
+)
+
+var (
+	// ErrRemoteStale is returned by the validator if a remote fork checksum is a
+	// subset of our already applied forks, but the announced next fork block is
+	// not on our already passed chain.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by the validator if a remote fork
+	// checksum does not match any local checksum variation, signalling that the
+	// two chains have diverged in the past at some point (possibly at genesis).
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+)
+
+// ID is a fork identifier as defined by EIP-2124.
+type ID struct {
+	Hash [4]byte // CRC32 checksum of the genesis block and passed fork block numbers
+	Next uint64  // Block number of the next upcoming fork, or 0 if no forks are known
+}
+
+// String implements fmt.Stringer.
+func (id ID) String() string {
+	return fmt.Sprintf("%x/%d", id.Hash, id.Next)
+}
+
+// Blockchain defines all necessary method to build a forkID.
+type Blockchain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// Genesis retrieves the chain's genesis block.
+	Genesis() *types.Block
+
+	// CurrentHeader retrieves the current head header of the canonical chain.
+	CurrentHeader() *types.Header
+}
+
+// Filter is a fork ID validator, checking a remote fork ID against the local
+// chain's state.
+type Filter func(id ID) error
+
+// NewID calculates the fork ID of the chain at the given head block.
+func NewID(config *params.ChainConfig, genesis common.Hash, head uint64) ID {
+	hash := crc32.ChecksumIEEE(genesis[:])
+
+	for _, fork := range gatherForks(config) {
+		if fork <= head {
+			hash = checksumUpdate(hash, fork)
+			continue
+		}
+		return ID{Hash: checksumToBytes(hash), Next: fork}
+	}
+	return ID{Hash: checksumToBytes(hash), Next: 0}
+}
+
+// NewIDFromChain calculates the fork ID of the chain at its current head.
+func NewIDFromChain(chain Blockchain) ID {
+	return NewID(chain.Config(), chain.Genesis().Hash(), chain.CurrentHeader().Number.Uint64())
+}
+
+// NewFilter creates a filter validating remote fork IDs against the current
+// state of the local chain.
+func NewFilter(chain Blockchain) Filter {
+	return newFilter(chain.Config(), chain.Genesis().Hash(), func() uint64 {
+		return chain.CurrentHeader().Number.Uint64()
+	})
+}
+
+// newFilter is the internal version of NewFilter, taking closures as its
+// inputs to allow testing without a real chain.
+func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() uint64) Filter {
+	// Calculate the all the valid fork hash and fork next combos
+	var (
+		forks = gatherForks(config)
+		sums  = make([][4]byte, len(forks)+1) // 0th is the genesis
+	)
+	hash := crc32.ChecksumIEEE(genesis[:])
+	sums[0] = checksumToBytes(hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		sums[i+1] = checksumToBytes(hash)
+	}
+	// Add a sentinel fork to avoid special casing the last known fork
+	forks = append(forks, ^uint64(0))
+
+	return func(id ID) error {
+		head := headfn()
+
+		for i, fork := range forks {
+			// If our head is beyond this fork, continue to the next (we have a
+			// dummy fork of maxuint64 as the last item to always fail this check
+			// eventually).
+			if head >= fork {
+				continue
+			}
+			// Found the first unpassed fork block, check if our current state
+			// matches the remote checksum (rule #1).
+			if sums[i] == id.Hash {
+				// Fork checksum matched, check if a remote future fork block
+				// already passed locally without the local node being aware of it
+				// (rule #1a).
+				if id.Next > 0 && head >= id.Next {
+					return ErrLocalIncompatibleOrStale
+				}
+				// Haven't passed locally a remote-only fork, accept the connection
+				// (rule #1b).
+				return nil
+			}
+			// The local and remote nodes are in different forks currently, check
+			// if the remote checksum is a subset of our local forks (rule #2).
+			for j := 0; j < i; j++ {
+				if sums[j] == id.Hash {
+					// Remote checksum is a subset, validate based on the announced
+					// next fork
+					if forks[j] != id.Next {
+						return ErrRemoteStale
+					}
+					return nil
+				}
+			}
+			// Remote chain is not a subset of our local one, check if it's a
+			// superset by any chance, signalling that we're simply out of sync
+			// (rule #3).
+			for j := i + 1; j < len(sums); j++ {
+				if sums[j] == id.Hash {
+					// Yay, remote checksum is a superset, ignore upcoming forks
+					return nil
+				}
+			}
+			// No exact, subset or superset match. We are on differing chains,
+			// reject.
+			return ErrLocalIncompatibleOrStale
+		}
+		// Unreachable, the sentinel fork always stops the iteration
+		return ErrLocalIncompatibleOrStale
+	}
+}
+
+// gatherForks gathers all the known forks and creates a sorted list out of
+// them: the block forks of the chain config (fields ending in "Block") plus the
+// network wide HardForkV1, V2 and V3 forks. Genesis and duplicate forks are
+// dropped.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+
+	kind := reflect.TypeOf(params.ChainConfig{})
+	conf := reflect.ValueOf(config).Elem()
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !strings.HasSuffix(field.Name, "Block") || field.Type != reflect.TypeOf(new(big.Int)) {
+			continue
+		}
+		if rule := conf.Field(i).Interface().(*big.Int); rule != nil {
+			forks = append(forks, rule.Uint64())
+		}
+	}
+	for _, fork := range []*big.Int{params.HardForkV1, params.HardForkV2, params.HardForkV3} {
+		forks = append(forks, fork.Uint64())
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	// Deduplicate block numbers applying multiple forks and skip genesis
+	for i := 1; i < len(forks); i++ {
+		if forks[i] == forks[i-1] {
+			forks = append(forks[:i], forks[i+1:]...)
+			i--
+		}
+	}
+	if len(forks) > 0 && forks[0] == 0 {
+		forks = forks[1:]
+	}
+	return forks
+}
+
+// checksumUpdate calculates the next IEEE CRC32 checksum based on the previous
+// one and a fork block number (equivalent to CRC32(original-blob || fork)).
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+// checksumToBytes converts a uint32 checksum into a [4]byte array.
+func checksumToBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rlp"
+)
+
+// Tests that the fork ID of the main network is calculated correctly at the
+// fork transitions, including the HardForkV1, V2 and V3 forks.
+func TestCreation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: checksumToBytes(0x6cda1326), Next: 1}},           // Unsynced
+		{1, ID{Hash: checksumToBytes(0x946da778), Next: 2}},           // Homestead
+		{2, ID{Hash: checksumToBytes(0xe67944d4), Next: 3}},           // EIP150
+		{3, ID{Hash: checksumToBytes(0x4581c929), Next: 4}},           // EIP155 and EIP158
+		{4, ID{Hash: checksumToBytes(0x980ca811), Next: 149500}},      // Byzantium
+		{149499, ID{Hash: checksumToBytes(0x980ca811), Next: 149500}}, // Last Byzantium block
+		{149500, ID{Hash: checksumToBytes(0x3f746ce4), Next: 175366}}, // HardForkV1
+		{175365, ID{Hash: checksumToBytes(0x3f746ce4), Next: 175366}}, // Last HardForkV1 block
+		{175366, ID{Hash: checksumToBytes(0x6c22011c), Next: 221500}}, // HardForkV2
+		{221499, ID{Hash: checksumToBytes(0x6c22011c), Next: 221500}}, // Last HardForkV2 block
+		{221500, ID{Hash: checksumToBytes(0xaf2856f0), Next: 0}},      // HardForkV3
+		{10000000, ID{Hash: checksumToBytes(0xaf2856f0), Next: 0}},    // Future
+	}
+	if sum := checksumToBytes(crc32.ChecksumIEEE(params.MainnetGenesisHash[:])); sum != tests[0].want.Hash {
+		t.Fatalf("genesis checksum mismatch: have %x, want %x", sum, tests[0].want.Hash)
+	}
+	for i, tt := range tests {
+		if have := NewID(params.MainnetChainConfig, params.MainnetGenesisHash, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the remote fork IDs are validated according to the rules of
+// EIP-2124 against the local chain state.
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local is mainnet HardForkV2, remote announces the same. No future fork is announced.
+		{200000, ID{Hash: checksumToBytes(0x6c22011c), Next: 0}, nil},
+
+		// Local is mainnet HardForkV2, remote announces the same and also HardForkV3.
+		{200000, ID{Hash: checksumToBytes(0x6c22011c), Next: 221500}, nil},
+
+		// Local is mainnet HardForkV2, remote announces the same and a future fork
+		// at a block we haven't reached yet.
+		{200000, ID{Hash: checksumToBytes(0x6c22011c), Next: 300000}, nil},
+
+		// Local is mainnet HardForkV2, remote announces the same and a fork at a
+		// block already passed locally. The remote is on a fork we don't know of.
+		{200000, ID{Hash: checksumToBytes(0x6c22011c), Next: 190000}, ErrLocalIncompatibleOrStale},
+
+		// Local is mainnet HardForkV2, remote is HardForkV1 and knows about HardForkV2.
+		// Remote is simply out of sync, accept.
+		{200000, ID{Hash: checksumToBytes(0x3f746ce4), Next: 175366}, nil},
+
+		// Local is mainnet HardForkV2, remote is HardForkV1 but doesn't know about
+		// HardForkV2. The remote is stuck before the fork, reject.
+		{200000, ID{Hash: checksumToBytes(0x3f746ce4), Next: 0}, ErrRemoteStale},
+
+		// Local is mainnet HardForkV3, remote is Byzantium announcing a wrong next
+		// fork. The remote needs an update, reject.
+		{230000, ID{Hash: checksumToBytes(0x980ca811), Next: 160000}, ErrRemoteStale},
+
+		// Local is mainnet HardForkV1, remote is HardForkV3. We're simply out of
+		// sync, accept.
+		{160000, ID{Hash: checksumToBytes(0xaf2856f0), Next: 0}, nil},
+
+		// Local is mainnet HardForkV3, remote announces a checksum not matching any
+		// local state (e.g. a different genesis), reject.
+		{230000, ID{Hash: checksumToBytes(0xdeadbeef), Next: 0}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		filter := newFilter(params.MainnetChainConfig, params.MainnetGenesisHash, func() uint64 { return tt.head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that fork IDs are RLP encoded as expected.
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		id   ID
+		want []byte
+	}{
+		{ID{Hash: checksumToBytes(0), Next: 0}, []byte{0xc6, 0x84, 0x00, 0x00, 0x00, 0x00, 0x80}},
+		{ID{Hash: checksumToBytes(0xdeadbeef), Next: 0xbaddcafe}, []byte{0xca, 0x84, 0xde, 0xad, 0xbe, 0xef, 0x84, 0xba, 0xdd, 0xca, 0xfe}},
+	}
+	for i, tt := range tests {
+		have, err := rlp.EncodeToBytes(tt.id)
+		if err != nil {
+			t.Errorf("test %d: failed to encode fork ID: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(have, tt.want) {
+			t.Errorf("test %d: RLP mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
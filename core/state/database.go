@@ -21,8 +21,9 @@ import (
 	"sync"
 
 	"github.com/wtc/go-wtc/common"
-	"github.com/wtc/go-wtc/wtcdb"
+	"github.com/wtc/go-wtc/rlp"
 	"github.com/wtc/go-wtc/trie"
+	"github.com/wtc/go-wtc/wtcdb"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -60,6 +61,7 @@ type Trie interface {
 	CommitTo(trie.DatabaseWriter) (common.Hash, error)
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
+	Prove(key []byte) []rlp.RawValue
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
 }
 
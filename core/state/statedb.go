@@ -257,6 +257,34 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the merkle proof of the account at the given address in the
+// state trie, proving its absence if it doesn't exist. Pending changes that are
+// not yet hashed into the trie are not reflected by the proof.
+func (self *StateDB) GetProof(a common.Address) ([]rlp.RawValue, error) {
+	proof := self.trie.Prove(a[:])
+	if len(proof) == 0 {
+		return nil, fmt.Errorf("can't prove account %x", a)
+	}
+	return proof, nil
+}
+
+// GetStorageProof returns the merkle proof of the given storage slot in the
+// storage trie of an account. The proof is nil for non-existent accounts.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([]rlp.RawValue, error) {
+	tr := self.StorageTrie(a)
+	if tr == nil {
+		return nil, nil
+	}
+	if tr.Hash() == types.EmptyRootHash {
+		return []rlp.RawValue{}, nil
+	}
+	proof := tr.Prove(key[:])
+	if len(proof) == 0 {
+		return nil, fmt.Errorf("can't prove storage slot %x of account %x", key, a)
+	}
+	return proof, nil
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -487,7 +515,7 @@ func (self *StateDB) Copy() *StateDB {
 	// Copy all the basic fields, initialize the memory ones
 	state := &StateDB{
 		db:                self.db,
-		trie:              self.trie,
+		trie:              self.db.CopyTrie(self.trie),
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            new(big.Int).Set(self.refund),
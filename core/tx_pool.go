@@ -467,6 +467,35 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	// log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// Limits retrieves the current configuration of the transaction pool.
+func (pool *TxPool) Limits() TxPoolConfig {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.config
+}
+
+// SetLimits updates the price bump, the slot and queue limits and the queue
+// lifetime of the transaction pool, evicting transactions in excess of lowered
+// limits. The remaining fields of the configuration are ignored.
+func (pool *TxPool) SetLimits(config TxPoolConfig) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	config = config.sanitize()
+	pool.config.PriceBump = config.PriceBump
+	pool.config.AccountSlots = config.AccountSlots
+	pool.config.GlobalSlots = config.GlobalSlots
+	pool.config.AccountQueue = config.AccountQueue
+	pool.config.GlobalQueue = config.GlobalQueue
+	pool.config.Lifetime = config.Lifetime
+
+	pool.promoteExecutables(nil)
+	log.Info("Transaction pool limits updated", "pricebump", pool.config.PriceBump,
+		"accountslots", pool.config.AccountSlots, "globalslots", pool.config.GlobalSlots,
+		"accountqueue", pool.config.AccountQueue, "globalqueue", pool.config.GlobalQueue, "lifetime", pool.config.Lifetime)
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -23,9 +23,13 @@ import (
 
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/common/math"
+	"github.com/wtc/go-wtc/consensus/ethash"
+	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/crypto"
 	"github.com/wtc/go-wtc/crypto/bn256"
+	"github.com/wtc/go-wtc/crypto/x11"
 	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rlp"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -59,6 +63,21 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// PrecompiledContractsX11 contains the default set of pre-compiled Wtc
+// contracts used after the X11 fork, adding X11 hashing and seal verification.
+var PrecompiledContractsX11 = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):  &ecrecover{},
+	common.BytesToAddress([]byte{2}):  &sha256hash{},
+	common.BytesToAddress([]byte{3}):  &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):  &dataCopy{},
+	common.BytesToAddress([]byte{5}):  &bigModExp{},
+	common.BytesToAddress([]byte{6}):  &bn256Add{},
+	common.BytesToAddress([]byte{7}):  &bn256ScalarMul{},
+	common.BytesToAddress([]byte{8}):  &bn256Pairing{},
+	common.BytesToAddress([]byte{9}):  &x11Hash{},
+	common.BytesToAddress([]byte{10}): &x11SealVerify{},
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -377,3 +396,79 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+// x11OrderLength is the number of hash rounds making up an X11 algorithm order.
+const x11OrderLength = 11
+
+var (
+	// errBadX11Order is returned if the X11 algorithm order is malformed.
+	errBadX11Order = errors.New("bad x11 algorithm order")
+
+	// errBadX11SealInput is returned if the X11 seal verification input is malformed.
+	errBadX11SealInput = errors.New("bad x11 seal input")
+
+	// errX11SealUnsupported is returned if the header's seal can't be verified
+	// without the state of its parent.
+	errX11SealUnsupported = errors.New("x11 seal verification unsupported after HardForkV2")
+)
+
+// x11Hash implements the X11 chained hash as a native contract. The input is
+// the 11 byte algorithm order (one of 'A'-'K' per round) followed by the data
+// to hash, the output the 32 byte digest.
+type x11Hash struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+//
+// This method does not require any overflow checking as the input size gas costs
+// required for anything significant is so high it's impossible to pay for.
+func (c *x11Hash) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.X11PerWordGas + params.X11BaseGas
+}
+
+func (c *x11Hash) Run(input []byte) ([]byte, error) {
+	if len(input) < x11OrderLength {
+		return nil, errBadX11Order
+	}
+	order := input[:x11OrderLength]
+	for _, algo := range order {
+		if algo < 'A' || algo > 'K' {
+			return nil, errBadX11Order
+		}
+	}
+	digest := make([]byte, 32)
+	x11.New().Hash(common.CopyBytes(input[x11OrderLength:]), digest, order)
+	return digest, nil
+}
+
+// x11SealVerify implements the X11 proof-of-work seal verification of a block
+// header as a native contract. The input is the RLP encoded header, the output
+// 1 if the seal is valid, 0 otherwise. Headers whose weighted target exceeds 256
+// bits are rejected with an error.
+//
+// From HardForkV2 on the target is weighted by the coinbase balance at the
+// header's parent, which a precompile has no access to, so such headers are
+// rejected with an error too.
+type x11SealVerify struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *x11SealVerify) RequiredGas(input []byte) uint64 {
+	return params.X11SealVerifyGas
+}
+
+func (c *x11SealVerify) Run(input []byte) ([]byte, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(input, header); err != nil || header.Number == nil {
+		return nil, errBadX11SealInput
+	}
+	if header.Number.Cmp(params.HardForkV2) >= 0 {
+		return nil, errX11SealUnsupported
+	}
+	switch err := ethash.VerifyX11Seal(header, nil); err {
+	case nil:
+		return true32Byte, nil
+	case ethash.ErrTargetOverflow:
+		return nil, err
+	default:
+		return false32Byte, nil
+	}
+}
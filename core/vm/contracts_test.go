@@ -1,11 +1,16 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"testing"
 
 	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/consensus/ethash"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rlp"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -165,6 +170,10 @@ var bn256AddTests = []precompiledTest{
 		input:    "17c139df0efee0f766bc0204762b774362e4ded88953a39ce849a8a7fa163fa901e0559bacb160664764a357af8a9fe70baa9258e0b959273ffc5718c6d4cc7c17c139df0efee0f766bc0204762b774362e4ded88953a39ce849a8a7fa163fa92e83f8d734803fc370eba25ed1f6b8768bd6d83887b87165fc2434fe11a830cb00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
 		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
 		name:     "cdetrio14",
+	}, {
+		input:    "00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000130644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45",
+		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		name:     "generator_plus_negated",
 	},
 }
 
@@ -255,6 +264,14 @@ var bn256ScalarMulTests = []precompiledTest{
 		expected:    "039730ea8dff1254c0fee9c0ea777d29a9c710b7e616683f194f18c43b43b869073a5ffcc6fc7a28c30723d6e58ce577356982d65b833a5a5c15bf9024b43d98",
 		name:        "cdetrio15",
 		noBenchmark: true,
+	}, {
+		input:    "000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000",
+		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		name:     "generator_times_zero",
+	}, {
+		input:    "0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000230644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001",
+		expected: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		name:     "generator_times_order",
 	},
 }
 
@@ -320,8 +337,22 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// x11HashTests are the test and benchmark data for the x11 hashing precompiled
+// contract.
+var x11HashTests = []precompiledTest{
+	{
+		input:    "4142434445464748494a4b54686520717569636b2062726f776e20666f78206a756d7073206f76657220746865206c617a7920646f67",
+		expected: "7bec6fdae396b8370a61022e7d51b2f6a2b72357c69400be7ae0a0e6cbbcafc4",
+		name:     "fox_ordered",
+	}, {
+		input:    "4b4a494847464544434241" + "54686520717569636b2062726f776e20666f78206a756d7073206f76657220746865206c617a7920646f67",
+		expected: "46948a94022af88ac7dcdc241c89b6d4a87a7fa60a0c70e5fba39b721184bf37",
+		name:     "fox_reversed",
+	},
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsX11[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), p.RequiredGas(in))
@@ -338,7 +369,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
 	}
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsX11[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	reqGas := p.RequiredGas(in)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
@@ -465,3 +496,82 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the sample inputs for the x11 hashing precompile.
+func TestPrecompiledX11Hash(t *testing.T) {
+	for _, test := range x11HashTests {
+		testPrecompiled("09", test, t)
+	}
+	p := PrecompiledContractsX11[common.HexToAddress("09")]
+	if _, err := p.Run([]byte("ABCDEFGHIJ")); err != errBadX11Order {
+		t.Errorf("short order: error mismatch: have %v, want %v", err, errBadX11Order)
+	}
+	if _, err := p.Run([]byte("ABCDEFGHIJZ")); err != errBadX11Order {
+		t.Errorf("unknown algorithm: error mismatch: have %v, want %v", err, errBadX11Order)
+	}
+}
+
+// Benchmarks the sample inputs for the x11 hashing precompile.
+func BenchmarkPrecompiledX11Hash(bench *testing.B) {
+	for _, test := range x11HashTests {
+		benchmarkPrecompiled("09", test, bench)
+	}
+}
+
+// Tests that the x11 seal verification precompile accepts valid seals, rejects
+// invalid ones and errors on malformed input and on headers it can't verify.
+func TestPrecompiledX11SealVerify(t *testing.T) {
+	p := PrecompiledContractsX11[common.HexToAddress("0a")]
+
+	// Header between HardForkV1 and HardForkV2 sealed without coin age
+	header := &types.Header{
+		ParentHash: common.HexToHash("0x5a1e5ea1e5"),
+		Coinbase:   common.HexToAddress("0x00000000000000000000000000000000000c0b1e"),
+		Number:     big.NewInt(160000),
+		Difficulty: big.NewInt(100000),
+		GasLimit:   big.NewInt(4712388),
+		GasUsed:    new(big.Int),
+		Time:       big.NewInt(1520000000),
+		CoinAge:    new(big.Int),
+		Nonce:      types.EncodeNonce(243807),
+	}
+	encode := func(header *types.Header) []byte {
+		blob, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			t.Fatalf("failed to encode header: %v", err)
+		}
+		return blob
+	}
+	if res, err := p.Run(encode(header)); err != nil || !bytes.Equal(res, true32Byte) {
+		t.Errorf("valid seal: result mismatch: have %x (%v), want %x", res, err, true32Byte)
+	}
+	invalid := types.CopyHeader(header)
+	invalid.Nonce = types.EncodeNonce(243806)
+	if res, err := p.Run(encode(invalid)); err != nil || !bytes.Equal(res, false32Byte) {
+		t.Errorf("invalid nonce: result mismatch: have %x (%v), want %x", res, err, false32Byte)
+	}
+	invalid = types.CopyHeader(header)
+	invalid.MixDigest = common.HexToHash("0x01")
+	if res, err := p.Run(encode(invalid)); err != nil || !bytes.Equal(res, false32Byte) {
+		t.Errorf("invalid mix digest: result mismatch: have %x (%v), want %x", res, err, false32Byte)
+	}
+	// A minimal difficulty weighted by a large coin age overflows the target
+	invalid = types.CopyHeader(header)
+	invalid.Difficulty = big.NewInt(1)
+	invalid.CoinAge = new(big.Int).Lsh(big.NewInt(1), 64)
+	if _, err := p.Run(encode(invalid)); err != ethash.ErrTargetOverflow {
+		t.Errorf("oversized target: error mismatch: have %v, want %v", err, ethash.ErrTargetOverflow)
+	}
+	// Seals weighted by the coinbase balance can't be verified
+	invalid = types.CopyHeader(header)
+	invalid.Number = new(big.Int).Set(params.HardForkV2)
+	if _, err := p.Run(encode(invalid)); err != errX11SealUnsupported {
+		t.Errorf("post HardForkV2 header: error mismatch: have %v, want %v", err, errX11SealUnsupported)
+	}
+	if _, err := p.Run(nil); err != errBadX11SealInput {
+		t.Errorf("missing header: error mismatch: have %v, want %v", err, errBadX11SealInput)
+	}
+	if _, err := p.Run([]byte{0xc2, 0x01}); err != errBadX11SealInput {
+		t.Errorf("malformed header: error mismatch: have %v, want %v", err, errBadX11SealInput)
+	}
+}
@@ -44,10 +44,20 @@ func run(evm *EVM, snapshot int, contract *Contract, input []byte) ([]byte, erro
 		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 			precompiles = PrecompiledContractsByzantium
 		}
+		if evm.ChainConfig().IsX11(evm.BlockNumber) {
+			precompiles = PrecompiledContractsX11
+		}
 		if p := precompiles[*contract.CodeAddr]; p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
+	// Increment the call depth which is restricted to 1024
+	evm.depth++
+	defer func() { evm.depth-- }()
+
+	if ext := evm.vmConfig.External; ext != nil && ext.CanRun(contract.Code) {
+		return runExternal(evm, ext, contract, input)
+	}
 	return evm.interpreter.Run(snapshot, contract, input)
 }
 
@@ -154,6 +164,9 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
 			precompiles = PrecompiledContractsByzantium
 		}
+		if evm.ChainConfig().IsX11(evm.BlockNumber) {
+			precompiles = PrecompiledContractsX11
+		}
 		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			return nil, gas, nil
 		}
@@ -371,3 +384,6 @@ func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
 // Interpreter returns the EVM interpreter
 func (evm *EVM) Interpreter() *Interpreter { return evm.interpreter }
+
+// Depth returns the current call stack depth of the EVM
+func (evm *EVM) Depth() int { return evm.depth }
@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// ExternalInterpreterSymbol is the name of the constructor a Go plugin needs to
+// export to be loadable as an external interpreter. Its type must be
+// func() ExternalInterpreter.
+const ExternalInterpreterSymbol = "NewInterpreter"
+
+// ExternalInterpreter is an alternative implementation of the EVM bytecode
+// interpreter, similar in spirit to EVMC. The EVM acts as the host: the
+// implementation reads and modifies the world state through evm.StateDB and
+// performs nested message calls and contract creations through the EVM's
+// Call, CallCode, DelegateCall, StaticCall and Create methods.
+//
+// Errors are treated exactly like the ones of the built-in interpreter: any
+// error reverts the state changes of the call and consumes all its gas, except
+// for ErrExecutionReverted which only reverts the state and refunds the gas
+// left in the contract.
+type ExternalInterpreter interface {
+	// Name returns a short identifier of the implementation.
+	Name() string
+
+	// CanRun reports whether the interpreter is able to execute the given code.
+	// Code it rejects is executed by the built-in interpreter instead.
+	CanRun(code []byte) bool
+
+	// Run executes the contract code with the given input, charging gas through
+	// contract.UseGas. If readOnly is set, any state modification must fail.
+	Run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error)
+}
+
+// runExternal executes a contract with an external interpreter. The call depth
+// is maintained by the caller for both interpreters alike.
+func runExternal(evm *EVM, ext ExternalInterpreter, contract *Contract, input []byte) ([]byte, error) {
+	// Don't bother with the execution if there's no code.
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
+	return ext.Run(evm, contract, input, evm.interpreter.readOnly)
+}
+
+// LoadExternalInterpreter opens the Go plugin at the given path and creates an
+// external interpreter through the constructor it exports.
+func LoadExternalInterpreter(path string) (ExternalInterpreter, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(ExternalInterpreterSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch ctor := sym.(type) {
+	case func() ExternalInterpreter:
+		return ctor(), nil
+	case *func() ExternalInterpreter:
+		return (*ctor)(), nil
+	default:
+		return nil, fmt.Errorf("plugin %s: %s has type %T, want func() vm.ExternalInterpreter", path, ExternalInterpreterSymbol, sym)
+	}
+}
@@ -32,7 +32,7 @@ var (
 	bigZero                  = new(big.Int)
 	errWriteProtection       = errors.New("evm: write protection")
 	errReturnDataOutOfBounds = errors.New("evm: return data out of bounds")
-	errExecutionReverted     = errors.New("evm: execution reverted")
+	errExecutionReverted     = ErrExecutionReverted
 	errMaxCodeSizeExceeded   = errors.New("evm: max code size exceeded")
 )
 
@@ -30,10 +30,6 @@ import (
 type Config struct {
 	// Debug enabled debugging Interpreter options
 	Debug bool
-	// EnableJit enabled the JIT VM
-	EnableJit bool
-	// ForceJit forces the JIT VM
-	ForceJit bool
 	// Tracer is the op code logger
 	Tracer Tracer
 	// NoRecursion disabled Interpreter call, callcode,
@@ -47,12 +43,15 @@ type Config struct {
 	// may be left uninitialised and will be set to the default
 	// table.
 	JumpTable [256]operation
+	// External is an alternative interpreter the EVM dispatches contract
+	// execution to. Code it can't run falls back to the built-in interpreter.
+	External ExternalInterpreter
 }
 
 // Interpreter is used to run Wtc based contracts and will utilise the
 // passed evmironment to query external sources for state information.
-// The Interpreter will run the byte code VM or JIT VM based on the passed
-// configuration.
+// The Interpreter runs the byte code VM; alternative implementations are
+// plugged in through Config.External.
 type Interpreter struct {
 	evm      *EVM
 	cfg      Config
@@ -109,11 +108,10 @@ func (in *Interpreter) enforceRestrictions(op OpCode, operation operation, stack
 // It's important to note that any errors returned by the interpreter should be
 // considered a revert-and-consume-all-gas operation. No error specific checks
 // should be handled to reduce complexity and errors further down the in.
+//
+// The call depth is maintained by the EVM, which runs the interpreter as part of
+// its message calls.
 func (in *Interpreter) Run(snapshot int, contract *Contract, input []byte) (ret []byte, err error) {
-	// Increment the call depth which is restricted to 1024
-	in.evm.depth++
-	defer func() { in.evm.depth-- }()
-
 	// Reset the previous call's return data. It's unimportant to preserve the old buffer
 	// as every returning call will return new data anyway.
 	in.returnData = nil
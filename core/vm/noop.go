@@ -46,8 +46,8 @@ func (NoopEVMCallContext) DelegateCall(me ContractRef, addr common.Address, data
 type NoopStateDB struct{}
 
 func (NoopStateDB) CreateAccount(common.Address)                                       {}
-func (NoopStateDB) SubBalance(common.Address, *big.Int)                                {}
-func (NoopStateDB) AddBalance(common.Address, *big.Int)                                {}
+func (NoopStateDB) SubBalance(common.Address, *big.Int, *big.Int, *big.Int)            {}
+func (NoopStateDB) AddBalance(common.Address, *big.Int, *big.Int, *big.Int)            {}
 func (NoopStateDB) GetBalance(common.Address) *big.Int                                 { return nil }
 func (NoopStateDB) GetNonce(common.Address) uint64                                     { return 0 }
 func (NoopStateDB) SetNonce(common.Address, uint64)                                    {}
@@ -0,0 +1,200 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Metric selects which aggregated value an exported profile reports.
+type Metric string
+
+const (
+	MetricGas  Metric = "gas"  // Exclusive gas used
+	MetricTime Metric = "time" // Exclusive wall time in nanoseconds
+)
+
+// value returns the value of the metric in a stat.
+func (m Metric) value(s *Stat) (uint64, error) {
+	switch m {
+	case MetricGas, "":
+		return s.Gas, nil
+	case MetricTime:
+		return uint64(s.Time.Nanoseconds()), nil
+	default:
+		return 0, fmt.Errorf("unknown profile metric %q", string(m))
+	}
+}
+
+// sortedStacks returns the folded call stacks recorded, in lexicographic order.
+func (p *Profiler) sortedStacks() []string {
+	p.finish()
+
+	stacks := make([]string, 0, len(p.stacks))
+	for stack := range p.stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	return stacks
+}
+
+// WriteFolded writes the profile in the folded stack format consumed by flame
+// graph tools (flamegraph.pl, speedscope, inferno), one line per call stack
+// with the chosen metric as the sample count.
+func (p *Profiler) WriteFolded(w io.Writer, metric Metric) error {
+	for _, stack := range p.sortedStacks() {
+		value, err := metric.value(p.stacks[stack])
+		if err != nil {
+			return err
+		}
+		if value == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePprof writes the profile as a gzip compressed pprof protocol buffer,
+// with the gas used, the wall time and the instruction count as sample values.
+// Every frame of the folded call stacks becomes a pprof function.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	var (
+		prof      protoBuffer // Profile message, fields in any order
+		funcs     protoBuffer // Function and location entries
+		strs      = map[string]uint64{"": 0}
+		strTable  = []string{""}
+		functions = make(map[string]uint64)
+	)
+	str := func(s string) uint64 {
+		if id, ok := strs[s]; ok {
+			return id
+		}
+		strs[s] = uint64(len(strTable))
+		strTable = append(strTable, s)
+		return strs[s]
+	}
+	location := func(name string) uint64 {
+		if id, ok := functions[name]; ok {
+			return id
+		}
+		id := uint64(len(functions) + 1)
+		functions[name] = id
+
+		var fn, line, loc protoBuffer
+		fn.uint64(1, id)
+		fn.uint64(2, str(name))
+		fn.uint64(3, str(name))
+		funcs.message(5, fn)
+
+		line.uint64(1, id)
+		loc.uint64(1, id)
+		loc.message(4, line)
+		funcs.message(4, loc)
+		return id
+	}
+	for _, typ := range [][2]string{{"gas", "count"}, {"time", "nanoseconds"}, {"instructions", "count"}} {
+		var vt protoBuffer
+		vt.uint64(1, str(typ[0]))
+		vt.uint64(2, str(typ[1]))
+		prof.message(1, vt)
+	}
+	for _, stack := range p.sortedStacks() {
+		stat := p.stacks[stack]
+
+		// Locations are listed leaf first
+		frames := strings.Split(stack, ";")
+		ids := make([]uint64, len(frames))
+		for i, name := range frames {
+			ids[len(frames)-1-i] = location(name)
+		}
+		var sample protoBuffer
+		sample.packed(1, ids)
+		sample.packed(2, []uint64{stat.Gas, uint64(stat.Time.Nanoseconds()), stat.Count})
+		prof.message(2, sample)
+	}
+	prof.data = append(prof.data, funcs.data...)
+
+	var period protoBuffer
+	period.uint64(1, str("gas"))
+	period.uint64(2, str("count"))
+	prof.message(11, period)
+	prof.uint64(12, 1)
+
+	for _, s := range strTable {
+		prof.bytes(6, []byte(s))
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(prof.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protoBuffer is a minimal protocol buffer encoder, sufficient for the pprof
+// profile format.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) key(field int, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+// uint64 encodes a varint field, omitting zero values like proto3 does.
+func (b *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+// bytes encodes a length delimited field.
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// message encodes an embedded message field.
+func (b *protoBuffer) message(field int, msg protoBuffer) {
+	b.bytes(field, msg.data)
+}
+
+// packed encodes a packed repeated varint field.
+func (b *protoBuffer) packed(field int, xs []uint64) {
+	var enc protoBuffer
+	for _, x := range xs {
+		enc.varint(x)
+	}
+	b.bytes(field, enc.data)
+}
@@ -0,0 +1,340 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package profiler implements an opcode level EVM profiler, aggregating the gas
+// and wall time spent per opcode, per contract and per program counter range.
+package profiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/vm"
+)
+
+// DefaultRangeSize is the default size of the program counter ranges the gas
+// heatmap is aggregated over.
+const DefaultRangeSize = 32
+
+// Config are the configuration options of the profiler.
+type Config struct {
+	RangeSize uint64 // Size of the program counter ranges to aggregate over (0 = default)
+}
+
+// Stat is the aggregated cost of a group of executed instructions. The gas and
+// time are exclusive, i.e. the cost of nested calls is attributed to the
+// instructions of the callee, not to the calling instruction.
+type Stat struct {
+	Count uint64        `json:"count"`
+	Gas   uint64        `json:"gas"`
+	Time  time.Duration `json:"time"`
+}
+
+func (s *Stat) add(gas uint64, t time.Duration) {
+	s.Count++
+	s.Gas += gas
+	s.Time += t
+}
+
+// OpcodeStat is the aggregated cost of an opcode.
+type OpcodeStat struct {
+	Op string `json:"op"`
+	Stat
+}
+
+// ContractStat is the aggregated cost of the code executed at an address.
+type ContractStat struct {
+	Address common.Address `json:"address"`
+	Stat
+}
+
+// Range is a span of program counters [Start, End) within the code executed
+// at an address.
+type Range struct {
+	Address common.Address `json:"address"`
+	Start   uint64         `json:"start"`
+	End     uint64         `json:"end"`
+}
+
+// RangeStat is the aggregated cost of a program counter range.
+type RangeStat struct {
+	Range
+	Stat
+}
+
+// frame is an active call frame of the execution being profiled.
+type frame struct {
+	address  common.Address
+	codeHash common.Hash
+	label    string // Name of the frame in call stacks
+
+	pending  *step  // Last executed step, waiting for its gas to be known
+	childGas uint64 // Gas used by nested calls since the pending step
+	used     uint64 // Gas used by the frame, including nested calls
+}
+
+// step is an executed instruction.
+type step struct {
+	op    vm.OpCode
+	pc    uint64
+	gas   uint64 // Gas available before execution
+	cost  uint64 // Gas cost as reported by the interpreter
+	stack string // Folded call stack of the instruction
+	time  time.Duration
+}
+
+// Profiler is an EVM tracer aggregating the execution cost of opcodes, contracts
+// and program counter ranges. The results can be retrieved as tables or exported
+// as pprof and flame graph profiles.
+//
+// A Profiler is not safe for concurrent use and should profile a single
+// execution.
+type Profiler struct {
+	cfg Config
+
+	frames   []*frame
+	last     *step     // Most recently executed step, being timed
+	lastTime time.Time // Time the most recent step started
+	done     bool      // Whether the profiled execution terminated
+
+	sourceMaps map[common.Hash]*SourceMap
+
+	opcodes   map[vm.OpCode]*Stat
+	contracts map[common.Address]*Stat
+	ranges    map[Range]*Stat
+	stacks    map[string]*Stat
+}
+
+// New creates a new profiler with the given configuration.
+func New(cfg *Config) *Profiler {
+	p := &Profiler{
+		sourceMaps: make(map[common.Hash]*SourceMap),
+		opcodes:    make(map[vm.OpCode]*Stat),
+		contracts:  make(map[common.Address]*Stat),
+		ranges:     make(map[Range]*Stat),
+		stacks:     make(map[string]*Stat),
+	}
+	if cfg != nil {
+		p.cfg = *cfg
+	}
+	if p.cfg.RangeSize == 0 {
+		p.cfg.RangeSize = DefaultRangeSize
+	}
+	return p
+}
+
+// AddSourceMap registers a source map for the code with the given hash, making
+// the profiler attribute the instructions of that code to source lines.
+func (p *Profiler) AddSourceMap(codeHash common.Hash, sourceMap *SourceMap) {
+	p.sourceMaps[codeHash] = sourceMap
+}
+
+// CaptureState implements vm.Tracer, accounting the previous instruction and
+// starting to time the current one.
+func (p *Profiler) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	now := time.Now()
+	if p.last != nil {
+		p.last.time = now.Sub(p.lastTime)
+	}
+	// Unwind the frames of returned calls and enter newly called ones
+	for len(p.frames) > depth {
+		p.pop()
+	}
+	for len(p.frames) < depth {
+		p.push(contract)
+	}
+	f := p.frames[depth-1]
+	if f.address != contract.Address() {
+		// A different contract at the same depth, the previous one returned
+		p.pop()
+		p.push(contract)
+		f = p.frames[depth-1]
+	}
+	// The gas left now reveals the cost of the frame's previous instruction
+	if f.pending != nil {
+		used := f.pending.gas - gas
+		if f.pending.gas < gas {
+			used = 0
+		}
+		if used >= f.childGas {
+			used -= f.childGas
+		} else {
+			used = 0
+		}
+		p.account(f, f.pending, used)
+	}
+	f.childGas = 0
+
+	s := &step{op: op, pc: pc, gas: gas, cost: cost, stack: p.foldedStack(f, pc, op)}
+	f.pending = s
+	p.last, p.lastTime = s, now
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, accounting the instructions still pending.
+func (p *Profiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	p.finish()
+	return nil
+}
+
+// finish terminates the profiled execution if not yet done, accounting the last
+// instruction of every still active frame with its reported cost.
+func (p *Profiler) finish() {
+	if p.done {
+		return
+	}
+	if p.last != nil {
+		p.last.time = time.Since(p.lastTime)
+	}
+	for len(p.frames) > 0 {
+		p.pop()
+	}
+	p.done = true
+}
+
+// push enters the call frame of a contract.
+func (p *Profiler) push(contract *vm.Contract) {
+	f := &frame{
+		address:  contract.Address(),
+		codeHash: contract.CodeHash,
+		label:    contract.Address().Hex(),
+	}
+	if len(p.frames) > 0 {
+		f.label = p.frames[len(p.frames)-1].label + ";" + f.label
+	}
+	p.frames = append(p.frames, f)
+}
+
+// pop leaves the innermost call frame, accounting its last instruction with the
+// reported cost and charging the gas used to the calling frame.
+func (p *Profiler) pop() {
+	f := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	if f.pending != nil {
+		used := f.pending.cost
+		if used >= f.childGas {
+			used -= f.childGas
+		} else {
+			used = 0
+		}
+		p.account(f, f.pending, used)
+		f.pending = nil
+	}
+	if len(p.frames) > 0 {
+		parent := p.frames[len(p.frames)-1]
+		parent.childGas += f.used
+		parent.used += f.used
+	}
+}
+
+// account aggregates the cost of an executed instruction.
+func (p *Profiler) account(f *frame, s *step, gas uint64) {
+	f.used += gas
+
+	if p.opcodes[s.op] == nil {
+		p.opcodes[s.op] = new(Stat)
+	}
+	p.opcodes[s.op].add(gas, s.time)
+
+	if p.contracts[f.address] == nil {
+		p.contracts[f.address] = new(Stat)
+	}
+	p.contracts[f.address].add(gas, s.time)
+
+	start := s.pc / p.cfg.RangeSize * p.cfg.RangeSize
+	r := Range{Address: f.address, Start: start, End: start + p.cfg.RangeSize}
+	if p.ranges[r] == nil {
+		p.ranges[r] = new(Stat)
+	}
+	p.ranges[r].add(gas, s.time)
+
+	if p.stacks[s.stack] == nil {
+		p.stacks[s.stack] = new(Stat)
+	}
+	p.stacks[s.stack].add(gas, s.time)
+}
+
+// foldedStack returns the call stack of an instruction in the folded format of
+// flame graphs, with the source location of the instruction if known.
+func (p *Profiler) foldedStack(f *frame, pc uint64, op vm.OpCode) string {
+	stack := f.label
+	if m := p.sourceMaps[f.codeHash]; m != nil {
+		if file, line, ok := m.Lookup(pc); ok {
+			stack += fmt.Sprintf(";%s:%d", strings.Replace(file, ";", "_", -1), line)
+		}
+	}
+	return stack + ";" + op.String()
+}
+
+// Opcodes returns the aggregated cost per opcode, the most expensive first.
+func (p *Profiler) Opcodes() []OpcodeStat {
+	p.finish()
+
+	stats := make([]OpcodeStat, 0, len(p.opcodes))
+	for op, stat := range p.opcodes {
+		stats = append(stats, OpcodeStat{Op: op.String(), Stat: *stat})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Gas != stats[j].Gas {
+			return stats[i].Gas > stats[j].Gas
+		}
+		return stats[i].Op < stats[j].Op
+	})
+	return stats
+}
+
+// Contracts returns the aggregated cost per contract address, the most
+// expensive first.
+func (p *Profiler) Contracts() []ContractStat {
+	p.finish()
+
+	stats := make([]ContractStat, 0, len(p.contracts))
+	for addr, stat := range p.contracts {
+		stats = append(stats, ContractStat{Address: addr, Stat: *stat})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Gas != stats[j].Gas {
+			return stats[i].Gas > stats[j].Gas
+		}
+		return stats[i].Address.Hex() < stats[j].Address.Hex()
+	})
+	return stats
+}
+
+// Ranges returns the aggregated cost per program counter range, the most
+// expensive first.
+func (p *Profiler) Ranges() []RangeStat {
+	p.finish()
+
+	stats := make([]RangeStat, 0, len(p.ranges))
+	for r, stat := range p.ranges {
+		stats = append(stats, RangeStat{Range: r, Stat: *stat})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Gas != stats[j].Gas {
+			return stats[i].Gas > stats[j].Gas
+		}
+		if stats[i].Address != stats[j].Address {
+			return stats[i].Address.Hex() < stats[j].Address.Hex()
+		}
+		return stats[i].Start < stats[j].Start
+	})
+	return stats
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/core/vm/runtime"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/wtcdb"
+)
+
+var (
+	callerAddr = common.HexToAddress("0x0a")
+	calleeAddr = common.HexToAddress("0x0b")
+
+	// calleeCode stores 1+2 into slot 0 and stops.
+	calleeCode = []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 2, byte(vm.ADD),
+		byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.STOP),
+	}
+	// callerCode calls the callee with all gas left and stops.
+	callerCode = []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0x0b, byte(vm.GAS), byte(vm.CALL),
+		byte(vm.POP), byte(vm.STOP),
+	}
+)
+
+// profile runs the caller contract with the given profiler attached and returns
+// the gas used.
+func profile(t *testing.T, prof *Profiler) uint64 {
+	db, _ := wtcdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetCode(callerAddr, callerCode)
+	statedb.SetCode(calleeAddr, calleeCode)
+
+	cfg := &runtime.Config{
+		State:     statedb,
+		GasLimit:  1000000,
+		EVMConfig: vm.Config{Debug: true, Tracer: prof},
+	}
+	_, left, err := runtime.Call(callerAddr, nil, cfg)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return cfg.GasLimit - left
+}
+
+// Tests that the gas is attributed exclusively, i.e. the per opcode and per
+// contract totals add up to the gas used by the whole execution.
+func TestProfilerExclusiveGas(t *testing.T) {
+	prof := New(nil)
+	used := profile(t, prof)
+
+	var total uint64
+	for _, stat := range prof.Opcodes() {
+		total += stat.Gas
+	}
+	if total != used {
+		t.Errorf("opcode gas total mismatch: have %d, want %d", total, used)
+	}
+	total = 0
+	contracts := prof.Contracts()
+	for _, stat := range contracts {
+		total += stat.Gas
+	}
+	if total != used {
+		t.Errorf("contract gas total mismatch: have %d, want %d", total, used)
+	}
+	if len(contracts) != 2 {
+		t.Fatalf("contract count mismatch: have %d, want 2", len(contracts))
+	}
+	// The callee's SSTORE should dominate, leaving the caller with the cheap rest
+	if contracts[0].Address != calleeAddr {
+		t.Errorf("most expensive contract mismatch: have %x, want %x", contracts[0].Address, calleeAddr)
+	}
+	if op := prof.Opcodes()[0].Op; op != vm.SSTORE.String() {
+		t.Errorf("most expensive opcode mismatch: have %s, want %s", op, vm.SSTORE)
+	}
+}
+
+// Tests that the program counter ranges cover the executed code.
+func TestProfilerRanges(t *testing.T) {
+	prof := New(&Config{RangeSize: 8})
+	profile(t, prof)
+
+	ranges := make(map[Range]bool)
+	for _, stat := range prof.Ranges() {
+		ranges[stat.Range] = true
+	}
+	for _, want := range []Range{
+		{Address: callerAddr, Start: 0, End: 8},
+		{Address: callerAddr, Start: 8, End: 16},
+		{Address: calleeAddr, Start: 0, End: 8},
+		{Address: calleeAddr, Start: 8, End: 16},
+	} {
+		if !ranges[want] {
+			t.Errorf("range %x [%d, %d) missing", want.Address, want.Start, want.End)
+		}
+	}
+}
+
+// Tests that the folded stacks nest the callee under the caller and map the
+// instructions to source lines if a source map is available.
+func TestProfilerFolded(t *testing.T) {
+	source := []byte("contract Callee {\n  function f() {\n    x = 1 + 2;\n  }\n}\n")
+	srcmap := "36:5:0:-;;;36:9:0;;" // PUSH1 PUSH1 ADD on line 3, the rest on line 3 too
+	m, err := NewSourceMap(calleeCode, srcmap, []string{"Callee.sol"}, [][]byte{source})
+	if err != nil {
+		t.Fatalf("failed to parse source map: %v", err)
+	}
+	prof := New(nil)
+	prof.AddSourceMap(crypto.Keccak256Hash(calleeCode), m)
+	profile(t, prof)
+
+	buf := new(bytes.Buffer)
+	if err := prof.WriteFolded(buf, MetricGas); err != nil {
+		t.Fatalf("failed to write folded stacks: %v", err)
+	}
+	want := callerAddr.Hex() + ";" + calleeAddr.Hex() + ";Callee.sol:3;SSTORE "
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("folded stacks missing %q:\n%s", want, buf.String())
+	}
+}
+
+// Tests that the pprof export produces a valid gzip stream with the expected
+// string table entries.
+func TestProfilerPprof(t *testing.T) {
+	prof := New(nil)
+	profile(t, prof)
+
+	buf := new(bytes.Buffer)
+	if err := prof.WritePprof(buf); err != nil {
+		t.Fatalf("failed to write pprof profile: %v", err)
+	}
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatalf("profile not gzip compressed: %v", err)
+	}
+	blob, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress profile: %v", err)
+	}
+	for _, want := range []string{"gas", "nanoseconds", "SSTORE", calleeAddr.Hex()} {
+		if !bytes.Contains(blob, []byte(want)) {
+			t.Errorf("profile missing string %q", want)
+		}
+	}
+}
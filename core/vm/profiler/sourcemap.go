@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wtc/go-wtc/core/vm"
+)
+
+// sourceRange is a single decoded entry of a solc source map.
+type sourceRange struct {
+	start  int // Byte offset of the range in the source file
+	length int // Length of the range in bytes
+	file   int // Index of the source file, -1 if the instruction has no source
+}
+
+// SourceMap maps the program counters of a contract's bytecode to locations in
+// its Solidity sources, using the compressed source mapping format emitted by
+// solc (--srcmap-runtime for deployed code, --srcmap for init code).
+type SourceMap struct {
+	instructions map[uint64]int // Program counter to instruction index
+	ranges       []sourceRange  // Source range of every instruction
+	files        []string       // Names of the source files by index
+	lines        [][]int        // Byte offsets of the line starts of every source file
+}
+
+// NewSourceMap decodes a solc source map of the given bytecode. The files are
+// the names of the source files in the order solc indexed them, the sources
+// their contents, used to turn byte offsets into line numbers.
+func NewSourceMap(code []byte, srcmap string, files []string, sources [][]byte) (*SourceMap, error) {
+	if len(files) != len(sources) {
+		return nil, fmt.Errorf("source file count mismatch: %d names, %d contents", len(files), len(sources))
+	}
+	m := &SourceMap{
+		instructions: make(map[uint64]int),
+		files:        files,
+		lines:        make([][]int, len(sources)),
+	}
+	// Index the instructions of the code, skipping over push data
+	for pc, index := uint64(0), 0; pc < uint64(len(code)); pc, index = pc+1, index+1 {
+		m.instructions[pc] = index
+		if op := vm.OpCode(code[pc]); op >= vm.PUSH1 && op <= vm.PUSH32 {
+			pc += uint64(op - vm.PUSH1 + 1)
+		}
+	}
+	// Decode the source map, empty fields repeat the value of the previous entry
+	prev := sourceRange{file: -1}
+	for i, entry := range strings.Split(srcmap, ";") {
+		fields := strings.Split(entry, ":")
+		values := []*int{&prev.start, &prev.length, &prev.file}
+		for j := 0; j < len(fields) && j < len(values); j++ {
+			if fields[j] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(fields[j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %d: %q", i, entry)
+			}
+			*values[j] = n
+		}
+		if prev.file >= len(files) {
+			return nil, fmt.Errorf("source map entry %d references unknown file %d", i, prev.file)
+		}
+		m.ranges = append(m.ranges, prev)
+	}
+	// Index the line starts of the sources
+	for i, source := range sources {
+		m.lines[i] = []int{0}
+		for offset, c := range source {
+			if c == '\n' {
+				m.lines[i] = append(m.lines[i], offset+1)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Lookup returns the source file and 1-based line number of the instruction at
+// the given program counter.
+func (m *SourceMap) Lookup(pc uint64) (string, int, bool) {
+	index, ok := m.instructions[pc]
+	if !ok || index >= len(m.ranges) {
+		return "", 0, false
+	}
+	r := m.ranges[index]
+	if r.file < 0 {
+		return "", 0, false
+	}
+	lines := m.lines[r.file]
+	line := sort.Search(len(lines), func(i int) bool { return lines[i] > r.start })
+	return m.files[r.file], line, true
+}
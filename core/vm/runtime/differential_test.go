@@ -0,0 +1,287 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rlp"
+	"github.com/wtc/go-wtc/tests"
+	"github.com/wtc/go-wtc/wtcdb"
+)
+
+var (
+	// differentialTestDir contains the contract executions run against both the
+	// built-in and the external interpreter.
+	differentialTestDir = filepath.Join("testdata", "differential")
+
+	// vmTestDir contains the VM tests of the tests submodule, which external
+	// interpreters need to pass the same way as the built-in one.
+	vmTestDir = filepath.Join("..", "..", "..", "tests", "testdata", "VMTests")
+)
+
+// differentialConfig is the chain configuration of the differential tests,
+// enabling every opcode the built-in interpreter supports.
+var differentialConfig = &params.ChainConfig{
+	ChainId:        big.NewInt(1),
+	HomesteadBlock: new(big.Int),
+	EIP150Block:    new(big.Int),
+	EIP155Block:    new(big.Int),
+	EIP158Block:    new(big.Int),
+	ByzantiumBlock: new(big.Int),
+}
+
+// differentialTest is a contract call executed on top of a pre-state.
+type differentialTest struct {
+	Pre  map[common.Address]differentialAccount `json:"pre"`
+	Exec struct {
+		Address common.Address `json:"address"`
+		Input   hexutil.Bytes  `json:"input"`
+		Gas     uint64         `json:"gas"`
+	} `json:"exec"`
+}
+
+type differentialAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// differentialResult is the outcome of a test execution, compared across the
+// interpreters.
+type differentialResult struct {
+	ret   []byte
+	gas   uint64
+	err   error
+	state *state.StateDB
+}
+
+// run executes the test on a fresh copy of its pre-state, dispatching to the
+// given external interpreter if not nil.
+func (dt *differentialTest) run(ext vm.ExternalInterpreter) *differentialResult {
+	db, _ := wtcdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for addr, account := range dt.Pre {
+		statedb.SetCode(addr, account.Code)
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance.ToInt(), new(big.Int), new(big.Int))
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	statedb.Finalise(true)
+
+	cfg := &Config{
+		ChainConfig: differentialConfig,
+		Time:        new(big.Int),
+		GasLimit:    dt.Exec.Gas,
+		State:       statedb,
+	}
+	cfg.EVMConfig.External = ext
+
+	ret, gas, err := Call(dt.Exec.Address, dt.Exec.Input, cfg)
+	return &differentialResult{ret: ret, gas: gas, err: err, state: statedb}
+}
+
+// compare reports any divergence of the return data, remaining gas, failure
+// status, post state or logs of an external execution from the built-in one.
+func (res *differentialResult) compare(want *differentialResult) error {
+	if (res.err == nil) != (want.err == nil) {
+		return fmt.Errorf("error mismatch: have %v, want %v", res.err, want.err)
+	}
+	if !bytes.Equal(res.ret, want.ret) {
+		return fmt.Errorf("return data mismatch: have %x, want %x", res.ret, want.ret)
+	}
+	if res.gas != want.gas {
+		return fmt.Errorf("remaining gas mismatch: have %d, want %d", res.gas, want.gas)
+	}
+	if have, want := res.state.IntermediateRoot(true), want.state.IntermediateRoot(true); have != want {
+		return fmt.Errorf("post state root mismatch: have %x, want %x", have, want)
+	}
+	haveLogs, _ := rlp.EncodeToBytes(res.state.Logs())
+	wantLogs, _ := rlp.EncodeToBytes(want.state.Logs())
+	if !bytes.Equal(haveLogs, wantLogs) {
+		return fmt.Errorf("logs mismatch: have %x, want %x", haveLogs, wantLogs)
+	}
+	return nil
+}
+
+// loadDifferentialTests reads all differential tests, keyed by file and name.
+func loadDifferentialTests() (map[string]*differentialTest, error) {
+	files, err := filepath.Glob(filepath.Join(differentialTestDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	tests := make(map[string]*differentialTest)
+	for _, file := range files {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var set map[string]*differentialTest
+		if err := json.Unmarshal(blob, &set); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		for name, test := range set {
+			tests[filepath.Base(file)+"/"+name] = test
+		}
+	}
+	return tests, nil
+}
+
+var (
+	externalOnce sync.Once
+	external     vm.ExternalInterpreter
+	externalErr  error
+)
+
+// loadExternal loads the external interpreter the differential tests run against
+// from the Go plugin named by the WTC_EVM_PLUGIN environment variable. It falls
+// back to building the plugin in testdata/plugin, which wraps the built-in
+// interpreter. A plugin can only be loaded once per process, so the interpreter
+// is shared by all tests.
+func loadExternal(t *testing.T) vm.ExternalInterpreter {
+	externalOnce.Do(func() {
+		path := os.Getenv("WTC_EVM_PLUGIN")
+		if path == "" {
+			dir, err := ioutil.TempDir("", "evm-plugin-")
+			if err != nil {
+				externalErr = err
+				return
+			}
+			defer os.RemoveAll(dir)
+
+			path = filepath.Join(dir, "interpreter.so")
+			if out, err := exec.Command("go", "build", "-buildmode=plugin", "-o", path, "./testdata/plugin").CombinedOutput(); err != nil {
+				externalErr = fmt.Errorf("failed to build plugin: %v\n%s", err, out)
+				return
+			}
+		}
+		external, externalErr = vm.LoadExternalInterpreter(path)
+	})
+	if externalErr != nil {
+		t.Fatalf("failed to load external interpreter: %v", externalErr)
+	}
+	return external
+}
+
+// TestVMDifferential runs contract executions against an external interpreter
+// and compares every one with the built-in interpreter.
+func TestVMDifferential(t *testing.T) {
+	ext := loadExternal(t)
+
+	tests, err := loadDifferentialTests()
+	if err != nil {
+		t.Fatalf("failed to load tests: %v", err)
+	}
+	if len(tests) == 0 {
+		t.Fatalf("no tests found in %s", differentialTestDir)
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			if err := test.run(ext).compare(test.run(nil)); err != nil {
+				t.Errorf("%s: %v", ext.Name(), err)
+			}
+		})
+	}
+}
+
+// TestVMDifferentialFixtures runs the VM tests of the tests submodule against an
+// external interpreter and ensures each one ends the same way as on the built-in
+// interpreter.
+func TestVMDifferentialFixtures(t *testing.T) {
+	if _, err := os.Stat(vmTestDir); os.IsNotExist(err) {
+		t.Skip("missing test files, did you clone the tests submodule?")
+	}
+	ext := loadExternal(t)
+
+	err := filepath.Walk(vmTestDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(path, vmTestDir+string(filepath.Separator)))
+		switch {
+		case strings.HasPrefix(filepath.Base(path), "vmInputLimits"):
+			return nil // log format broken
+		case strings.HasPrefix(filepath.Base(path), "vmPerformance") && testing.Short():
+			return nil
+		}
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var set map[string]*tests.VMTest
+		if err := json.Unmarshal(blob, &set); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		for key, test := range set {
+			test := test
+			t.Run(name+"/"+key, func(t *testing.T) {
+				want := test.Run(vm.Config{})
+				if have := test.Run(vm.Config{External: ext}); fmt.Sprint(have) != fmt.Sprint(want) {
+					t.Errorf("%s: result mismatch: have %v, want %v", ext.Name(), have, want)
+				}
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that executions dispatched to an external interpreter reach the same
+// call depth limit as the built-in interpreter, i.e. that the depth of a call
+// is only counted once.
+func TestExternalCallDepth(t *testing.T) {
+	tests, err := loadDifferentialTests()
+	if err != nil {
+		t.Fatalf("failed to load tests: %v", err)
+	}
+	test := tests["calls.json/recursionToDepthLimit"]
+	if test == nil {
+		t.Fatalf("recursion test missing")
+	}
+	// The contract counts its frames in slot 0, recursing until the call fails
+	want := common.BigToHash(big.NewInt(int64(params.CallCreateDepth) + 1))
+	for _, ext := range []vm.ExternalInterpreter{nil, loadExternal(t)} {
+		res := test.run(ext)
+		if res.err != nil {
+			t.Fatalf("external %v: execution failed: %v", ext != nil, res.err)
+		}
+		if have := res.state.GetState(test.Exec.Address, common.Hash{}); have != want {
+			t.Errorf("external %v: frame count mismatch: have %x, want %x", ext != nil, have, want)
+		}
+	}
+}
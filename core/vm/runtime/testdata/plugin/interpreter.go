@@ -0,0 +1,39 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package main implements an external interpreter plugin wrapping the built-in
+// interpreter, exercising the plugin loading and dispatch paths of the EVM in
+// the differential tests.
+package main
+
+import "github.com/wtc/go-wtc/core/vm"
+
+// interpreter runs every contract on the built-in interpreter of the host EVM.
+type interpreter struct{}
+
+func (interpreter) Name() string            { return "wrapper" }
+func (interpreter) CanRun(code []byte) bool { return true }
+
+func (interpreter) Run(evm *vm.EVM, contract *vm.Contract, input []byte, readOnly bool) ([]byte, error) {
+	return evm.Interpreter().Run(0, contract, input)
+}
+
+// NewInterpreter is the constructor vm.LoadExternalInterpreter looks up.
+func NewInterpreter() vm.ExternalInterpreter {
+	return interpreter{}
+}
+
+func main() {}
@@ -124,6 +124,41 @@ func FromECDSAPub(pub *ecdsa.PublicKey) []byte {
 	return elliptic.Marshal(S256(), pub.X, pub.Y)
 }
 
+// CompressPubkey encodes a public key to the 33-byte compressed format.
+func CompressPubkey(pubkey *ecdsa.PublicKey) []byte {
+	b := make([]byte, 33)
+	b[0] = 2 + byte(pubkey.Y.Bit(0))
+	math.ReadBits(pubkey.X, b[1:])
+	return b
+}
+
+// DecompressPubkey parses a public key in the 33-byte compressed format,
+// recovering its Y coordinate from the curve equation y^2 = x^3 + 7.
+func DecompressPubkey(pubkey []byte) (*ecdsa.PublicKey, error) {
+	if len(pubkey) != 33 || (pubkey[0] != 2 && pubkey[0] != 3) {
+		return nil, errors.New("invalid compressed public key")
+	}
+	params := S256().Params()
+
+	x := new(big.Int).SetBytes(pubkey[1:])
+	if x.Cmp(params.P) >= 0 {
+		return nil, errors.New("invalid public key x coordinate")
+	}
+	y2 := new(big.Int).Mul(x, x)
+	y2.Mul(y2, x)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil, errors.New("invalid public key, not on curve")
+	}
+	if y.Bit(0) != uint(pubkey[0]&1) {
+		y.Sub(params.P, y)
+	}
+	return &ecdsa.PublicKey{Curve: S256(), X: x, Y: y}, nil
+}
+
 // HexToECDSA parses a secp256k1 private key.
 func HexToECDSA(hexkey string) (*ecdsa.PrivateKey, error) {
 	b, err := hex.DecodeString(hexkey)
@@ -92,6 +92,27 @@ func TestInvalidSign(t *testing.T) {
 	}
 }
 
+func TestPubkeyCompression(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		key, _ := GenerateKey()
+
+		compressed := CompressPubkey(&key.PublicKey)
+		if len(compressed) != 33 {
+			t.Fatalf("compressed key length mismatch: have %d, want 33", len(compressed))
+		}
+		pubkey, err := DecompressPubkey(compressed)
+		if err != nil {
+			t.Fatalf("failed to decompress key: %v", err)
+		}
+		if pubkey.X.Cmp(key.X) != 0 || pubkey.Y.Cmp(key.Y) != 0 {
+			t.Fatalf("decompressed key mismatch: have %x, want %x", FromECDSAPub(pubkey), FromECDSAPub(&key.PublicKey))
+		}
+	}
+	if _, err := DecompressPubkey(make([]byte, 33)); err == nil {
+		t.Errorf("decompressed key with invalid prefix")
+	}
+}
+
 func TestNewContractAddress(t *testing.T) {
 	key, _ := HexToECDSA(testPrivHex)
 	addr := common.HexToAddress(testAddrHex)
@@ -0,0 +1,748 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+)
+
+const (
+	// maxErrors is the number of errors after which the execution of a query is
+	// aborted, preventing a broken query from walking the whole chain.
+	maxErrors = 100
+
+	// maxQueryDepth is the maximum nesting depth of the fields of a query.
+	maxQueryDepth = 12
+
+	// maxQueryFields is the maximum number of fields a query may resolve, bounding
+	// the work of queries fanning out over blocks, transactions and logs.
+	maxQueryFields = 50000
+)
+
+// resolver is implemented by the values of GraphQL object types.
+type resolver interface {
+	// resolve returns the value of a field of the object. The arguments are
+	// coerced to their Go types according to the schema.
+	resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error)
+}
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Error is an error raised while parsing or executing a request.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is the result of a GraphQL request.
+type Response struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors []*Error        `json:"errors,omitempty"`
+}
+
+var (
+	// errTooManyErrors aborts the execution of a request failing everywhere.
+	errTooManyErrors = errors.New("too many errors")
+
+	// errTooComplex aborts the execution of a request resolving too many fields.
+	errTooComplex = fmt.Errorf("query too complex: more than %d fields", maxQueryFields)
+)
+
+// orderedMap is a JSON object keeping the order of its fields, as results need
+// to follow the order of the selections of the query.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// execution is the state of executing an operation.
+type execution struct {
+	schema *schema
+	doc    *document
+	vars   map[string]interface{} // Variable values, validated but not coerced
+	errors []*Error
+	fields int // Number of fields resolved so far
+}
+
+// execute runs a GraphQL request against a root query object.
+func execute(ctx context.Context, s *schema, root resolver, req *Request) *Response {
+	doc, err := parseDocument(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}}
+	}
+	depth, err := selectionDepth(doc, op.selections, make(map[string]int))
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if depth > maxQueryDepth {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("query too deep: %d levels, max %d", depth, maxQueryDepth)}}}
+	}
+	e := &execution{schema: s, doc: doc, vars: make(map[string]interface{})}
+	for _, v := range op.vars {
+		if s.named(v.typ) == nil || s.named(v.typ).kind == objectKind {
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s has invalid type %s", v.name, v.typ)}}}
+		}
+		value, ok := req.Variables[v.name]
+		if !ok {
+			if !v.hasDef {
+				if v.typ.nonNull {
+					return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s of type %s not provided", v.name, v.typ)}}}
+				}
+				continue
+			}
+			value = v.def
+		} else {
+			value = normalizeJSON(value)
+		}
+		// Validate the value against the declared type, coercing it to the type
+		// of the arguments it's used for later
+		if _, err := e.coerce(v.typ, value); err != nil {
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s: %v", v.name, err)}}}
+		}
+		e.vars[v.name] = value
+	}
+	data := e.selectionSet(ctx, s.query, root, op.selections, nil)
+
+	resp := &Response{Errors: e.errors}
+	if data != nil {
+		if resp.Data, err = json.Marshal(data); err != nil {
+			resp.Errors = append(resp.Errors, &Error{Message: err.Error()})
+		}
+	}
+	return resp
+}
+
+// selectOperation picks the operation of a document to execute.
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operation name required for documents with multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+// selectionDepth returns the nesting depth of the fields of a selection set,
+// expanding fragments. The depths of the fragments are memoized, with -1 marking
+// the ones being expanded to detect cycles.
+func selectionDepth(doc *document, sels []selection, fragments map[string]int) (int, error) {
+	depth := 0
+	for _, sel := range sels {
+		var (
+			d   int
+			err error
+		)
+		switch sel := sel.(type) {
+		case *field:
+			d, err = selectionDepth(doc, sel.selections, fragments)
+			d++
+
+		case *fragmentSpread:
+			frag, ok := doc.fragments[sel.name]
+			if !ok {
+				continue // reported during execution
+			}
+			known, ok := fragments[sel.name]
+			switch {
+			case ok && known < 0:
+				return 0, fmt.Errorf("fragment %s spreads itself", sel.name)
+			case ok:
+				d = known
+			default:
+				fragments[sel.name] = -1
+				if d, err = selectionDepth(doc, frag.selections, fragments); err == nil {
+					fragments[sel.name] = d
+				}
+			}
+
+		case *inlineFragment:
+			d, err = selectionDepth(doc, sel.selections, fragments)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth, nil
+}
+
+// fail records an error at a path of the result.
+func (e *execution) fail(path []interface{}, err error) {
+	if len(e.errors) >= maxErrors {
+		return
+	}
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+	if len(e.errors) == maxErrors {
+		e.errors = append(e.errors, &Error{Message: errTooManyErrors.Error()})
+	}
+}
+
+// selectionSet resolves the selected fields of an object, returning nil if a
+// non-null field failed.
+func (e *execution) selectionSet(ctx context.Context, t *typeDef, obj resolver, sels []selection, path []interface{}) *orderedMap {
+	keys, fields, err := e.collect(t, sels, nil, nil, make(map[string]bool))
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+	result := newOrderedMap()
+	for _, key := range keys {
+		fs := fields[key]
+		f := fs[0]
+		fpath := extend(path, key)
+
+		if len(e.errors) >= maxErrors || e.fields > maxQueryFields || ctx.Err() != nil {
+			if ctx.Err() != nil {
+				e.fail(fpath, ctx.Err())
+			}
+			return nil
+		}
+		if f.name == "__typename" {
+			result.set(key, t.name)
+			continue
+		}
+		if e.fields++; e.fields > maxQueryFields {
+			e.fail(fpath, errTooComplex)
+			return nil
+		}
+		def := t.fields[f.name]
+		if def == nil {
+			e.fail(fpath, fmt.Errorf("unknown field %s on type %s", f.name, t.name))
+			return nil
+		}
+		args, err := e.arguments(def, f)
+		if err != nil {
+			e.fail(fpath, err)
+			return nil
+		}
+		// Merge the sub-selections of all fields with the same response key
+		var subsels []selection
+		for _, f := range fs {
+			subsels = append(subsels, f.selections...)
+		}
+		value, err := obj.resolve(ctx, f.name, args)
+		if err != nil {
+			e.fail(fpath, err)
+			value = nil
+		}
+		completed, ok := e.complete(ctx, def.typ, value, subsels, fpath, err != nil)
+		if !ok {
+			return nil
+		}
+		result.set(key, completed)
+	}
+	return result
+}
+
+// collect gathers the fields selected on an object type, grouped by response
+// key, expanding fragments and applying @skip and @include directives.
+func (e *execution) collect(t *typeDef, sels []selection, keys []string, fields map[string][]*field, visited map[string]bool) ([]string, map[string][]*field, error) {
+	if fields == nil {
+		fields = make(map[string][]*field)
+	}
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *field:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !include {
+				continue
+			}
+			key := sel.key()
+			if prev, ok := fields[key]; ok {
+				if prev[0].name != sel.name {
+					return nil, nil, fmt.Errorf("fields %s and %s conflict on response key %s", prev[0].name, sel.name, key)
+				}
+			} else {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+
+		case *fragmentSpread:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !include || visited[sel.name] {
+				continue
+			}
+			visited[sel.name] = true
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown fragment %s", sel.name)
+			}
+			if e.schema.types[frag.on] == nil {
+				return nil, nil, fmt.Errorf("fragment %s on unknown type %s", frag.name, frag.on)
+			}
+			if frag.on != t.name {
+				continue
+			}
+			if keys, fields, err = e.collect(t, frag.selections, keys, fields, visited); err != nil {
+				return nil, nil, err
+			}
+
+		case *inlineFragment:
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !include || (sel.on != "" && sel.on != t.name) {
+				continue
+			}
+			if keys, fields, err = e.collect(t, sel.selections, keys, fields, visited); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return keys, fields, nil
+}
+
+// included evaluates the @skip and @include directives of a selection.
+func (e *execution) included(dirs []*directive) (bool, error) {
+	for _, dir := range dirs {
+		if dir.name != "skip" && dir.name != "include" {
+			continue
+		}
+		cond, err := e.value(&typeRef{name: "Boolean", nonNull: true}, dir.args["if"])
+		if err != nil {
+			return false, fmt.Errorf("@%s: %v", dir.name, err)
+		}
+		if cond.(bool) == (dir.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments coerces the arguments of a field selection according to the field
+// definition.
+func (e *execution) arguments(def *fieldDef, f *field) (map[string]interface{}, error) {
+	for name := range f.args {
+		known := false
+		for _, arg := range def.args {
+			known = known || arg.name == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown argument %s of field %s", name, f.name)
+		}
+	}
+	args := make(map[string]interface{})
+	for _, arg := range def.args {
+		raw, ok := f.args[arg.name]
+		if v, isVar := raw.(variable); ok && isVar {
+			if _, set := e.vars[string(v)]; !set {
+				ok = false
+			}
+		}
+		if !ok {
+			if arg.hasDef {
+				raw, ok = arg.def, true
+			} else if arg.typ.nonNull {
+				return nil, fmt.Errorf("missing argument %s of field %s", arg.name, f.name)
+			} else {
+				continue
+			}
+		}
+		value, err := e.value(arg.typ, raw)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s of field %s: %v", arg.name, f.name, err)
+		}
+		args[arg.name] = value
+	}
+	return args, nil
+}
+
+// value coerces an input literal of the query, resolving variables.
+func (e *execution) value(t *typeRef, raw interface{}) (interface{}, error) {
+	switch raw := raw.(type) {
+	case variable:
+		value, ok := e.vars[string(raw)]
+		if !ok && t.nonNull {
+			return nil, fmt.Errorf("variable $%s not provided", raw)
+		}
+		return e.coerce(t, value)
+
+	case []interface{}:
+		// Resolve variables nested in lists
+		if t.elem != nil {
+			list := make([]interface{}, len(raw))
+			for i, item := range raw {
+				v, err := e.value(t.elem, item)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		}
+
+	case map[string]interface{}:
+		// Resolve variables nested in input objects
+		if def := e.schema.types[t.name]; def != nil && def.kind == inputKind {
+			obj := make(map[string]interface{})
+			for name, item := range raw {
+				fd := def.fields[name]
+				if fd == nil {
+					return nil, fmt.Errorf("unknown field %s of %s", name, def.name)
+				}
+				v, err := e.value(fd.typ, item)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: %v", name, err)
+				}
+				obj[name] = v
+			}
+			for name, fd := range def.fields {
+				if _, ok := obj[name]; !ok && fd.typ.nonNull {
+					return nil, fmt.Errorf("missing field %s of %s", name, def.name)
+				}
+			}
+			return obj, nil
+		}
+	}
+	return e.coerce(t, raw)
+}
+
+// coerce converts an input value to the Go representation of its type. Input
+// values are literals of the query or variables decoded from JSON, normalized
+// to int64, float64, string, bool, nil, []interface{} and maps.
+func (e *execution) coerce(t *typeRef, raw interface{}) (interface{}, error) {
+	if raw == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("null value for %s", t)
+		}
+		return nil, nil
+	}
+	if t.elem != nil {
+		list, ok := raw.([]interface{})
+		if !ok {
+			list = []interface{}{raw}
+		}
+		coerced := make([]interface{}, len(list))
+		for i, item := range list {
+			v, err := e.coerce(t.elem, item)
+			if err != nil {
+				return nil, err
+			}
+			coerced[i] = v
+		}
+		return coerced, nil
+	}
+	def := e.schema.types[t.name]
+	if def == nil {
+		return nil, fmt.Errorf("unknown type %s", t.name)
+	}
+	if def.kind == inputKind {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected %s object", def.name)
+		}
+		coerced := make(map[string]interface{})
+		for name, item := range obj {
+			fd := def.fields[name]
+			if fd == nil {
+				return nil, fmt.Errorf("unknown field %s of %s", name, def.name)
+			}
+			v, err := e.coerce(fd.typ, item)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", name, err)
+			}
+			coerced[name] = v
+		}
+		for name, fd := range def.fields {
+			if _, ok := coerced[name]; !ok && fd.typ.nonNull {
+				return nil, fmt.Errorf("missing field %s of %s", name, def.name)
+			}
+		}
+		return coerced, nil
+	}
+	return coerceScalar(def.name, raw)
+}
+
+// coerceScalar converts an input value to the Go type of a scalar: int32 for
+// Int, float64 for Float, string for String and ID, bool for Boolean, uint64
+// for Long, *big.Int for BigInt, []byte for Bytes, common.Hash for Bytes32 and
+// common.Address for Address.
+func coerceScalar(name string, raw interface{}) (interface{}, error) {
+	if enum, ok := raw.(enumValue); ok {
+		raw = string(enum)
+	}
+	switch name {
+	case "Int":
+		if n, ok := raw.(int64); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int32(n), nil
+		}
+	case "Float":
+		switch n := raw.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case "String":
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case "ID":
+		switch v := raw.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		}
+	case "Boolean":
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+	case "Long":
+		switch v := raw.(type) {
+		case int64:
+			if v >= 0 {
+				return uint64(v), nil
+			}
+		case string:
+			n, ok := parseBig(v)
+			if ok && n.Sign() >= 0 && n.BitLen() <= 64 {
+				return n.Uint64(), nil
+			}
+		}
+	case "BigInt":
+		switch v := raw.(type) {
+		case int64:
+			return big.NewInt(v), nil
+		case string:
+			if n, ok := parseBig(v); ok {
+				return n, nil
+			}
+		}
+	case "Bytes":
+		if s, ok := raw.(string); ok {
+			if b, err := hexutil.Decode(s); err == nil {
+				return b, nil
+			}
+		}
+	case "Bytes32":
+		if s, ok := raw.(string); ok {
+			if b, err := hexutil.Decode(s); err == nil && len(b) <= common.HashLength {
+				return common.BytesToHash(b), nil
+			}
+		}
+	case "Address":
+		if s, ok := raw.(string); ok {
+			if b, err := hexutil.Decode(s); err == nil && len(b) == common.AddressLength {
+				return common.BytesToAddress(b), nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scalar %s", name)
+	}
+	return nil, fmt.Errorf("invalid %s value %v", name, raw)
+}
+
+// parseBig parses a 0x-prefixed hexadecimal or a decimal integer.
+func parseBig(s string) (*big.Int, bool) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return new(big.Int).SetString(s[2:], 16)
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// normalizeJSON converts a value decoded from JSON to the representation of
+// query literals, turning integral numbers into int64. Integers overflowing
+// int64 are kept as decimal strings, which Long and BigInt accept.
+func normalizeJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if _, ok := new(big.Int).SetString(string(v), 10); ok {
+			return string(v)
+		}
+		f, _ := v.Float64()
+		return f
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeJSON(item)
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			obj[key] = normalizeJSON(item)
+		}
+		return obj
+	}
+	return v
+}
+
+// complete converts a resolved value to its result according to the field
+// type. It returns false if a null value violated a non-null type, in which
+// case the parent needs to be nulled too.
+func (e *execution) complete(ctx context.Context, t *typeRef, value interface{}, sels []selection, path []interface{}, failed bool) (interface{}, bool) {
+	if isNil(value) {
+		if t.nonNull {
+			if !failed {
+				e.fail(path, fmt.Errorf("null value for non-null field of type %s", t))
+			}
+			return nil, false
+		}
+		return nil, true
+	}
+	if t.elem != nil {
+		list := reflect.ValueOf(value)
+		if list.Kind() != reflect.Slice {
+			e.fail(path, fmt.Errorf("expected list of type %s", t))
+			return nil, !t.nonNull
+		}
+		result := make([]interface{}, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			item, ok := e.complete(ctx, t.elem, list.Index(i).Interface(), sels, extend(path, i), false)
+			if !ok {
+				return nil, !t.nonNull
+			}
+			result[i] = item
+		}
+		return result, true
+	}
+	def := e.schema.types[t.name]
+	if def.kind == objectKind {
+		if len(sels) == 0 {
+			e.fail(path, fmt.Errorf("field of type %s needs a selection of subfields", t.name))
+			return nil, !t.nonNull
+		}
+		obj, ok := value.(resolver)
+		if !ok {
+			e.fail(path, fmt.Errorf("value of type %T is not a %s object", value, t.name))
+			return nil, !t.nonNull
+		}
+		result := e.selectionSet(ctx, def, obj, sels, path)
+		if result == nil {
+			return nil, !t.nonNull
+		}
+		return result, true
+	}
+	if len(sels) > 0 {
+		e.fail(path, fmt.Errorf("field of scalar type %s has no subfields", t.name))
+		return nil, !t.nonNull
+	}
+	return serializeScalar(def.name, value), true
+}
+
+// serializeScalar converts the Go value of a scalar to its JSON representation.
+func serializeScalar(name string, value interface{}) interface{} {
+	switch name {
+	case "BigInt":
+		if n, ok := value.(*big.Int); ok {
+			return (*hexutil.Big)(n)
+		}
+	case "Bytes":
+		if b, ok := value.([]byte); ok {
+			return hexutil.Bytes(b)
+		}
+	}
+	return value
+}
+
+// isNil reports whether a resolved value is nil, including typed nil pointers.
+// Nil slices are empty lists.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// extend returns a copy of a result path with an element appended.
+func extend(path []interface{}, elem interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(path)+1), path...), elem)
+}
@@ -0,0 +1,244 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+scalar BigInt
+scalar Long
+
+type Item {
+    id: Long!
+    value: BigInt!
+    broken: Long!
+    next: Item
+}
+
+type Query {
+    item(id: Long!): Item
+    items(ids: [Long!]!): [Item!]!
+    greeting(name: String = "wtc"): String!
+}
+`
+
+// testItem is an Item of the test schema, with the value id*10.
+type testItem struct{ id uint64 }
+
+func (i *testItem) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "id":
+		return i.id, nil
+	case "value":
+		return new(big.Int).SetUint64(i.id * 10), nil
+	case "broken":
+		return nil, errors.New("broken field")
+	case "next":
+		return &testItem{i.id + 1}, nil
+	}
+	return nil, unknownField("Item", field)
+}
+
+type testQuery struct{}
+
+func (q *testQuery) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "item":
+		return &testItem{args["id"].(uint64)}, nil
+	case "items":
+		var items []*testItem
+		for _, id := range args["ids"].([]interface{}) {
+			items = append(items, &testItem{id.(uint64)})
+		}
+		return items, nil
+	case "greeting":
+		return "hello " + args["name"].(string), nil
+	}
+	return nil, unknownField("Query", field)
+}
+
+var executeTests = []struct {
+	query  string
+	vars   map[string]interface{}
+	data   string
+	errors []string
+}{
+	{
+		query: `{ item(id: 1) { id value } }`,
+		data:  `{"item":{"id":1,"value":"0xa"}}`,
+	},
+	{
+		query: `{ a: item(id: 2) { id } b: item(id: "0x3") { next { next { id } } } }`,
+		data:  `{"a":{"id":2},"b":{"next":{"next":{"id":5}}}}`,
+	},
+	{
+		query: `query Q($ids: [Long!]!) { items(ids: $ids) { ...f } } fragment f on Item { id }`,
+		vars:  map[string]interface{}{"ids": []interface{}{int64(1), "0x2"}},
+		data:  `{"items":[{"id":1},{"id":2}]}`,
+	},
+	{
+		query: `{ greeting other: greeting(name: "you") }`,
+		data:  `{"greeting":"hello wtc","other":"hello you"}`,
+	},
+	{
+		query: `query($skip: Boolean!) { item(id: 1) { id value @skip(if: $skip) } }`,
+		vars:  map[string]interface{}{"skip": true},
+		data:  `{"item":{"id":1}}`,
+	},
+	// A failing non-null field nulls its nullable parent
+	{
+		query:  `{ item(id: 1) { id broken } }`,
+		data:   `{"item":null}`,
+		errors: []string{"broken field"},
+	},
+	{
+		query:  `{ item(id: 1) { missing } }`,
+		data:   `{"item":null}`,
+		errors: []string{"unknown field missing on type Item"},
+	},
+	{
+		query:  `{ item(id: -1) { id } }`,
+		errors: []string{"invalid Long value"},
+	},
+	{
+		query:  `query($id: Long!) { item(id: $id) { id } }`,
+		errors: []string{"variable $id of type Long! not provided"},
+	},
+	{
+		query:  `mutation { item(id: 1) { id } }`,
+		errors: []string{"mutation operations are not supported"},
+	},
+	{
+		query:  `{ item(id: 1) { id }`,
+		errors: []string{"unexpected"},
+	},
+}
+
+func TestExecute(t *testing.T) {
+	s, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	for i, tt := range executeTests {
+		resp := execute(context.Background(), s, new(testQuery), &Request{Query: tt.query, Variables: tt.vars})
+		if string(resp.Data) != tt.data {
+			t.Errorf("test %d: data mismatch: have %s, want %s", i, resp.Data, tt.data)
+		}
+		if len(resp.Errors) != len(tt.errors) {
+			t.Errorf("test %d: error count mismatch: have %d (%v), want %d", i, len(resp.Errors), resp.Errors, len(tt.errors))
+			continue
+		}
+		for j, want := range tt.errors {
+			if !strings.Contains(resp.Errors[j].Message, want) {
+				t.Errorf("test %d: error %d mismatch: have %q, want %q", i, j, resp.Errors[j].Message, want)
+			}
+		}
+	}
+}
+
+func TestInvalidSchema(t *testing.T) {
+	tests := []string{
+		`type Query { a: Missing }`,
+		`type Query { a: Int } type Query { b: Int }`,
+		`input In { a: Int } type Query { a: In }`,
+		`type Obj { a: Int } type Query { a(o: Obj): Int }`,
+		`type Other { a: Int }`,
+	}
+	for i, src := range tests {
+		if _, err := parseSchema(src); err == nil {
+			t.Errorf("test %d: expected error for schema %q", i, src)
+		}
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	post := httptest.NewRequest("POST", Path, strings.NewReader(`{"query":"{ gasPrice }","variables":{"n":18446744073709551615}}`))
+	req, err := readRequest(post)
+	if err != nil {
+		t.Fatalf("failed to read POST request: %v", err)
+	}
+	if req.Query != "{ gasPrice }" {
+		t.Errorf("query mismatch: have %q", req.Query)
+	}
+	if n := normalizeJSON(req.Variables["n"]); n != "18446744073709551615" {
+		t.Errorf("large number mismatch: have %v (%T)", n, n)
+	}
+	raw := httptest.NewRequest("POST", Path, strings.NewReader(`{ syncing { currentBlock } }`))
+	raw.Header.Set("Content-Type", "application/graphql")
+	if req, err := readRequest(raw); err != nil || req.Query != `{ syncing { currentBlock } }` {
+		t.Errorf("raw query mismatch: have %v, %v", req, err)
+	}
+	get := httptest.NewRequest("GET", Path+`?query=%7B+gasPrice+%7D&variables=%7B%22a%22%3A1%7D`, nil)
+	if req, err := readRequest(get); err != nil || req.Query != "{ gasPrice }" || req.Variables["a"] == nil {
+		t.Errorf("GET query mismatch: have %v, %v", req, err)
+	}
+	if _, err := readRequest(httptest.NewRequest("POST", Path, strings.NewReader(`{}`))); err == nil {
+		t.Errorf("expected error for missing query")
+	}
+	if _, err := readRequest(httptest.NewRequest("PUT", Path, nil)); err == nil {
+		t.Errorf("expected error for unsupported method")
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	s, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	run := func(ctx context.Context, query string, vars map[string]interface{}) *Response {
+		return execute(ctx, s, new(testQuery), &Request{Query: query, Variables: vars})
+	}
+	// Queries nesting fields too deep are rejected before execution
+	nested := func(depth int) string {
+		return "{ item(id: 1) {" + strings.Repeat(" next {", depth-2) + " id" + strings.Repeat(" }", depth-1) + " }"
+	}
+	if resp := run(context.Background(), nested(maxQueryDepth), nil); len(resp.Errors) != 0 {
+		t.Errorf("query at max depth rejected: %v", resp.Errors[0].Message)
+	}
+	if resp := run(context.Background(), nested(maxQueryDepth+1), nil); resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "too deep") {
+		t.Errorf("query above max depth not rejected: %s %v", resp.Data, resp.Errors)
+	}
+	cyclic := `{ item(id: 1) { ...f } } fragment f on Item { next { ...f } }`
+	if resp := run(context.Background(), cyclic, nil); resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "spreads itself") {
+		t.Errorf("cyclic fragment not rejected: %s %v", resp.Data, resp.Errors)
+	}
+	// Queries resolving too many fields are aborted
+	ids := make([]interface{}, maxQueryFields)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	query := `query($ids: [Long!]!) { items(ids: $ids) { id } }`
+	if resp := run(context.Background(), query, map[string]interface{}{"ids": ids}); len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "too complex") {
+		t.Errorf("complex query not aborted: %v", resp.Errors)
+	}
+	if resp := run(context.Background(), query, map[string]interface{}{"ids": ids[:maxQueryFields-1]}); len(resp.Errors) != 0 {
+		t.Errorf("query within field limit rejected: %v", resp.Errors[0].Message)
+	}
+	// Queries past their deadline are aborted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if resp := run(ctx, `{ item(id: 1) { id } }`, nil); resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "canceled") {
+		t.Errorf("cancelled query not aborted: %s %v", resp.Data, resp.Errors)
+	}
+}
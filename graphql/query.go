@@ -0,0 +1,710 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the type of a lexical token of the GraphQL language.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical token of a GraphQL document.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexer splits a GraphQL document into tokens. Whitespace, commas and comments
+// are insignificant and skipped.
+type lexer struct {
+	src string
+	pos int
+	tok token // Current token
+}
+
+func newLexer(src string) (*lexer, error) {
+	l := &lexer{src: strings.TrimPrefix(src, "\ufeff")}
+	if err := l.next(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// next advances the lexer to the next token.
+func (l *lexer) next() error {
+	// Skip over insignificant characters and comments
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		l.tok = token{kind: tokenEOF, pos: start}
+		return nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$()=:@[]{}|&", c) >= 0:
+		l.pos++
+		l.tok = token{kind: tokenPunct, text: string(c), pos: start}
+
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return l.errorf(start, "unexpected character '.'")
+		}
+		l.pos += 3
+		l.tok = token{kind: tokenPunct, text: "...", pos: start}
+
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		l.tok = token{kind: tokenName, text: l.src[start:l.pos], pos: start}
+
+	case c == '-' || isDigit(c):
+		return l.number()
+
+	case c == '"':
+		return l.string()
+
+	default:
+		return l.errorf(start, "unexpected character %q", c)
+	}
+	return nil
+}
+
+// number lexes an integer or float literal.
+func (l *lexer) number() error {
+	start, float := l.pos, false
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return l.errorf(start, "invalid number")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		float = true
+		if digits() == 0 {
+			return l.errorf(start, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		float = true
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return l.errorf(start, "invalid number")
+		}
+	}
+	kind := tokenInt
+	if float {
+		kind = tokenFloat
+	}
+	l.tok = token{kind: kind, text: l.src[start:l.pos], pos: start}
+	return nil
+}
+
+// string lexes a quoted string literal, resolving its escape sequences.
+func (l *lexer) string() error {
+	start := l.pos
+	l.pos++
+
+	var b bytes.Buffer
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			l.tok = token{kind: tokenString, text: b.String(), pos: start}
+			return nil
+		case c == '\n' || c == '\r':
+			return l.errorf(start, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return l.errorf(start, "unterminated string")
+			}
+			l.pos++
+			switch e := l.src[l.pos]; e {
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+5 > len(l.src) {
+					return l.errorf(start, "invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos+1:l.pos+5], 16, 32)
+				if err != nil {
+					return l.errorf(start, "invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				return l.errorf(start, "invalid escape sequence \\%c", e)
+			}
+			l.pos++
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return l.errorf(start, "unterminated string")
+}
+
+// errorf creates a syntax error at the given position of the document.
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	line, col := 1, 1
+	for i := 0; i < pos && i < len(l.src); i++ {
+		if l.src[i] == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// parser is a recursive descent parser of GraphQL documents.
+type parser struct {
+	*lexer
+}
+
+func newParser(src string) (*parser, error) {
+	l, err := newLexer(src)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{l}, nil
+}
+
+// peek reports whether the current token is the given punctuator or name.
+func (p *parser) peek(text string) bool {
+	return (p.tok.kind == tokenPunct || p.tok.kind == tokenName) && p.tok.text == text
+}
+
+// skip consumes the current token if it is the given punctuator or name.
+func (p *parser) skip(text string) (bool, error) {
+	if !p.peek(text) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+// expect consumes the given punctuator or name, failing if it's missing.
+func (p *parser) expect(text string) error {
+	if !p.peek(text) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+// name consumes a name token.
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.text
+	return name, p.next()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return p.errorf(p.tok.pos, "unexpected end of document")
+	}
+	return p.errorf(p.tok.pos, "unexpected %q", p.tok.text)
+}
+
+// typeRef is a reference to a named, list or non-null type.
+type typeRef struct {
+	name    string   // Name of a named type, empty for lists
+	elem    *typeRef // Element type of lists
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// parseType parses a type reference, e.g. [Bytes32!]!
+func (p *parser) parseType() (*typeRef, error) {
+	t := new(typeRef)
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	ok, err := p.skip("!")
+	t.nonNull = ok
+	return t, err
+}
+
+// Values of the GraphQL input language. Literals are represented by their Go
+// equivalents: int64, float64, string, bool, nil, []interface{} for lists and
+// map[string]interface{} for input objects.
+type (
+	variable  string // Reference to a variable of the operation
+	enumValue string // Enum value, coerced to its name
+)
+
+// parseValue parses an input value. Variables are only allowed in queries,
+// not in constant contexts like default values.
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok.pos, "integer %s out of range", tok.text)
+		}
+		return n, p.next()
+
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok.pos, "invalid float %s", tok.text)
+		}
+		return f, p.next()
+
+	case tokenString:
+		return tok.text, p.next()
+
+	case tokenName:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.text), nil
+	}
+	switch {
+	case p.peek("$"):
+		if constant {
+			return nil, p.errorf(tok.pos, "unexpected variable in constant value")
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return variable(name), err
+
+	case p.peek("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.peek("]") {
+			v, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+
+	case p.peek("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]interface{})
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+// document is a parsed executable GraphQL document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query, mutation or subscription of a document.
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []*varDef
+	selections []selection
+}
+
+// varDef is the declaration of a variable of an operation.
+type varDef struct {
+	name   string
+	typ    *typeRef
+	def    interface{}
+	hasDef bool
+}
+
+// fragment is a named fragment definition.
+type fragment struct {
+	name       string
+	on         string
+	selections []selection
+}
+
+// selection is a field, fragment spread or inline fragment.
+type selection interface{}
+
+// directive is a directive annotating a selection, e.g. @include(if: $flag).
+type directive struct {
+	name string
+	args map[string]interface{}
+}
+
+// field is the selection of a field of an object.
+type field struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []*directive
+	selections []selection
+	pos        int
+}
+
+// key returns the name of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread is a reference to a named fragment, e.g. ...blockFields.
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+// inlineFragment is an anonymous fragment, e.g. ... on Block { number }.
+type inlineFragment struct {
+	on         string
+	directives []*directive
+	selections []selection
+}
+
+// parseDocument parses an executable GraphQL document.
+func parseDocument(src string) (*document, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			sels, err := p.parseSelections()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels})
+
+		case p.peek("query") || p.peek("mutation") || p.peek("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case p.peek("fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, fmt.Errorf("duplicate fragment %s", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operation in document")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.tok.text}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			v := new(varDef)
+			if v.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if v.typ, err = p.parseType(); err != nil {
+				return nil, err
+			}
+			if v.hasDef, err = p.skip("="); err != nil {
+				return nil, err
+			}
+			if v.hasDef {
+				if v.def, err = p.parseValue(true); err != nil {
+					return nil, err
+				}
+			}
+			op.vars = append(op.vars, v)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.parseSelections()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	frag := new(fragment)
+	var err error
+	if frag.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("on"); err != nil {
+		return nil, err
+	}
+	if frag.on, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelections(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+// parseSelections parses a selection set enclosed in braces.
+func (p *parser) parseSelections() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.peek("}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, p.errorf(p.tok.pos, "empty selection set")
+	}
+	return sels, p.next()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		// Fragment spread or inline fragment
+		if p.tok.kind == tokenName && p.tok.text != "on" {
+			spread := &fragmentSpread{name: p.tok.text}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			spread.directives, err = p.parseDirectives()
+			return spread, err
+		}
+		inline := new(inlineFragment)
+		if ok, err := p.skip("on"); err != nil {
+			return nil, err
+		} else if ok {
+			if inline.on, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if inline.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		inline.selections, err = p.parseSelections()
+		return inline, err
+	}
+	f := &field{pos: p.tok.pos}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+	if f.args, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.parseSelections(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// parseArguments parses an optional argument list enclosed in parentheses.
+func (p *parser) parseArguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if ok, err := p.skip("("); err != nil || !ok {
+		return args, err
+	}
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := args[name]; ok {
+			return nil, p.errorf(p.tok.pos, "duplicate argument %s", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.next()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, &directive{name: name, args: args})
+	}
+	return dirs, nil
+}
@@ -0,0 +1,718 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/internal/ethapi"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/wtc/filters"
+)
+
+// maxBlockRange is the maximum number of blocks returned by a blocks query.
+const maxBlockRange = 1024
+
+var (
+	errBlockNotFound       = errors.New("block not found")
+	errFiltersNotSupported = errors.New("log filtering not supported by the backend")
+)
+
+// unknownField is returned by resolvers for fields they don't know, which
+// indicates a mismatch between the schema and the resolvers.
+func unknownField(object, field string) error {
+	return fmt.Errorf("field %s of %s not implemented", field, object)
+}
+
+// Account resolves the fields of an account at a particular block.
+type Account struct {
+	backend ethapi.Backend
+	address common.Address
+	blockNr rpc.BlockNumber
+
+	state  *state.StateDB
+	header *types.Header
+}
+
+// getState retrieves the state the account is resolved in.
+func (a *Account) getState(ctx context.Context) (*state.StateDB, *types.Header, error) {
+	if a.state == nil {
+		statedb, header, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if statedb == nil {
+			return nil, nil, errBlockNotFound
+		}
+		a.state, a.header = statedb, header
+	}
+	return a.state, a.header, nil
+}
+
+func (a *Account) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	if field == "address" {
+		return a.address, nil
+	}
+	statedb, header, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	switch field {
+	case "balance":
+		result = statedb.GetBalance(a.address)
+	case "transactionCount":
+		result = statedb.GetNonce(a.address)
+	case "code":
+		result = statedb.GetCode(a.address)
+	case "storage":
+		result = statedb.GetState(a.address, args["slot"].(common.Hash))
+	case "coinAge":
+		result = statedb.GetCoinAge(a.address, header.Number, header.Time)
+	default:
+		return nil, unknownField("Account", field)
+	}
+	return result, statedb.Error()
+}
+
+// Log resolves the fields of a log entry.
+type Log struct {
+	backend ethapi.Backend
+	log     *types.Log
+	tx      *Transaction
+}
+
+func (l *Log) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "index":
+		return int32(l.log.Index), nil
+	case "account":
+		return &Account{backend: l.backend, address: l.log.Address, blockNr: rpc.BlockNumber(l.log.BlockNumber)}, nil
+	case "topics":
+		return l.log.Topics, nil
+	case "data":
+		return l.log.Data, nil
+	case "transaction":
+		if l.tx == nil {
+			l.tx = &Transaction{backend: l.backend, hash: l.log.TxHash}
+		}
+		return l.tx, nil
+	}
+	return nil, unknownField("Log", field)
+}
+
+// Transaction resolves the fields of a mined or pending transaction.
+type Transaction struct {
+	backend ethapi.Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block // Block the transaction was mined in, nil if pending
+	index   uint64
+}
+
+// getTx retrieves the transaction, looking it up in the chain and the pool.
+func (t *Transaction) getTx() *types.Transaction {
+	if t.tx == nil {
+		if tx, blockHash, number, index := core.GetTransaction(t.backend.ChainDb(), t.hash); tx != nil {
+			t.tx, t.index = tx, index
+			t.block = &Block{backend: t.backend, num: rpc.BlockNumber(number), hash: blockHash}
+		} else {
+			t.tx = t.backend.GetPoolTransaction(t.hash)
+		}
+	}
+	return t.tx
+}
+
+// accountBlock returns the block number to resolve the accounts of the
+// transaction at: the block of the transaction or the pending one.
+func (t *Transaction) accountBlock() rpc.BlockNumber {
+	if t.block == nil {
+		return rpc.PendingBlockNumber
+	}
+	return t.block.num
+}
+
+// getReceipt retrieves the receipt of a mined transaction.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if t.getTx() == nil || t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.getReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, fmt.Errorf("receipt of transaction %x not found", t.hash)
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	if field == "hash" {
+		return t.hash, nil
+	}
+	tx := t.getTx()
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", t.hash)
+	}
+	switch field {
+	case "nonce":
+		return tx.Nonce(), nil
+	case "index":
+		if t.block == nil {
+			return nil, nil
+		}
+		return int32(t.index), nil
+	case "from":
+		var signer types.Signer = types.FrontierSigner{}
+		if tx.Protected() {
+			signer = types.NewEIP155Signer(tx.ChainId())
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		return &Account{backend: t.backend, address: from, blockNr: t.accountBlock()}, nil
+	case "to":
+		if tx.To() == nil {
+			return nil, nil
+		}
+		return &Account{backend: t.backend, address: *tx.To(), blockNr: t.accountBlock()}, nil
+	case "value":
+		return tx.Value(), nil
+	case "gasPrice":
+		return tx.GasPrice(), nil
+	case "gas":
+		return tx.Gas().Uint64(), nil
+	case "inputData":
+		return tx.Data(), nil
+	case "block":
+		if t.block == nil {
+			return nil, nil
+		}
+		return t.block, nil
+	case "receipt":
+		if t.block == nil {
+			return nil, nil
+		}
+		return &Receipt{tx: t}, nil
+	}
+	return nil, unknownField("Transaction", field)
+}
+
+// Receipt resolves the fields of the receipt of a mined transaction.
+type Receipt struct {
+	tx *Transaction
+}
+
+func (r *Receipt) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	if field == "transaction" {
+		return r.tx, nil
+	}
+	receipt, err := r.tx.getReceipt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("receipt of transaction %x not found", r.tx.hash)
+	}
+	switch field {
+	case "status":
+		return uint64(receipt.Status), nil
+	case "root":
+		if len(receipt.PostState) != common.HashLength {
+			return nil, nil
+		}
+		return common.BytesToHash(receipt.PostState), nil
+	case "gasUsed":
+		return receipt.GasUsed.Uint64(), nil
+	case "cumulativeGasUsed":
+		return receipt.CumulativeGasUsed.Uint64(), nil
+	case "createdContract":
+		if r.tx.tx.To() != nil {
+			return nil, nil
+		}
+		return &Account{backend: r.tx.backend, address: receipt.ContractAddress, blockNr: r.tx.accountBlock()}, nil
+	case "logs":
+		logs := make([]*Log, len(receipt.Logs))
+		for i, log := range receipt.Logs {
+			logs[i] = &Log{backend: r.tx.backend, log: log, tx: r.tx}
+		}
+		return logs, nil
+	case "logsBloom":
+		return receipt.Bloom.Bytes(), nil
+	}
+	return nil, unknownField("Receipt", field)
+}
+
+// Block resolves the fields of a block, which is loaded lazily by number or
+// hash. Only the header is retrieved as long as the selected fields allow, so
+// light clients don't need to fetch bodies and receipts.
+type Block struct {
+	backend ethapi.Backend
+	num     rpc.BlockNumber
+	hash    common.Hash // Hash of the block, zero if selected by number
+
+	header   *types.Header
+	block    *types.Block
+	receipts types.Receipts
+}
+
+// getHeader retrieves the header of the block.
+func (b *Block) getHeader(ctx context.Context) (*types.Header, error) {
+	if b.header != nil {
+		return b.header, nil
+	}
+	if b.block == nil && b.hash != (common.Hash{}) {
+		if _, err := b.getBlock(ctx); err != nil {
+			return nil, err
+		}
+		return b.header, nil
+	}
+	header, err := b.backend.HeaderByNumber(ctx, b.num)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errBlockNotFound
+	}
+	b.header = header
+	if b.num != rpc.PendingBlockNumber {
+		b.num, b.hash = rpc.BlockNumber(header.Number.Uint64()), header.Hash()
+	}
+	return header, nil
+}
+
+// getBlock retrieves the full block including its body.
+func (b *Block) getBlock(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	var (
+		block *types.Block
+		err   error
+	)
+	if b.num == rpc.PendingBlockNumber {
+		block, err = b.backend.BlockByNumber(ctx, b.num)
+	} else {
+		if b.hash == (common.Hash{}) {
+			if _, err := b.getHeader(ctx); err != nil {
+				return nil, err
+			}
+		}
+		block, err = b.backend.GetBlock(ctx, b.hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errBlockNotFound
+	}
+	b.block, b.header = block, block.Header()
+	if b.num != rpc.PendingBlockNumber {
+		b.num, b.hash = rpc.BlockNumber(block.NumberU64()), block.Hash()
+	}
+	return block, nil
+}
+
+// getReceipts retrieves the receipts of the transactions of the block.
+func (b *Block) getReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts == nil {
+		if _, err := b.getHeader(ctx); err != nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, b.header.Hash())
+		if err != nil {
+			return nil, err
+		}
+		b.receipts = receipts
+	}
+	return b.receipts, nil
+}
+
+// transaction creates the resolver of a transaction of the block.
+func (b *Block) transaction(tx *types.Transaction, index int) *Transaction {
+	t := &Transaction{backend: b.backend, hash: tx.Hash(), tx: tx, index: uint64(index)}
+	if b.num != rpc.PendingBlockNumber {
+		t.block = b
+	}
+	return t
+}
+
+func (b *Block) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	// Fields needing the block body
+	switch field {
+	case "transactionCount", "transactions", "transactionAt", "ommerCount", "ommers":
+		block, err := b.getBlock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case "transactionCount":
+			return int32(len(block.Transactions())), nil
+		case "transactions":
+			txs := make([]*Transaction, len(block.Transactions()))
+			for i, tx := range block.Transactions() {
+				txs[i] = b.transaction(tx, i)
+			}
+			return txs, nil
+		case "transactionAt":
+			index := int(args["index"].(int32))
+			if index < 0 || index >= len(block.Transactions()) {
+				return nil, nil
+			}
+			return b.transaction(block.Transactions()[index], index), nil
+		case "ommerCount":
+			return int32(len(block.Uncles())), nil
+		case "ommers":
+			ommers := make([]*Block, len(block.Uncles()))
+			for i, uncle := range block.Uncles() {
+				ommers[i] = &Block{backend: b.backend, num: rpc.BlockNumber(uncle.Number.Uint64()), hash: uncle.Hash(), header: uncle}
+			}
+			return ommers, nil
+		}
+	}
+	// Fields of the header
+	header, err := b.getHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch field {
+	case "number":
+		return header.Number.Uint64(), nil
+	case "hash":
+		return header.Hash(), nil
+	case "parent":
+		if header.Number.Sign() == 0 {
+			return nil, nil
+		}
+		return &Block{backend: b.backend, num: rpc.BlockNumber(header.Number.Uint64() - 1), hash: header.ParentHash}, nil
+	case "nonce":
+		return header.Nonce[:], nil
+	case "transactionsRoot":
+		return header.TxHash, nil
+	case "stateRoot":
+		return header.Root, nil
+	case "receiptsRoot":
+		return header.ReceiptHash, nil
+	case "miner":
+		return &Account{backend: b.backend, address: header.Coinbase, blockNr: b.num}, nil
+	case "extraData":
+		return header.Extra, nil
+	case "gasLimit":
+		return header.GasLimit.Uint64(), nil
+	case "gasUsed":
+		return header.GasUsed.Uint64(), nil
+	case "timestamp":
+		return header.Time, nil
+	case "logsBloom":
+		return header.Bloom.Bytes(), nil
+	case "mixHash":
+		return header.MixDigest, nil
+	case "difficulty":
+		return header.Difficulty, nil
+	case "totalDifficulty":
+		td := b.backend.GetTd(header.Hash())
+		if td == nil {
+			return nil, fmt.Errorf("total difficulty of block %x not found", header.Hash())
+		}
+		return td, nil
+	case "coinAge":
+		return header.CoinAge, nil
+	case "account":
+		return &Account{backend: b.backend, address: args["address"].(common.Address), blockNr: b.num}, nil
+	case "logs":
+		return b.logs(ctx, args["filter"].(map[string]interface{}))
+	}
+	return nil, unknownField("Block", field)
+}
+
+// logs returns the logs of the block matching the filter criteria.
+func (b *Block) logs(ctx context.Context, filter map[string]interface{}) ([]*Log, error) {
+	addresses, topics := filterCriteria(filter)
+
+	header, err := b.getHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !bloomMatches(header.Bloom, addresses, topics) {
+		return []*Log{}, nil
+	}
+	receipts, err := b.getReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*Log{}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if logMatches(log, addresses, topics) {
+				logs = append(logs, &Log{backend: b.backend, log: log})
+			}
+		}
+	}
+	return logs, nil
+}
+
+// filterCriteria extracts the addresses and topics of coerced filter criteria.
+func filterCriteria(filter map[string]interface{}) ([]common.Address, [][]common.Hash) {
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	if list, ok := filter["addresses"].([]interface{}); ok {
+		for _, addr := range list {
+			addresses = append(addresses, addr.(common.Address))
+		}
+	}
+	if list, ok := filter["topics"].([]interface{}); ok {
+		for _, position := range list {
+			var hashes []common.Hash
+			for _, topic := range position.([]interface{}) {
+				hashes = append(hashes, topic.(common.Hash))
+			}
+			topics = append(topics, hashes)
+		}
+	}
+	return addresses, topics
+}
+
+// bloomMatches reports whether a block may contain logs matching the criteria.
+func bloomMatches(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		found := len(sub) == 0 // empty rule set is a wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches reports whether a log matches the filter criteria.
+func logMatches(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, addr := range addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		found := len(sub) == 0 // empty rule set is a wildcard
+		for _, topic := range sub {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Pending resolves the fields of the pending state.
+type Pending struct {
+	backend ethapi.Backend
+}
+
+func (p *Pending) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "transactionCount", "transactions":
+		pending, err := p.backend.GetPoolTransactions()
+		if err != nil {
+			return nil, err
+		}
+		if field == "transactionCount" {
+			return int32(len(pending)), nil
+		}
+		txs := make([]*Transaction, len(pending))
+		for i, tx := range pending {
+			txs[i] = &Transaction{backend: p.backend, hash: tx.Hash(), tx: tx}
+		}
+		return txs, nil
+	case "account":
+		return &Account{backend: p.backend, address: args["address"].(common.Address), blockNr: rpc.PendingBlockNumber}, nil
+	}
+	return nil, unknownField("Pending", field)
+}
+
+// Query resolves the fields of the root query type.
+type Query struct {
+	backend ethapi.Backend
+}
+
+func (q *Query) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "block":
+		var block *Block
+		if hash, ok := args["hash"].(common.Hash); ok {
+			block = &Block{backend: q.backend, hash: hash}
+			if _, err := block.getBlock(ctx); err == errBlockNotFound {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+		} else {
+			block = &Block{backend: q.backend, num: rpc.LatestBlockNumber}
+			if number, ok := args["number"].(uint64); ok {
+				block.num = rpc.BlockNumber(number)
+			}
+			if _, err := block.getHeader(ctx); err == errBlockNotFound {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return block, nil
+
+	case "blocks":
+		from := args["from"].(uint64)
+		head, err := q.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		to := head.Number.Uint64()
+		if number, ok := args["to"].(uint64); ok && number < to {
+			to = number
+		}
+		if from > to {
+			return []*Block{}, nil
+		}
+		if to-from >= maxBlockRange {
+			return nil, fmt.Errorf("block range too large: %d blocks, max %d", to-from+1, maxBlockRange)
+		}
+		blocks := make([]*Block, 0, to-from+1)
+		for number := from; number <= to; number++ {
+			block := &Block{backend: q.backend, num: rpc.BlockNumber(number)}
+			if _, err := block.getHeader(ctx); err == errBlockNotFound {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+		}
+		return blocks, nil
+
+	case "pending":
+		return &Pending{backend: q.backend}, nil
+
+	case "transaction":
+		tx := &Transaction{backend: q.backend, hash: args["hash"].(common.Hash)}
+		if tx.getTx() == nil {
+			return nil, nil
+		}
+		return tx, nil
+
+	case "logs":
+		return q.logs(ctx, args["filter"].(map[string]interface{}))
+
+	case "gasPrice":
+		return q.backend.SuggestPrice(ctx)
+
+	case "protocolVersion":
+		return int32(q.backend.ProtocolVersion()), nil
+
+	case "syncing":
+		progress := q.backend.Downloader().Progress()
+		if progress.CurrentBlock >= progress.HighestBlock {
+			return nil, nil
+		}
+		return &SyncState{progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, progress.PulledStates, progress.KnownStates}, nil
+	}
+	return nil, unknownField("Query", field)
+}
+
+// logs returns the logs of a range of blocks matching the filter criteria,
+// using the bloom bits index of the backend.
+func (q *Query) logs(ctx context.Context, filter map[string]interface{}) ([]*Log, error) {
+	backend, ok := q.backend.(filters.Backend)
+	if !ok {
+		return nil, errFiltersNotSupported
+	}
+	from, to := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+	if number, ok := filter["fromBlock"].(uint64); ok {
+		from = int64(number)
+	}
+	if number, ok := filter["toBlock"].(uint64); ok {
+		to = int64(number)
+	}
+	addresses, topics := filterCriteria(filter)
+
+	found, err := filters.New(backend, from, to, addresses, topics).Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]*Log, len(found))
+	for i, log := range found {
+		logs[i] = &Log{backend: q.backend, log: log}
+	}
+	return logs, nil
+}
+
+// SyncState resolves the fields of the synchronisation progress.
+type SyncState struct {
+	startingBlock, currentBlock, highestBlock, pulledStates, knownStates uint64
+}
+
+func (s *SyncState) resolve(ctx context.Context, field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "startingBlock":
+		return s.startingBlock, nil
+	case "currentBlock":
+		return s.currentBlock, nil
+	case "highestBlock":
+		return s.highestBlock, nil
+	case "pulledStates":
+		return s.pulledStates, nil
+	case "knownStates":
+		return s.knownStates, nil
+	}
+	return nil, unknownField("SyncState", field)
+}
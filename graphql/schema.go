@@ -0,0 +1,404 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import "fmt"
+
+// schemaSource is the GraphQL schema of the chain data served by the node.
+const schemaSource = `
+# Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+scalar Bytes32
+# Address is a 20 byte account address, represented as 0x-prefixed hexadecimal.
+scalar Address
+# Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+scalar Bytes
+# BigInt is a large integer, represented as 0x-prefixed hexadecimal in results
+# and accepted as hexadecimal or decimal strings in inputs.
+scalar BigInt
+# Long is a 64 bit unsigned integer.
+scalar Long
+
+schema {
+    query: Query
+}
+
+# Account is an account at a particular block.
+type Account {
+    address: Address!
+    balance: BigInt!
+    # transactionCount is the nonce of the account.
+    transactionCount: Long!
+    code: Bytes!
+    storage(slot: Bytes32!): Bytes32!
+    coinAge: BigInt!
+}
+
+# Log is a log entry emitted by a contract during the execution of a transaction.
+type Log {
+    index: Int!
+    account: Account!
+    topics: [Bytes32!]!
+    data: Bytes!
+    transaction: Transaction!
+}
+
+# Transaction is a transaction, either mined or pending.
+type Transaction {
+    hash: Bytes32!
+    nonce: Long!
+    # index is the position of the transaction in its block, null if pending.
+    index: Int
+    from: Account!
+    # to is the recipient of the transaction, null for contract creations.
+    to: Account
+    value: BigInt!
+    gasPrice: BigInt!
+    gas: Long!
+    inputData: Bytes!
+    # block is the block the transaction was mined in, null if pending.
+    block: Block
+    # receipt is the execution receipt of the transaction, null if pending.
+    receipt: Receipt
+}
+
+# Receipt is the outcome of executing a mined transaction.
+type Receipt {
+    transaction: Transaction!
+    status: Long!
+    root: Bytes32
+    gasUsed: Long!
+    cumulativeGasUsed: Long!
+    createdContract: Account
+    logs: [Log!]!
+    logsBloom: Bytes!
+}
+
+# BlockFilterCriteria selects the logs of a block.
+input BlockFilterCriteria {
+    # addresses lists the contracts to return logs of, all if empty.
+    addresses: [Address!]
+    # topics lists the topics to match at each position, any if empty.
+    topics: [[Bytes32!]!]
+}
+
+# Block is a block of the chain, or the pending block.
+type Block {
+    number: Long!
+    hash: Bytes32!
+    parent: Block
+    nonce: Bytes!
+    transactionsRoot: Bytes32!
+    transactionCount: Int
+    stateRoot: Bytes32!
+    receiptsRoot: Bytes32!
+    miner: Account!
+    extraData: Bytes!
+    gasLimit: Long!
+    gasUsed: Long!
+    timestamp: BigInt!
+    logsBloom: Bytes!
+    mixHash: Bytes32!
+    difficulty: BigInt!
+    totalDifficulty: BigInt!
+    coinAge: BigInt!
+    ommerCount: Int
+    ommers: [Block]
+    transactions: [Transaction!]
+    transactionAt(index: Int!): Transaction
+    logs(filter: BlockFilterCriteria!): [Log!]!
+    account(address: Address!): Account!
+}
+
+# FilterCriteria selects logs of a range of blocks.
+input FilterCriteria {
+    # fromBlock is the first block of the range, the latest one by default.
+    fromBlock: Long
+    # toBlock is the last block of the range, the latest one by default.
+    toBlock: Long
+    addresses: [Address!]
+    topics: [[Bytes32!]!]
+}
+
+# Pending is the state of the pending block.
+type Pending {
+    transactionCount: Int!
+    transactions: [Transaction!]
+    account(address: Address!): Account!
+}
+
+# SyncState is the progress of the chain synchronisation.
+type SyncState {
+    startingBlock: Long!
+    currentBlock: Long!
+    highestBlock: Long!
+    pulledStates: Long
+    knownStates: Long
+}
+
+type Query {
+    # block returns a block by number or hash, the latest one if neither is given.
+    block(number: Long, hash: Bytes32): Block
+    # blocks returns the blocks in the given range, up to the latest one.
+    blocks(from: Long!, to: Long): [Block!]!
+    pending: Pending!
+    transaction(hash: Bytes32!): Transaction
+    logs(filter: FilterCriteria!): [Log!]!
+    gasPrice: BigInt!
+    protocolVersion: Int!
+    # syncing returns the synchronisation progress, null if not syncing.
+    syncing: SyncState
+}
+`
+
+// typeKind is the kind of a type of the schema.
+type typeKind int
+
+const (
+	scalarKind typeKind = iota
+	objectKind
+	inputKind
+)
+
+// typeDef is a scalar, object or input object type of the schema.
+type typeDef struct {
+	kind   typeKind
+	name   string
+	fields map[string]*fieldDef
+}
+
+// fieldDef is a field of an object or input object type. Fields of input
+// objects have no arguments.
+type fieldDef struct {
+	name string
+	args []*argDef
+	typ  *typeRef
+}
+
+// argDef is an argument of a field, or a field of an input object.
+type argDef struct {
+	name   string
+	typ    *typeRef
+	def    interface{}
+	hasDef bool
+}
+
+// schema is a parsed GraphQL schema.
+type schema struct {
+	types map[string]*typeDef
+	query *typeDef
+}
+
+// builtinScalars are the scalar types every schema has.
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+// parseSchema parses a schema in the GraphQL schema definition language. Only
+// scalar, object and input object types are supported.
+func parseSchema(src string) (*schema, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	s := &schema{types: make(map[string]*typeDef)}
+	for _, name := range builtinScalars {
+		s.types[name] = &typeDef{kind: scalarKind, name: name}
+	}
+	query := "Query"
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("scalar"):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := s.define(&typeDef{kind: scalarKind, name: name}); err != nil {
+				return nil, err
+			}
+
+		case p.peek("type") || p.peek("input"):
+			kind := objectKind
+			if p.peek("input") {
+				kind = inputKind
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			t := &typeDef{kind: kind, fields: make(map[string]*fieldDef)}
+			if t.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.parseFieldDefs(t); err != nil {
+				return nil, err
+			}
+			if err := s.define(t); err != nil {
+				return nil, err
+			}
+
+		case p.peek("schema"):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if err := p.expect("{"); err != nil {
+				return nil, err
+			}
+			for !p.peek("}") {
+				op, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if op != "query" {
+					return nil, fmt.Errorf("unsupported %s operation type", op)
+				}
+				query = name
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	if s.query = s.types[query]; s.query == nil || s.query.kind != objectKind {
+		return nil, fmt.Errorf("query type %s not defined", query)
+	}
+	return s, nil
+}
+
+// parseFieldDefs parses the field definitions of an object or input type.
+func (p *parser) parseFieldDefs(t *typeDef) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.peek("}") {
+		f := new(fieldDef)
+		var err error
+		if f.name, err = p.name(); err != nil {
+			return err
+		}
+		if ok, err := p.skip("("); err != nil {
+			return err
+		} else if ok {
+			if t.kind == inputKind {
+				return p.errorf(p.tok.pos, "input field %s.%s with arguments", t.name, f.name)
+			}
+			for !p.peek(")") {
+				arg, err := p.parseArgDef()
+				if err != nil {
+					return err
+				}
+				f.args = append(f.args, arg)
+			}
+			if err := p.next(); err != nil {
+				return err
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if f.typ, err = p.parseType(); err != nil {
+			return err
+		}
+		if _, ok := t.fields[f.name]; ok {
+			return fmt.Errorf("duplicate field %s.%s", t.name, f.name)
+		}
+		t.fields[f.name] = f
+	}
+	return p.next()
+}
+
+// parseArgDef parses an argument definition with an optional default value.
+func (p *parser) parseArgDef() (*argDef, error) {
+	arg := new(argDef)
+	var err error
+	if arg.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if arg.typ, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if arg.hasDef, err = p.skip("="); err != nil {
+		return nil, err
+	}
+	if arg.hasDef {
+		if arg.def, err = p.parseValue(true); err != nil {
+			return nil, err
+		}
+	}
+	return arg, nil
+}
+
+// define adds a type to the schema.
+func (s *schema) define(t *typeDef) error {
+	if _, ok := s.types[t.name]; ok {
+		return fmt.Errorf("duplicate type %s", t.name)
+	}
+	s.types[t.name] = t
+	return nil
+}
+
+// check verifies that all type references of the schema resolve, and that
+// fields and arguments are of output and input types respectively.
+func (s *schema) check() error {
+	for _, t := range s.types {
+		for _, f := range t.fields {
+			ft := s.named(f.typ)
+			if ft == nil {
+				return fmt.Errorf("field %s.%s has undefined type %s", t.name, f.name, f.typ)
+			}
+			if t.kind == objectKind && ft.kind == inputKind {
+				return fmt.Errorf("field %s.%s has input type %s", t.name, f.name, f.typ)
+			}
+			if t.kind == inputKind && ft.kind == objectKind {
+				return fmt.Errorf("input field %s.%s has object type %s", t.name, f.name, f.typ)
+			}
+			for _, arg := range f.args {
+				at := s.named(arg.typ)
+				if at == nil {
+					return fmt.Errorf("argument %s of %s.%s has undefined type %s", arg.name, t.name, f.name, arg.typ)
+				}
+				if at.kind == objectKind {
+					return fmt.Errorf("argument %s of %s.%s has object type %s", arg.name, t.name, f.name, arg.typ)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// named resolves the named type of a (wrapped) type reference.
+func (s *schema) named(t *typeRef) *typeDef {
+	for t.elem != nil {
+		t = t.elem
+	}
+	return s.types[t.name]
+}
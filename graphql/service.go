@@ -0,0 +1,196 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to the chain data of a node.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+	"github.com/wtc/go-wtc/internal/ethapi"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/rpc"
+)
+
+const (
+	// maxRequestContentLength is the maximum size of a GraphQL request body.
+	maxRequestContentLength = 1024 * 128
+
+	// queryTimeout is the execution deadline of a query, unless the RPC limits of
+	// the node set a shorter one.
+	queryTimeout = 30 * time.Second
+)
+
+// Path is the URL path the GraphQL endpoint is served under.
+const Path = "/graphql"
+
+// chainSchema is the parsed schema of the chain data.
+var chainSchema = mustParseSchema(schemaSource)
+
+func mustParseSchema(src string) *schema {
+	s, err := parseSchema(src)
+	if err != nil {
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	return s
+}
+
+// Service is a node service serving GraphQL queries over the chain data of a
+// full or light client backend on the HTTP-RPC listener of the node.
+type Service struct {
+	handler http.Handler
+}
+
+// New creates a GraphQL service on top of an API backend. The endpoint applies
+// its own CORS and virtual host policies, independently of the JSON-RPC API.
+func New(backend ethapi.Backend, cors []string, vhosts []string) (*Service, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("no backend to serve GraphQL queries from")
+	}
+	handler := newCorsHandler(&Handler{backend: backend}, cors)
+	return &Service{handler: rpc.NewVirtualHostHandler(vhosts, handler)}, nil
+}
+
+// Protocols implements node.Service, returning no p2p protocols.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning no RPC APIs.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service. The endpoint is served by the node.
+func (s *Service) Start(server *p2p.Server) error {
+	log.Info("GraphQL endpoint enabled", "path", Path)
+	return nil
+}
+
+// Stop implements node.Service.
+func (s *Service) Stop() error { return nil }
+
+// HTTPHandlers implements node.HTTPService, serving the GraphQL endpoint.
+func (s *Service) HTTPHandlers() map[string]http.Handler {
+	return map[string]http.Handler{Path: s.handler}
+}
+
+// newCorsHandler wraps a handler with CORS support for the allowed origins.
+func newCorsHandler(handler http.Handler, allowedOrigins []string) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return handler
+	}
+	c := cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{"POST", "GET"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         600,
+	})
+	return c.Handler(handler)
+}
+
+// Handler executes GraphQL requests against an API backend. Queries are
+// accepted as JSON POST bodies, as raw application/graphql bodies and as GET
+// query parameters.
+type Handler struct {
+	backend ethapi.Backend
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: err.Error()}}})
+		return
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Error("GraphQL query panicked", "err", err, "stack", string(buf))
+			writeResponse(w, http.StatusInternalServerError, &Response{Errors: []*Error{{Message: "internal error"}}})
+		}
+	}()
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	resp := execute(ctx, chainSchema, &Query{backend: h.backend}, req)
+
+	status := http.StatusOK
+	if resp.Data == nil && len(resp.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	writeResponse(w, status, resp)
+}
+
+// readRequest extracts the GraphQL request from an HTTP request.
+func readRequest(r *http.Request) (*Request, error) {
+	req := new(Request)
+	switch r.Method {
+	case "GET":
+		params := r.URL.Query()
+		req.Query, req.OperationName = params.Get("query"), params.Get("operationName")
+		if vars := params.Get("variables"); vars != "" {
+			if err := decodeJSON(strings.NewReader(vars), &req.Variables); err != nil {
+				return nil, fmt.Errorf("invalid variables: %v", err)
+			}
+		}
+	case "POST":
+		if r.ContentLength > maxRequestContentLength {
+			return nil, fmt.Errorf("content length too large (%d>%d)", r.ContentLength, maxRequestContentLength)
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestContentLength+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > maxRequestContentLength {
+			return nil, fmt.Errorf("content length too large (>%d)", maxRequestContentLength)
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			req.Query = string(body)
+		} else if err := decodeJSON(bytes.NewReader(body), req); err != nil {
+			return nil, fmt.Errorf("invalid request: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported method %s", r.Method)
+	}
+	if req.Query == "" {
+		return nil, fmt.Errorf("missing query")
+	}
+	return req, nil
+}
+
+// decodeJSON decodes JSON keeping numbers exact, as Long and BigInt variables
+// may exceed the precision of floats.
+func decodeJSON(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
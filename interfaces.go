@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/core/types"
@@ -103,6 +104,10 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	Phase      string        // Sync phase currently waited on (headers, bodies or receipts)
+	Throughput uint64        // Number of items processed per second in the current phase
+	ETA        time.Duration // Estimated time left until the current phase completes
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
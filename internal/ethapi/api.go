@@ -33,6 +33,7 @@ import (
 	"github.com/wtc/go-wtc/common/math"
 	"github.com/wtc/go-wtc/consensus/ethash"
 	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/core/vm"
 	"github.com/wtc/go-wtc/crypto"
@@ -41,6 +42,7 @@ import (
 	"github.com/wtc/go-wtc/params"
 	"github.com/wtc/go-wtc/rlp"
 	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/trie"
 )
 
 const (
@@ -76,6 +78,9 @@ func (s *PublicWtcAPI) ProtocolVersion() hexutil.Uint {
 // - highestBlock:  block number of the highest block header this node has received from peers
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
+// - phase:         sync phase currently waited on (headers, bodies or receipts)
+// - throughput:    number of items processed per second in the current phase
+// - eta:           estimated number of seconds until the current phase completes
 func (s *PublicWtcAPI) Syncing() (interface{}, error) {
 	progress := s.b.Downloader().Progress()
 
@@ -90,6 +95,9 @@ func (s *PublicWtcAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"phase":         progress.Phase,
+		"throughput":    hexutil.Uint64(progress.Throughput),
+		"eta":           hexutil.Uint64(progress.ETA / time.Second),
 	}, nil
 }
 
@@ -382,13 +390,20 @@ func signHash(data []byte) []byte {
 	return crypto.Keccak256([]byte(msg))
 }
 
+// textSigner is implemented by wallets signing messages themselves rather than
+// their hashes, e.g. external signers showing the message to their user.
+type textSigner interface {
+	SignText(account accounts.Account, text []byte) ([]byte, error)
+}
+
 // Sign calculates an Wtc ECDSA signature for:
 // keccack256("\x19Wtc Signed Message:\n" + len(message) + message))
 //
 // Note, the produced signature conforms to the secp256k1 curve R, S and V values,
 // where the V value will be 27 or 28 for legacy reasons.
 //
-// The key used to calculate the signature is decrypted with the given password.
+// The key used to calculate the signature is decrypted with the given password,
+// unless the wallet signs messages itself and approves them by other means.
 //
 // https://github.com/wtc/go-wtc/wiki/Management-APIs#personal_sign
 func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr common.Address, passwd string) (hexutil.Bytes, error) {
@@ -400,7 +415,12 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 		return nil, err
 	}
 	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, passwd, signHash(data))
+	var signature []byte
+	if signer, ok := wallet.(textSigner); ok {
+		signature, err = signer.SignText(account, data)
+	} else {
+		signature, err = wallet.SignHashWithPassphrase(account, passwd, signHash(data))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -575,6 +595,103 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the merkle proof of an account in the state trie, along with
+// the account fields it proves and the proofs of the requested storage slots.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CoinAge      *hexutil.Big    `json:"coinAge"`
+	FUBlockTime  *hexutil.Big    `json:"fuBlockTime"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the merkle proof of a storage slot in the storage trie of
+// an account.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the merkle proof of the given account and storage slots in
+// the state of the given block number. The account and storage values returned
+// are decoded from the proofs, which are verified against the state root of
+// the block before being returned.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	accountProof, err := statedb.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := trie.VerifyProof(header.Root, crypto.Keccak256(address[:]), accountProof)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	// Non-existent accounts are proven by absence and reported empty
+	account := state.Account{
+		Balance:     new(big.Int),
+		CoinAge:     new(big.Int),
+		FUBlockTime: new(big.Int),
+		Root:        types.EmptyRootHash,
+		CodeHash:    crypto.Keccak256(nil),
+	}
+	if enc != nil {
+		if err := rlp.DecodeBytes(enc, &account); err != nil {
+			return nil, fmt.Errorf("invalid account in proof: %v", err)
+		}
+	}
+	result := &AccountResult{
+		Address:      address,
+		AccountProof: encodeProof(accountProof),
+		Balance:      (*hexutil.Big)(account.Balance),
+		CoinAge:      (*hexutil.Big)(account.CoinAge),
+		FUBlockTime:  (*hexutil.Big)(account.FUBlockTime),
+		CodeHash:     common.BytesToHash(account.CodeHash),
+		Nonce:        hexutil.Uint64(account.Nonce),
+		StorageHash:  account.Root,
+		StorageProof: make([]StorageResult, len(storageKeys)),
+	}
+	for i, key := range storageKeys {
+		hash := common.HexToHash(key)
+		proof, err := statedb.GetStorageProof(address, hash)
+		if err != nil {
+			return nil, err
+		}
+		value := new(big.Int)
+		if account.Root != types.EmptyRootHash {
+			slot, err := trie.VerifyProof(account.Root, crypto.Keccak256(hash[:]), proof)
+			if err != nil {
+				return nil, fmt.Errorf("invalid storage proof for %s: %v", key, err)
+			}
+			if slot != nil {
+				_, content, _, err := rlp.Split(slot)
+				if err != nil {
+					return nil, fmt.Errorf("invalid storage value for %s: %v", key, err)
+				}
+				value.SetBytes(content)
+			}
+		}
+		result.StorageProof[i] = StorageResult{Key: key, Value: (*hexutil.Big)(value), Proof: encodeProof(proof)}
+	}
+	return result, statedb.Error()
+}
+
+// encodeProof hex encodes the nodes of a merkle proof.
+func encodeProof(proof []rlp.RawValue) []string {
+	nodes := make([]string, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Encode(node)
+	}
+	return nodes
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -644,6 +761,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if err := vmError(); err != nil {
 		return nil, common.Big0, false, err
 	}
+	// If the execution was aborted by a timeout or the caller's deadline, the
+	// result is incomplete, don't report it as a real outcome.
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, common.Big0, false, fmt.Errorf("execution aborted: %v", ctx.Err())
+	}
 	return res, gas, failed, err
 }
 
@@ -677,6 +799,9 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*
 		(*big.Int)(&args.Gas).SetUint64(mid)
 
 		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 
 		// If the transaction became invalid or execution failed, raise the gas limit
 		if err != nil || failed {
@@ -1172,7 +1297,12 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 		return nil, err
 	}
 	// Sign the requested hash with the wallet
-	signature, err := wallet.SignHash(account, signHash(data))
+	var signature []byte
+	if signer, ok := wallet.(textSigner); ok {
+		signature, err = signer.SignText(account, data)
+	} else {
+		signature, err = wallet.SignHash(account, signHash(data))
+	}
 	if err == nil {
 		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
 	}
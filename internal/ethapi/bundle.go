@@ -0,0 +1,272 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/consensus"
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/rlp"
+	"github.com/wtc/go-wtc/rpc"
+)
+
+const (
+	// maxBundleSize is the maximum number of transactions simulated in a bundle.
+	maxBundleSize = 256
+
+	// bundleTimeout is the maximum time a bundle is executed for before it's
+	// aborted.
+	bundleTimeout = 5 * time.Second
+)
+
+// maxBundleGas is the maximum amount of gas the transactions of a bundle may use
+// together, regardless of the gas limit of the block they're executed on.
+var maxBundleGas = big.NewInt(50000000)
+
+// revertSelector is the function selector of Error(string), which solidity uses
+// to encode revert reasons.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// BundleTxResult is the outcome of a single transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash          common.Hash     `json:"txHash"`
+	From            common.Address  `json:"from"`
+	To              *common.Address `json:"to"`
+	ContractAddress *common.Address `json:"contractAddress"`
+	GasUsed         *hexutil.Big    `json:"gasUsed"`
+	Failed          bool            `json:"failed"`
+	ReturnData      hexutil.Bytes   `json:"returnData"`
+	RevertReason    string          `json:"revertReason,omitempty"`
+	Logs            []*types.Log    `json:"logs"`
+}
+
+// BundleResult is the outcome of a simulated bundle of transactions.
+type BundleResult struct {
+	BlockNumber  *hexutil.Big     `json:"blockNumber"`
+	StateRoot    common.Hash      `json:"stateRoot"`
+	Coinbase     common.Address   `json:"coinbase"`
+	CoinbaseDiff *hexutil.Big     `json:"coinbaseDiff"`
+	GasUsed      *hexutil.Big     `json:"gasUsed"`
+	Results      []BundleTxResult `json:"results"`
+}
+
+// bundleTx is a transaction of a bundle, converted into a message.
+type bundleTx struct {
+	hash    common.Hash
+	msg     types.Message
+	gasLeft bool // Whether the message gets all the gas left in the bundle
+}
+
+// CallBundle executes an ordered bundle of signed, RLP encoded transactions on
+// top of the state of the given block number, each transaction seeing the state
+// changes of the previous ones. Senders pay for their gas and nonces are checked
+// like in a real block. No state is persisted.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, encodedTxs []hexutil.Bytes, blockNr rpc.BlockNumber) (*BundleResult, error) {
+	if len(encodedTxs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(encodedTxs) > maxBundleSize {
+		return nil, fmt.Errorf("bundle too large: %d transactions, max %d", len(encodedTxs), maxBundleSize)
+	}
+	return s.simulateBundle(ctx, blockNr, func(header *types.Header) ([]bundleTx, error) {
+		signer := types.MakeSigner(s.b.ChainConfig(), header.Number)
+
+		txs := make([]bundleTx, len(encodedTxs))
+		for i, encodedTx := range encodedTxs {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			msg, err := tx.AsMessage(signer)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			txs[i] = bundleTx{hash: tx.Hash(), msg: msg}
+		}
+		return txs, nil
+	})
+}
+
+// SimulateBundle executes an ordered bundle of unsigned calls on top of the
+// state of the given block number, each call seeing the state changes of the
+// previous ones. Unlike Call, senders pay for their gas at the given gas price
+// (zero by default) and nonces are not checked. Calls without a gas allowance
+// may use all the gas left in the bundle. No state is persisted.
+func (s *PublicBlockChainAPI) SimulateBundle(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber) (*BundleResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(calls) > maxBundleSize {
+		return nil, fmt.Errorf("bundle too large: %d calls, max %d", len(calls), maxBundleSize)
+	}
+	return s.simulateBundle(ctx, blockNr, func(header *types.Header) ([]bundleTx, error) {
+		txs := make([]bundleTx, len(calls))
+		for i, args := range calls {
+			msg := types.NewMessage(args.From, args.To, 0, args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), args.Data, false)
+
+			// Unsigned calls have no transaction hash, derive a unique one for
+			// the logs from the bundle position and the call itself.
+			enc, _ := rlp.EncodeToBytes([]interface{}{uint64(i), args.From, args.To, args.Value.ToInt(), []byte(args.Data)})
+			hash := crypto.Keccak256Hash(enc)
+			txs[i] = bundleTx{hash: hash, msg: msg, gasLeft: msg.Gas().Sign() == 0}
+		}
+		return txs, nil
+	})
+}
+
+// simulateBundle applies the messages of a bundle on a copy of the state of the
+// given block number and collects their results. The bundle may use up to the
+// block gas limit, capped at maxBundleGas, and is aborted after bundleTimeout.
+func (s *PublicBlockChainAPI) simulateBundle(ctx context.Context, blockNr rpc.BlockNumber, makeTxs func(*types.Header) ([]bundleTx, error)) (*BundleResult, error) {
+	defer func(start time.Time) { log.Debug("Executing bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	txs, err := makeTxs(header)
+	if err != nil {
+		return nil, err
+	}
+	// Work on a private copy, the backend may share the pending state
+	statedb = statedb.Copy()
+
+	ctx, cancel := context.WithTimeout(ctx, bundleTimeout)
+	defer cancel()
+
+	gasLimit := header.GasLimit
+	if gasLimit.Cmp(maxBundleGas) > 0 {
+		gasLimit = maxBundleGas
+	}
+	var (
+		config   = s.b.ChainConfig()
+		chain    = &backendChain{ctx: ctx, b: s.b}
+		coinbase = header.Coinbase
+		before   = new(big.Int).Set(statedb.GetBalance(coinbase))
+		gp       = new(core.GasPool).AddGas(gasLimit)
+		usedGas  = new(big.Int)
+		results  = make([]BundleTxResult, len(txs))
+	)
+	for i, tx := range txs {
+		if tx.gasLeft {
+			msg := tx.msg
+			tx.msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), new(big.Int).Set((*big.Int)(gp)), msg.GasPrice(), msg.Data(), msg.CheckNonce())
+		}
+		statedb.Prepare(tx.hash, common.Hash{}, i)
+		nonce := statedb.GetNonce(tx.msg.From())
+
+		evm := vm.NewEVM(core.NewEVMContext(tx.msg, header, chain, &coinbase), statedb, config, vm.Config{})
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
+		ret, gas, failed, err := core.ApplyMessage(evm, tx.msg, gp)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.hash, err)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("execution aborted: %v", ctx.Err())
+		}
+		if config.IsByzantium(header.Number) {
+			statedb.Finalise(true)
+		} else {
+			statedb.IntermediateRoot(config.IsEIP158(header.Number))
+		}
+		usedGas.Add(usedGas, gas)
+
+		result := BundleTxResult{
+			TxHash:     tx.hash,
+			From:       tx.msg.From(),
+			To:         tx.msg.To(),
+			GasUsed:    (*hexutil.Big)(gas),
+			Failed:     failed,
+			ReturnData: ret,
+			Logs:       statedb.GetLogs(tx.hash),
+		}
+		if tx.msg.To() == nil {
+			addr := crypto.CreateAddress(tx.msg.From(), nonce)
+			result.ContractAddress = &addr
+		}
+		if failed {
+			result.RevertReason = unpackRevertReason(ret)
+		}
+		if result.Logs == nil {
+			result.Logs = []*types.Log{}
+		}
+		results[i] = result
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	return &BundleResult{
+		BlockNumber:  (*hexutil.Big)(header.Number),
+		StateRoot:    statedb.IntermediateRoot(config.IsEIP158(header.Number)),
+		Coinbase:     coinbase,
+		CoinbaseDiff: (*hexutil.Big)(new(big.Int).Sub(statedb.GetBalance(coinbase), before)),
+		GasUsed:      (*hexutil.Big)(usedGas),
+		Results:      results,
+	}, nil
+}
+
+// unpackRevertReason decodes the reason string of a reverted execution, if the
+// return data is an ABI encoded Error(string).
+func unpackRevertReason(ret []byte) string {
+	if len(ret) < 4+64 || !bytes.Equal(ret[:4], revertSelector) {
+		return ""
+	}
+	data := ret[4:]
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)) {
+		return ""
+	}
+	start := offset.Uint64() + 32
+	size := new(big.Int).SetBytes(data[start-32 : start])
+	if !size.IsUint64() || start+size.Uint64() > uint64(len(data)) {
+		return ""
+	}
+	return string(data[start : start+size.Uint64()])
+}
+
+// backendChain adapts an API backend to core.ChainContext for resolving the
+// block hashes of the canonical chain. The consensus engine is not available,
+// callers need to pass the block author explicitly.
+type backendChain struct {
+	ctx context.Context
+	b   Backend
+}
+
+func (c *backendChain) Engine() consensus.Engine { return nil }
+
+func (c *backendChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, _ := c.b.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	if header == nil || header.Hash() != hash {
+		return nil
+	}
+	return header
+}
@@ -0,0 +1,235 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/wtcdb"
+)
+
+func TestUnpackRevertReason(t *testing.T) {
+	tests := []struct {
+		ret  string
+		want string
+	}{
+		// require(false, "insufficient collateral")
+		{"08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000017" +
+			"696e73756666696369656e7420636f6c6c61746572616c000000000000000000",
+			"insufficient collateral"},
+		// Plain revert without data
+		{"", ""},
+		// Custom return data not encoding a reason
+		{"deadbeef", ""},
+		// Length pointing past the return data
+		{"08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"00000000000000000000000000000000000000000000000000000000000000ff" +
+			"696e73756666696369656e7420636f6c6c61746572616c000000000000000000",
+			""},
+		// Offset pointing past the return data
+		{"08c379a0" +
+			"00000000000000000000000000000000000000000000000000000000000000ff" +
+			"0000000000000000000000000000000000000000000000000000000000000017",
+			""},
+	}
+	for i, tt := range tests {
+		if have := unpackRevertReason(common.Hex2Bytes(tt.ret)); have != tt.want {
+			t.Errorf("test %d: reason mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+var (
+	bundleSender   = common.HexToAddress("0x0100")
+	bundleRelay    = common.HexToAddress("0x0200")
+	bundleReceiver = common.HexToAddress("0x0300")
+	bundleReverter = common.HexToAddress("0x0400")
+	bundleInvalid  = common.HexToAddress("0x0500")
+	bundleCoinbase = common.HexToAddress("0x0600")
+)
+
+// bundleBackend is an API backend serving a single state and header to execute
+// bundles on.
+type bundleBackend struct {
+	Backend
+	statedb *state.StateDB
+	header  *types.Header
+}
+
+func (b *bundleBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.statedb, b.header, nil
+}
+
+func (b *bundleBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	return b.header, nil
+}
+
+func (b *bundleBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+// newBundleAPI creates a blockchain API over a state with a funded sender, a
+// contract reverting with a reason and a contract hitting an invalid opcode.
+func newBundleAPI(t *testing.T) (*PublicBlockChainAPI, *state.StateDB) {
+	db, _ := wtcdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(bundleSender, big.NewInt(1000000000), new(big.Int), new(big.Int))
+
+	// revert(reason) with the ABI encoded Error("nope") appended to the code
+	reason := common.Hex2Bytes("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	statedb.SetCode(bundleReverter, append(common.Hex2Bytes("6064600c60003960646000fd"), reason...))
+	statedb.SetCode(bundleInvalid, []byte{0xfe})
+
+	root, err := statedb.CommitTo(db, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, state.NewDatabase(db))
+
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Coinbase:   bundleCoinbase,
+		Difficulty: big.NewInt(1),
+		GasLimit:   big.NewInt(100000000),
+		GasUsed:    new(big.Int),
+		Time:       big.NewInt(1),
+	}
+	return NewPublicBlockChainAPI(&bundleBackend{statedb: statedb, header: header}), statedb
+}
+
+// transfer creates a bundle call moving value between two accounts.
+func transfer(from, to common.Address, value int64, gasPrice int64) CallArgs {
+	return CallArgs{
+		From:     from,
+		To:       &to,
+		Value:    hexutil.Big(*big.NewInt(value)),
+		GasPrice: hexutil.Big(*big.NewInt(gasPrice)),
+	}
+}
+
+// Tests that each call of a bundle sees the state changes of the previous ones,
+// that the coinbase difference accounts for the paid gas and that no state is
+// persisted afterwards.
+func TestSimulateBundle(t *testing.T) {
+	api, statedb := newBundleAPI(t)
+	txGas := new(big.Int).SetUint64(params.TxGas)
+
+	// The relay can only forward the funds it received in the same bundle
+	calls := []CallArgs{
+		transfer(bundleSender, bundleRelay, 1000, 2),
+		transfer(bundleRelay, bundleReceiver, 1000, 0),
+	}
+	res, err := api.SimulateBundle(context.Background(), calls, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if len(res.Results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(res.Results), len(calls))
+	}
+	for i, result := range res.Results {
+		if result.Failed {
+			t.Errorf("call %d failed", i)
+		}
+		if result.GasUsed.ToInt().Cmp(txGas) != 0 {
+			t.Errorf("call %d: gas used mismatch: have %v, want %v", i, result.GasUsed, txGas)
+		}
+	}
+	if want := new(big.Int).Mul(txGas, big.NewInt(2)); res.GasUsed.ToInt().Cmp(want) != 0 {
+		t.Errorf("bundle gas used mismatch: have %v, want %v", res.GasUsed, want)
+	}
+	if want := new(big.Int).Mul(txGas, big.NewInt(2)); res.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("coinbase difference mismatch: have %v, want %v", res.CoinbaseDiff, want)
+	}
+	// Ensure the simulation didn't leak into the backend's state
+	for _, addr := range []common.Address{bundleRelay, bundleReceiver, bundleCoinbase} {
+		if balance := statedb.GetBalance(addr); balance.Sign() != 0 {
+			t.Errorf("balance of %x persisted: %v", addr, balance)
+		}
+	}
+	if balance := statedb.GetBalance(bundleSender); balance.Cmp(big.NewInt(1000000000)) != 0 {
+		t.Errorf("sender balance persisted: have %v, want %v", balance, 1000000000)
+	}
+	// Without the funding call, the relay has nothing to forward
+	if _, err := api.SimulateBundle(context.Background(), calls[1:], rpc.LatestBlockNumber); err == nil {
+		t.Errorf("unfunded transfer succeeded")
+	}
+}
+
+// Tests that reverted and failed calls are reported along with their reason,
+// without aborting the bundle.
+func TestSimulateBundleFailures(t *testing.T) {
+	api, _ := newBundleAPI(t)
+
+	gas := hexutil.Big(*big.NewInt(100000))
+	calls := []CallArgs{
+		{From: bundleSender, To: &bundleReverter, Gas: gas},
+		{From: bundleSender, To: &bundleInvalid, Gas: gas},
+		transfer(bundleSender, bundleReceiver, 1, 0),
+	}
+	res, err := api.SimulateBundle(context.Background(), calls, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if reverted := res.Results[0]; !reverted.Failed || reverted.RevertReason != "nope" || len(reverted.ReturnData) != 100 {
+		t.Errorf("reverted call mismatch: failed %v, reason %q, return data %x", reverted.Failed, reverted.RevertReason, reverted.ReturnData)
+	}
+	if invalid := res.Results[1]; !invalid.Failed || invalid.RevertReason != "" || len(invalid.ReturnData) != 0 {
+		t.Errorf("failed call mismatch: failed %v, reason %q, return data %x", invalid.Failed, invalid.RevertReason, invalid.ReturnData)
+	}
+	if res.Results[2].Failed {
+		t.Errorf("call after failures failed")
+	}
+}
+
+// Tests that the gas used by a bundle is capped regardless of the block gas
+// limit, with calls lacking a gas allowance sharing the gas left.
+func TestSimulateBundleGasCap(t *testing.T) {
+	api, _ := newBundleAPI(t)
+
+	calls := []CallArgs{
+		{From: bundleSender, To: &bundleInvalid, Gas: hexutil.Big(*big.NewInt(30000000))},
+		{From: bundleSender, To: &bundleInvalid},
+	}
+	res, err := api.SimulateBundle(context.Background(), calls, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if gas := res.Results[1].GasUsed.ToInt(); gas.Cmp(big.NewInt(20000000)) != 0 {
+		t.Errorf("gas left mismatch: have %v, want %v", gas, 20000000)
+	}
+	if res.GasUsed.ToInt().Cmp(maxBundleGas) != 0 {
+		t.Errorf("bundle gas used mismatch: have %v, want %v", res.GasUsed, maxBundleGas)
+	}
+	calls = []CallArgs{{From: bundleSender, To: &bundleInvalid, Gas: hexutil.Big(*new(big.Int).Add(maxBundleGas, common.Big1))}}
+	if _, err := api.SimulateBundle(context.Background(), calls, rpc.LatestBlockNumber); err == nil {
+		t.Errorf("bundle exceeding the gas cap succeeded")
+	}
+}
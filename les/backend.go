@@ -117,6 +117,7 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightWtc, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, true, config.NetworkId, eth.eventMux, eth.engine, eth.peers, eth.blockchain, nil, chainDb, eth.odr, eth.relay, quitSync, &eth.wg); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.downloader.SetCheckpoint(params.TrustedCheckpoints[genesisHash])
 	eth.ApiBackend = &LesApiBackend{eth, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -195,9 +195,9 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, network
 		return nil, errIncompatibleConfig
 	}
 
-	removePeer := manager.removePeer
+	removePeer := func(id string, reason error) { manager.removePeer(id) }
 	if disableClientRemovePeer {
-		removePeer = func(id string) {}
+		removePeer = func(id string, reason error) {}
 	}
 
 	if lightSync {
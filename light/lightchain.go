@@ -66,7 +66,8 @@ type LightChain struct {
 	procInterrupt int32 // interrupt signaler for block processing
 	wg            sync.WaitGroup
 
-	engine consensus.Engine
+	engine     consensus.Engine
+	checkpoint *params.TrustedCheckpoint // Trusted checkpoint the CHT originates from (nil = unknown)
 }
 
 // NewLightChain returns a fully initialised light chain using information
@@ -95,17 +96,9 @@ func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.
 	if bc.genesisBlock == nil {
 		return nil, core.ErrNoGenesis
 	}
-	if bc.genesisBlock.Hash() == params.MainnetGenesisHash {
-		// add trusted CHT
-		WriteTrustedCht(bc.chainDb, TrustedCht{Number: 1040, Root: common.HexToHash("bb4fb4076cbe6923c8a8ce8f158452bbe19564959313466989fda095a60884ca")})
-		log.Info("Added trusted CHT for mainnet")
+	if checkpoint, ok := params.TrustedCheckpoints[bc.genesisBlock.Hash()]; ok {
+		bc.AddTrustedCheckpoint(checkpoint)
 	}
-	if bc.genesisBlock.Hash() == params.TestnetGenesisHash {
-		// add trusted CHT
-		WriteTrustedCht(bc.chainDb, TrustedCht{Number: 400, Root: common.HexToHash("2a4befa19e4675d939c3dc22dca8c6ae9fcd642be1f04b06bd6e4203cc304660")})
-		log.Info("Added trusted CHT for ropsten testnet")
-	}
-
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
@@ -453,6 +446,26 @@ func (self *LightChain) GetHeaderByNumberOdr(ctx context.Context, number uint64)
 	return GetHeaderByNumber(ctx, self.odr, number)
 }
 
+// AddTrustedCheckpoint injects a trusted checkpoint into the light chain, making
+// the headers it covers retrievable via its canonical hash trie instead of having
+// to sync and verify them from the genesis. Checkpoints older than the one in use
+// are ignored.
+func (self *LightChain) AddTrustedCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	cht := TrustedCht{Number: checkpoint.SectionIndex + 1, Root: checkpoint.CHTRoot}
+	if GetTrustedCht(self.chainDb).Number > cht.Number {
+		return
+	}
+	WriteTrustedCht(self.chainDb, cht)
+	self.checkpoint = checkpoint
+
+	log.Info("Added trusted checkpoint", "section", checkpoint.SectionIndex, "head", checkpoint.SectionHead, "cht", checkpoint.CHTRoot)
+}
+
+// SyncCht fast forwards the header chain to the head of the trusted canonical
+// hash trie, if the local chain is still below it.
 func (self *LightChain) SyncCht(ctx context.Context) bool {
 	headNum := self.CurrentHeader().Number.Uint64()
 	cht := GetTrustedCht(self.chainDb)
@@ -460,6 +473,15 @@ func (self *LightChain) SyncCht(ctx context.Context) bool {
 		num := cht.Number*ChtFrequency - 1
 		header, err := GetHeaderByNumber(ctx, self.odr, num)
 		if header != nil && err == nil {
+			// Make sure the trie leads to the checkpoint's section head, if known
+			self.mu.RLock()
+			checkpoint := self.checkpoint
+			self.mu.RUnlock()
+
+			if checkpoint != nil && checkpoint.SectionIndex+1 == cht.Number && checkpoint.SectionHead != (common.Hash{}) && header.Hash() != checkpoint.SectionHead {
+				log.Warn("Trusted CHT doesn't match checkpoint", "number", num, "hash", header.Hash(), "checkpoint", checkpoint.SectionHead)
+				return false
+			}
 			self.mu.Lock()
 			if self.hc.CurrentHeader().Number.Uint64() < header.Number.Uint64() {
 				self.hc.SetCurrentHeader(header)
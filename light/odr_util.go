@@ -26,6 +26,7 @@ import (
 	"github.com/wtc/go-wtc/core"
 	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/params"
 	"github.com/wtc/go-wtc/wtcdb"
 	"github.com/wtc/go-wtc/rlp"
 )
@@ -36,7 +37,7 @@ var (
 	ErrNoTrustedCht = errors.New("No trusted canonical hash trie")
 	ErrNoHeader     = errors.New("Header not found")
 
-	ChtFrequency     = uint64(4096)
+	ChtFrequency     = params.CHTFrequency
 	ChtConfirmations = uint64(2048)
 	trustedChtKey    = []byte("TrustedCHT")
 )
@@ -24,6 +24,7 @@ import (
 	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/rlp"
 	"github.com/wtc/go-wtc/trie"
 )
 
@@ -131,6 +132,22 @@ func (t *odrTrie) NodeIterator(startkey []byte) trie.NodeIterator {
 	return newNodeIterator(t, startkey)
 }
 
+// Prove constructs a merkle proof for the hashed key, retrieving the trie nodes
+// on the path to it on demand.
+func (t *odrTrie) Prove(key []byte) []rlp.RawValue {
+	key = crypto.Keccak256(key)
+	var proof []rlp.RawValue
+	t.do(key, func() error {
+		// Resolve the path first, Prove itself doesn't report missing nodes
+		if _, err := t.trie.TryGet(key); err != nil {
+			return err
+		}
+		proof = t.trie.Prove(key)
+		return nil
+	})
+	return proof
+}
+
 func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
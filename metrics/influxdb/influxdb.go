@@ -0,0 +1,178 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package influxdb pushes the metrics registry to an InfluxDB server using the
+// line protocol.
+package influxdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/wtc/go-wtc/log"
+)
+
+// Config is the InfluxDB server to report to and how.
+type Config struct {
+	Endpoint  string            // Base URL of the server, e.g. http://localhost:8086
+	Database  string            // Database to write the measurements into
+	Username  string            // Username to authenticate with, if any
+	Password  string            // Password to authenticate with, if any
+	Namespace string            // Prefix of the measurement names, e.g. gwtc.
+	Tags      map[string]string // Tags added to all measurements, e.g. host
+}
+
+// reporter periodically pushes the metrics of a registry to InfluxDB.
+type reporter struct {
+	reg    metrics.Registry
+	config Config
+	client *http.Client
+}
+
+// Report pushes the metrics of a registry to an InfluxDB server in the given
+// interval, until the process exits. Failed pushes are logged and retried in
+// the next interval.
+func Report(reg metrics.Registry, interval time.Duration, config Config) {
+	r := &reporter{reg: reg, config: config, client: &http.Client{Timeout: interval}}
+	for range time.Tick(interval) {
+		if err := r.send(time.Now()); err != nil {
+			log.Warn("Failed to push metrics to InfluxDB", "endpoint", config.Endpoint, "err", err)
+		}
+	}
+}
+
+// send writes a single batch of all the metrics to the server.
+func (r *reporter) send(now time.Time) error {
+	body := Encode(r.reg, r.config.Namespace, r.config.Tags, now)
+	if len(body) == 0 {
+		return nil
+	}
+	query := url.Values{"db": {r.config.Database}, "precision": {"s"}}
+	req, err := http.NewRequest("POST", strings.TrimRight(r.config.Endpoint, "/")+"/write?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if r.config.Username != "" {
+		req.SetBasicAuth(r.config.Username, r.config.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Encode renders all the metrics of a registry as InfluxDB line protocol points
+// with the given measurement name prefix and tags, ordered by name.
+func Encode(reg metrics.Registry, namespace string, tags map[string]string, now time.Time) []byte {
+	var names []string
+	all := make(map[string]interface{})
+	reg.Each(func(name string, metric interface{}) {
+		names = append(names, name)
+		all[name] = metric
+	})
+	sort.Strings(names)
+
+	// Render the tags shared by all points once
+	var keys []string
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var tagset string
+	for _, key := range keys {
+		tagset += "," + escape(key, ",= ") + "=" + escape(tags[key], ",= ")
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		fields := encodeFields(all[name])
+		if fields == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "%s%s %s %s\n", escape(namespace+name, ", "), tagset, fields, timestamp)
+	}
+	return buf.Bytes()
+}
+
+// encodeFields renders the field set of a metric, empty for unsupported types.
+func encodeFields(metric interface{}) string {
+	switch metric := metric.(type) {
+	case metrics.Counter:
+		return fmt.Sprintf("value=%di", metric.Count())
+	case metrics.Gauge:
+		return fmt.Sprintf("value=%di", metric.Value())
+	case metrics.GaugeFloat64:
+		return "value=" + formatFloat(metric.Value())
+	case metrics.Meter:
+		m := metric.Snapshot()
+		return fmt.Sprintf("count=%di,m1=%s,m5=%s,m15=%s,mean=%s",
+			m.Count(), formatFloat(m.Rate1()), formatFloat(m.Rate5()), formatFloat(m.Rate15()), formatFloat(m.RateMean()))
+	case metrics.Timer:
+		t := metric.Snapshot()
+		ps := t.Percentiles([]float64{0.5, 0.95, 0.99})
+		return fmt.Sprintf("count=%di,min=%di,max=%di,mean=%s,p50=%s,p95=%s,p99=%s,m1=%s,m5=%s,m15=%s,meanrate=%s",
+			t.Count(), t.Min(), t.Max(), formatFloat(t.Mean()), formatFloat(ps[0]), formatFloat(ps[1]), formatFloat(ps[2]),
+			formatFloat(t.Rate1()), formatFloat(t.Rate5()), formatFloat(t.Rate15()), formatFloat(t.RateMean()))
+	case metrics.Histogram:
+		h := metric.Snapshot()
+		ps := h.Percentiles([]float64{0.5, 0.95, 0.99})
+		return fmt.Sprintf("count=%di,min=%di,max=%di,mean=%s,p50=%s,p95=%s,p99=%s",
+			h.Count(), h.Min(), h.Max(), formatFloat(h.Mean()), formatFloat(ps[0]), formatFloat(ps[1]), formatFloat(ps[2]))
+	}
+	return ""
+}
+
+// formatFloat renders a float field value. The line protocol has no notation
+// for NaN and infinities, they are reported as zero.
+func formatFloat(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// escape backslash-escapes the given special characters of a name.
+func escape(s string, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var buf bytes.Buffer
+	for _, c := range s {
+		if strings.ContainsRune(special, c) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
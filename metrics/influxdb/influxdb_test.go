@@ -0,0 +1,97 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestEncode(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("chain/head/block", reg).Update(1234)
+	metrics.NewRegisteredGaugeFloat64("chain/head/td", reg).Update(2.5)
+	metrics.NewRegisteredCounter("rpc/subscriptions", reg).Inc(3)
+	metrics.NewRegisteredMeter("p2p/InboundTraffic", reg).Mark(42)
+
+	have := string(Encode(reg, "gwtc/", map[string]string{"host": "node 1", "net": "main"}, time.Unix(1500000000, 0)))
+	want := "gwtc/chain/head/block,host=node\\ 1,net=main value=1234i 1500000000\n" +
+		"gwtc/chain/head/td,host=node\\ 1,net=main value=2.5 1500000000\n" +
+		"gwtc/p2p/InboundTraffic,host=node\\ 1,net=main count=42i,"
+	if !strings.HasPrefix(have, want) {
+		t.Errorf("output mismatch:\nhave %q\nwant prefix %q", have, want)
+	}
+	if !strings.HasSuffix(have, "gwtc/rpc/subscriptions,host=node\\ 1,net=main value=3i 1500000000\n") {
+		t.Errorf("counter missing from output: %q", have)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var (
+		query, user, pass string
+		body              []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		user, pass, _ = r.BasicAuth()
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("miner/hashrate", reg).Update(99)
+
+	r := &reporter{
+		reg:    reg,
+		config: Config{Endpoint: server.URL + "/", Database: "gwtc", Username: "ops", Password: "secret"},
+		client: http.DefaultClient,
+	}
+	if err := r.send(time.Unix(1500000000, 0)); err != nil {
+		t.Fatalf("failed to send metrics: %v", err)
+	}
+	if query != "db=gwtc&precision=s" {
+		t.Errorf("query mismatch: have %q", query)
+	}
+	if user != "ops" || pass != "secret" {
+		t.Errorf("credentials mismatch: have %q/%q", user, pass)
+	}
+	if string(body) != "miner/hashrate value=99i 1500000000\n" {
+		t.Errorf("body mismatch: have %q", body)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("miner/hashrate", reg).Update(1)
+
+	r := &reporter{reg: reg, config: Config{Endpoint: server.URL, Database: "missing"}, client: http.DefaultClient}
+	if err := r.send(time.Now()); err == nil || !strings.Contains(err.Error(), "database not found") {
+		t.Errorf("expected server error, got %v", err)
+	}
+}
@@ -31,6 +31,10 @@ import (
 // MetricsEnabledFlag is the CLI flag name to use to enable metrics collections.
 const MetricsEnabledFlag = "metrics"
 
+// MetricsExporterFlags are the CLI flag names of the metrics exporters, which
+// enable metrics collection too.
+var MetricsExporterFlags = []string{"metrics.addr", "metrics.influxdb"}
+
 // Enabled is the flag specifying if metrics are enable or not.
 var Enabled = false
 
@@ -39,14 +43,30 @@ var Enabled = false
 // and peek into the command line args for the metrics flag.
 func init() {
 	for _, arg := range os.Args {
-		if strings.TrimLeft(arg, "-") == MetricsEnabledFlag {
+		if enables(arg) {
 			log.Info("Enabling metrics collection")
 			Enabled = true
+			break
 		}
 	}
 	exp.Exp(metrics.DefaultRegistry)
 }
 
+// enables reports whether a command line argument is a flag enabling metrics
+// collection, either directly or by requesting an exporter.
+func enables(arg string) bool {
+	name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+	if name == MetricsEnabledFlag {
+		return true
+	}
+	for _, flag := range MetricsExporterFlags {
+		if name == flag {
+			return true
+		}
+	}
+	return false
+}
+
 // NewCounter create a new metrics Counter, either a real one of a NOP stub depending
 // on the metrics flag.
 func NewCounter(name string) metrics.Counter {
@@ -74,6 +94,35 @@ func NewTimer(name string) metrics.Timer {
 	return metrics.GetOrRegisterTimer(name, metrics.DefaultRegistry)
 }
 
+// NewGauge create a new metrics Gauge, either a real one of a NOP stub depending
+// on the metrics flag.
+func NewGauge(name string) metrics.Gauge {
+	if !Enabled {
+		return metrics.NilGauge{}
+	}
+	return metrics.GetOrRegisterGauge(name, metrics.DefaultRegistry)
+}
+
+// NewGaugeFloat64 create a new metrics GaugeFloat64, either a real one of a NOP
+// stub depending on the metrics flag.
+func NewGaugeFloat64(name string) metrics.GaugeFloat64 {
+	if !Enabled {
+		return metrics.NilGaugeFloat64{}
+	}
+	return metrics.GetOrRegisterGaugeFloat64(name, metrics.DefaultRegistry)
+}
+
+// NewFunctionalGauge create a new metrics Gauge reporting the value returned by
+// a function, either a real one of a NOP stub depending on the metrics flag. A
+// previously registered gauge of the same name is replaced.
+func NewFunctionalGauge(name string, f func() int64) metrics.Gauge {
+	if !Enabled {
+		return metrics.NilGauge{}
+	}
+	metrics.DefaultRegistry.Unregister(name)
+	return metrics.NewRegisteredFunctionalGauge(name, metrics.DefaultRegistry, f)
+}
+
 // CollectProcessMetrics periodically collects various metrics about the running
 // process.
 func CollectProcessMetrics(refresh time.Duration) {
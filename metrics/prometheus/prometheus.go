@@ -0,0 +1,113 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package prometheus exposes the metrics registry in the Prometheus text format.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Path is the URL path the metrics are served under.
+const Path = "/metrics"
+
+// quantiles are the quantiles reported for timers and histograms.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
+// Handler returns an HTTP handler serving all the metrics of a registry in the
+// Prometheus text exposition format. Meters are reported as counters of their
+// events, timers and histograms as summaries. Timer values are nanoseconds.
+func Handler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(Encode(reg))
+	})
+}
+
+// Encode renders all the metrics of a registry in the Prometheus text format,
+// ordered by name.
+func Encode(reg metrics.Registry) []byte {
+	var names []string
+	all := make(map[string]interface{})
+	reg.Each(func(name string, metric interface{}) {
+		names = append(names, name)
+		all[name] = metric
+	})
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		writeMetric(buf, Name(name), all[name])
+	}
+	return buf.Bytes()
+}
+
+// writeMetric renders a single metric, skipping unsupported types.
+func writeMetric(buf *bytes.Buffer, name string, metric interface{}) {
+	switch metric := metric.(type) {
+	case metrics.Counter:
+		writeValue(buf, name, "gauge", float64(metric.Count()))
+	case metrics.Gauge:
+		writeValue(buf, name, "gauge", float64(metric.Value()))
+	case metrics.GaugeFloat64:
+		writeValue(buf, name, "gauge", metric.Value())
+	case metrics.Meter:
+		writeValue(buf, name, "counter", float64(metric.Count()))
+	case metrics.Timer:
+		t := metric.Snapshot()
+		writeSummary(buf, name, t.Count(), t.Sum(), t.Percentiles(quantiles))
+	case metrics.Histogram:
+		h := metric.Snapshot()
+		writeSummary(buf, name, h.Count(), h.Sum(), h.Percentiles(quantiles))
+	}
+}
+
+func writeValue(buf *bytes.Buffer, name, kind string, value float64) {
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(buf, "%s %s\n", name, formatFloat(value))
+}
+
+func writeSummary(buf *bytes.Buffer, name string, count, sum int64, values []float64) {
+	fmt.Fprintf(buf, "# TYPE %s summary\n", name)
+	for i, q := range quantiles {
+		fmt.Fprintf(buf, "%s{quantile=\"%s\"} %s\n", name, formatFloat(q), formatFloat(values[i]))
+	}
+	fmt.Fprintf(buf, "%s_sum %d\n", name, sum)
+	fmt.Fprintf(buf, "%s_count %d\n", name, count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Name converts a registry metric name, e.g. chain/head/block, into a valid
+// Prometheus metric name, e.g. chain_head_block.
+func Name(name string) string {
+	out := []byte(name)
+	for i, c := range out {
+		valid := c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
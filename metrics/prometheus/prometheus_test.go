@@ -0,0 +1,85 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestEncode(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("chain/head/block", reg).Update(1234)
+	metrics.NewRegisteredGaugeFloat64("chain/head/td", reg).Update(1.5e20)
+	metrics.NewRegisteredCounter("rpc/subscriptions", reg).Inc(3)
+	metrics.NewRegisteredMeter("p2p/InboundTraffic", reg).Mark(42)
+	metrics.NewRegisteredFunctionalGauge("miner/hashrate", reg, func() int64 { return 7 })
+	metrics.NewRegisteredTimer("rpc/duration/eth_call", reg).Update(time.Millisecond)
+
+	want := []string{
+		"# TYPE chain_head_block gauge\nchain_head_block 1234\n",
+		"# TYPE chain_head_td gauge\nchain_head_td 1.5e+20\n",
+		"# TYPE miner_hashrate gauge\nminer_hashrate 7\n",
+		"# TYPE p2p_InboundTraffic counter\np2p_InboundTraffic 42\n",
+		"# TYPE rpc_duration_eth_call summary\nrpc_duration_eth_call{quantile=\"0.5\"} 1e+06\n",
+		"rpc_duration_eth_call_sum 1000000\nrpc_duration_eth_call_count 1\n",
+		"# TYPE rpc_subscriptions gauge\nrpc_subscriptions 3\n",
+	}
+	have := string(Encode(reg))
+	for _, w := range want {
+		if !strings.Contains(have, w) {
+			t.Errorf("output missing %q:\n%s", w, have)
+		}
+	}
+	if strings.Index(have, "chain_head_block") > strings.Index(have, "rpc_subscriptions") {
+		t.Errorf("metrics not sorted by name:\n%s", have)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("chain/head/block", reg).Update(1)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", Path, nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type mismatch: have %q", ct)
+	}
+	body, _ := ioutil.ReadAll(rec.Body)
+	if string(body) != "# TYPE chain_head_block gauge\nchain_head_block 1\n" {
+		t.Errorf("body mismatch: have %q", body)
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"chain/head/block":      "chain_head_block",
+		"rpc/duration/eth_call": "rpc_duration_eth_call",
+		"les/misc/in/packets":   "les_misc_in_packets",
+		"1st.metric-name":       "_st_metric_name",
+	}
+	for in, want := range tests {
+		if have := Name(in); have != want {
+			t.Errorf("Name(%q) mismatch: have %q, want %q", in, have, want)
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/wtc/go-wtc/wtcdb"
 	"github.com/wtc/go-wtc/event"
 	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/metrics"
 	"github.com/wtc/go-wtc/params"
 )
 
@@ -68,6 +69,7 @@ func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine con
 		canStart: 1,
 	}
 	miner.Register(NewCpuAgent(eth.BlockChain(), engine))
+	metrics.NewFunctionalGauge("miner/hashrate", miner.HashRate)
 	go miner.update()
 
 	return miner
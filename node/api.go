@@ -149,7 +149,7 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins); err != nil {
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, api.node.config.HTTPVirtualHosts); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -243,6 +243,16 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerScores retrieves the reputation of every remote node the server has seen
+// behave well or badly, best first.
+func (api *PublicAdminAPI) PeerScores() ([]*p2p.PeerScore, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerScores(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -258,6 +268,39 @@ func (api *PublicAdminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// PrivateDebugAPI is the collection of debugging related API methods exposed
+// only over a secure RPC channel.
+type PrivateDebugAPI struct {
+	node *Node // Node interfaced by this API
+}
+
+// NewPrivateDebugAPI creates a new API definition for the private debug methods
+// of the node itself.
+func NewPrivateDebugAPI(node *Node) *PrivateDebugAPI {
+	return &PrivateDebugAPI{node: node}
+}
+
+// SetRPCAccessLog configures the access log of all the RPC endpoints of the
+// node, logging the given fraction of calls and all calls taking longer than
+// nsec nanoseconds. Zero values disable the access log.
+func (api *PrivateDebugAPI) SetRPCAccessLog(sampleRate float64, nsec uint) (bool, error) {
+	if sampleRate < 0 || sampleRate > 1 {
+		return false, fmt.Errorf("invalid sample rate %v, must be between 0 and 1", sampleRate)
+	}
+	config := &rpc.AccessLog{SampleRate: sampleRate, SlowThreshold: time.Duration(nsec)}
+
+	api.node.lock.Lock()
+	defer api.node.lock.Unlock()
+
+	api.node.config.RPCAccessLog = config
+	for _, handler := range []*rpc.Server{api.node.inprocHandler, api.node.ipcHandler, api.node.httpHandler, api.node.wsHandler} {
+		if handler != nil {
+			handler.SetAccessLog(config)
+		}
+	}
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of debugging related API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicDebugAPI struct {
@@ -325,6 +368,9 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 					},
 				}
 
+			case metrics.Counter:
+				root[name] = float64(metric.Count())
+
 			default:
 				root[name] = "Unknown metric type"
 			}
@@ -355,6 +401,9 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 					},
 				}
 
+			case metrics.Counter:
+				root[name] = round(float64(metric.Count()), 0)
+
 			default:
 				root[name] = "Unknown metric type"
 			}
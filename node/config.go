@@ -26,13 +26,16 @@ import (
 	"strings"
 
 	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/accounts/external"
 	"github.com/wtc/go-wtc/accounts/keystore"
+	"github.com/wtc/go-wtc/accounts/policy"
 	"github.com/wtc/go-wtc/accounts/usbwallet"
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/crypto"
 	"github.com/wtc/go-wtc/log"
 	"github.com/wtc/go-wtc/p2p"
 	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/rpc"
 )
 
 const (
@@ -85,6 +88,15 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the IPC path or HTTP URL of an external signer, e.g.
+	// wtcsigner, whose accounts are made available besides the local ones.
+	ExternalSigner string `toml:",omitempty"`
+
+	// SigningPolicy is the rules file of the policy vetting all transactions
+	// before they are signed by any account. If empty, unlocked accounts sign
+	// all transactions requested.
+	SigningPolicy string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -110,6 +122,15 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPVirtualHosts is the list of virtual hostnames which are allowed on incoming
+	// requests. This is by default {'localhost'}. Using this prevents attacks like
+	// DNS rebinding, which bypasses SOP by simply masquerading as being within the same
+	// origin. These attacks do not utilize CORS, since they are not cross-domain.
+	// By explicitly checking the Host-header, the server will not allow requests
+	// made against the server with a malicious host domain.
+	// Requests using ip address directly are not affected
+	HTTPVirtualHosts []string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -135,6 +156,31 @@ type Config struct {
 	// *WARNING* Only set this if the node is running in a trusted network, exposing
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
+
+	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
+	// clients of the GraphQL endpoint. Please be aware that CORS is a browser
+	// enforced security, it's fully useless for custom HTTP clients.
+	GraphQLCors []string `toml:",omitempty"`
+
+	// GraphQLVirtualHosts is the list of virtual hostnames which are allowed on
+	// incoming requests of the GraphQL endpoint. This is by default {'localhost'}.
+	GraphQLVirtualHosts []string `toml:",omitempty"`
+
+	// RPCAuth configures the authentication of the clients of the IPC, HTTP and
+	// websocket RPC interfaces. If nil, all clients may call all exposed APIs.
+	RPCAuth *rpc.AuthConfig `toml:",omitempty"`
+
+	// RPCLimits bounds the resources the requests of the clients of the IPC, HTTP
+	// and websocket RPC interfaces may consume. If nil, requests are unlimited.
+	RPCLimits *rpc.Limits `toml:",omitempty"`
+
+	// RPCAccessLog configures the logging of the calls served over the in-process,
+	// IPC, HTTP and websocket RPC interfaces. If nil, calls aren't logged.
+	RPCAccessLog *rpc.AccessLog `toml:",omitempty"`
+
+	// Health enables the /health and /ready endpoints on the HTTP-RPC server,
+	// with the given default readiness thresholds. If nil, they're disabled.
+	Health *HealthConfig `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -404,6 +450,13 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
 	}
+	if conf.ExternalSigner != "" {
+		extapi, err := external.NewBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", err
+		}
+		backends = append(backends, extapi)
+	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -418,5 +471,15 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 			backends = append(backends, trezorhub)
 		}
 	}
-	return accounts.NewManager(backends...), ephemeral, nil
+	am := accounts.NewManager(backends...)
+	if conf.SigningPolicy != "" {
+		p, err := policy.Load(conf.SigningPolicy)
+		if err != nil {
+			am.Close()
+			return nil, "", err
+		}
+		am.SetTxPolicy(p)
+		log.Info("Loaded transaction signing policy", "file", conf.SigningPolicy)
+	}
+	return am, ephemeral, nil
 }
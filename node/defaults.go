@@ -35,11 +35,13 @@ const (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:     DefaultDataDir(),
-	HTTPPort:    DefaultHTTPPort,
-	HTTPModules: []string{"net", "web3"},
-	WSPort:      DefaultWSPort,
-	WSModules:   []string{"net", "web3"},
+	DataDir:             DefaultDataDir(),
+	HTTPPort:            DefaultHTTPPort,
+	HTTPModules:         []string{"net", "web3"},
+	HTTPVirtualHosts:    []string{"localhost"},
+	GraphQLVirtualHosts: []string{"localhost"},
+	WSPort:              DefaultWSPort,
+	WSModules:           []string{"net", "web3"},
 	P2P: p2p.Config{
 		ListenAddr:      ":10101",
 		DiscoveryV5Addr: ":30304",
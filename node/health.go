@@ -0,0 +1,198 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	// HealthPath is the URL path of the liveness endpoint, reporting whether the
+	// node is functional.
+	HealthPath = "/health"
+
+	// ReadyPath is the URL path of the readiness endpoint, reporting whether the
+	// node is functional, connected and in sync with the network.
+	ReadyPath = "/ready"
+)
+
+// HealthConfig holds the default thresholds of the readiness checks. Requests
+// to the endpoints may override them by query parameters of the same names.
+type HealthConfig struct {
+	// MinPeers is the number of peers a ready node is connected to at least.
+	MinPeers int `toml:",omitempty"`
+
+	// MaxHeadAge is the maximum age of the head block of a ready node. Zero
+	// disables the check.
+	MaxHeadAge time.Duration `toml:",omitempty"`
+
+	// MaxBlockLag is the maximum number of blocks a ready node may be behind the
+	// best known peer while synchronising.
+	MaxBlockLag uint64 `toml:",omitempty"`
+}
+
+// DefaultHealthConfig contains the default readiness thresholds.
+var DefaultHealthConfig = HealthConfig{
+	MinPeers:    1,
+	MaxHeadAge:  10 * time.Minute,
+	MaxBlockLag: 5,
+}
+
+// HealthCheck is a single check of the health of a node.
+type HealthCheck struct {
+	// Name identifies the check in the report, e.g. "peers".
+	Name string
+
+	// Readiness marks the checks only run by the readiness endpoint, failing if
+	// the node is functional but shouldn't be served traffic yet.
+	Readiness bool
+
+	// Check runs the check against the requested thresholds, returning an error
+	// describing why the node is unhealthy, or a status message otherwise.
+	Check func(thresholds *HealthConfig) (string, error)
+}
+
+// HealthService is implemented by services contributing checks to the health
+// and readiness endpoints of the node.
+type HealthService interface {
+	Service
+
+	// HealthChecks retrieves the checks of the service.
+	HealthChecks() []HealthCheck
+}
+
+// healthResult is the outcome of a single check.
+type healthResult struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// healthReport is the JSON body of the health and readiness endpoints.
+type healthReport struct {
+	Healthy bool                     `json:"healthy"`
+	Checks  map[string]*healthResult `json:"checks"`
+}
+
+// healthHandler serves the health or readiness endpoint of a node.
+type healthHandler struct {
+	defaults  HealthConfig
+	checks    []HealthCheck
+	readiness bool
+}
+
+// healthChecks gathers the checks of the node itself and of its services.
+func (n *Node) healthChecks(services map[reflect.Type]Service) []HealthCheck {
+	checks := []HealthCheck{{
+		Name:      "peers",
+		Readiness: true,
+		Check: func(t *HealthConfig) (string, error) {
+			server := n.Server()
+			if server == nil {
+				return "", ErrNodeStopped
+			}
+			peers := server.PeerCount()
+			if peers < t.MinPeers {
+				return "", fmt.Errorf("%d peers, want at least %d", peers, t.MinPeers)
+			}
+			return fmt.Sprintf("%d peers", peers), nil
+		},
+	}}
+	for _, service := range services {
+		if service, ok := service.(HealthService); ok {
+			checks = append(checks, service.HealthChecks()...)
+		}
+	}
+	return checks
+}
+
+// healthHandlers creates the handlers of the health and readiness endpoints.
+func healthHandlers(config *HealthConfig, checks []HealthCheck) map[string]http.Handler {
+	return map[string]http.Handler{
+		HealthPath: &healthHandler{defaults: *config, checks: checks},
+		ReadyPath:  &healthHandler{defaults: *config, checks: checks, readiness: true},
+	}
+}
+
+// ServeHTTP implements http.Handler, running the checks and replying with 200
+// if all passed or 503 otherwise.
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	thresholds, err := h.thresholds(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report := &healthReport{Healthy: true, Checks: make(map[string]*healthResult)}
+	for _, check := range h.checks {
+		if check.Readiness && !h.readiness {
+			continue
+		}
+		result := &healthResult{Healthy: true}
+		if msg, err := check.Check(thresholds); err != nil {
+			result.Healthy, result.Message = false, err.Error()
+			report.Healthy = false
+		} else {
+			result.Message = msg
+		}
+		report.Checks[check.Name] = result
+	}
+	status := http.StatusOK
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// thresholds returns the thresholds of a request, the configured defaults
+// overridden by the query parameters.
+func (h *healthHandler) thresholds(r *http.Request) (*HealthConfig, error) {
+	t := h.defaults
+	query := r.URL.Query()
+	if v := query.Get("minPeers"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid minPeers %q", v)
+		}
+		t.MinPeers = n
+	}
+	if v := query.Get("maxHeadAge"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid maxHeadAge %q", v)
+		}
+		t.MaxHeadAge = d
+	}
+	if v := query.Get("maxBlockLag"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maxBlockLag %q", v)
+		}
+		t.MaxBlockLag = n
+	}
+	return &t, nil
+}
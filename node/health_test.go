@@ -0,0 +1,169 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testHealthChecks returns a liveness check that always passes and a readiness
+// check requiring at least MinPeers of the given number of peers.
+func testHealthChecks(peers int) []HealthCheck {
+	return []HealthCheck{
+		{Name: "database", Check: func(t *HealthConfig) (string, error) { return "writable", nil }},
+		{Name: "peers", Readiness: true, Check: func(t *HealthConfig) (string, error) {
+			if peers < t.MinPeers {
+				return "", fmt.Errorf("%d peers, want at least %d", peers, t.MinPeers)
+			}
+			return fmt.Sprintf("%d peers", peers), nil
+		}},
+	}
+}
+
+func queryHealth(t *testing.T, handler http.Handler, url string) (int, *healthReport) {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+
+	report := new(healthReport)
+	if rec.Code != http.StatusBadRequest {
+		if err := json.NewDecoder(rec.Body).Decode(report); err != nil {
+			t.Fatalf("%s: invalid report: %v", url, err)
+		}
+	}
+	return rec.Code, report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	config := &HealthConfig{MinPeers: 3, MaxHeadAge: time.Minute}
+	handlers := healthHandlers(config, testHealthChecks(2))
+
+	// The liveness endpoint skips the readiness checks
+	code, report := queryHealth(t, handlers[HealthPath], HealthPath)
+	if code != http.StatusOK || !report.Healthy {
+		t.Errorf("health: have %d %+v, want healthy", code, report)
+	}
+	if _, ok := report.Checks["peers"]; ok {
+		t.Errorf("health: readiness check ran")
+	}
+	// The readiness endpoint fails on too few peers
+	code, report = queryHealth(t, handlers[ReadyPath], ReadyPath)
+	if code != http.StatusServiceUnavailable || report.Healthy {
+		t.Errorf("ready: have %d %+v, want unhealthy", code, report)
+	}
+	if res := report.Checks["peers"]; res == nil || res.Healthy || res.Message == "" {
+		t.Errorf("ready: peers check mismatch: %+v", res)
+	}
+	if res := report.Checks["database"]; res == nil || !res.Healthy {
+		t.Errorf("ready: database check mismatch: %+v", res)
+	}
+	// Thresholds can be relaxed by query parameters
+	code, report = queryHealth(t, handlers[ReadyPath], ReadyPath+"?minPeers=2")
+	if code != http.StatusOK || !report.Healthy {
+		t.Errorf("ready with minPeers=2: have %d %+v, want healthy", code, report)
+	}
+	// Invalid thresholds are rejected
+	for _, query := range []string{"?minPeers=x", "?minPeers=-1", "?maxHeadAge=1", "?maxBlockLag=-5"} {
+		if code, _ := queryHealth(t, handlers[ReadyPath], ReadyPath+query); code != http.StatusBadRequest {
+			t.Errorf("ready%s: have code %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHealthThresholds(t *testing.T) {
+	h := &healthHandler{defaults: DefaultHealthConfig}
+
+	thresholds, err := h.thresholds(httptest.NewRequest("GET", ReadyPath+"?maxHeadAge=90s&maxBlockLag=12", nil))
+	if err != nil {
+		t.Fatalf("failed to parse thresholds: %v", err)
+	}
+	want := HealthConfig{MinPeers: DefaultHealthConfig.MinPeers, MaxHeadAge: 90 * time.Second, MaxBlockLag: 12}
+	if *thresholds != want {
+		t.Errorf("thresholds mismatch: have %+v, want %+v", *thresholds, want)
+	}
+	if h.defaults != DefaultHealthConfig {
+		t.Errorf("defaults modified by request: %+v", h.defaults)
+	}
+}
+
+// healthService is a service contributing a failing liveness check.
+type healthService struct{ NoopService }
+
+func (s *healthService) HealthChecks() []HealthCheck {
+	return []HealthCheck{{Name: "broken", Check: func(t *HealthConfig) (string, error) {
+		return "", errors.New("broken")
+	}}}
+}
+
+func TestHealthServiceChecks(t *testing.T) {
+	config := testNodeConfig()
+	config.Health = &DefaultHealthConfig
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Register(func(*ServiceContext) (Service, error) { return new(healthService), nil }); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	handler := stack.httpPaths[HealthPath]
+	if handler == nil {
+		t.Fatalf("health endpoint not registered")
+	}
+	code, report := queryHealth(t, handler, HealthPath)
+	if code != http.StatusServiceUnavailable || report.Checks["broken"] == nil {
+		t.Errorf("have %d %+v, want the service check failing", code, report)
+	}
+}
+
+func TestHealthVirtualHosts(t *testing.T) {
+	config := testNodeConfig()
+	config.Health = &DefaultHealthConfig
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPVirtualHosts = []string{"localhost"}
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	url := "http://" + stack.httpListener.Addr().String() + HealthPath
+	for host, want := range map[string]int{"localhost": http.StatusOK, "evil.com": http.StatusForbidden} {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Host = host
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("host %s: request failed: %v", host, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Errorf("host %s: have code %d, want %d", host, res.StatusCode, want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -58,10 +59,11 @@ type Node struct {
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
-	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpEndpoint  string                  // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	httpWhitelist []string                // HTTP RPC modules to allow through this endpoint
+	httpListener  net.Listener            // HTTP RPC listener socket to server API requests
+	httpHandler   *rpc.Server             // HTTP RPC request handler to process the API requests
+	httpPaths     map[string]http.Handler // Service HTTP handlers served on the HTTP RPC listener, by path
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
@@ -248,6 +250,25 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
+	// Gather the HTTP handlers to serve besides the RPC APIs
+	paths := make(map[string]http.Handler)
+	for _, service := range services {
+		if service, ok := service.(HTTPService); ok {
+			for path, handler := range service.HTTPHandlers() {
+				paths[path] = handler
+			}
+		}
+	}
+	if n.config.Health != nil {
+		for path, handler := range healthHandlers(n.config.Health, n.healthChecks(services)) {
+			paths[path] = handler
+		}
+	}
+	if len(paths) > 0 && n.httpEndpoint == "" {
+		log.Warn("Service HTTP handlers need the HTTP-RPC server enabled", "handlers", len(paths))
+	}
+	n.httpPaths = paths
+
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -256,7 +277,7 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
@@ -276,6 +297,7 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	handler.SetAccessLog(n.config.RPCAccessLog)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -302,6 +324,11 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	if err := handler.SetAuth(n.config.RPCAuth); err != nil {
+		return err
+	}
+	handler.SetLimits(n.config.RPCLimits)
+	handler.SetAccessLog(n.config.RPCAccessLog)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -358,7 +385,7 @@ func (n *Node) stopIPC() {
 }
 
 // startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string) error {
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -370,6 +397,11 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	if err := handler.SetAuth(n.config.RPCAuth); err != nil {
+		return err
+	}
+	handler.SetLimits(n.config.RPCLimits)
+	handler.SetAccessLog(n.config.RPCAccessLog)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -386,7 +418,20 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, handler).Serve(listener)
+	server := rpc.NewHTTPServer(cors, vhosts, handler)
+	if len(n.httpPaths) > 0 {
+		// Serve the service handlers under their paths, the RPC API under all others.
+		// The handlers are subject to the virtual host allowlist, authentication and
+		// limits of the RPC API.
+		mux := http.NewServeMux()
+		mux.Handle("/", server.Handler)
+		for path, h := range n.httpPaths {
+			mux.Handle(path, rpc.NewVirtualHostHandler(vhosts, handler.GuardHandler(httpHandlerMethod(path), h)))
+			log.Info("HTTP handler registered", "url", "http://"+endpoint+path)
+		}
+		server.Handler = mux
+	}
+	go server.Serve(listener)
 	// log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
 
 	// All listeners booted successfully
@@ -397,6 +442,12 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// httpHandlerMethod returns the RPC method the requests of a service HTTP
+// handler are authorized and limited as, e.g. graphql_query for /graphql.
+func httpHandlerMethod(path string) string {
+	return strings.Replace(strings.Trim(path, "/"), "/", "_", -1) + "_query"
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -424,6 +475,11 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	if err := handler.SetAuth(n.config.RPCAuth); err != nil {
+		return err
+	}
+	handler.SetLimits(n.config.RPCLimits)
+	handler.SetAccessLog(n.config.RPCAccessLog)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -465,6 +521,51 @@ func (n *Node) stopWS() {
 	}
 }
 
+// SetHTTPCors changes the CORS origins of the HTTP-RPC endpoint, restarting it
+// on the same address if it's running.
+func (n *Node) SetHTTPCors(cors []string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.config.HTTPCors = cors
+	return n.restartHTTP()
+}
+
+// SetHTTPVirtualHosts changes the virtual hostnames accepted by the HTTP-RPC
+// endpoint, restarting it on the same address if it's running.
+func (n *Node) SetHTTPVirtualHosts(vhosts []string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.config.HTTPVirtualHosts = vhosts
+	return n.restartHTTP()
+}
+
+// SetWSOrigins changes the origins accepted by the websocket RPC endpoint,
+// restarting it on the same address if it's running.
+func (n *Node) SetWSOrigins(origins []string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.config.WSOrigins = origins
+	if n.wsHandler == nil {
+		return nil
+	}
+	endpoint := n.wsEndpoint
+	n.stopWS()
+	return n.startWS(endpoint, n.rpcAPIs, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll)
+}
+
+// restartHTTP restarts a running HTTP-RPC endpoint to apply a changed config.
+func (n *Node) restartHTTP() error {
+	if n.httpHandler == nil {
+		return nil
+	}
+	endpoint := n.httpEndpoint
+	n.stopHTTP()
+	return n.startHTTP(endpoint, n.rpcAPIs, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts)
+}
+
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
@@ -663,6 +764,10 @@ func (n *Node) apis() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   debug.Handler,
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateDebugAPI(n),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -17,6 +17,7 @@
 package node
 
 import (
+	"net/http"
 	"reflect"
 
 	"github.com/wtc/go-wtc/accounts"
@@ -96,3 +97,15 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// HTTPService is implemented by services serving HTTP endpoints besides their
+// RPC APIs, e.g. GraphQL. The handlers are served on the HTTP-RPC listener and
+// need to apply their own CORS and virtual host policies. The RPC authentication
+// and limits apply to their requests as calls of the method <path>_query, e.g.
+// graphql_query for /graphql.
+type HTTPService interface {
+	Service
+
+	// HTTPHandlers retrieves the HTTP handlers to serve, keyed by URL path.
+	HTTPHandlers() map[string]http.Handler
+}
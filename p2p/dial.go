@@ -72,6 +72,7 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	reputation  *reputation // Reputation of remote nodes, nil to dial indiscriminately
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
@@ -90,6 +91,7 @@ type discoverTable interface {
 	Resolve(target discover.NodeID) *discover.Node
 	Lookup(target discover.NodeID) []*discover.Node
 	ReadRandomNodes([]*discover.Node) int
+	RequestENR(*discover.Node) (*discover.Record, error)
 }
 
 // the dial history remembers recent dials.
@@ -154,6 +156,9 @@ func (s *dialstate) addStatic(n *discover.Node) {
 func (s *dialstate) removeStatic(n *discover.Node) {
 	// This removes a task so future attempts to connect will not be made.
 	delete(s.static, n.ID)
+	// This removes a previous dial timestamp so that application
+	// can force a server to reconnect with chosen peer immediately.
+	s.hist.remove(n.ID)
 }
 
 func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now time.Time) []task {
@@ -213,10 +218,16 @@ func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now
 		}
 	}
 	// Use random nodes from the table for half of the necessary
-	// dynamic dials.
+	// dynamic dials, preferring the ones with the best reputation.
 	randomCandidates := needDynDials / 2
 	if randomCandidates > 0 {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
+		if n > len(s.randomNodes) {
+			n = len(s.randomNodes)
+		}
+		if s.reputation != nil {
+			s.reputation.sort(s.randomNodes[:n])
+		}
 		for i := 0; i < randomCandidates && i < n; i++ {
 			if addDial(dynDialedConn, s.randomNodes[i]) {
 				needDynDials--
@@ -255,6 +266,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errBanned           = errors.New("banned for misbehaving")
 )
 
 func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer) error {
@@ -270,6 +282,8 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 		return errNotWhitelisted
 	case s.hist.contains(n.ID):
 		return errRecentlyDialed
+	case s.reputation != nil && s.reputation.banned(n.ID):
+		return errBanned
 	}
 	return nil
 }
@@ -281,16 +295,26 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 		delete(s.dialing, t.dest.ID)
 	case *discoverTask:
 		s.lookupRunning = false
+		if s.reputation != nil {
+			s.reputation.sort(t.results)
+		}
 		s.lookupBuf = append(s.lookupBuf, t.results...)
 	}
 }
 
+func (s *dialstate) setMaxDynDials(n int) {
+	s.maxDynDials = n
+}
+
 func (t *dialTask) Do(srv *Server) {
 	if t.dest.Incomplete() {
 		if !t.resolve(srv) {
 			return
 		}
 	}
+	if t.flags&dynDialedConn != 0 && !srv.checkDialFilters(t.dest) {
+		return
+	}
 	success := t.dial(srv, t.dest)
 	// Try resolving the ID of static nodes if dialing failed.
 	if !success && t.flags&staticDialedConn != 0 {
@@ -386,6 +410,15 @@ func (h dialHistory) min() pastDial {
 func (h *dialHistory) add(id discover.NodeID, exp time.Time) {
 	heap.Push(h, pastDial{id, exp})
 }
+func (h *dialHistory) remove(id discover.NodeID) bool {
+	for i, v := range *h {
+		if v.id == id {
+			heap.Remove(h, i)
+			return true
+		}
+	}
+	return false
+}
 func (h dialHistory) contains(id discover.NodeID) bool {
 	for _, v := range h {
 		if v.id == id {
@@ -18,6 +18,7 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
@@ -83,6 +84,9 @@ func (t fakeTable) Close()                                   {}
 func (t fakeTable) Lookup(discover.NodeID) []*discover.Node  { return nil }
 func (t fakeTable) Resolve(discover.NodeID) *discover.Node   { return nil }
 func (t fakeTable) ReadRandomNodes(buf []*discover.Node) int { return copy(buf, t) }
+func (t fakeTable) RequestENR(*discover.Node) (*discover.Record, error) {
+	return nil, errors.New("no records")
+}
 
 // This test checks that dynamic dials are launched from discovery results.
 func TestDialStateDynDial(t *testing.T) {
@@ -515,6 +519,28 @@ func TestDialStateStaticDial(t *testing.T) {
 	})
 }
 
+// This test checks that a static node which is removed and added again is
+// dialed immediately, even if it was dialed recently.
+func TestDialStateStaticReAdd(t *testing.T) {
+	var (
+		now  time.Time
+		node = &discover.Node{ID: uintID(1)}
+		want = []task{&dialTask{flags: staticDialedConn, dest: node}}
+		s    = newDialState([]*discover.Node{node}, nil, fakeTable{}, 0, nil)
+	)
+	tasks := s.newTasks(0, nil, now)
+	if !sametasks(tasks, want) {
+		t.Fatalf("initial tasks mismatch: have %v, want %v", tasks, want)
+	}
+	s.taskDone(tasks[0], now)
+
+	s.removeStatic(node)
+	s.addStatic(node)
+	if tasks := s.newTasks(0, nil, now); !sametasks(tasks, want) {
+		t.Errorf("tasks after re-adding mismatch: have %v, want %v", tasks, want)
+	}
+}
+
 // This test checks that past dials are not retried for some time.
 func TestDialStateCache(t *testing.T) {
 	wantStatic := []*discover.Node{
@@ -634,10 +660,56 @@ func uintID(i uint32) discover.NodeID {
 	return id
 }
 
-// implements discoverTable for TestDialResolve
+// This test checks that dynamically dialed nodes are skipped if their node
+// record is rejected by all dial filters.
+func TestDialFilter(t *testing.T) {
+	accepted, rejected := new(discover.Record), new(discover.Record)
+	accepted.Set("test", uint(1))
+	rejected.Set("test", uint(2))
+
+	table := &resolveMock{records: map[discover.NodeID]*discover.Record{
+		uintID(1): accepted,
+		uintID(2): rejected,
+	}}
+	filter := func(r *discover.Record) bool {
+		var v uint
+		return r.Load("test", &v) == nil && v == 1
+	}
+	srv := &Server{ntab: table}
+	srv.Protocols = []Protocol{{Name: "test", DialFilter: filter}}
+
+	tests := []struct {
+		id   discover.NodeID
+		want bool
+	}{
+		{uintID(1), true},  // record accepted
+		{uintID(2), false}, // record rejected
+		{uintID(3), true},  // no record served
+	}
+	for _, tt := range tests {
+		if ok := srv.checkDialFilters(&discover.Node{ID: tt.id}); ok != tt.want {
+			t.Errorf("node %x: filter result mismatch: have %v, want %v", tt.id[:4], ok, tt.want)
+		}
+	}
+	// Without any filters, records should not even be requested
+	srv.Protocols = []Protocol{{Name: "test"}}
+	if !srv.checkDialFilters(&discover.Node{ID: uintID(2)}) {
+		t.Errorf("node rejected without dial filters")
+	}
+}
+
+// implements discoverTable for TestDialResolve and TestDialFilter
 type resolveMock struct {
 	resolveCalls []discover.NodeID
 	answer       *discover.Node
+	records      map[discover.NodeID]*discover.Record
+}
+
+func (t *resolveMock) RequestENR(n *discover.Node) (*discover.Record, error) {
+	if r, ok := t.records[n.ID]; ok {
+		return r, nil
+	}
+	return nil, errors.New("no record")
 }
 
 func (t *resolveMock) Resolve(id discover.NodeID) *discover.Node {
@@ -61,6 +61,10 @@ var (
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
 	nodeDBDiscoverPong      = nodeDBDiscoverRoot + ":lastpong"
 	nodeDBDiscoverFindFails = nodeDBDiscoverRoot + ":findfail"
+
+	nodeDBReputationRoot   = ":reputation"
+	nodeDBReputationScore  = nodeDBReputationRoot + ":score"
+	nodeDBReputationBanned = nodeDBReputationRoot + ":banned"
 )
 
 // newNodeDB creates a new node database for storing and retrieving infos about
@@ -298,6 +302,37 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// reputation retrieves the reputation score of a remote node and the time until
+// which it is banned.
+func (db *nodeDB) reputation(id NodeID) NodeReputation {
+	return NodeReputation{
+		Score:  db.fetchInt64(makeKey(id, nodeDBReputationScore)),
+		Banned: time.Unix(db.fetchInt64(makeKey(id, nodeDBReputationBanned)), 0),
+	}
+}
+
+// updateReputation updates the reputation score and ban expiration of a node.
+func (db *nodeDB) updateReputation(id NodeID, rep NodeReputation) error {
+	if err := db.storeInt64(makeKey(id, nodeDBReputationScore), rep.Score); err != nil {
+		return err
+	}
+	return db.storeInt64(makeKey(id, nodeDBReputationBanned), rep.Banned.Unix())
+}
+
+// reputations retrieves the reputation of all nodes that have one recorded.
+func (db *nodeDB) reputations() map[NodeID]NodeReputation {
+	it := db.lvl.NewIterator(util.BytesPrefix(nodeDBItemPrefix), nil)
+	defer it.Release()
+
+	reps := make(map[NodeID]NodeReputation)
+	for it.Next() {
+		if id, field := splitKey(it.Key()); field == nodeDBReputationScore {
+			reps[id] = db.reputation(id)
+		}
+	}
+	return reps
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 func (db *nodeDB) querySeeds(n int, maxAge time.Duration) []*Node {
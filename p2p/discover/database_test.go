@@ -158,6 +158,37 @@ func TestNodeDBFetchStore(t *testing.T) {
 	}
 }
 
+// Tests that node reputations can be stored, retrieved and listed.
+func TestNodeDBReputation(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	good := MustHexID("0x1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+	bad := MustHexID("0x57d9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+
+	if stored := db.reputation(good); stored.Score != 0 || stored.Banned.Unix() != 0 {
+		t.Errorf("reputation: non-existing object: %v", stored)
+	}
+	reps := map[NodeID]NodeReputation{
+		good: {Score: 42, Banned: time.Unix(0, 0)},
+		bad:  {Score: -50, Banned: time.Unix(time.Now().Add(time.Hour).Unix(), 0)},
+	}
+	for id, rep := range reps {
+		if err := db.updateReputation(id, rep); err != nil {
+			t.Errorf("reputation: failed to update: %v", err)
+		}
+		if stored := db.reputation(id); stored != rep {
+			t.Errorf("reputation: value mismatch: have %v, want %v", stored, rep)
+		}
+	}
+	// Unrelated node metadata must not show up in the reputation listing
+	db.updateLastPong(NodeID{0x01}, time.Now())
+
+	if stored := db.reputations(); !reflect.DeepEqual(stored, reps) {
+		t.Errorf("reputations: listing mismatch: have %v, want %v", stored, reps)
+	}
+}
+
 var nodeDBSeedQueryNodes = []struct {
 	node *Node
 	pong time.Time
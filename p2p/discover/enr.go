@@ -0,0 +1,344 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the implementation of Ethereum Node Records (EIP-778), signed and
+// versioned key/value records advertising the endpoint of a node.
+
+package discover
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/rlp"
+)
+
+const (
+	maxRecordSize  = 300    // Maximum encoded size of a node record in bytes
+	recordIDScheme = "v4"   // Identity scheme of the records signed by secp256k1 keys
+	recordPrefix   = "enr:" // Prefix of the textual representation of a record
+)
+
+// Keys of the predefined record entries.
+const (
+	RecordKeyID        = "id"        // Name of the identity scheme
+	RecordKeySecp256k1 = "secp256k1" // Compressed secp256k1 public key of the node
+	RecordKeyIP        = "ip"        // IPv4 address of the node
+	RecordKeyIP6       = "ip6"       // IPv6 address of the node
+	RecordKeyUDP       = "udp"       // UDP port of the discovery protocol
+	RecordKeyTCP       = "tcp"       // TCP port of the RLPx protocol
+)
+
+var (
+	// ErrRecordKeyNotFound is returned when loading an entry missing from a record.
+	ErrRecordKeyNotFound = errors.New("record key not found")
+
+	errRecordTooBig       = fmt.Errorf("record bigger than %d bytes", maxRecordSize)
+	errRecordUnsorted     = errors.New("record keys are not sorted")
+	errRecordDuplicateKey = errors.New("record contains duplicate key")
+	errRecordUnsigned     = errors.New("record is not signed")
+	errRecordBadSignature = errors.New("invalid record signature")
+	errRecordBadPrefix    = errors.New("record text lacks the \"enr:\" prefix")
+)
+
+// recordPair is a key/value entry of a record, with the value kept RLP encoded.
+type recordPair struct {
+	k string
+	v rlp.RawValue
+}
+
+// Record represents an Ethereum Node Record. Records can be freely modified,
+// but any modification invalidates the signature, which has to be renewed via
+// Sign before the record can be encoded.
+type Record struct {
+	seq       uint64       // Sequence number, to be increased on every change
+	signature []byte       // Signature over the content, nil if unsigned
+	raw       []byte       // RLP encoding of the signed record, nil if unsigned
+	pairs     []recordPair // Key/value entries, sorted by key
+}
+
+// Seq returns the sequence number of the record.
+func (r *Record) Seq() uint64 {
+	return r.seq
+}
+
+// SetSeq updates the sequence number of the record, invalidating its signature.
+func (r *Record) SetSeq(seq uint64) {
+	r.seq = seq
+	r.invalidate()
+}
+
+// Set adds or overwrites the entry with the given key, invalidating the record
+// signature. The value is stored RLP encoded.
+func (r *Record) Set(key string, value interface{}) error {
+	blob, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(r.pairs), func(i int) bool { return r.pairs[i].k >= key })
+	switch {
+	case i < len(r.pairs) && r.pairs[i].k == key:
+		r.pairs[i].v = blob
+	default:
+		r.pairs = append(r.pairs, recordPair{})
+		copy(r.pairs[i+1:], r.pairs[i:])
+		r.pairs[i] = recordPair{k: key, v: blob}
+	}
+	r.invalidate()
+	return nil
+}
+
+// Load decodes the entry with the given key into value, which must be a pointer.
+// ErrRecordKeyNotFound is returned if the record has no such entry.
+func (r *Record) Load(key string, value interface{}) error {
+	i := sort.Search(len(r.pairs), func(i int) bool { return r.pairs[i].k >= key })
+	if i == len(r.pairs) || r.pairs[i].k != key {
+		return ErrRecordKeyNotFound
+	}
+	if err := rlp.DecodeBytes(r.pairs[i].v, value); err != nil {
+		return fmt.Errorf("invalid record entry %q: %v", key, err)
+	}
+	return nil
+}
+
+// SetEndpoint stores the IP address and ports of a node in the record.
+func (r *Record) SetEndpoint(ip net.IP, udp, tcp uint16) {
+	if ip4 := ip.To4(); ip4 != nil {
+		r.Set(RecordKeyIP, ip4)
+	} else if ip6 := ip.To16(); ip6 != nil {
+		r.Set(RecordKeyIP6, ip6)
+	}
+	r.Set(RecordKeyUDP, udp)
+	r.Set(RecordKeyTCP, tcp)
+}
+
+// Signed reports whether the record carries a valid signature.
+func (r *Record) Signed() bool {
+	return r.signature != nil
+}
+
+// invalidate drops the signature after the content of the record changed.
+func (r *Record) invalidate() {
+	r.signature, r.raw = nil, nil
+}
+
+// content returns the RLP encoding of the signed part of the record.
+func (r *Record) content() []byte {
+	list := []interface{}{r.seq}
+	for _, p := range r.pairs {
+		list = append(list, p.k, p.v)
+	}
+	blob, _ := rlp.EncodeToBytes(list)
+	return blob
+}
+
+// Sign sets the "v4" identity scheme and the public key of the given private key
+// in the record, then signs it.
+func (r *Record) Sign(priv *ecdsa.PrivateKey) error {
+	r.Set(RecordKeyID, recordIDScheme)
+	r.Set(RecordKeySecp256k1, crypto.CompressPubkey(&priv.PublicKey))
+
+	sig, err := crypto.Sign(crypto.Keccak256(r.content()), priv)
+	if err != nil {
+		return err
+	}
+	sig = sig[:64] // The recovery ID is not part of the record signature
+
+	list := []interface{}{sig, r.seq}
+	for _, p := range r.pairs {
+		list = append(list, p.k, p.v)
+	}
+	raw, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		return err
+	}
+	if len(raw) > maxRecordSize {
+		return errRecordTooBig
+	}
+	r.signature, r.raw = sig, raw
+	return nil
+}
+
+// verify checks the signature of the record against the public key it contains.
+func (r *Record) verify() error {
+	var scheme string
+	if err := r.Load(RecordKeyID, &scheme); err != nil {
+		return err
+	}
+	if scheme != recordIDScheme {
+		return fmt.Errorf("unknown record identity scheme %q", scheme)
+	}
+	pubkey, err := r.pubkey()
+	if err != nil {
+		return err
+	}
+	if len(r.signature) != 64 {
+		return errRecordBadSignature
+	}
+	// Only r and s are signed, try both recovery IDs against the known key
+	var (
+		hash = crypto.Keccak256(r.content())
+		want = crypto.FromECDSAPub(pubkey)
+		sig  = append(append([]byte{}, r.signature...), 0)
+	)
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if signer, err := crypto.SigToPub(hash, sig); err == nil && bytes.Equal(crypto.FromECDSAPub(signer), want) {
+			return nil
+		}
+	}
+	return errRecordBadSignature
+}
+
+// pubkey retrieves the public key of the node from the record.
+func (r *Record) pubkey() (*ecdsa.PublicKey, error) {
+	var compressed []byte
+	if err := r.Load(RecordKeySecp256k1, &compressed); err != nil {
+		return nil, err
+	}
+	return crypto.DecompressPubkey(compressed)
+}
+
+// NodeID returns the identifier of the node the record belongs to.
+func (r *Record) NodeID() (NodeID, error) {
+	pubkey, err := r.pubkey()
+	if err != nil {
+		return NodeID{}, err
+	}
+	return PubkeyID(pubkey), nil
+}
+
+// Node assembles the node the record describes. Records without an IP address
+// result in an incomplete node.
+func (r *Record) Node() (*Node, error) {
+	id, err := r.NodeID()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ip       net.IP
+		udp, tcp uint16
+	)
+	if err := r.Load(RecordKeyIP, &ip); err == ErrRecordKeyNotFound {
+		if err := r.Load(RecordKeyIP6, &ip); err != nil && err != ErrRecordKeyNotFound {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := r.Load(RecordKeyUDP, &udp); err != nil && err != ErrRecordKeyNotFound {
+		return nil, err
+	}
+	if err := r.Load(RecordKeyTCP, &tcp); err != nil && err != ErrRecordKeyNotFound {
+		return nil, err
+	}
+	if len(ip) == 0 {
+		ip = nil
+	}
+	return NewNode(id, ip, udp, tcp), nil
+}
+
+// EncodeRLP implements rlp.Encoder. Only signed records can be encoded.
+func (r *Record) EncodeRLP(w io.Writer) error {
+	if !r.Signed() {
+		return errRecordUnsigned
+	}
+	_, err := w.Write(r.raw)
+	return err
+}
+
+// DecodeRLP implements rlp.Decoder, verifying the signature of the record.
+func (r *Record) DecodeRLP(s *rlp.Stream) error {
+	raw, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	if len(raw) > maxRecordSize {
+		return errRecordTooBig
+	}
+	var dec Record
+	dec.raw = raw
+
+	s = rlp.NewStream(bytes.NewReader(raw), 0)
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	if dec.signature, err = s.Bytes(); err != nil {
+		return err
+	}
+	if dec.seq, err = s.Uint(); err != nil {
+		return err
+	}
+	for {
+		key, err := s.Bytes()
+		if err == rlp.EOL {
+			break
+		} else if err != nil {
+			return err
+		}
+		value, err := s.Raw()
+		if err != nil {
+			return err
+		}
+		if n := len(dec.pairs); n > 0 {
+			switch prev := dec.pairs[n-1].k; {
+			case prev == string(key):
+				return errRecordDuplicateKey
+			case prev > string(key):
+				return errRecordUnsorted
+			}
+		}
+		dec.pairs = append(dec.pairs, recordPair{k: string(key), v: value})
+	}
+	if err := s.ListEnd(); err != nil {
+		return err
+	}
+	if err := dec.verify(); err != nil {
+		return err
+	}
+	*r = dec
+	return nil
+}
+
+// String returns the textual representation of a signed record: its RLP encoding
+// in URL-safe base64 without padding, prefixed with "enr:".
+func (r *Record) String() string {
+	return recordPrefix + base64.RawURLEncoding.EncodeToString(r.raw)
+}
+
+// ParseRecord parses and verifies a record in its textual representation.
+func ParseRecord(text string) (*Record, error) {
+	if !strings.HasPrefix(text, recordPrefix) {
+		return nil, errRecordBadPrefix
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(text[len(recordPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	r := new(Record)
+	if err := rlp.DecodeBytes(blob, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
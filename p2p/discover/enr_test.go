@@ -0,0 +1,95 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/wtc/go-wtc/rlp"
+)
+
+// Tests that records survive a signing, encoding and parsing round trip, and
+// describe the node they were signed by.
+func TestRecordRoundTrip(t *testing.T) {
+	key := newkey()
+
+	r := new(Record)
+	r.SetSeq(7)
+	r.SetEndpoint(net.IP{10, 0, 0, 1}, 30301, 30303)
+	r.Set("custom", []uint{1, 2, 3})
+
+	if _, err := rlp.EncodeToBytes(r); err != errRecordUnsigned {
+		t.Fatalf("unsigned record encoding error mismatch: have %v, want %v", err, errRecordUnsigned)
+	}
+	if err := r.Sign(key); err != nil {
+		t.Fatalf("failed to sign record: %v", err)
+	}
+	parsed, err := ParseRecord(r.String())
+	if err != nil {
+		t.Fatalf("failed to parse record: %v", err)
+	}
+	if parsed.Seq() != 7 {
+		t.Errorf("sequence mismatch: have %d, want %d", parsed.Seq(), 7)
+	}
+	var custom []uint
+	if err := parsed.Load("custom", &custom); err != nil || len(custom) != 3 || custom[2] != 3 {
+		t.Errorf("custom entry mismatch: have %v, %v", custom, err)
+	}
+	if err := parsed.Load("missing", &custom); err != ErrRecordKeyNotFound {
+		t.Errorf("missing entry error mismatch: have %v, want %v", err, ErrRecordKeyNotFound)
+	}
+	node, err := parsed.Node()
+	if err != nil {
+		t.Fatalf("failed to assemble node: %v", err)
+	}
+	want := NewNode(PubkeyID(&key.PublicKey), net.IP{10, 0, 0, 1}, 30301, 30303)
+	if node.String() != want.String() {
+		t.Errorf("node mismatch:\n  have %v\n  want %v", node, want)
+	}
+	// Modifications must invalidate the signature
+	parsed.Set(RecordKeyTCP, uint16(1))
+	if parsed.Signed() {
+		t.Errorf("modified record still signed")
+	}
+}
+
+// Tests that tampered, oversized and malformed records are rejected.
+func TestRecordValidation(t *testing.T) {
+	r := new(Record)
+	r.SetEndpoint(net.IP{10, 0, 0, 1}, 30301, 30303)
+	if err := r.Sign(newkey()); err != nil {
+		t.Fatalf("failed to sign record: %v", err)
+	}
+	// Flip a bit of the signature and the content respectively
+	for _, pos := range []int{3, len(r.raw) - 1} {
+		tampered := Record{raw: append([]byte{}, r.raw...)}
+		tampered.raw[pos] ^= 0x01
+		if _, err := ParseRecord(tampered.String()); err == nil {
+			t.Errorf("tampered record (byte %d) accepted", pos)
+		}
+	}
+	// Oversized records cannot be signed
+	r.Set("junk", strings.Repeat("x", maxRecordSize))
+	if err := r.Sign(newkey()); err != errRecordTooBig {
+		t.Errorf("oversized record error mismatch: have %v, want %v", err, errRecordTooBig)
+	}
+	if _, err := ParseRecord("enode://abc"); err != errRecordBadPrefix {
+		t.Errorf("prefix error mismatch: have %v, want %v", err, errRecordBadPrefix)
+	}
+}
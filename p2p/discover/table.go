@@ -23,6 +23,7 @@
 package discover
 
 import (
+	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
@@ -67,8 +68,11 @@ type Table struct {
 
 	nodeAddedHook func(*Node) // for testing
 
-	net  transport
-	self *Node // metadata of the local node
+	net       transport
+	self      *Node             // metadata of the local node
+	record    *Record           // signed record of the local node
+	recordKey *ecdsa.PrivateKey // key signing the updates of the local record
+	recordMu  sync.RWMutex      // protects record
 }
 
 type bondproc struct {
@@ -84,6 +88,7 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	requestENR(toid NodeID, addr *net.UDPAddr) (*Record, error)
 	close()
 }
 
@@ -123,6 +128,50 @@ func (tab *Table) Self() *Node {
 	return tab.self
 }
 
+// Record returns the signed node record of the local node.
+// The returned record should not be modified by the caller.
+func (tab *Table) Record() *Record {
+	tab.recordMu.RLock()
+	defer tab.recordMu.RUnlock()
+
+	return tab.record
+}
+
+// SetRecordEntry adds or overwrites an entry of the local node record, signing
+// the updated record with an increased sequence number. Nodes requesting the
+// record afterwards receive the new version.
+func (tab *Table) SetRecordEntry(key string, value interface{}) error {
+	tab.recordMu.Lock()
+	defer tab.recordMu.Unlock()
+
+	record := *tab.record
+	record.pairs = append([]recordPair{}, record.pairs...)
+	if err := record.Set(key, value); err != nil {
+		return err
+	}
+	record.SetSeq(record.Seq() + 1)
+	if err := record.Sign(tab.recordKey); err != nil {
+		return err
+	}
+	tab.record = &record
+	return nil
+}
+
+// RequestENR retrieves the current node record of a remote node. The node has
+// to be bonded with, i.e. it must know the local node, to answer.
+func (tab *Table) RequestENR(n *Node) (*Record, error) {
+	if _, err := tab.bond(false, n.ID, n.addr(), n.TCP); err != nil {
+		return nil, err
+	}
+	r, err := tab.net.requestENR(n.ID, n.addr())
+	if err != nil {
+		// A request sent right after bonding may reach the remote node before
+		// it stored the bond, try once more.
+		r, err = tab.net.requestENR(n.ID, n.addr())
+	}
+	return r, err
+}
+
 // ReadRandomNodes fills the given slice with random nodes from the
 // table. It will not write the same node more than once. The nodes in
 // the slice are copies and can be modified by the caller.
@@ -161,6 +210,28 @@ func (tab *Table) ReadRandomNodes(buf []*Node) (n int) {
 	return i + 1
 }
 
+// NodeReputation is the reputation of a remote node as tracked by the p2p server,
+// persisted in the node database alongside the discovery metadata.
+type NodeReputation struct {
+	Score  int64     // Accumulated score of the node's past behaviour
+	Banned time.Time // Time until which connections to the node are refused
+}
+
+// Reputation retrieves the persisted reputation of a node.
+func (tab *Table) Reputation(id NodeID) NodeReputation {
+	return tab.db.reputation(id)
+}
+
+// SetReputation persists the reputation of a node.
+func (tab *Table) SetReputation(id NodeID, rep NodeReputation) error {
+	return tab.db.updateReputation(id, rep)
+}
+
+// Reputations retrieves the persisted reputation of all nodes that have one.
+func (tab *Table) Reputations() map[NodeID]NodeReputation {
+	return tab.db.reputations()
+}
+
 func randUint(max uint32) uint32 {
 	if max == 0 {
 		return 0
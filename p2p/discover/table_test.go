@@ -161,6 +161,10 @@ func (t *pingRecorder) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID)
 	panic("findnode called on pingRecorder")
 }
 func (t *pingRecorder) close() {}
+func (t *pingRecorder) requestENR(toid NodeID, toaddr *net.UDPAddr) (*Record, error) {
+	return nil, errTimeout
+}
+
 func (t *pingRecorder) waitping(from NodeID) error {
 	return nil // remote always pings
 }
@@ -527,6 +531,10 @@ func (*preminedTestnet) close()                                      {}
 func (*preminedTestnet) waitping(from NodeID) error                  { return nil }
 func (*preminedTestnet) ping(toid NodeID, toaddr *net.UDPAddr) error { return nil }
 
+func (*preminedTestnet) requestENR(toid NodeID, toaddr *net.UDPAddr) (*Record, error) {
+	return nil, errTimeout
+}
+
 // mine generates a testnet struct literal with nodes at
 // various distances to the given target.
 func (n *preminedTestnet) mine(target NodeID) {
@@ -323,6 +323,72 @@ func TestUDP_findnodeMultiReply(t *testing.T) {
 	}
 }
 
+func TestUDP_enrRequest(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// Requests of unknown nodes should be ignored
+	test.packetIn(errUnknownNode, enrRequestPacket, &enrRequest{Expiration: futureExp})
+
+	// Bonded nodes should receive the local record
+	test.table.db.updateNode(NewNode(PubkeyID(&test.remotekey.PublicKey), test.remoteaddr.IP, uint16(test.remoteaddr.Port), 99))
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.waitPacketOut(func(p *enrResponse) {
+		if !bytes.Equal(p.ReplyTok, test.sent[len(test.sent)-1][:macSize]) {
+			t.Errorf("reply token mismatch: have %x", p.ReplyTok)
+		}
+		if id, err := p.Record.NodeID(); err != nil || id != test.table.self.ID {
+			t.Errorf("record node mismatch: have %x, %v, want %x", id[:8], err, test.table.self.ID[:8])
+		}
+		if p.Record.Seq() != test.table.Record().Seq() {
+			t.Errorf("record sequence mismatch: have %d, want %d", p.Record.Seq(), test.table.Record().Seq())
+		}
+	})
+	// Updates of the local record should be served with an increased sequence number
+	seq := test.table.Record().Seq()
+	if err := test.table.SetRecordEntry("test", uint(42)); err != nil {
+		t.Fatalf("failed to update local record: %v", err)
+	}
+	test.packetIn(nil, enrRequestPacket, &enrRequest{Expiration: futureExp})
+	test.waitPacketOut(func(p *enrResponse) {
+		var v uint
+		if err := p.Record.Load("test", &v); err != nil || v != 42 {
+			t.Errorf("updated record entry mismatch: have %d, %v, want 42", v, err)
+		}
+		if p.Record.Seq() != seq+1 {
+			t.Errorf("updated record sequence mismatch: have %d, want %d", p.Record.Seq(), seq+1)
+		}
+	})
+	// Records requested from remote nodes should be returned and verified
+	remote := new(Record)
+	remote.SetEndpoint(test.remoteaddr.IP, uint16(test.remoteaddr.Port), 99)
+	if err := remote.Sign(test.remotekey); err != nil {
+		t.Fatalf("failed to sign remote record: %v", err)
+	}
+	resultc, errc := make(chan *Record), make(chan error)
+	go func() {
+		record, err := test.udp.requestENR(PubkeyID(&test.remotekey.PublicKey), test.remoteaddr)
+		if err != nil {
+			errc <- err
+		} else {
+			resultc <- record
+		}
+	}()
+	test.waitPacketOut(func(p *enrRequest) {})
+	test.packetIn(nil, enrResponsePacket, &enrResponse{ReplyTok: []byte{}, Record: *remote})
+
+	select {
+	case record := <-resultc:
+		if record.String() != remote.String() {
+			t.Errorf("record mismatch: have %v, want %v", record, remote)
+		}
+	case err := <-errc:
+		t.Errorf("record request failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Error("record request did not return within 5 seconds")
+	}
+}
+
 func TestUDP_successfulPing(t *testing.T) {
 	test := newUDPTest(t)
 	added := make(chan *Node, 1)
@@ -0,0 +1,221 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dnsdisc implements node discovery via DNS (EIP-1459): lists of signed
+// node records published as a Merkle tree of TXT records under a domain.
+package dnsdisc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+const (
+	defaultTimeout = 5 * time.Second // Time allowance for a single TXT lookup
+	maxTreeEntries = 10000           // Maximum number of entries resolved per tree, bounding malicious trees
+)
+
+var (
+	errNoRoot         = errors.New("no tree root found")
+	errHashMismatch   = errors.New("entry hash mismatch")
+	errTooManyEntries = fmt.Errorf("tree has more than %d entries", maxTreeEntries)
+)
+
+// Resolver is a DNS resolver that can look up TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Config holds the settings of a DNS discovery client.
+type Config struct {
+	Timeout  time.Duration // Timeout of a single TXT lookup, defaults to 5 seconds
+	Resolver Resolver      // Resolver to use, defaults to the system resolver
+}
+
+// Client resolves node lists published via DNS.
+type Client struct {
+	cfg Config
+}
+
+// NewClient creates a DNS discovery client.
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	return &Client{cfg: cfg}
+}
+
+// SyncTree downloads the complete tree at the given enrtree:// URL, verifying
+// the root signature against the key in the URL and every entry against the
+// hash it is referenced by. Linked trees are not followed.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	link, err := parseLink(url)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.resolveRoot(link)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{root: root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(t, link.domain, root.eroot); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(t, link.domain, root.lroot); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ResolveNodes downloads the trees at the given URLs along with all the trees
+// they link to, returning the nodes contained in them. Trees failing to resolve
+// are skipped, an error is only returned if none could be resolved.
+func (c *Client) ResolveNodes(urls ...string) ([]*discover.Node, error) {
+	var (
+		nodes   []*discover.Node
+		seen    = make(map[discover.NodeID]bool)
+		visited = make(map[string]bool)
+		queue   = append([]string{}, urls...)
+		lastErr error
+		synced  int
+	)
+	for len(queue) > 0 {
+		url := queue[0]
+		queue = queue[1:]
+		if visited[url] {
+			continue
+		}
+		visited[url] = true
+
+		t, err := c.SyncTree(url)
+		if err != nil {
+			log.Debug("Failed to resolve DNS node tree", "url", url, "err", err)
+			lastErr = err
+			continue
+		}
+		synced++
+		for _, n := range t.Nodes() {
+			if !seen[n.ID] {
+				seen[n.ID] = true
+				nodes = append(nodes, n)
+			}
+		}
+		queue = append(queue, t.Links()...)
+	}
+	if synced == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return nodes, nil
+}
+
+// resolveRoot retrieves the root of the tree and verifies its signature.
+func (c *Client) resolveRoot(link *linkEntry) (*rootEntry, error) {
+	txts, err := c.lookupTXT(link.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, rootPrefix) {
+			root, err := parseRoot(txt)
+			if err != nil {
+				return nil, err
+			}
+			if !root.verifySignature(link.pubkey) {
+				return nil, errInvalidSig
+			}
+			return root, nil
+		}
+	}
+	return nil, errNoRoot
+}
+
+// syncSubtree retrieves the entry with the given hash and all its descendants.
+func (c *Client) syncSubtree(t *Tree, domain string, hash string) error {
+	if _, ok := t.entries[hash]; ok {
+		return nil
+	}
+	if len(t.entries) >= maxTreeEntries {
+		return errTooManyEntries
+	}
+	e, err := c.resolveEntry(domain, hash)
+	if err != nil {
+		return err
+	}
+	t.entries[hash] = e
+
+	if branch, ok := e.(*branchEntry); ok {
+		for _, child := range branch.children {
+			if err := c.syncSubtree(t, domain, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveEntry retrieves a single entry of the tree, checking its hash.
+func (c *Client) resolveEntry(domain, hash string) (entry, error) {
+	txts, err := c.lookupTXT(hash + "." + domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		// Entries split into several character strings are joined by the resolver,
+		// check the hash to tell them apart from unrelated records
+		sum := crypto.Keccak256([]byte(txt))
+		if b32format.EncodeToString(sum[:hashAbbrev]) != hash {
+			continue
+		}
+		e, err := parseEntry(txt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entry %s: %v", hash, err)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("%v at %s.%s", errHashMismatch, hash, domain)
+}
+
+// lookupTXT looks up the TXT records of a name, bounded by the lookup timeout.
+func (c *Client) lookupTXT(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	return c.cfg.Resolver.LookupTXT(ctx, name)
+}
+
+// MapResolver is an in-memory resolver serving TXT records from a map keyed by
+// the fully qualified name, as produced by Tree.ToTXT. It is mostly useful for
+// testing and for checking trees before publishing them.
+type MapResolver map[string]string
+
+// LookupTXT implements Resolver.
+func (mr MapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, fmt.Errorf("no TXT record for %s", name)
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"crypto/ecdsa"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+// makeRecords creates a number of signed node records with random keys.
+func makeRecords(t *testing.T, n int) []*discover.Record {
+	records := make([]*discover.Record, n)
+	for i := range records {
+		key, _ := crypto.GenerateKey()
+
+		records[i] = new(discover.Record)
+		records[i].SetEndpoint(net.IP{10, 0, byte(i >> 8), byte(i)}, 30303, 30303)
+		if err := records[i].Sign(key); err != nil {
+			t.Fatalf("failed to sign record %d: %v", i, err)
+		}
+	}
+	return records
+}
+
+// makeTree creates and signs a tree, publishing it into the resolver.
+func makeTree(t *testing.T, resolver MapResolver, domain string, records []*discover.Record, links []string) (string, *ecdsa.PrivateKey) {
+	tree, err := MakeTree(1, records, links)
+	if err != nil {
+		t.Fatalf("failed to create tree: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatalf("failed to sign tree: %v", err)
+	}
+	for name, txt := range tree.ToTXT(domain) {
+		resolver[name] = txt
+	}
+	return url, key
+}
+
+// recordStrings returns the sorted textual form of a list of records.
+func recordStrings(records []*discover.Record) []string {
+	strs := make([]string, len(records))
+	for i, r := range records {
+		strs[i] = r.String()
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// Tests that trees of various sizes can be published and synced back.
+func TestSyncTree(t *testing.T) {
+	for _, n := range []int{0, 1, maxChildren, maxChildren + 1, 3 * maxChildren * maxChildren} {
+		resolver := make(MapResolver)
+		records := makeRecords(t, n)
+		url, _ := makeTree(t, resolver, "nodes.example.org", records, nil)
+
+		tree, err := NewClient(Config{Resolver: resolver}).SyncTree(url)
+		if err != nil {
+			t.Fatalf("%d records: failed to sync tree: %v", n, err)
+		}
+		have, want := recordStrings(tree.Records()), recordStrings(records)
+		if strings.Join(have, ",") != strings.Join(want, ",") {
+			t.Errorf("%d records: synced records mismatch: have %d, want %d", n, len(have), len(want))
+		}
+		if len(tree.Nodes()) != n {
+			t.Errorf("%d records: node count mismatch: have %d, want %d", n, len(tree.Nodes()), n)
+		}
+	}
+}
+
+// Tests that trees with invalid signatures or tampered entries are rejected.
+func TestSyncTreeInvalid(t *testing.T) {
+	resolver := make(MapResolver)
+	url, _ := makeTree(t, resolver, "nodes.example.org", makeRecords(t, 20), nil)
+	client := NewClient(Config{Resolver: resolver})
+
+	// A URL with a different public key must fail the root signature check
+	other, _ := crypto.GenerateKey()
+	forged := newLinkEntry("nodes.example.org", &other.PublicKey).String()
+	if _, err := client.SyncTree(forged); err != errInvalidSig {
+		t.Errorf("forged key error mismatch: have %v, want %v", err, errInvalidSig)
+	}
+	// Replacing a leaf must fail the hash check of the entry
+	for name, txt := range resolver {
+		if strings.HasPrefix(txt, enrPrefix) {
+			resolver[name] = makeRecords(t, 1)[0].String()
+			break
+		}
+	}
+	if _, err := client.SyncTree(url); err == nil || !strings.Contains(err.Error(), errHashMismatch.Error()) {
+		t.Errorf("tampered tree error mismatch: have %v, want %v", err, errHashMismatch)
+	}
+	// Missing roots and malformed URLs must be rejected too
+	if _, err := client.SyncTree(strings.Replace(url, "nodes.example.org", "missing.example.org", 1)); err == nil {
+		t.Errorf("missing tree synced")
+	}
+	if _, err := client.SyncTree("enrtree://nodes.example.org"); err != errNoPubkey {
+		t.Errorf("keyless URL error mismatch: have %v, want %v", err, errNoPubkey)
+	}
+}
+
+// Tests that resolving nodes follows the links between trees, deduplicating the
+// nodes and tolerating link cycles.
+func TestResolveNodesLinks(t *testing.T) {
+	resolver := make(MapResolver)
+	shared := makeRecords(t, 5)
+
+	leafURL, _ := makeTree(t, resolver, "leaf.example.org", append(makeRecords(t, 3), shared...), nil)
+	rootURL, _ := makeTree(t, resolver, "root.example.org", append(makeRecords(t, 4), shared...), []string{leafURL})
+
+	nodes, err := NewClient(Config{Resolver: resolver}).ResolveNodes(rootURL, leafURL)
+	if err != nil {
+		t.Fatalf("failed to resolve nodes: %v", err)
+	}
+	if len(nodes) != 12 {
+		t.Errorf("node count mismatch: have %d, want %d", len(nodes), 12)
+	}
+	if _, err := NewClient(Config{Resolver: make(MapResolver)}).ResolveNodes(rootURL); err == nil {
+		t.Errorf("nodes resolved from empty resolver")
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+const (
+	rootPrefix   = "enrtree-root:v1"
+	branchPrefix = "enrtree-branch:"
+	linkPrefix   = "enrtree://"
+	enrPrefix    = "enr:"
+
+	maxChildren = 13 // Maximum number of hashes in a branch, keeping TXT records below 370 bytes
+	hashAbbrev  = 16 // Number of keccak256 bytes making up the subdomain of an entry
+)
+
+var (
+	b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+	b64format = base64.RawURLEncoding
+)
+
+var (
+	errUnknownEntry = errors.New("unknown entry type")
+	errInvalidSig   = errors.New("invalid tree root signature")
+	errNoPubkey     = errors.New("missing public key in tree URL")
+)
+
+// entry is a TXT record of a tree: its root, an intermediate branch or a leaf
+// holding a node record or a link to another tree.
+type entry interface {
+	fmt.Stringer
+}
+
+type (
+	rootEntry struct {
+		eroot string // Subdomain of the root of the node record subtree
+		lroot string // Subdomain of the root of the link subtree
+		seq   uint   // Sequence number, increased on every update of the tree
+		sig   []byte // Signature of the root by the tree owner, in [R || S || V] format
+	}
+	branchEntry struct {
+		children []string
+	}
+	enrEntry struct {
+		record *discover.Record
+	}
+	linkEntry struct {
+		str    string
+		domain string
+		pubkey *ecdsa.PublicKey
+	}
+)
+
+// Tree is a Merkle tree of node records and links to other trees, published as
+// TXT records under a DNS domain and signed by its owner.
+type Tree struct {
+	root    *rootEntry
+	entries map[string]entry
+}
+
+// MakeTree creates a tree containing the given node records and links. The tree
+// needs to be signed before it can be published.
+func MakeTree(seq uint, records []*discover.Record, links []string) (*Tree, error) {
+	// Sort the leaves to make the tree deterministic
+	records = append([]*discover.Record{}, records...)
+	sort.Slice(records, func(i, j int) bool { return records[i].String() < records[j].String() })
+
+	enrEntries := make([]entry, len(records))
+	for i, r := range records {
+		if !r.Signed() {
+			return nil, errors.New("unsigned node record")
+		}
+		enrEntries[i] = &enrEntry{record: r}
+	}
+	linkEntries := make([]entry, len(links))
+	for i, l := range links {
+		le, err := parseLink(l)
+		if err != nil {
+			return nil, err
+		}
+		linkEntries[i] = le
+	}
+	// Build the subtrees and link them into the root
+	t := &Tree{entries: make(map[string]entry)}
+	eroot := t.build(enrEntries)
+	t.entries[subdomain(eroot)] = eroot
+	lroot := t.build(linkEntries)
+	t.entries[subdomain(lroot)] = lroot
+	t.root = &rootEntry{eroot: subdomain(eroot), lroot: subdomain(lroot), seq: seq}
+	return t, nil
+}
+
+// build creates the subtree over the given leaves, returning its root entry.
+func (t *Tree) build(entries []entry) entry {
+	if len(entries) == 1 {
+		return entries[0]
+	}
+	if len(entries) <= maxChildren {
+		branch := &branchEntry{children: make([]string, len(entries))}
+		for i, e := range entries {
+			branch.children[i] = subdomain(e)
+			t.entries[branch.children[i]] = e
+		}
+		return branch
+	}
+	var subtrees []entry
+	for len(entries) > 0 {
+		n := maxChildren
+		if len(entries) < n {
+			n = len(entries)
+		}
+		sub := t.build(entries[:n])
+		entries = entries[n:]
+		subtrees = append(subtrees, sub)
+		t.entries[subdomain(sub)] = sub
+	}
+	return t.build(subtrees)
+}
+
+// Sign signs the root of the tree with the given key, returning the URL clients
+// can resolve the tree with once published under the domain.
+func (t *Tree) Sign(key *ecdsa.PrivateKey, domain string) (url string, err error) {
+	sig, err := crypto.Sign(t.root.sigHash(), key)
+	if err != nil {
+		return "", err
+	}
+	t.root.sig = sig
+	return newLinkEntry(domain, &key.PublicKey).String(), nil
+}
+
+// Seq returns the sequence number of the tree.
+func (t *Tree) Seq() uint {
+	return t.root.seq
+}
+
+// Records returns all node records contained in the tree.
+func (t *Tree) Records() []*discover.Record {
+	var records []*discover.Record
+	for _, e := range t.entries {
+		if ee, ok := e.(*enrEntry); ok {
+			records = append(records, ee.record)
+		}
+	}
+	return records
+}
+
+// Nodes returns all nodes contained in the tree, skipping records which don't
+// describe a valid node.
+func (t *Tree) Nodes() []*discover.Node {
+	var nodes []*discover.Node
+	for _, r := range t.Records() {
+		if n, err := r.Node(); err == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Links returns the URLs of all other trees linked from the tree.
+func (t *Tree) Links() []string {
+	var links []string
+	for _, e := range t.entries {
+		if le, ok := e.(*linkEntry); ok {
+			links = append(links, le.str)
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// ToTXT returns the TXT records of the tree when published under the given
+// domain, keyed by their fully qualified name.
+func (t *Tree) ToTXT(domain string) map[string]string {
+	records := map[string]string{domain: t.root.String()}
+	for sub, e := range t.entries {
+		records[sub+"."+domain] = e.String()
+	}
+	return records
+}
+
+// subdomain returns the name of the entry in the tree: the base32 encoding of
+// the abbreviated keccak256 hash of its text.
+func subdomain(e entry) string {
+	hash := crypto.Keccak256([]byte(e.String()))
+	return b32format.EncodeToString(hash[:hashAbbrev])
+}
+
+func (e *rootEntry) sigHash() []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf(rootPrefix+" e=%s l=%s seq=%d", e.eroot, e.lroot, e.seq)))
+}
+
+func (e *rootEntry) verifySignature(pubkey *ecdsa.PublicKey) bool {
+	if len(e.sig) != 65 {
+		return false
+	}
+	signer, err := crypto.SigToPub(e.sigHash(), e.sig)
+	return err == nil && bytes.Equal(crypto.FromECDSAPub(signer), crypto.FromECDSAPub(pubkey))
+}
+
+func (e *rootEntry) String() string {
+	return fmt.Sprintf(rootPrefix+" e=%s l=%s seq=%d sig=%s", e.eroot, e.lroot, e.seq, b64format.EncodeToString(e.sig))
+}
+
+func (e *branchEntry) String() string {
+	return branchPrefix + strings.Join(e.children, ",")
+}
+
+func (e *enrEntry) String() string {
+	return e.record.String()
+}
+
+func (e *linkEntry) String() string {
+	return e.str
+}
+
+func newLinkEntry(domain string, pubkey *ecdsa.PublicKey) *linkEntry {
+	key := b32format.EncodeToString(crypto.CompressPubkey(pubkey))
+	return &linkEntry{str: linkPrefix + key + "@" + domain, domain: domain, pubkey: pubkey}
+}
+
+// parseEntry parses the text of a non-root tree entry.
+func parseEntry(text string) (entry, error) {
+	switch {
+	case strings.HasPrefix(text, linkPrefix):
+		return parseLink(text)
+	case strings.HasPrefix(text, branchPrefix):
+		return parseBranch(text)
+	case strings.HasPrefix(text, enrPrefix):
+		record, err := discover.ParseRecord(text)
+		if err != nil {
+			return nil, err
+		}
+		return &enrEntry{record: record}, nil
+	default:
+		return nil, errUnknownEntry
+	}
+}
+
+// parseRoot parses the text of a tree root entry.
+func parseRoot(text string) (*rootEntry, error) {
+	var (
+		e   rootEntry
+		sig string
+	)
+	if _, err := fmt.Sscanf(text, rootPrefix+" e=%s l=%s seq=%d sig=%s", &e.eroot, &e.lroot, &e.seq, &sig); err != nil {
+		return nil, fmt.Errorf("invalid tree root: %v", err)
+	}
+	if !isValidHash(e.eroot) || !isValidHash(e.lroot) {
+		return nil, errors.New("invalid subtree hash in tree root")
+	}
+	var err error
+	if e.sig, err = b64format.DecodeString(sig); err != nil || len(e.sig) != 65 {
+		return nil, errInvalidSig
+	}
+	return &e, nil
+}
+
+// parseBranch parses the text of a branch entry.
+func parseBranch(text string) (entry, error) {
+	text = text[len(branchPrefix):]
+	if text == "" {
+		return &branchEntry{}, nil
+	}
+	children := strings.Split(text, ",")
+	for _, c := range children {
+		if !isValidHash(c) {
+			return nil, fmt.Errorf("invalid child hash %q", c)
+		}
+	}
+	return &branchEntry{children: children}, nil
+}
+
+// parseLink parses a tree URL of the form enrtree://<key>@<domain>.
+func parseLink(text string) (*linkEntry, error) {
+	if !strings.HasPrefix(text, linkPrefix) {
+		return nil, errors.New("tree URL lacks the \"enrtree://\" scheme")
+	}
+	pos := strings.IndexByte(text, '@')
+	if pos == -1 {
+		return nil, errNoPubkey
+	}
+	keystring, domain := text[len(linkPrefix):pos], text[pos+1:]
+	if domain == "" {
+		return nil, errors.New("missing domain in tree URL")
+	}
+	keybytes, err := b32format.DecodeString(keystring)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in tree URL: %v", err)
+	}
+	pubkey, err := crypto.DecompressPubkey(keybytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in tree URL: %v", err)
+	}
+	return &linkEntry{str: text, domain: domain, pubkey: pubkey}, nil
+}
+
+// isValidHash reports whether a string is a well formed entry subdomain.
+func isValidHash(s string) bool {
+	blob, err := b32format.DecodeString(s)
+	return err == nil && len(blob) >= 12 && len(blob) <= 32 && !strings.Contains(s, "\n")
+}
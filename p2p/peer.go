@@ -109,6 +109,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// server tracks the reputation of the peer, nil for test peers
+	server *Server
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -155,6 +158,18 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// AdjustScore changes the reputation score of the peer by delta, using one of the
+// Score* adjustments. If the score drops too low, the peer is disconnected and
+// banned temporarily.
+func (p *Peer) AdjustScore(delta int64) {
+	if p.server == nil || p.server.reputation == nil {
+		return
+	}
+	if p.server.reputation.adjust(p.ID(), delta) {
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	return fmt.Sprintf("Peer %x %v", p.rw.id[:8], p.RemoteAddr())
@@ -263,6 +278,8 @@ func (p *Peer) readLoop(errc chan<- error) {
 
 func (p *Peer) handle(msg Msg) error {
 	switch {
+	case msg.Code < baseProtocolLength && msg.Size > baseProtocolMaxMsgSize:
+		return fmt.Errorf("base protocol message too large: %d > %d", msg.Size, baseProtocolMaxMsgSize)
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
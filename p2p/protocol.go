@@ -52,6 +52,12 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// DialFilter is an optional check of the node records of nodes found via
+	// discovery. If any protocol sets one, such nodes are only dialed if their
+	// record passes the filter of at least one protocol. Nodes not serving a
+	// record are dialed regardless.
+	DialFilter func(record *discover.Record) bool
 }
 
 func (p Protocol) cap() Cap {
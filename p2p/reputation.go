@@ -0,0 +1,166 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+// Score adjustments sub-protocols can report for their peers' behaviour. The
+// penalties stay above the ban threshold, so a peer without any history is only
+// banned on its second offence, not for a single bad message.
+const (
+	ScoreUsefulData        = 1   // Peer delivered data that was requested and accepted
+	ScoreTimeout           = -10 // Peer failed to answer a request in time
+	ScoreInvalidData       = -50 // Peer delivered invalid blocks, headers or state
+	ScoreProtocolViolation = -60 // Peer sent a malformed or unexpected message
+)
+
+const (
+	maxPeerScore = 1000  // Upper bound of a peer's score, limiting how much misbehaviour good history can absorb
+	minPeerScore = -1000 // Lower bound of a peer's score
+
+	peerBanThreshold = -100      // Score at or below which a peer gets banned
+	peerBanDuration  = time.Hour // Time a misbehaving peer is banned for
+)
+
+// reputationStore is the persistent backend of the peer reputations, implemented
+// by the node database of the discovery table.
+type reputationStore interface {
+	Reputation(id discover.NodeID) discover.NodeReputation
+	SetReputation(id discover.NodeID, rep discover.NodeReputation) error
+	Reputations() map[discover.NodeID]discover.NodeReputation
+}
+
+// memoryReputationStore is an in-memory reputation store, used when discovery
+// (and with it the node database) is disabled.
+type memoryReputationStore map[discover.NodeID]discover.NodeReputation
+
+func (s memoryReputationStore) Reputation(id discover.NodeID) discover.NodeReputation {
+	return s[id]
+}
+
+func (s memoryReputationStore) SetReputation(id discover.NodeID, rep discover.NodeReputation) error {
+	s[id] = rep
+	return nil
+}
+
+func (s memoryReputationStore) Reputations() map[discover.NodeID]discover.NodeReputation {
+	reps := make(map[discover.NodeID]discover.NodeReputation, len(s))
+	for id, rep := range s {
+		reps[id] = rep
+	}
+	return reps
+}
+
+// reputation tracks the scores of remote nodes based on the behaviour reported
+// by the sub-protocols, banning nodes whose score drops too low.
+type reputation struct {
+	store reputationStore
+	lock  sync.Mutex // Protects the read-modify-write cycles of the store
+}
+
+// newReputation creates a reputation tracker on top of a persistent store.
+func newReputation(store reputationStore) *reputation {
+	return &reputation{store: store}
+}
+
+// adjust changes the score of a node by delta, banning it if the score drops to
+// or below the ban threshold. Upon banning, the score is reset to half the
+// threshold, so the node gets a second chance once the ban expires, but not a
+// third one.
+func (r *reputation) adjust(id discover.NodeID, delta int64) (banned bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rep := r.store.Reputation(id)
+	rep.Score += delta
+	if rep.Score > maxPeerScore {
+		rep.Score = maxPeerScore
+	}
+	if rep.Score < minPeerScore {
+		rep.Score = minPeerScore
+	}
+	if rep.Score <= peerBanThreshold {
+		rep.Score = peerBanThreshold / 2
+		rep.Banned = time.Now().Add(peerBanDuration)
+		banned = true
+
+		log.Debug("Banning misbehaving node", "id", id, "until", rep.Banned)
+	}
+	if err := r.store.SetReputation(id, rep); err != nil {
+		log.Warn("Failed to store node reputation", "id", id, "err", err)
+	}
+	return banned
+}
+
+// score retrieves the current score of a node.
+func (r *reputation) score(id discover.NodeID) int64 {
+	return r.store.Reputation(id).Score
+}
+
+// banned reports whether a node is currently banned.
+func (r *reputation) banned(id discover.NodeID) bool {
+	return time.Now().Before(r.store.Reputation(id).Banned)
+}
+
+// sort orders a list of nodes by descending score, keeping the original order
+// of equally scored nodes.
+func (r *reputation) sort(nodes []*discover.Node) {
+	scores := make(map[discover.NodeID]int64, len(nodes))
+	for _, n := range nodes {
+		scores[n.ID] = r.score(n.ID)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i].ID] > scores[nodes[j].ID]
+	})
+}
+
+// PeerScore is the reputation of a remote node as reported by admin_peerScores.
+type PeerScore struct {
+	ID     string     `json:"id"`               // Unique node identifier
+	Score  int64      `json:"score"`            // Accumulated score of the node's past behaviour
+	Banned *time.Time `json:"banned,omitempty"` // Time until which the node is banned, if it is
+}
+
+// scores returns the reputation of all nodes known to the tracker, ordered by
+// descending score.
+func (r *reputation) scores() []*PeerScore {
+	now := time.Now()
+
+	var scores []*PeerScore
+	for id, rep := range r.store.Reputations() {
+		score := &PeerScore{ID: id.String(), Score: rep.Score}
+		if rep.Banned.After(now) {
+			banned := rep.Banned
+			score.Banned = &banned
+		}
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
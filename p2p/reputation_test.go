@@ -0,0 +1,113 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/wtc/go-wtc/p2p/discover"
+)
+
+// Tests that reported behaviour accumulates into a bounded score, and that
+// nodes are banned once their score drops to the threshold.
+func TestReputationBanning(t *testing.T) {
+	rep := newReputation(make(memoryReputationStore))
+	id := uintID(1)
+
+	// Useful data should raise the score, up to the cap
+	for i := 0; i < maxPeerScore+10; i++ {
+		if rep.adjust(id, ScoreUsefulData) {
+			t.Fatalf("node banned for useful data")
+		}
+	}
+	if score := rep.score(id); score != maxPeerScore {
+		t.Fatalf("score mismatch: have %d, want %d", score, maxPeerScore)
+	}
+	// Misbehaviour should be absorbed by the good history for a while
+	for i := 0; rep.score(id)+ScoreProtocolViolation > peerBanThreshold; i++ {
+		if rep.adjust(id, ScoreProtocolViolation) {
+			t.Fatalf("violation %d: node banned with score %d", i, rep.score(id))
+		}
+	}
+	if rep.banned(id) {
+		t.Fatalf("node banned prematurely")
+	}
+	// Dropping to the threshold should ban the node and reset its score
+	if !rep.adjust(id, ScoreProtocolViolation) {
+		t.Fatalf("node not banned at score %d", rep.score(id))
+	}
+	if !rep.banned(id) {
+		t.Fatalf("banned node not reported banned")
+	}
+	if score := rep.score(id); score != peerBanThreshold/2 {
+		t.Fatalf("banned score mismatch: have %d, want %d", score, peerBanThreshold/2)
+	}
+	// Other nodes must not be affected
+	if rep.banned(uintID(2)) {
+		t.Fatalf("unrelated node banned")
+	}
+	// A single offence of a node without history should not ban it, a second one should
+	if rep.adjust(uintID(3), ScoreProtocolViolation) {
+		t.Fatalf("fresh node banned for a single violation")
+	}
+	if !rep.adjust(uintID(3), ScoreProtocolViolation) {
+		t.Fatalf("fresh node not banned for a second violation, score %d", rep.score(uintID(3)))
+	}
+}
+
+// Tests that dial candidates are ordered by reputation and that banned nodes
+// are not dialed at all.
+func TestReputationDialing(t *testing.T) {
+	rep := newReputation(make(memoryReputationStore))
+	rep.adjust(uintID(2), ScoreUsefulData)
+	rep.adjust(uintID(3), 5*ScoreUsefulData)
+	rep.adjust(uintID(4), ScoreTimeout)
+	rep.adjust(uintID(5), ScoreProtocolViolation)
+	rep.adjust(uintID(5), ScoreProtocolViolation)
+
+	nodes := []*discover.Node{
+		{ID: uintID(1)}, {ID: uintID(2)}, {ID: uintID(3)}, {ID: uintID(4)}, {ID: uintID(5)}, {ID: uintID(6)},
+	}
+	rep.sort(nodes)
+
+	want := []discover.NodeID{uintID(3), uintID(2), uintID(1), uintID(6), uintID(4), uintID(5)}
+	for i, n := range nodes {
+		if n.ID != want[i] {
+			t.Errorf("position %d: node mismatch: have %x, want %x", i, n.ID[:4], want[i][:4])
+		}
+	}
+	dialer := newDialState(nil, nil, nil, 10, nil)
+	dialer.reputation = rep
+
+	if err := dialer.checkDial(&discover.Node{ID: uintID(4)}, nil); err != nil {
+		t.Errorf("penalised node not dialable: %v", err)
+	}
+	if err := dialer.checkDial(&discover.Node{ID: uintID(5)}, nil); err != errBanned {
+		t.Errorf("banned node dial error mismatch: have %v, want %v", err, errBanned)
+	}
+	// The reputation listing should be ordered best first, flagging bans
+	scores := rep.scores()
+	if len(scores) != 4 {
+		t.Fatalf("score count mismatch: have %d, want %d", len(scores), 4)
+	}
+	if scores[0].ID != uintID(3).String() || scores[0].Banned != nil {
+		t.Errorf("best score mismatch: have %+v", scores[0])
+	}
+	if scores[3].ID != uintID(5).String() || scores[3].Banned == nil {
+		t.Errorf("worst score mismatch: have %+v", scores[3])
+	}
+}
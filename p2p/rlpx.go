@@ -691,7 +691,9 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 	msg.Size = uint32(content.Len())
 	msg.Payload = content
 
-	// if snappy is enabled, verify and decompress message
+	// if snappy is enabled, verify the message and decompress it on demand.
+	// The reported size is the decompressed one, so protocols rejecting large
+	// messages do so before any memory is allocated for them.
 	if rw.snappy {
 		payload, err := ioutil.ReadAll(msg.Payload)
 		if err != nil {
@@ -704,13 +706,33 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 		if size > int(maxUint24) {
 			return msg, errPlainMessageTooLarge
 		}
-		payload, err = snappy.Decode(nil, payload)
+		msg.Size, msg.Payload = uint32(size), &snappyPayload{compressed: payload}
+	}
+	return msg, nil
+}
+
+// snappyPayload is the payload of a compressed message, decompressed on the
+// first read from it.
+type snappyPayload struct {
+	compressed []byte
+	plain      *bytes.Reader
+	err        error
+}
+
+func (p *snappyPayload) Read(b []byte) (int, error) {
+	if p.plain == nil && p.err == nil {
+		plain, err := snappy.Decode(nil, p.compressed)
 		if err != nil {
-			return msg, err
+			p.err = err
+		} else {
+			p.plain = bytes.NewReader(plain)
 		}
-		msg.Size, msg.Payload = uint32(size), bytes.NewReader(payload)
+		p.compressed = nil
 	}
-	return msg, nil
+	if p.err != nil {
+		return 0, p.err
+	}
+	return p.plain.Read(b)
 }
 
 // updateMAC reseeds the given hash with encrypted seed.
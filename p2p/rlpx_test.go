@@ -374,6 +374,61 @@ func TestRLPXFrameRW(t *testing.T) {
 	}
 }
 
+// Tests that compressed messages report their decompressed size, but are only
+// decompressed once their payload is read.
+func TestRLPXFrameRWSnappy(t *testing.T) {
+	var (
+		aesSecret = make([]byte, 16)
+		macSecret = make([]byte, 16)
+		macInit   = make([]byte, 32)
+	)
+	rand.Read(aesSecret)
+	rand.Read(macSecret)
+	rand.Read(macInit)
+
+	newRW := func(conn io.ReadWriter) *rlpxFrameRW {
+		s := secrets{AES: aesSecret, MAC: macSecret, EgressMAC: sha3.NewKeccak256(), IngressMAC: sha3.NewKeccak256()}
+		s.EgressMAC.Write(macInit)
+		s.IngressMAC.Write(macInit)
+		rw := newRLPXFrameRW(conn, s)
+		rw.snappy = true
+		return rw
+	}
+	conn := new(bytes.Buffer)
+	rw1, rw2 := newRW(conn), newRW(conn)
+
+	// A highly compressible message far above the usual protocol limits
+	plain := make([]byte, 12*1024*1024)
+	if err := rw1.WriteMsg(Msg{Code: 16, Size: uint32(len(plain)), Payload: bytes.NewReader(plain)}); err != nil {
+		t.Fatalf("failed to write message: %v", err)
+	}
+	if conn.Len() >= len(plain)/10 {
+		t.Errorf("message not compressed: %d bytes on the wire", conn.Len())
+	}
+	msg, err := rw2.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if msg.Size != uint32(len(plain)) {
+		t.Errorf("message size mismatch: have %d, want %d", msg.Size, len(plain))
+	}
+	if payload, ok := msg.Payload.(*snappyPayload); !ok || payload.plain != nil {
+		t.Fatalf("message decompressed before reading")
+	}
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+	if !bytes.Equal(payload, plain) {
+		t.Errorf("payload mismatch")
+	}
+	// Corrupt payloads are reported when reading them
+	corrupt := &snappyPayload{compressed: []byte{0x10, 0xff, 0xff}}
+	if _, err := ioutil.ReadAll(corrupt); err == nil {
+		t.Errorf("corrupt payload decompressed")
+	}
+}
+
 type handshakeAuthTest struct {
 	input       string
 	isPlain     bool
@@ -31,6 +31,7 @@ import (
 	"github.com/wtc/go-wtc/log"
 	"github.com/wtc/go-wtc/p2p/discover"
 	"github.com/wtc/go-wtc/p2p/discv5"
+	"github.com/wtc/go-wtc/p2p/dnsdisc"
 	"github.com/wtc/go-wtc/p2p/nat"
 	"github.com/wtc/go-wtc/p2p/netutil"
 )
@@ -94,6 +95,10 @@ type Config struct {
 	// protocol.
 	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
 
+	// DNSDiscovery contains enrtree:// URLs of DNS published node lists, which
+	// are resolved on startup and used as additional bootstrap nodes.
+	DNSDiscovery []string `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*discover.Node
@@ -155,6 +160,7 @@ type Server struct {
 	running bool
 
 	ntab         discoverTable
+	reputation   *reputation
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
@@ -167,6 +173,7 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	setmaxpeers   chan int
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
@@ -297,6 +304,29 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// SetMaxPeers changes the maximum number of peers of a running server. Peers
+// in excess of a lowered limit are kept, but no new ones are accepted until the
+// peer count drops below it.
+func (srv *Server) SetMaxPeers(n int) {
+	select {
+	case srv.setmaxpeers <- n:
+	case <-srv.quit:
+	}
+}
+
+// PeerScores returns the reputation of all remote nodes known to the server,
+// ordered by descending score.
+func (srv *Server) PeerScores() []*PeerScore {
+	srv.lock.Lock()
+	rep := srv.reputation
+	srv.lock.Unlock()
+
+	if rep == nil {
+		return nil
+	}
+	return rep.scores()
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -377,6 +407,7 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.setmaxpeers = make(chan int)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
@@ -390,6 +421,10 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 		srv.ntab = ntab
+
+		if len(srv.DNSDiscovery) > 0 {
+			go srv.resolveDNSBootnodes(ntab)
+		}
 	}
 
 	if srv.DiscoveryV5 {
@@ -403,11 +438,19 @@ func (srv *Server) Start() (err error) {
 		srv.DiscV5 = ntab
 	}
 
+	// peer reputations, persisted in the node database if discovery is running
+	if store, ok := srv.ntab.(reputationStore); ok {
+		srv.reputation = newReputation(store)
+	} else {
+		srv.reputation = newReputation(make(memoryReputationStore))
+	}
+
 	dynPeers := (srv.MaxPeers + 1) / 2
 	if srv.NoDiscovery {
 		dynPeers = 0
 	}
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.reputation = srv.reputation
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -430,6 +473,66 @@ func (srv *Server) Start() (err error) {
 	return nil
 }
 
+// SetRecordEntry adds or overwrites an entry of the local node record published
+// via discovery. It does nothing if discovery is not running.
+func (srv *Server) SetRecordEntry(key string, value interface{}) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if ntab, ok := srv.ntab.(*discover.Table); ok && srv.running {
+		return ntab.SetRecordEntry(key, value)
+	}
+	return nil
+}
+
+// checkDialFilters reports whether a node found via discovery passes the dial
+// filters of the protocols, retrieving its node record for the check.
+func (srv *Server) checkDialFilters(n *discover.Node) bool {
+	var filters []func(*discover.Record) bool
+	for _, proto := range srv.Protocols {
+		if proto.DialFilter != nil {
+			filters = append(filters, proto.DialFilter)
+		}
+	}
+	if len(filters) == 0 || srv.ntab == nil {
+		return true
+	}
+	record, err := srv.ntab.RequestENR(n)
+	if err != nil {
+		// Nodes predating node records can't be checked, leave it to the handshake
+		log.Trace("Failed to retrieve node record", "id", n.ID, "err", err)
+		return true
+	}
+	for _, filter := range filters {
+		if filter(record) {
+			return true
+		}
+	}
+	log.Trace("Skipping node rejected by dial filters", "id", n.ID)
+	return false
+}
+
+// resolveDNSBootnodes resolves the configured DNS node lists and adds the found
+// nodes to the fallback nodes of the discovery table.
+func (srv *Server) resolveDNSBootnodes(ntab *discover.Table) {
+	nodes, err := dnsdisc.NewClient(dnsdisc.Config{}).ResolveNodes(srv.DNSDiscovery...)
+	if err != nil {
+		log.Warn("Failed to resolve DNS node lists", "err", err)
+		return
+	}
+	fallback := append([]*discover.Node{}, srv.BootstrapNodes...)
+	for _, n := range nodes {
+		if n.Incomplete() {
+			continue
+		}
+		fallback = append(fallback, n)
+	}
+	log.Info("Resolved DNS node lists", "nodes", len(nodes))
+	if err := ntab.SetFallbackNodes(fallback); err != nil {
+		log.Warn("Failed to add DNS bootstrap nodes", "err", err)
+	}
+}
+
 func (srv *Server) startListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
@@ -457,6 +560,7 @@ type dialer interface {
 	taskDone(task, time.Time)
 	addStatic(*discover.Node)
 	removeStatic(*discover.Node)
+	setMaxDynDials(int)
 }
 
 func (srv *Server) run(dialstate dialer) {
@@ -528,6 +632,14 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case n := <-srv.setmaxpeers:
+			// This channel is used by SetMaxPeers to update the
+			// peer limit and the number of dynamic dials.
+			log.Debug("Updating peer limit", "maxpeers", n)
+			srv.MaxPeers = n
+			if !srv.NoDiscovery {
+				dialstate.setMaxDynDials((n + 1) / 2)
+			}
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -559,6 +671,7 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				p.server = srv
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -626,6 +739,8 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn)
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !c.is(trustedConn) && srv.reputation != nil && srv.reputation.banned(c.id):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -803,10 +918,11 @@ func (srv *Server) runPeer(p *Peer) {
 
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
-	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
-	Name  string `json:"name"`  // Name of the node, including client type, version, OS, custom data
-	Enode string `json:"enode"` // Enode URL for adding this peer from remote peers
-	IP    string `json:"ip"`    // IP address of the node
+	ID    string `json:"id"`            // Unique node identifier (also the encryption key)
+	Name  string `json:"name"`          // Name of the node, including client type, version, OS, custom data
+	Enode string `json:"enode"`         // Enode URL for adding this peer from remote peers
+	ENR   string `json:"enr,omitempty"` // Signed node record, if discovery is running
+	IP    string `json:"ip"`            // IP address of the node
 	Ports struct {
 		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
 		Listener  int `json:"listener"`  // TCP listening port for RLPx
@@ -831,6 +947,12 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
 
+	srv.lock.Lock()
+	if ntab, ok := srv.ntab.(*discover.Table); ok && srv.running {
+		info.ENR = ntab.Record().String()
+	}
+	srv.lock.Unlock()
+
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
 		if _, ok := info.Protocols[proto.Name]; !ok {
@@ -0,0 +1,49 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p/simulations"
+	"github.com/wtc/go-wtc/p2p/simulations/adapters"
+	"github.com/wtc/go-wtc/p2p/simulations/wtcsim"
+)
+
+var verbosity = flag.Int("verbosity", int(log.LvlInfo), "log level of the simulated nodes")
+
+// main() starts a simulation server able to run the wtc consensus scenarios,
+// each on a fresh network of in-memory wtc nodes. The scenarios override the
+// fork heights of the process, so it must not run a real node.
+func main() {
+	flag.Parse()
+
+	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(*verbosity), log.StreamHandler(os.Stderr, log.TerminalFormat(false))))
+
+	// the scenarios boot their own networks, the served one stays empty
+	network := simulations.NewNetwork(adapters.NewSimAdapter(nil), &simulations.NetworkConfig{})
+	server := simulations.NewServer(network)
+	wtcsim.RegisterAPI(server)
+
+	log.Info("starting simulation server on 0.0.0.0:8888...")
+	if err := http.ListenAndServe(":8888", server); err != nil {
+		log.Crit("error starting simulation server", "err", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcsim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/wtc/go-wtc/p2p/simulations"
+)
+
+// RegisterAPI adds the scenario endpoints to a simulation API server:
+//
+//	GET  /scenarios        lists the known scenarios
+//	POST /scenarios/:name  runs a scenario on a fresh network and returns its report
+//
+// The request body of a run may hold a Config overriding the fields of the
+// scenario's default configuration. Runs are refused while another one is in
+// progress.
+//
+// The scenarios change the fork heights of the whole process, so the API must
+// only be served by standalone simulation binaries, never next to a real node.
+func RegisterAPI(server *simulations.Server) {
+	server.GET("/scenarios", func(w http.ResponseWriter, req *http.Request) {
+		server.JSON(w, http.StatusOK, Scenarios)
+	})
+	for _, sc := range Scenarios {
+		server.POST("/scenarios/"+sc.Name, runHandler(server, sc))
+	}
+}
+
+// runHandler returns the HTTP handler running a scenario.
+func runHandler(server *simulations.Server, sc *Scenario) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		config := sc.Config
+		if config.Forks != nil {
+			forks := *config.Forks
+			config.Forks = &forks
+		}
+		if err := json.NewDecoder(req.Body).Decode(&config); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := sc.Run(&config)
+		if err == errRunning {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		server.JSON(w, http.StatusOK, report)
+	}
+}
+
+// RunRemote runs a scenario through the simulation API, with the given
+// configuration or the scenario's default one if nil.
+func RunRemote(client *simulations.Client, name string, config *Config) (*Report, error) {
+	var report Report
+	if err := client.Post(fmt.Sprintf("/scenarios/%s", name), config, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcsim
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/consensus/ethash"
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/params"
+)
+
+// weightBase is the reference target the miner weights are derived from.
+var weightBase = new(big.Int).Lsh(common.Big1, 200)
+
+// Mine runs the given number of mining rounds among the miners of the given
+// nodes, which need to be connected to each other. In every round each miner
+// draws the time to find a block on top of its head, scaled by its weight. The
+// first finder seals a block, as does every miner finding one within the
+// contention window after it. The round ends when all nodes received the blocks.
+func (s *Simulation) Mine(rounds int, nodes []*Node) error {
+	for i := 0; i < rounds; i++ {
+		if err := s.mineRound(nodes, s.config.Contention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Settle runs mining rounds without contention until all given nodes agree on
+// the head. Competing blocks have the same difficulty, so forks they leave are
+// only resolved by a block extending one of them.
+func (s *Simulation) Settle(nodes []*Node) error {
+	for !converged(nodes) {
+		if err := s.mineRound(nodes, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MineBy seals a single block by the given node on top of its head, waiting
+// until all given nodes received it.
+func (s *Simulation) MineBy(miner *Node, nodes []*Node) error {
+	block, err := s.mineBlock(miner, miner.Head())
+	if err != nil {
+		return err
+	}
+	return s.waitBlocks(nodes, []*types.Block{block})
+}
+
+// mineRound runs a single mining round among the miners of the given nodes,
+// letting all miners within the contention window seal a block.
+func (s *Simulation) mineRound(nodes []*Node, contention float64) error {
+	type draw struct {
+		miner  *Node
+		parent *types.Block
+		delay  float64
+	}
+	var draws []draw
+	for _, n := range nodes {
+		if n.Stake == nil {
+			continue
+		}
+		parent := n.Head()
+		weight, err := minerWeight(n.backend.BlockChain(), parent, n.Coinbase)
+		if err != nil {
+			return err
+		}
+		draws = append(draws, draw{n, parent, s.rand.ExpFloat64() / weight})
+	}
+	if len(draws) == 0 {
+		return errNoMiners
+	}
+	sort.Slice(draws, func(i, j int) bool { return draws[i].delay < draws[j].delay })
+
+	var blocks []*types.Block
+	for _, d := range draws {
+		if d.delay-draws[0].delay > contention {
+			break
+		}
+		block, err := s.mineBlock(d.miner, d.parent)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+	}
+	return s.waitBlocks(nodes, blocks)
+}
+
+// minerWeight returns the relative rate at which a miner finds blocks on top of
+// the given parent. It follows how the X11 target of the miner is scaled: by the
+// sixth root of its coin age before HardForkV2, and by its coinbase balance from
+// HardForkV2 on.
+func minerWeight(chain *core.BlockChain, parent *types.Block, coinbase common.Address) (float64, error) {
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return 0, err
+	}
+	if number := new(big.Int).Add(parent.Number(), common.Big1); number.Cmp(params.HardForkV2) >= 0 {
+		target := ethash.TargetDiff(statedb.GetBalance(coinbase), weightBase)
+		weight, _ := new(big.Float).Quo(new(big.Float).SetInt(target), new(big.Float).SetInt(weightBase)).Float64()
+		return weight, nil
+	}
+	// The target is only scaled by the coin age if its root is positive
+	coinage := statedb.GetCoinAge(coinbase, parent.Number(), new(big.Int).Add(parent.Time(), common.Big1))
+	if root := ethash.Sqrt(coinage, 6); root.Sign() > 0 {
+		weight, _ := new(big.Float).SetInt(root).Float64()
+		return weight, nil
+	}
+	return 1, nil
+}
+
+// mineBlock seals a block of the node's miner on top of the given parent with
+// the pending transactions of the node. The block is imported into the node's
+// chain and broadcast, like the miner does with the blocks it seals.
+func (s *Simulation) mineBlock(miner *Node, parent *types.Block) (*types.Block, error) {
+	var (
+		backend = miner.backend
+		chain   = backend.BlockChain()
+		config  = chain.Config()
+	)
+	tstamp := new(big.Int).Add(parent.Time(), common.Big1)
+	if tstamp.Int64() > time.Now().Unix() {
+		return nil, errOutOfTime
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent),
+		GasUsed:    new(big.Int),
+		Coinbase:   miner.Coinbase,
+		Time:       tstamp,
+	}
+	if err := backend.Engine().Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	// Include the pending transactions, skipping the senders of failing ones
+	pending, err := backend.TxPool().Pending()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		txs      = types.NewTransactionsByPriceAndNonce(types.NewEIP155Signer(config.ChainId), pending)
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		included types.Transactions
+		receipts []*types.Receipt
+	)
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+
+		snap := statedb.Snapshot()
+		receipt, _, err := core.ApplyTransaction(config, chain, &miner.Coinbase, gp, statedb, header, tx, header.GasUsed, vm.Config{})
+		if err != nil {
+			statedb.RevertToSnapshot(snap)
+			txs.Pop()
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+		txs.Shift()
+	}
+	block, err := backend.Engine().Finalize(chain, header, statedb, included, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	if block, err = backend.Engine().Seal(chain, block, nil, nil); err != nil {
+		return nil, err
+	}
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		return nil, fmt.Errorf("%s failed to import own block %d: %v", miner.Name, block.NumberU64(), err)
+	}
+	backend.EventMux().Post(core.NewMinedBlockEvent{Block: block})
+
+	s.lock.Lock()
+	s.mined = append(s.mined, block)
+	s.lock.Unlock()
+
+	return block, nil
+}
+
+// waitBlocks waits until all given nodes have the given blocks.
+func (s *Simulation) waitBlocks(nodes []*Node, blocks []*types.Block) error {
+	deadline := time.Now().Add(blockTimeout)
+	for _, n := range nodes {
+		for _, block := range blocks {
+			for !n.backend.BlockChain().HasBlock(block.Hash(), block.NumberU64()) {
+				if time.Now().After(deadline) {
+					return fmt.Errorf("block %d not received by %s", block.NumberU64(), n.Name)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+	return nil
+}
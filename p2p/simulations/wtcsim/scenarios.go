@@ -0,0 +1,375 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcsim
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/params"
+)
+
+// convergeTimeout is the time nodes have to agree on the head after a partition
+// heals. Nodes with few peers only sync on a timer, so this is fairly generous.
+const convergeTimeout = 30 * time.Second
+
+// Report is the outcome of a scenario.
+type Report struct {
+	Scenario        string         `json:"scenario"`
+	Nodes           int            `json:"nodes"`
+	Head            uint64         `json:"head"`            // Number of the head block all nodes converged on
+	Mined           int            `json:"mined"`           // Blocks mined during the scenario
+	Orphans         int            `json:"orphans"`         // Mined blocks not on the final canonical chain
+	OrphanRate      float64        `json:"orphanRate"`      // Ratio of orphans to mined blocks
+	ConvergenceTime time.Duration  `json:"convergenceTime"` // Time for all nodes to agree on the head after the last disruption
+	ReorgDepth      uint64         `json:"reorgDepth"`      // Number of canonical blocks dropped by the deepest reorg
+	MinedBy         map[string]int `json:"minedBy"`         // Canonical blocks per miner node
+
+	TxCount       int           `json:"txCount,omitempty"`       // Transactions sent during the scenario
+	TxPropagation time.Duration `json:"txPropagation,omitempty"` // Time for the transactions to reach all pools
+	TxInclusion   time.Duration `json:"txInclusion,omitempty"`   // Time for the transactions to be included on all nodes
+}
+
+// Scenario is a simulated sequence of network events.
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Config      Config `json:"config"` // Default network configuration
+
+	run func(s *Simulation, r *Report) error
+}
+
+// Scenarios are the scenarios known to the simulator.
+var Scenarios = []*Scenario{
+	{
+		Name:        "partition",
+		Description: "Splits the network in two halves mining separately, then heals the partition",
+		Config: Config{
+			Nodes:      6,
+			Stakes:     equalStakes(6, 1000),
+			Contention: 0.05,
+		},
+		run: runPartition,
+	},
+	{
+		Name:        "fork-reorg",
+		Description: "Partitions the network below HardForkV2 and heals it past HardForkV3, reorging the lighter side across both forks",
+		Config: Config{
+			Nodes:      6,
+			Stakes:     equalStakes(6, 1000),
+			Forks:      &Forks{V1: 3, V2: 6, V3: 9},
+			Contention: 0.05,
+		},
+		run: runForkReorg,
+	},
+	{
+		Name:        "competing-miners",
+		Description: "Lets miners of different stakes compete across HardForkV2, where the weighting moves from coin age to balance",
+		Config: Config{
+			Nodes:      5,
+			Stakes:     []*big.Int{wtc(0), wtc(100), wtc(5000), wtc(500000)},
+			Forks:      &Forks{V1: 1, V2: 10, V3: 1000},
+			Contention: 0.1,
+		},
+		run: runCompetingMiners,
+	},
+	{
+		Name:        "tx-propagation",
+		Description: "Sends transactions into a ring of nodes and measures how fast they spread and get mined",
+		Config: Config{
+			Nodes:  6,
+			Stakes: equalStakes(6, 1000),
+		},
+		run: runTxPropagation,
+	},
+}
+
+// Lookup returns the scenario with the given name, or nil if it doesn't exist.
+func Lookup(name string) *Scenario {
+	for _, sc := range Scenarios {
+		if sc.Name == name {
+			return sc
+		}
+	}
+	return nil
+}
+
+// Run boots a network with the given configuration, or the scenario's default
+// one if nil, and runs the scenario on it.
+func (sc *Scenario) Run(config *Config) (*Report, error) {
+	if config == nil {
+		config = &sc.Config
+	}
+	s, err := New(*config)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	r := &Report{Scenario: sc.Name, Nodes: len(s.nodes), MinedBy: make(map[string]int)}
+	if err := sc.run(s, r); err != nil {
+		return nil, fmt.Errorf("scenario %s: %v", sc.Name, err)
+	}
+	s.fillReport(r)
+	return r, nil
+}
+
+// fillReport sets the chain statistics of a report from the view of the first
+// node, which all nodes are expected to agree with.
+func (s *Simulation) fillReport(r *Report) {
+	chain := s.nodes[0].backend.BlockChain()
+	r.Head = chain.CurrentBlock().NumberU64()
+
+	miners := make(map[common.Address]string)
+	for _, n := range s.nodes {
+		miners[n.Coinbase] = n.Name
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, block := range s.mined {
+		if canon := chain.GetBlockByNumber(block.NumberU64()); canon == nil || canon.Hash() != block.Hash() {
+			r.Orphans++
+			continue
+		}
+		r.MinedBy[miners[block.Coinbase()]]++
+	}
+	r.Mined = len(s.mined)
+	if r.Mined > 0 {
+		r.OrphanRate = float64(r.Orphans) / float64(r.Mined)
+	}
+}
+
+// runPartition splits the network into two halves, lets the first one mine
+// twice as many blocks as the second and heals the partition.
+func runPartition(s *Simulation, r *Report) error {
+	nodes := s.Nodes()
+	if err := s.ConnectAll(); err != nil {
+		return err
+	}
+	if err := s.Mine(3, nodes); err != nil {
+		return err
+	}
+	heavy, light := nodes[:len(nodes)/2], nodes[len(nodes)/2:]
+	if err := s.Partition(heavy, light); err != nil {
+		return err
+	}
+	if err := s.Mine(6, heavy); err != nil {
+		return err
+	}
+	if err := s.Mine(3, light); err != nil {
+		return err
+	}
+	return s.heal(r, heavy, light)
+}
+
+// runForkReorg partitions the network below HardForkV2, lets both sides mine
+// past HardForkV3 and heals the partition, reorging the lighter side across the
+// forks. Mining continues afterwards to check the chain progresses.
+func runForkReorg(s *Simulation, r *Report) error {
+	if f := s.config.Forks; f == nil || f.V2 < 2 {
+		return errors.New("fork heights from 2 on needed")
+	}
+	nodes := s.Nodes()
+	if err := s.ConnectAll(); err != nil {
+		return err
+	}
+	before := params.HardForkV2.Uint64() - 2
+	if err := s.Mine(int(before), nodes); err != nil {
+		return err
+	}
+	heavy, light := nodes[:len(nodes)/2], nodes[len(nodes)/2:]
+	if err := s.Partition(heavy, light); err != nil {
+		return err
+	}
+	past := params.HardForkV3.Uint64() - before
+	if err := s.Mine(int(past)+4, heavy); err != nil {
+		return err
+	}
+	if err := s.Mine(int(past)+1, light); err != nil {
+		return err
+	}
+	if err := s.heal(r, heavy, light); err != nil {
+		return err
+	}
+	if err := s.Mine(2, nodes); err != nil {
+		return err
+	}
+	// Ensure all nodes ended up with the same fork blocks
+	for _, number := range []uint64{params.HardForkV2.Uint64(), params.HardForkV3.Uint64()} {
+		want := nodes[0].backend.BlockChain().GetBlockByNumber(number)
+		for _, n := range nodes[1:] {
+			if have := n.backend.BlockChain().GetBlockByNumber(number); have == nil || have.Hash() != want.Hash() {
+				return fmt.Errorf("%s has a different fork block %d", n.Name, number)
+			}
+		}
+	}
+	return nil
+}
+
+// runCompetingMiners lets all miners compete in a connected network, until they
+// settle on a head.
+func runCompetingMiners(s *Simulation, r *Report) error {
+	nodes := s.Nodes()
+	if err := s.ConnectAll(); err != nil {
+		return err
+	}
+	if err := s.Mine(40, nodes); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := s.Settle(nodes); err != nil {
+		return err
+	}
+	r.ConvergenceTime = time.Since(start)
+	return nil
+}
+
+// runTxPropagation sends transactions into one node of a ring and waits for
+// them to reach all pools and be mined.
+func runTxPropagation(s *Simulation, r *Report) error {
+	nodes := s.Nodes()
+	if err := s.ConnectRing(); err != nil {
+		return err
+	}
+	// Every node needs to import a block from the network to accept transactions
+	for _, n := range nodes {
+		if err := s.MineBy(n, nodes); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	hashes, err := s.SendTransactions(nodes[0], 20)
+	if err != nil {
+		return err
+	}
+	r.TxCount = len(hashes)
+	for _, n := range nodes {
+		for _, hash := range hashes {
+			for n.backend.TxPool().Get(hash) == nil {
+				if time.Since(start) > blockTimeout {
+					return fmt.Errorf("transaction %x not received by %s", hash[:4], n.Name)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+	r.TxPropagation = time.Since(start)
+
+	// Mine until all transactions are included on all nodes
+	for !included(nodes, hashes) {
+		if time.Since(start) > convergeTimeout {
+			return fmt.Errorf("transactions not included in %v", convergeTimeout)
+		}
+		if err := s.Mine(1, nodes); err != nil {
+			return err
+		}
+	}
+	r.TxInclusion = time.Since(start)
+	return nil
+}
+
+// heal heals a partition between a heavier and a lighter side, and records the
+// convergence time and the reorg depth of the lighter side.
+func (s *Simulation) heal(r *Report, heavy, light []*Node) error {
+	var dropped []*types.Block
+	for _, n := range light {
+		dropped = append(dropped, n.Head())
+	}
+	if err := s.Heal(); err != nil {
+		return err
+	}
+	converged, err := s.WaitConverged(append(heavy, light...), convergeTimeout)
+	if err != nil {
+		return err
+	}
+	r.ConvergenceTime = converged
+
+	db := heavy[0].backend.ChainDb()
+	for i, head := range dropped {
+		// Walk back from the dropped head to the first canonical ancestor
+		block := head
+		for block != nil && core.GetCanonicalHash(db, block.NumberU64()) != block.Hash() {
+			block = light[i].backend.BlockChain().GetBlock(block.ParentHash(), block.NumberU64()-1)
+		}
+		if block == nil {
+			return fmt.Errorf("no common ancestor with %s", light[i].Name)
+		}
+		if depth := head.NumberU64() - block.NumberU64(); depth > r.ReorgDepth {
+			r.ReorgDepth = depth
+		}
+	}
+	return nil
+}
+
+// SendTransactions submits the given number of value transfers from the faucet
+// account to the pool of a node, returning their hashes.
+func (s *Simulation) SendTransactions(n *Node, count int) ([]common.Hash, error) {
+	var (
+		pool   = n.backend.TxPool()
+		from   = crypto.PubkeyToAddress(s.faucet.PublicKey)
+		signer = types.NewEIP155Signer(s.genesis.Config.ChainId)
+		price  = new(big.Int).Mul(big.NewInt(18), big.NewInt(params.Shannon))
+		hashes []common.Hash
+	)
+	nonce := pool.State().GetNonce(from)
+	for i := 0; i < count; i++ {
+		to := s.nodes[i%len(s.nodes)].Coinbase
+		tx, err := types.SignTx(types.NewTransaction(nonce+uint64(i), to, big.NewInt(params.Ether), big.NewInt(21000), price, nil), signer, s.faucet)
+		if err != nil {
+			return nil, err
+		}
+		if err := pool.AddLocal(tx); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, tx.Hash())
+	}
+	return hashes, nil
+}
+
+// included reports whether the given transactions are in the canonical chain of
+// all nodes.
+func included(nodes []*Node, hashes []common.Hash) bool {
+	for _, n := range nodes {
+		for _, hash := range hashes {
+			if tx, _, _, _ := core.GetTransaction(n.backend.ChainDb(), hash); tx == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wtc converts an amount of WTC into wei.
+func wtc(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(params.Ether))
+}
+
+// equalStakes returns the stakes for a number of miners with the same balance.
+func equalStakes(miners int, amount int64) []*big.Int {
+	stakes := make([]*big.Int, miners)
+	for i := range stakes {
+		stakes[i] = wtc(amount)
+	}
+	return stakes
+}
@@ -0,0 +1,422 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package wtcsim simulates networks of full wtc nodes running in memory, to
+// regression test consensus changes against partitions, reorgs and competing
+// miners.
+//
+// The nodes run ethash in fake mode, so no proof-of-work is done. Instead the
+// simulation decides which miners find the next block, weighting them by the
+// coin age or balance their X11 target would be scaled with.
+//
+// The fork heights of a simulation replace the global ones in the params
+// package, so simulations run one at a time and must not share a process with
+// a real node.
+package wtcsim
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/consensus/ethash"
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/p2p/simulations"
+	"github.com/wtc/go-wtc/p2p/simulations/adapters"
+	"github.com/wtc/go-wtc/params"
+	"github.com/wtc/go-wtc/wtc"
+	"github.com/wtc/go-wtc/wtc/downloader"
+)
+
+const (
+	// genesisAge is how far the genesis timestamp lies in the past. Blocks are
+	// sealed one second after their parent, so this bounds the chain length.
+	genesisAge = 24 * time.Hour
+
+	peerTimeout  = 10 * time.Second // Time to wait for connections to be set up or torn down
+	blockTimeout = 10 * time.Second // Time to wait for mined blocks to reach all nodes
+)
+
+var (
+	errNoMiners    = errors.New("no miners among the nodes")
+	errOutOfTime   = errors.New("block time ahead of the clock, chain too long")
+	errInvalidFork = errors.New("fork heights must be positive and increasing")
+	errRunning     = errors.New("another simulation is running")
+)
+
+// The fork heights a simulation uses are process-wide, so only one simulation
+// may run at a time, and never next to a real node.
+var (
+	activeLock sync.Mutex
+	active     bool
+)
+
+// Forks are the heights of the WTC hard forks used during a simulation. They
+// replace the mainnet heights, so the fork rules are reachable in a few blocks.
+type Forks struct {
+	V1 uint64 `json:"v1"`
+	V2 uint64 `json:"v2"`
+	V3 uint64 `json:"v3"`
+}
+
+// apply installs the fork heights, returning a function restoring the previous
+// ones. The fixed difficulty required at HardForkV2 is set to the difficulty the
+// simulated chain has at that height, which is deterministic as blocks are sealed
+// one second after their parent.
+func (f *Forks) apply(genesis *types.Header, config *params.ChainConfig) (func(), error) {
+	if f.V1 == 0 || f.V1 >= f.V2 || f.V2 >= f.V3 {
+		return nil, errInvalidFork
+	}
+	v1, v2, v2diff, v3 := params.HardForkV1, params.HardForkV2, params.HardForkV2diff, params.HardForkV3
+
+	params.HardForkV1 = new(big.Int).SetUint64(f.V1)
+	params.HardForkV2 = new(big.Int).SetUint64(f.V2)
+	params.HardForkV3 = new(big.Int).SetUint64(f.V3)
+
+	parent := genesis
+	for parent.Number.Uint64() < f.V2 {
+		time := new(big.Int).Add(parent.Time, common.Big1)
+		parent = &types.Header{
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       time,
+			Difficulty: ethash.CalcDifficulty(config, time.Uint64(), parent),
+		}
+	}
+	params.HardForkV2diff = parent.Difficulty
+
+	return func() {
+		params.HardForkV1, params.HardForkV2, params.HardForkV2diff, params.HardForkV3 = v1, v2, v2diff, v3
+	}, nil
+}
+
+// Config are the settings of a simulated network.
+type Config struct {
+	Nodes  int        `json:"nodes"`  // Number of wtc nodes to boot
+	Stakes []*big.Int `json:"stakes"` // Genesis balance of the miner of each node (nil or missing = not mining)
+	Forks  *Forks     `json:"forks"`  // Fork heights to simulate (nil = mainnet heights)
+
+	// Contention is the head start, as a fraction of the mean block time, the
+	// first miner of a round needs to prevent others from finding a competing
+	// block before its own one propagates.
+	Contention float64 `json:"contention"`
+
+	Seed int64 `json:"seed"` // Seed of the random source picking the miners
+}
+
+// Node is a wtc node of a simulated network.
+type Node struct {
+	ID       discover.NodeID
+	Name     string
+	Coinbase common.Address // Address the node mines to
+	Stake    *big.Int       // Genesis balance of the coinbase, nil if the node doesn't mine
+
+	backend *eth.Wtc
+}
+
+// Backend returns the wtc service of the node, nil if it isn't running.
+func (n *Node) Backend() *eth.Wtc {
+	return n.backend
+}
+
+// Head returns the current head block of the node.
+func (n *Node) Head() *types.Block {
+	return n.backend.BlockChain().CurrentBlock()
+}
+
+// Simulation is a network of in-memory wtc nodes, along with the blocks mined
+// in it.
+type Simulation struct {
+	config  Config
+	adapter *adapters.SimAdapter
+	network *simulations.Network
+	genesis *core.Genesis
+	faucet  *ecdsa.PrivateKey
+	restore func()
+
+	nodes []*Node
+	byID  map[discover.NodeID]*Node
+	links map[[2]int]bool // Connections of the topology, true if cut by a partition
+	mined []*types.Block  // Blocks mined during the simulation
+	rand  *rand.Rand
+	lock  sync.Mutex
+}
+
+// New boots a simulated network of wtc nodes, which are not yet connected to
+// each other. Only one simulation may exist at a time, New fails until the
+// previous one is closed.
+func New(config Config) (*Simulation, error) {
+	faucet, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulation{
+		config: config,
+		faucet: faucet,
+		byID:   make(map[discover.NodeID]*Node),
+		links:  make(map[[2]int]bool),
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		genesis: &core.Genesis{
+			Config:     params.AllProtocolChanges,
+			Timestamp:  uint64(time.Now().Add(-genesisAge).Unix()),
+			GasLimit:   params.GenesisGasLimit.Uint64(),
+			Difficulty: params.GenesisDifficulty,
+			Alloc: core.GenesisAlloc{
+				crypto.PubkeyToAddress(faucet.PublicKey): {Balance: new(big.Int).Mul(big.NewInt(1e9), big.NewInt(params.Ether))},
+			},
+		},
+	}
+	// Create the nodes, funding the miners in the genesis
+	for i := 0; i < config.Nodes; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		n := &Node{Name: fmt.Sprintf("node%02d", i+1), Coinbase: crypto.PubkeyToAddress(key.PublicKey)}
+		if i < len(config.Stakes) && config.Stakes[i] != nil {
+			n.Stake = config.Stakes[i]
+			s.genesis.Alloc[n.Coinbase] = core.GenesisAccount{Balance: n.Stake}
+		}
+		s.nodes = append(s.nodes, n)
+	}
+	// Install the fork heights and start the nodes
+	activeLock.Lock()
+	defer activeLock.Unlock()
+	if active {
+		return nil, errRunning
+	}
+	s.restore = func() {}
+	if config.Forks != nil {
+		genesis, _ := s.genesis.ToBlock()
+		restore, err := config.Forks.apply(genesis.Header(), s.genesis.Config)
+		if err != nil {
+			return nil, err
+		}
+		s.restore = restore
+	}
+	active = true
+
+	s.adapter = adapters.NewSimAdapter(adapters.Services{"wtc": s.newService})
+	s.network = simulations.NewNetwork(s.adapter, &simulations.NetworkConfig{ID: "wtc", DefaultService: "wtc"})
+
+	for _, n := range s.nodes {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = n.Name
+		n.ID = conf.ID
+		s.byID[n.ID] = n
+
+		if _, err := s.network.NewNodeWithConfig(conf); err != nil {
+			s.close()
+			return nil, err
+		}
+		if err := s.network.Start(n.ID); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// newService creates the wtc service of a simulated node.
+func (s *Simulation) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	s.lock.Lock()
+	n := s.byID[ctx.Config.ID]
+	s.lock.Unlock()
+	if n == nil {
+		return nil, fmt.Errorf("unknown node %s", ctx.Config.ID)
+	}
+	config := eth.DefaultConfig
+	config.Genesis = s.genesis
+	config.NetworkId = s.genesis.Config.ChainId.Uint64()
+	config.SyncMode = downloader.FullSync
+	config.PowFake = true
+	config.Etherbase = n.Coinbase
+	config.TxPool.Journal = ""
+
+	backend, err := eth.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	n.backend = backend
+	s.lock.Unlock()
+	return backend, nil
+}
+
+// Close stops all nodes and restores the fork heights.
+func (s *Simulation) Close() {
+	activeLock.Lock()
+	defer activeLock.Unlock()
+
+	s.close()
+}
+
+// close stops all nodes and restores the fork heights, with activeLock held.
+func (s *Simulation) close() {
+	s.network.Shutdown()
+	s.restore()
+	active = false
+}
+
+// Network returns the underlying simulation network, which can be served by the
+// simulation HTTP API.
+func (s *Simulation) Network() *simulations.Network {
+	return s.network
+}
+
+// Nodes returns all nodes of the simulation.
+func (s *Simulation) Nodes() []*Node {
+	return s.nodes
+}
+
+// Connect connects the given pairs of nodes, waiting until they completed the
+// wtc handshake.
+func (s *Simulation) Connect(pairs ...[2]int) error {
+	for _, pair := range pairs {
+		if err := s.network.Connect(s.nodes[pair[0]].ID, s.nodes[pair[1]].ID); err != nil {
+			return err
+		}
+		s.links[pair] = false
+	}
+	return s.waitLinks(pairs, true)
+}
+
+// ConnectAll connects every node to every other one.
+func (s *Simulation) ConnectAll() error {
+	var pairs [][2]int
+	for i := range s.nodes {
+		for j := i + 1; j < len(s.nodes); j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	return s.Connect(pairs...)
+}
+
+// ConnectRing connects every node to its successor, and the last to the first.
+func (s *Simulation) ConnectRing() error {
+	var pairs [][2]int
+	for i := range s.nodes {
+		if j := (i + 1) % len(s.nodes); j != i {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	return s.Connect(pairs...)
+}
+
+// Partition splits the network by cutting all connections between nodes of
+// different groups. Nodes not in any group keep their connections.
+func (s *Simulation) Partition(groups ...[]*Node) error {
+	group := make(map[*Node]int)
+	for i, nodes := range groups {
+		for _, n := range nodes {
+			group[n] = i + 1
+		}
+	}
+	var cut [][2]int
+	for pair, down := range s.links {
+		one, other := s.nodes[pair[0]], s.nodes[pair[1]]
+		if down || group[one] == 0 || group[other] == 0 || group[one] == group[other] {
+			continue
+		}
+		if err := s.network.Disconnect(one.ID, other.ID); err != nil {
+			return err
+		}
+		s.links[pair] = true
+		cut = append(cut, pair)
+	}
+	return s.waitLinks(cut, false)
+}
+
+// Heal restores all connections cut by partitions.
+func (s *Simulation) Heal() error {
+	var pairs [][2]int
+	for pair, down := range s.links {
+		if down {
+			pairs = append(pairs, pair)
+		}
+	}
+	return s.Connect(pairs...)
+}
+
+// waitLinks waits until the wtc protocol runs between the given node pairs, or
+// until they are disconnected.
+func (s *Simulation) waitLinks(pairs [][2]int, up bool) error {
+	deadline := time.Now().Add(peerTimeout)
+	for _, pair := range pairs {
+		one, other := s.nodes[pair[0]], s.nodes[pair[1]]
+		for s.linked(one, other) != up || s.linked(other, one) != up {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timeout waiting for %s and %s to connect (%v)", one.Name, other.Name, up)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// linked reports whether a node finished the wtc handshake with a peer.
+func (s *Simulation) linked(n, peer *Node) bool {
+	simNode, ok := s.adapter.GetNode(n.ID)
+	if !ok || simNode.Server() == nil {
+		return false
+	}
+	for _, info := range simNode.Server().PeersInfo() {
+		if info.ID != peer.ID.String() {
+			continue
+		}
+		// The protocol info is a status string until the handshake completes
+		_, pending := info.Protocols["eth"].(string)
+		return info.Protocols["eth"] != nil && !pending
+	}
+	return false
+}
+
+// WaitConverged waits until all given nodes agree on the head block, returning
+// the time it took.
+func (s *Simulation) WaitConverged(nodes []*Node, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	for {
+		if converged(nodes) {
+			return time.Since(start), nil
+		}
+		if time.Since(start) > timeout {
+			heads := make(map[string]uint64)
+			for _, n := range nodes {
+				heads[n.Name] = n.Head().NumberU64()
+			}
+			return 0, fmt.Errorf("nodes didn't converge in %v, heads %v", timeout, heads)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// converged reports whether all nodes have the same head block.
+func converged(nodes []*Node) bool {
+	for _, n := range nodes[1:] {
+		if n.Head().Hash() != nodes[0].Head().Hash() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcsim
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wtc/go-wtc/p2p/simulations"
+	"github.com/wtc/go-wtc/p2p/simulations/adapters"
+	"github.com/wtc/go-wtc/params"
+)
+
+// runScenario runs a scenario with its default configuration.
+func runScenario(t *testing.T, name string) *Report {
+	if testing.Short() {
+		t.Skip("skipping network simulation in short mode")
+	}
+	report, err := Lookup(name).Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", report)
+	return report
+}
+
+// Tests that a healed partition converges on the heavier side, orphaning the
+// blocks of the lighter one.
+func TestPartition(t *testing.T) {
+	report := runScenario(t, "partition")
+	if report.Head != 9 {
+		t.Errorf("head mismatch: have %d, want %d", report.Head, 9)
+	}
+	if report.ReorgDepth != 3 {
+		t.Errorf("reorg depth mismatch: have %d, want %d", report.ReorgDepth, 3)
+	}
+	if report.Orphans < 3 {
+		t.Errorf("too few orphans: have %d, want at least %d", report.Orphans, 3)
+	}
+}
+
+// Tests that a partition healed past both forks converges on the heavier side,
+// and that the chain keeps progressing under the fork rules afterwards.
+func TestForkReorg(t *testing.T) {
+	report := runScenario(t, "fork-reorg")
+	if report.Head != 15 {
+		t.Errorf("head mismatch: have %d, want %d", report.Head, 15)
+	}
+	if report.ReorgDepth != 6 {
+		t.Errorf("reorg depth mismatch: have %d, want %d", report.ReorgDepth, 6)
+	}
+	if report.Orphans < 6 {
+		t.Errorf("too few orphans: have %d, want at least %d", report.Orphans, 6)
+	}
+}
+
+// Tests that miners with larger stakes win more blocks.
+func TestCompetingMiners(t *testing.T) {
+	report := runScenario(t, "competing-miners")
+	if report.Head < 40 {
+		t.Errorf("head too low: have %d, want at least %d", report.Head, 40)
+	}
+	if poor, rich := report.MinedBy["node01"], report.MinedBy["node04"]; rich <= poor {
+		t.Errorf("richest miner didn't win more blocks: have %d, poorest miner %d", rich, poor)
+	}
+	if report.MinedBy["node05"] != 0 {
+		t.Errorf("node without stake mined %d blocks", report.MinedBy["node05"])
+	}
+}
+
+// Tests that transactions spread through a ring of nodes and get mined.
+func TestTxPropagation(t *testing.T) {
+	report := runScenario(t, "tx-propagation")
+	if report.TxCount != 20 {
+		t.Errorf("transaction count mismatch: have %d, want %d", report.TxCount, 20)
+	}
+	if report.TxPropagation <= 0 || report.TxInclusion < report.TxPropagation {
+		t.Errorf("invalid transaction timings: propagation %v, inclusion %v", report.TxPropagation, report.TxInclusion)
+	}
+}
+
+// Tests that scenarios can be listed and run through the simulation API, with
+// the configuration overridden by the request.
+func TestHTTPAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping network simulation in short mode")
+	}
+	server := simulations.NewServer(simulations.NewNetwork(adapters.NewSimAdapter(nil), &simulations.NetworkConfig{}))
+	RegisterAPI(server)
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	client := simulations.NewClient(s.URL)
+	var scenarios []*Scenario
+	if err := client.Get("/scenarios", &scenarios); err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != len(Scenarios) {
+		t.Fatalf("scenario count mismatch: have %d, want %d", len(scenarios), len(Scenarios))
+	}
+	config := Lookup("partition").Config
+	config.Nodes = 4
+	config.Stakes = equalStakes(4, 1000)
+
+	report, err := RunRemote(client, "partition", &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Nodes != 4 || report.Head != 9 {
+		t.Errorf("report mismatch: have %d nodes at head %d, want %d at %d", report.Nodes, report.Head, 4, 9)
+	}
+	if _, err := RunRemote(client, "unknown", nil); err == nil {
+		t.Error("unknown scenario run")
+	}
+}
+
+// Tests that simulations are refused while another one is running, as the fork
+// heights they install are global.
+func TestConcurrentSimulations(t *testing.T) {
+	forks := &Forks{V1: 2, V2: 4, V3: 6}
+	s, err := New(Config{Forks: forks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Config{}); err != errRunning {
+		t.Fatalf("concurrent simulation error mismatch: have %v, want %v", err, errRunning)
+	}
+	if params.HardForkV2.Uint64() != forks.V2 {
+		t.Errorf("fork heights changed by refused simulation: V2 at %v", params.HardForkV2)
+	}
+	s.Close()
+
+	if s, err = New(Config{}); err != nil {
+		t.Fatalf("simulation refused after close: %v", err)
+	}
+	s.Close()
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/binary"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/crypto/sha3"
+)
+
+// The CHT roots below are the ones the light chain used to hard code. No section
+// heads are published for them, so full nodes don't challenge peers or skip seal
+// checks until real checkpoints are filled in here. No checkpoint oracle is
+// deployed either, so gwtc doesn't run the checkpoint service.
+var (
+	// MainnetTrustedCheckpoint contains the trusted checkpoint of the main network.
+	MainnetTrustedCheckpoint = &TrustedCheckpoint{
+		SectionIndex: 1039,
+		CHTRoot:      common.HexToHash("0xbb4fb4076cbe6923c8a8ce8f158452bbe19564959313466989fda095a60884ca"),
+	}
+
+	// TestnetTrustedCheckpoint contains the trusted checkpoint of the test network.
+	TestnetTrustedCheckpoint = &TrustedCheckpoint{
+		SectionIndex: 399,
+		CHTRoot:      common.HexToHash("0x2a4befa19e4675d939c3dc22dca8c6ae9fcd642be1f04b06bd6e4203cc304660"),
+	}
+
+	// TrustedCheckpoints associates each known checkpoint with the genesis hash of
+	// the chain it belongs to.
+	TrustedCheckpoints = map[common.Hash]*TrustedCheckpoint{
+		MainnetGenesisHash: MainnetTrustedCheckpoint,
+		TestnetGenesisHash: TestnetTrustedCheckpoint,
+	}
+
+	// CheckpointOracles associates each known checkpoint registrar contract with
+	// the genesis hash of the chain it belongs to.
+	CheckpointOracles = map[common.Hash]*CheckpointOracleConfig{}
+)
+
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and bloom
+// trie) associated with the appropriate section index and head hash.
+//
+// Light clients use it to start syncing from the checkpoint instead of the
+// genesis, while full nodes use the section head to reject chains forking off
+// before it and to skip the seal checks of the ancient blocks leading up to it.
+// An empty section head disables the latter, leaving only the CHT in use.
+type TrustedCheckpoint struct {
+	SectionIndex uint64      `json:"sectionIndex"`
+	SectionHead  common.Hash `json:"sectionHead"`
+	CHTRoot      common.Hash `json:"chtRoot"`
+	BloomRoot    common.Hash `json:"bloomRoot"`
+}
+
+// HeadNumber returns the number of the last block covered by the checkpoint.
+func (c *TrustedCheckpoint) HeadNumber() uint64 {
+	return (c.SectionIndex+1)*CHTFrequency - 1
+}
+
+// Hash returns the hash of the checkpoint's four key fields (index, section head,
+// CHT root and bloom trie root), the value signed by the registrar's signers.
+func (c *TrustedCheckpoint) Hash() common.Hash {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], c.SectionIndex)
+
+	hasher := sha3.NewKeccak256()
+	hasher.Write(index[:])
+	hasher.Write(c.SectionHead[:])
+	hasher.Write(c.CHTRoot[:])
+	hasher.Write(c.BloomRoot[:])
+
+	var hash common.Hash
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// CheckpointOracleConfig represents a set of checkpoint registrar contract
+// configs, consisting of the contract address, the list of accounts allowed to
+// sign checkpoints and the number of signatures needed to accept one.
+type CheckpointOracleConfig struct {
+	Address   common.Address   `json:"address"`
+	Signers   []common.Address `json:"signers"`
+	Threshold uint64           `json:"threshold"`
+}
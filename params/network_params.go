@@ -23,4 +23,8 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// CHTFrequency is the number of blocks a single canonical hash trie section
+	// covers, also the granularity of the trusted checkpoints.
+	CHTFrequency uint64 = 4096
 )
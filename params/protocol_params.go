@@ -69,6 +69,9 @@ const (
 	Bn256ScalarMulGas       uint64 = 10000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 25000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 20000  // Per-point price for an elliptic curve pairing check
+	X11BaseGas              uint64 = 600    // Base price for an X11 hashing operation
+	X11PerWordGas           uint64 = 120    // Per-word price for an X11 hashing operation
+	X11SealVerifyGas        uint64 = 20000  // Gas needed to verify an X11 proof-of-work block seal
 )
 
 var (
@@ -0,0 +1,277 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/wtc/go-wtc/log"
+)
+
+var (
+	// ErrAuthDisabled is returned when credentials are presented to a server
+	// without authentication configured.
+	ErrAuthDisabled = errors.New("authentication disabled")
+
+	// ErrInvalidCredentials is returned for unknown, expired or forged tokens.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// AuthToken is a static bearer token and the RPC calls it grants access to.
+type AuthToken struct {
+	Name  string   // Name of the token holder, reported in the audit log
+	Token string   // Secret presented by the holder
+	Allow []string // Permitted namespaces ("eth"), methods ("eth_getBalance") or "*" for all
+}
+
+// AuthConfig configures the authentication of RPC clients. Clients present a
+// static bearer token or an HMAC signed JSON web token, either in the HTTP
+// Authorization header ("Bearer <token>") of HTTP requests and websocket
+// handshakes, or in-band through rpc_authenticate. Calls not permitted to the
+// client are rejected and logged.
+type AuthConfig struct {
+	// Tokens are the accepted static bearer tokens.
+	Tokens []AuthToken `toml:",omitempty"`
+
+	// JWTSecret is the HMAC secret of accepted JSON web tokens. The tokens need
+	// to be signed with HS256, expire ("exp") and list the permitted calls in an
+	// "allow" claim. The "sub" claim names the holder.
+	JWTSecret string `toml:",omitempty"`
+
+	// Anonymous lists the calls permitted to clients without credentials.
+	Anonymous []string `toml:",omitempty"`
+
+	// RestrictIPC subjects IPC clients to authentication too, instead of granting
+	// them access to all calls.
+	RestrictIPC bool `toml:",omitempty"`
+}
+
+// grant is the set of calls permitted to a client.
+type grant struct {
+	name    string
+	all     bool
+	modules map[string]bool
+	methods map[string]bool
+}
+
+// newGrant creates a grant from a list of namespaces and methods.
+func newGrant(name string, allow []string) *grant {
+	g := &grant{name: name, modules: make(map[string]bool), methods: make(map[string]bool)}
+	for _, entry := range allow {
+		switch {
+		case entry == "*":
+			g.all = true
+		case strings.Contains(entry, serviceMethodSeparator):
+			g.methods[entry] = true
+		default:
+			g.modules[entry] = true
+		}
+	}
+	return g
+}
+
+// permits reports whether the grant allows calling the given method.
+func (g *grant) permits(method string) bool {
+	if g.all || g.methods[method] {
+		return true
+	}
+	module := strings.SplitN(method, serviceMethodSeparator, 2)[0]
+	return g.modules[module]
+}
+
+// authenticator verifies client credentials against an auth configuration.
+type authenticator struct {
+	tokens      []AuthToken
+	grants      []*grant // Grants of the static tokens, by index
+	secret      []byte
+	anonymous   *grant
+	restrictIPC bool
+}
+
+func newAuthenticator(config *AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		tokens:      config.Tokens,
+		anonymous:   newGrant("anonymous", config.Anonymous),
+		restrictIPC: config.RestrictIPC,
+	}
+	for i, token := range config.Tokens {
+		if token.Token == "" {
+			return nil, fmt.Errorf("empty token #%d (%s)", i, token.Name)
+		}
+		name := token.Name
+		if name == "" {
+			name = fmt.Sprintf("token #%d", i)
+		}
+		a.grants = append(a.grants, newGrant(name, token.Allow))
+	}
+	if config.JWTSecret != "" {
+		a.secret = []byte(config.JWTSecret)
+	}
+	return a, nil
+}
+
+// authenticate returns the grant of the given static or JSON web token.
+func (a *authenticator) authenticate(token string) (*grant, error) {
+	var match *grant
+	for i, t := range a.tokens {
+		// Compare all tokens in constant time, not leaking which one matched
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			match = a.grants[i]
+		}
+	}
+	if match != nil {
+		return match, nil
+	}
+	if a.secret == nil || strings.Count(token, ".") != 2 {
+		return nil, ErrInvalidCredentials
+	}
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidCredentials
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !claims.VerifyExpiresAt(0, true) {
+		return nil, ErrInvalidCredentials
+	}
+	name, _ := claims["sub"].(string)
+	if name == "" {
+		name = "jwt"
+	}
+	list, _ := claims["allow"].([]interface{})
+	allow := make([]string, 0, len(list))
+	for _, entry := range list {
+		if s, ok := entry.(string); ok {
+			allow = append(allow, s)
+		}
+	}
+	return newGrant(name, allow), nil
+}
+
+// authSession holds the credentials of a client connection.
+type authSession struct {
+	remote string
+
+	lock  sync.Mutex
+	grant *grant
+}
+
+func (s *authSession) current() *grant {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.grant
+}
+
+type authSessionKey struct{}
+
+// SetAuth enables the authentication of the clients of the server. Clients are
+// anonymous until they present credentials. Calling it with a nil config
+// disables authentication. It needs to be called before serving any clients.
+func (s *Server) SetAuth(config *AuthConfig) error {
+	if config == nil {
+		s.auth = nil
+		return nil
+	}
+	auth, err := newAuthenticator(config)
+	if err != nil {
+		return err
+	}
+	s.auth = auth
+	return nil
+}
+
+// authContext returns a context carrying the session of a new connection.
+// Local connections are granted full access unless IPC is restricted. A
+// non-empty Authorization header value needs to hold valid credentials.
+func (s *Server) authContext(ctx context.Context, remote, authorization string, local bool) (context.Context, error) {
+	if s.auth == nil {
+		return ctx, nil
+	}
+	session := &authSession{remote: remote, grant: s.auth.anonymous}
+	if local && !s.auth.restrictIPC {
+		session.grant = newGrant("local", []string{"*"})
+	}
+	if authorization != "" {
+		const prefix = "Bearer "
+		if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+			log.Warn("Rejected RPC client", "remote", remote, "err", "malformed authorization header")
+			return nil, ErrInvalidCredentials
+		}
+		g, err := s.auth.authenticate(strings.TrimSpace(authorization[len(prefix):]))
+		if err != nil {
+			log.Warn("Rejected RPC client", "remote", remote, "err", err)
+			return nil, err
+		}
+		session.grant = g
+	}
+	return context.WithValue(ctx, authSessionKey{}, session), nil
+}
+
+// httpAuthContext authenticates an HTTP request or websocket handshake.
+func (s *Server) httpAuthContext(ctx context.Context, r *http.Request) (context.Context, error) {
+	return s.authContext(ctx, r.RemoteAddr, r.Header.Get("Authorization"), false)
+}
+
+// authorize checks whether the client of the connection may call a method,
+// writing denied calls to the audit log.
+func (s *Server) authorize(ctx context.Context, method string) Error {
+	if s.auth == nil || method == MetadataApi+serviceMethodSeparator+"modules" || method == MetadataApi+serviceMethodSeparator+"authenticate" {
+		return nil
+	}
+	g, remote := s.auth.anonymous, "unknown"
+	if session, ok := ctx.Value(authSessionKey{}).(*authSession); ok {
+		g, remote = session.current(), session.remote
+	}
+	if g.permits(method) {
+		return nil
+	}
+	log.Warn("Denied RPC call", "client", g.name, "remote", remote, "method", method)
+	return &unauthorizedError{method}
+}
+
+// Authenticate presents credentials for the current connection, replacing the
+// permissions of the client with the ones granted to the token. It returns the
+// name of the token holder.
+func (s *RPCService) Authenticate(ctx context.Context, token string) (string, error) {
+	if s.server.auth == nil {
+		return "", ErrAuthDisabled
+	}
+	session, ok := ctx.Value(authSessionKey{}).(*authSession)
+	if !ok {
+		return "", ErrAuthDisabled
+	}
+	g, err := s.server.auth.authenticate(token)
+	if err != nil {
+		log.Warn("Rejected RPC credentials", "remote", session.remote, "err", err)
+		return "", err
+	}
+	session.lock.Lock()
+	session.grant = g
+	session.lock.Unlock()
+	return g.name, nil
+}
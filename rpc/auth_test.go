@@ -0,0 +1,198 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var testAuthConfig = &AuthConfig{
+	Tokens: []AuthToken{
+		{Name: "partner", Token: "partner-secret", Allow: []string{"test_echo"}},
+		{Name: "operator", Token: "operator-secret", Allow: []string{"*"}},
+	},
+	JWTSecret: "jwt-secret",
+	Anonymous: []string{"test_rets"},
+}
+
+func newAuthTestServer(t *testing.T, config *AuthConfig) *Server {
+	server := newTestServer("test", new(Service))
+	if err := server.SetAuth(config); err != nil {
+		t.Fatalf("failed to set auth config: %v", err)
+	}
+	return server
+}
+
+// postRPC sends a raw JSON-RPC request over HTTP, returning the status code and
+// the JSON-RPC error code of the response (0 on success).
+func postRPC(t *testing.T, url, token, body string) (int, int) {
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, 0
+	}
+	blob, _ := ioutil.ReadAll(resp.Body)
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(blob, &msg); err != nil {
+		t.Fatalf("invalid response %q: %v", blob, err)
+	}
+	if msg.Error != nil {
+		return resp.StatusCode, msg.Error.Code
+	}
+	return resp.StatusCode, 0
+}
+
+const (
+	echoRequest = `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1,{"S":"y"}]}`
+	retsRequest = `{"jsonrpc":"2.0","id":1,"method":"test_rets","params":[]}`
+)
+
+func TestAuthHTTPStaticTokens(t *testing.T) {
+	hs := httptest.NewServer(newAuthTestServer(t, testAuthConfig))
+	defer hs.Close()
+
+	tests := []struct {
+		token, body string
+		status      int
+		code        int
+	}{
+		{"", retsRequest, http.StatusOK, 0},                             // anonymous call permitted
+		{"", echoRequest, http.StatusOK, -32001},                        // anonymous call denied
+		{"partner-secret", echoRequest, http.StatusOK, 0},               // permitted method
+		{"partner-secret", retsRequest, http.StatusOK, -32001},          // method not granted to token
+		{"operator-secret", echoRequest, http.StatusOK, 0},              // wildcard grant
+		{"wrong-secret", retsRequest, http.StatusUnauthorized, 0},       // unknown token
+		{"", `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`, 200, 0}, // metadata always available
+	}
+	for i, tt := range tests {
+		status, code := postRPC(t, hs.URL, tt.token, tt.body)
+		if status != tt.status || code != tt.code {
+			t.Errorf("test %d: result mismatch: have status %d code %d, want status %d code %d", i, status, code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestAuthHTTPBatch(t *testing.T) {
+	hs := httptest.NewServer(newAuthTestServer(t, testAuthConfig))
+	defer hs.Close()
+
+	req, _ := http.NewRequest("POST", hs.URL, strings.NewReader("["+echoRequest+","+retsRequest+"]"))
+	req.Header.Set("Authorization", "Bearer partner-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var msgs []jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msgs); err != nil {
+		t.Fatalf("invalid batch response: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("response count mismatch: have %d, want 2", len(msgs))
+	}
+	if msgs[0].Error != nil {
+		t.Errorf("permitted call failed: %v", msgs[0].Error.Message)
+	}
+	if msgs[1].Error == nil || msgs[1].Error.Code != -32001 {
+		t.Errorf("denied call not rejected: %+v", msgs[1].Error)
+	}
+}
+
+func TestAuthHTTPJWT(t *testing.T) {
+	hs := httptest.NewServer(newAuthTestServer(t, testAuthConfig))
+	defer hs.Close()
+
+	sign := func(secret string, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+	valid := jwt.MapClaims{"sub": "bot", "allow": []string{"test"}, "exp": time.Now().Add(time.Hour).Unix()}
+	expired := jwt.MapClaims{"sub": "bot", "allow": []string{"test"}, "exp": time.Now().Add(-time.Hour).Unix()}
+	eternal := jwt.MapClaims{"sub": "bot", "allow": []string{"test"}}
+
+	tests := []struct {
+		token  string
+		status int
+		code   int
+	}{
+		{sign("jwt-secret", valid), http.StatusOK, 0},
+		{sign("jwt-secret", expired), http.StatusUnauthorized, 0},
+		{sign("jwt-secret", eternal), http.StatusUnauthorized, 0},
+		{sign("other-secret", valid), http.StatusUnauthorized, 0},
+	}
+	for i, tt := range tests {
+		status, code := postRPC(t, hs.URL, tt.token, echoRequest)
+		if status != tt.status || code != tt.code {
+			t.Errorf("test %d: result mismatch: have status %d code %d, want status %d code %d", i, status, code, tt.status, tt.code)
+		}
+	}
+}
+
+func TestAuthIPC(t *testing.T) {
+	// Unrestricted local clients may call anything
+	client := DialInProc(newAuthTestServer(t, testAuthConfig))
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1, &Args{"y"}); err != nil {
+		t.Fatalf("local call failed: %v", err)
+	}
+	// Restricted local clients need to authenticate in-band
+	config := *testAuthConfig
+	config.RestrictIPC = true
+
+	client = DialInProc(newAuthTestServer(t, &config))
+	defer client.Close()
+
+	err := client.Call(&result, "test_echo", "x", 1, &Args{"y"})
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32001 {
+		t.Fatalf("unauthenticated call not rejected: %v", err)
+	}
+	var name string
+	if err := client.Call(&name, "rpc_authenticate", "wrong-secret"); err == nil {
+		t.Fatalf("invalid credentials accepted")
+	}
+	if err := client.Call(&name, "rpc_authenticate", "partner-secret"); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if name != "partner" {
+		t.Errorf("token holder mismatch: have %s, want partner", name)
+	}
+	if err := client.Call(&result, "test_echo", "x", 1, &Args{"y"}); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+}
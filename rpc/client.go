@@ -247,7 +247,9 @@ func (c *Client) Call(result interface{}, method string, args ...interface{}) er
 //
 // The result must be a pointer so that package json can unmarshal into it. You
 // can also pass nil, in which case the result is ignored.
-func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) (err error) {
+	defer func(start time.Time) { observeCall(method, start, err) }(time.Now())
+
 	msg, err := c.newMessage(method, args...)
 	if err != nil {
 		return err
@@ -297,7 +299,17 @@ func (c *Client) BatchCall(b []BatchElem) error {
 // Error field of the corresponding BatchElem.
 //
 // Note that batch calls may not be executed atomically on the server side.
-func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
+func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) (err error) {
+	defer func(start time.Time) {
+		for _, elem := range b {
+			if err != nil {
+				observeCall(elem.Method, start, err)
+			} else {
+				observeCall(elem.Method, start, elem.Error)
+			}
+		}
+	}(time.Now())
+
 	msgs := make([]*jsonrpcMessage, len(b))
 	op := &requestOp{
 		ids:  make([]json.RawMessage, len(b)),
@@ -312,7 +324,6 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 		op.ids[i] = msg.ID
 	}
 
-	var err error
 	if c.isHTTP {
 		err = c.sendBatchHTTP(ctx, op, msgs)
 	} else {
@@ -737,6 +748,9 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 }
 
 func (sub *ClientSubscription) start() {
+	clientSubscriptionGauge.Inc(1)
+	defer clientSubscriptionGauge.Dec(1)
+
 	sub.quitWithError(sub.forward())
 }
 
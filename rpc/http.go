@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -136,11 +137,13 @@ func (t *httpReadWriteNopCloser) Close() error {
 	return nil
 }
 
-// NewHTTPServer creates a new HTTP RPC server around an API provider.
+// NewHTTPServer creates a new HTTP RPC server around an API provider. Requests
+// are only served if their Host header names one of the allowed virtual hosts,
+// see NewVirtualHostHandler.
 //
 // Deprecated: Server implements http.Handler
-func NewHTTPServer(cors []string, srv *Server) *http.Server {
-	return &http.Server{Handler: newCorsHandler(srv, cors)}
+func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
+	return &http.Server{Handler: NewVirtualHostHandler(vhosts, newCorsHandler(srv, cors))}
 }
 
 // ServeHTTP serves JSON-RPC requests over HTTP.
@@ -151,6 +154,11 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.StatusRequestEntityTooLarge)
 		return
 	}
+	ctx, err := srv.httpAuthContext(withRemote(context.Background(), r.RemoteAddr), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
 	w.Header().Set("content-type", "application/json")
 
 	// create a codec that reads direct from the request body until
@@ -158,7 +166,38 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// a single request.
 	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
 	defer codec.Close()
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
+}
+
+// GuardHandler wraps an HTTP handler served besides the JSON-RPC API, such as
+// GraphQL, with the authentication and limits of the server. Each request is
+// authorized and rate limited as a call of the given method, and carries the
+// execution deadline of the method in its context. CORS preflight requests are
+// passed through unchecked.
+func (srv *Server) GuardHandler(method string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, err := srv.httpAuthContext(withRemote(r.Context(), r.RemoteAddr), r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err := srv.authorize(ctx, method); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := srv.checkRate(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		ctx, cancel := srv.callContext(ctx, method)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
 
 func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
@@ -175,3 +214,54 @@ func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
 	})
 	return c.Handler(srv)
 }
+
+// virtualHostHandler is a handler which validates the Host-header of incoming
+// requests. It protects the HTTP endpoint against DNS rebinding attacks, where
+// a malicious website resolves its own domain name to the address of the node
+// to get around the same-origin policy of the browser. Such requests still
+// carry the domain name of the attacker in the Host header.
+type virtualHostHandler struct {
+	vhosts map[string]struct{}
+	next   http.Handler
+}
+
+// NewVirtualHostHandler wraps a handler, rejecting the requests whose Host
+// header doesn't name one of the given virtual hosts. A "*" allows any host.
+// Requests addressing the server by IP address are always accepted, since the
+// browser's same-origin policy prevents those from being rebound.
+func NewVirtualHostHandler(vhosts []string, next http.Handler) http.Handler {
+	vhostMap := make(map[string]struct{})
+	for _, allowedHost := range vhosts {
+		vhostMap[strings.ToLower(allowedHost)] = struct{}{}
+	}
+	return &virtualHostHandler{vhostMap, next}
+}
+
+// ServeHTTP serves JSON-RPC requests over HTTP, implements http.Handler
+func (h *virtualHostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// If r.Host is not set, we can continue serving since a browser would set the Host header
+	if r.Host == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		// Either invalid (too many colons) or no port specified
+		host = r.Host
+	}
+	if ipAddr := net.ParseIP(strings.Trim(host, "[]")); ipAddr != nil {
+		// It's an IP address, we can serve that
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	// Not an IP address, but a hostname. Need to validate
+	if _, exist := h.vhosts["*"]; exist {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if _, exist := h.vhosts[strings.ToLower(host)]; exist {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "invalid host specified", http.StatusForbidden)
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that requests are only served if their Host header names an allowed
+// virtual host, simulating DNS rebinding attacks where a browser sends requests
+// to the node carrying the attacker's domain name.
+func TestVirtualHostHandler(t *testing.T) {
+	tests := []struct {
+		vhosts []string
+		host   string
+		status int
+	}{
+		// Default allowlist, the attacker's domain rebound to 127.0.0.1
+		{[]string{"localhost"}, "attacker.com", http.StatusForbidden},
+		{[]string{"localhost"}, "attacker.com:8545", http.StatusForbidden},
+		{[]string{"localhost"}, "localhost.attacker.com:8545", http.StatusForbidden},
+		// Legitimate local requests
+		{[]string{"localhost"}, "localhost:8545", http.StatusOK},
+		{[]string{"localhost"}, "LocalHost:8545", http.StatusOK},
+		{[]string{"localhost"}, "127.0.0.1:8545", http.StatusOK},
+		{[]string{"localhost"}, "[::1]:8545", http.StatusOK},
+		{[]string{"localhost"}, "", http.StatusOK},
+		// Custom and wildcard allowlists
+		{[]string{"node.example.org"}, "node.example.org", http.StatusOK},
+		{[]string{"node.example.org"}, "localhost:8545", http.StatusForbidden},
+		{[]string{"*"}, "attacker.com:8545", http.StatusOK},
+		{nil, "localhost:8545", http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		handler := NewVirtualHostHandler(tt.vhosts, newTestServer("test", new(Service)))
+
+		req := httptest.NewRequest("POST", "http://localhost:8545", strings.NewReader(retsRequest))
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("test %d (vhosts %v, host %q): status mismatch: have %d, want %d", i, tt.vhosts, tt.host, rec.Code, tt.status)
+		}
+	}
+}
+
+// Tests that the HTTP server returned by NewHTTPServer enforces the allowlist.
+func TestHTTPServerVirtualHosts(t *testing.T) {
+	server := NewHTTPServer(nil, []string{"localhost"}, newTestServer("test", new(Service)))
+	hs := httptest.NewServer(server.Handler)
+	defer hs.Close()
+
+	for host, want := range map[string]int{"attacker.com": http.StatusForbidden, "localhost": http.StatusOK} {
+		req, _ := http.NewRequest("POST", hs.URL, strings.NewReader(retsRequest))
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("host %q: status mismatch: have %d, want %d", host, resp.StatusCode, want)
+		}
+	}
+}
+
+// Tests that plain HTTP handlers guarded by the server are subject to its
+// authentication, rate limits and execution deadlines.
+func TestGuardHandler(t *testing.T) {
+	server := newAuthTestServer(t, &AuthConfig{
+		Tokens: []AuthToken{
+			{Name: "reader", Token: "reader-secret", Allow: []string{"graphql"}},
+			{Name: "other", Token: "other-secret", Allow: []string{"test"}},
+		},
+	})
+	server.SetLimits(&Limits{RateLimit: 0.001, RateBurst: 2, ExecutionTime: time.Minute})
+
+	var deadline bool
+	guarded := server.GuardHandler("graphql_query", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, deadline = r.Context().Deadline()
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(token string) int {
+		req := httptest.NewRequest("POST", "/graphql", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		guarded.ServeHTTP(w, req)
+		return w.Code
+	}
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusForbidden},
+		{"forged", http.StatusUnauthorized},
+		{"other-secret", http.StatusForbidden},
+		{"reader-secret", http.StatusOK},
+		{"reader-secret", http.StatusOK},
+		{"reader-secret", http.StatusTooManyRequests},
+	}
+	for i, tt := range tests {
+		if status := request(tt.token); status != tt.status {
+			t.Errorf("request %d: status mismatch: have %d, want %d", i, status, tt.status)
+		}
+	}
+	if !deadline {
+		t.Errorf("execution deadline not propagated to the handler")
+	}
+}
@@ -333,11 +333,24 @@ func (c *jsonCodec) CreateNotification(subid, namespace string, event interface{
 		Params: jsonSubscription{Subscription: subid, Result: event}}
 }
 
+// encode encodes a response without writing it, for it to be written as is.
+func (c *jsonCodec) encode(res interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(res); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // Write message to client
 func (c *jsonCodec) Write(res interface{}) error {
 	c.encMu.Lock()
 	defer c.encMu.Unlock()
 
+	if blob, ok := res.(json.RawMessage); ok {
+		_, err := c.rw.Write(append(blob, '\n'))
+		return err
+	}
 	return c.e.Encode(res)
 }
 
@@ -0,0 +1,273 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/metrics"
+)
+
+// maxRateBuckets is the number of rate limiting buckets after which idle ones
+// are dropped to bound the memory used by tracking many clients.
+const maxRateBuckets = 4096
+
+var (
+	rejectedBatchMeter     = metrics.NewMeter("rpc/rejected/batch")
+	rejectedResponseMeter  = metrics.NewMeter("rpc/rejected/response")
+	rejectedTimeoutMeter   = metrics.NewMeter("rpc/rejected/timeout")
+	rejectedRateLimitMeter = metrics.NewMeter("rpc/rejected/ratelimit")
+)
+
+// Limits bounds the resources the requests of remote clients may consume. Zero
+// values disable the respective limit.
+type Limits struct {
+	// BatchItems is the maximum number of requests in a batch.
+	BatchItems int `toml:",omitempty"`
+
+	// ResponseBytes is the maximum size of the JSON encoded response of a request
+	// or a whole batch.
+	ResponseBytes int `toml:",omitempty"`
+
+	// ExecutionTime is the deadline of method calls. It is propagated through the
+	// context of the call, methods supporting cancellation abort when it passes.
+	ExecutionTime time.Duration `toml:",omitempty"`
+
+	// MethodTimeouts overrides the execution deadline of individual methods, such
+	// as "eth_getLogs" or "eth_call".
+	MethodTimeouts map[string]time.Duration `toml:",omitempty"`
+
+	// RateLimit is the number of requests per second a client may make, clients
+	// being identified by their credentials or otherwise their IP address. Local
+	// clients, e.g. over IPC, are not rate limited.
+	RateLimit float64 `toml:",omitempty"`
+
+	// RateBurst is the number of requests a client may make in a burst above the
+	// rate limit. It defaults to the rate limit.
+	RateBurst int `toml:",omitempty"`
+}
+
+// timeout returns the execution deadline of a method, zero if unlimited.
+func (l *Limits) timeout(method string) time.Duration {
+	if timeout, ok := l.MethodTimeouts[method]; ok {
+		return timeout
+	}
+	return l.ExecutionTime
+}
+
+// SetLimits sets the resource limits of the server. Calling it with nil removes
+// all limits. It needs to be called before serving any clients.
+func (s *Server) SetLimits(limits *Limits) {
+	if limits == nil {
+		s.limits, s.limiter = nil, nil
+		return
+	}
+	s.limits = limits
+	s.limiter = nil
+	if limits.RateLimit > 0 {
+		burst := limits.RateBurst
+		if burst <= 0 {
+			burst = int(limits.RateLimit)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		s.limiter = newRateLimiter(limits.RateLimit, burst)
+	}
+}
+
+// remoteKey is the context key of the remote address of a client connection.
+type remoteKey struct{}
+
+// withRemote returns a context carrying the remote address of a connection.
+func withRemote(ctx context.Context, remote string) context.Context {
+	return context.WithValue(ctx, remoteKey{}, remote)
+}
+
+// rateLimitKey returns the identity a client is rate limited by: the holder of
+// its credentials if it authenticated, its IP address otherwise. Local clients
+// are not rate limited and have no key.
+func rateLimitKey(ctx context.Context) string {
+	remote, ok := ctx.Value(remoteKey{}).(string)
+	if !ok {
+		return ""
+	}
+	if session, ok := ctx.Value(authSessionKey{}).(*authSession); ok {
+		if g := session.current(); g.name != "anonymous" {
+			return "auth:" + g.name
+		}
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	return "ip:" + remote
+}
+
+// checkBatch rejects batches exceeding the batch limit, returning the error
+// to reply with.
+func (s *Server) checkBatch(reqs []*serverRequest) Error {
+	if s.limits == nil || s.limits.BatchItems <= 0 || len(reqs) <= s.limits.BatchItems {
+		return nil
+	}
+	rejectedBatchMeter.Mark(1)
+	return &limitExceededError{fmt.Sprintf("batch too large: %d requests, max %d", len(reqs), s.limits.BatchItems)}
+}
+
+// checkRate consumes a request from the rate limiting bucket of the client,
+// returning an error if the bucket is depleted.
+func (s *Server) checkRate(ctx context.Context) Error {
+	if s.limiter == nil {
+		return nil
+	}
+	key := rateLimitKey(ctx)
+	if key == "" || s.limiter.allow(key) {
+		return nil
+	}
+	rejectedRateLimitMeter.Mark(1)
+	log.Debug("Rate limited RPC client", "client", key)
+	return &limitExceededError{"rate limit exceeded"}
+}
+
+// callContext derives the context of a method call, bounded by its execution
+// deadline if the method has one.
+func (s *Server) callContext(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	if s.limits != nil {
+		if timeout := s.limits.timeout(method); timeout > 0 {
+			return context.WithTimeout(ctx, timeout)
+		}
+	}
+	return context.WithCancel(ctx)
+}
+
+// responseBudget tracks the number of response bytes left for a request or a
+// batch of requests.
+type responseBudget struct {
+	left int
+}
+
+// newResponseBudget creates the response budget of a request or batch, nil if
+// response sizes are unlimited.
+func (s *Server) newResponseBudget() *responseBudget {
+	if s.limits == nil || s.limits.ResponseBytes <= 0 {
+		return nil
+	}
+	return &responseBudget{left: s.limits.ResponseBytes}
+}
+
+// responseEncoder is implemented by codecs able to encode a response ahead of
+// writing it, so its size can be charged without encoding it twice.
+type responseEncoder interface {
+	encode(response interface{}) (json.RawMessage, error)
+}
+
+// check charges the encoded size of a response to the budget, returning the
+// encoded response to write. If it doesn't fit, an error response to send
+// instead is returned along with its error.
+func (b *responseBudget) check(codec ServerCodec, id interface{}, response interface{}) (interface{}, Error) {
+	if b == nil {
+		return response, nil
+	}
+	var (
+		blob json.RawMessage
+		err  error
+	)
+	if enc, ok := codec.(responseEncoder); ok {
+		blob, err = enc.encode(response)
+	} else {
+		blob, err = json.Marshal(response)
+	}
+	if err != nil {
+		return response, nil
+	}
+	if len(blob) > b.left {
+		b.left = 0
+		rejectedResponseMeter.Mark(1)
+		rejected := &limitExceededError{"response too large"}
+		return codec.CreateErrorResponse(&id, rejected), rejected
+	}
+	b.left -= len(blob)
+	return blob, nil
+}
+
+// rateBucket is a token bucket refilling at a constant rate.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter maintains the token buckets of the clients of a server.
+type rateLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64 // Capacity of the buckets
+
+	lock    sync.Mutex
+	buckets map[string]*rateBucket
+	now     func() time.Time // Overridable for tests
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*rateBucket),
+		now:     time.Now,
+	}
+}
+
+// allow reports whether the client with the given key may make a request,
+// consuming a token from its bucket if so.
+func (l *rateLimiter) allow(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.expire(now)
+		}
+		bucket = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// expire drops the buckets that have refilled completely, as they are in the
+// same state as newly created ones.
+func (l *rateLimiter) expire(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
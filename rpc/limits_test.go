@@ -0,0 +1,194 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLimitsTestServer(limits *Limits) *httptest.Server {
+	server := newTestServer("test", new(Service))
+	server.SetLimits(limits)
+	return httptest.NewServer(server)
+}
+
+func TestLimitsBatchItems(t *testing.T) {
+	hs := newLimitsTestServer(&Limits{BatchItems: 2})
+	defer hs.Close()
+
+	// Batches within the limit are served
+	if _, code := postRPC(t, hs.URL, "", retsRequest); code != 0 {
+		t.Fatalf("single request rejected with code %d", code)
+	}
+	resp, err := http.Post(hs.URL, "application/json", strings.NewReader("["+retsRequest+","+retsRequest+"]"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var msgs []jsonrpcMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msgs); err != nil {
+		t.Fatalf("invalid batch response: %v", err)
+	}
+	resp.Body.Close()
+	if len(msgs) != 2 {
+		t.Fatalf("response count mismatch: have %d, want 2", len(msgs))
+	}
+	// Oversized batches are rejected as a whole
+	batch := "[" + retsRequest + "," + retsRequest + "," + retsRequest + "]"
+	if _, code := postRPC(t, hs.URL, "", batch); code != -32005 {
+		t.Fatalf("oversized batch not rejected: code %d", code)
+	}
+}
+
+func TestLimitsResponseBytes(t *testing.T) {
+	hs := newLimitsTestServer(&Limits{ResponseBytes: 64})
+	defer hs.Close()
+
+	if _, code := postRPC(t, hs.URL, "", retsRequest); code != 0 {
+		t.Fatalf("small response rejected with code %d", code)
+	}
+	large := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + strings.Repeat("x", 128) + `",1,{"S":"y"}]}`
+	if _, code := postRPC(t, hs.URL, "", large); code != -32005 {
+		t.Fatalf("large response not rejected: code %d", code)
+	}
+}
+
+// DroppedSubscriptionService reports the subscriptions whose error channel is
+// closed by the server.
+type DroppedSubscriptionService struct {
+	dropped chan ID
+}
+
+func (s *DroppedSubscriptionService) Watch(ctx context.Context) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		<-sub.Err()
+		s.dropped <- sub.ID
+	}()
+	return sub, nil
+}
+
+func TestLimitsResponseBytesSubscription(t *testing.T) {
+	service := &DroppedSubscriptionService{dropped: make(chan ID, 1)}
+	server := NewServer()
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	server.SetLimits(&Limits{ResponseBytes: 16})
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation|OptionSubscriptions)
+
+	if _, err := clientConn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"test_subscribe","params":["watch"]}`)); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	var msg jsonrpcMessage
+	if err := json.NewDecoder(clientConn).Decode(&msg); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if msg.Error == nil || msg.Error.Code != -32005 {
+		t.Fatalf("oversized subscription response not rejected: %s", msg.Result)
+	}
+	// The subscription whose id was never sent is dropped
+	select {
+	case <-service.dropped:
+	case <-time.After(time.Second):
+		t.Fatalf("rejected subscription not dropped")
+	}
+}
+
+func TestLimitsExecutionTime(t *testing.T) {
+	hs := newLimitsTestServer(&Limits{ExecutionTime: 50 * time.Millisecond})
+	defer hs.Close()
+
+	sleep := func(d time.Duration) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[` + strconv.FormatInt(int64(d), 10) + `]}`
+	}
+	if _, code := postRPC(t, hs.URL, "", sleep(time.Millisecond)); code != 0 {
+		t.Fatalf("fast call rejected with code %d", code)
+	}
+	start := time.Now()
+	if _, code := postRPC(t, hs.URL, "", sleep(10*time.Second)); code != -32002 {
+		t.Fatalf("slow call not timed out: code %d", code)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("deadline not propagated to the call: took %v", elapsed)
+	}
+}
+
+func TestLimitsRateLimit(t *testing.T) {
+	hs := newLimitsTestServer(&Limits{RateLimit: 0.001, RateBurst: 2})
+	defer hs.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, code := postRPC(t, hs.URL, "", retsRequest); code != 0 {
+			t.Fatalf("request %d within burst rejected with code %d", i, code)
+		}
+	}
+	if _, code := postRPC(t, hs.URL, "", retsRequest); code != -32005 {
+		t.Fatalf("request above rate limit not rejected: code %d", code)
+	}
+	// Local clients are not rate limited
+	server := newTestServer("test", new(Service))
+	server.SetLimits(&Limits{RateLimit: 0.001, RateBurst: 1})
+
+	client := DialInProc(server)
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		if err := client.Call(nil, "test_rets"); err != nil {
+			t.Fatalf("local call %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !limiter.allow("a") {
+			t.Fatalf("request %d within burst denied", i)
+		}
+	}
+	if limiter.allow("a") {
+		t.Fatalf("request above burst allowed")
+	}
+	if !limiter.allow("b") {
+		t.Fatalf("other client denied")
+	}
+	// Half a second refills a single token at two requests per second
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.allow("a") {
+		t.Fatalf("refilled request denied")
+	}
+	if limiter.allow("a") {
+		t.Fatalf("request above refill allowed")
+	}
+}
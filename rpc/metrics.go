@@ -0,0 +1,127 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/metrics"
+)
+
+var (
+	requestMeter      = metrics.NewMeter("rpc/requests")
+	subscriptionGauge = metrics.NewCounter("rpc/subscriptions")
+
+	clientRequestMeter      = metrics.NewMeter("rpc/client/requests")
+	clientSubscriptionGauge = metrics.NewCounter("rpc/client/subscriptions")
+)
+
+// metricsName returns the name a request is metered under. Only the names of
+// resolved methods are used, as arbitrary names sent by clients would bloat the
+// metrics registry.
+func metricsName(req *serverRequest) string {
+	switch {
+	case req.method != "":
+		return req.method
+	case req.isUnsubscribe:
+		return "unsubscribe"
+	default:
+		return "invalid"
+	}
+}
+
+// AccessLog configures the logging of the calls served by the server. Calls
+// slower than the threshold are always logged, others are sampled.
+type AccessLog struct {
+	// SampleRate is the fraction of calls to log, between 0 and 1.
+	SampleRate float64 `toml:",omitempty"`
+
+	// SlowThreshold is the duration above which calls are logged regardless of
+	// the sample rate. Zero disables the logging of slow calls.
+	SlowThreshold time.Duration `toml:",omitempty"`
+}
+
+// SetAccessLog configures the access log of the server. Calling it with nil
+// disables the access log. It may be called while serving clients.
+func (s *Server) SetAccessLog(config *AccessLog) {
+	if config != nil && config.SampleRate <= 0 && config.SlowThreshold <= 0 {
+		config = nil
+	}
+	s.accessLog.Store(accessLogConfig{config})
+}
+
+// accessLogConfig wraps the access log config, as an atomic.Value cannot hold
+// nil values.
+type accessLogConfig struct {
+	*AccessLog
+}
+
+// logged reports whether a call taking the given time is to be logged.
+func (s *Server) logged(elapsed time.Duration) bool {
+	config, _ := s.accessLog.Load().(accessLogConfig)
+	if config.AccessLog == nil {
+		return false
+	}
+	if config.SlowThreshold > 0 && elapsed >= config.SlowThreshold {
+		return true
+	}
+	return config.SampleRate > 0 && rand.Float64() < config.SampleRate
+}
+
+// observe updates the metrics of a served request and writes it to the access
+// log if it is sampled. A nil error signals a successful call.
+func (s *Server) observe(ctx context.Context, req *serverRequest, elapsed time.Duration, response interface{}, err error) {
+	name := metricsName(req)
+	requestMeter.Mark(1)
+	metrics.NewTimer("rpc/duration/" + name).Update(elapsed)
+	if err == nil {
+		metrics.NewMeter("rpc/success/" + name).Mark(1)
+	} else {
+		metrics.NewMeter("rpc/failure/" + name).Mark(1)
+	}
+	if !s.logged(elapsed) {
+		return
+	}
+	size := 0
+	if blob, err := json.Marshal(response); err == nil {
+		size = len(blob)
+	}
+	client := rateLimitKey(ctx)
+	if client == "" {
+		client = "local"
+	}
+	if err != nil {
+		log.Info("Served RPC call", "method", name, "duration", elapsed, "client", client, "size", size, "err", err)
+	} else {
+		log.Info("Served RPC call", "method", name, "duration", elapsed, "client", client, "size", size)
+	}
+}
+
+// observeCall updates the client metrics of a call to a method.
+func observeCall(method string, start time.Time, err error) {
+	clientRequestMeter.Mark(1)
+	metrics.NewTimer("rpc/client/duration/" + method).UpdateSince(start)
+	if err == nil {
+		metrics.NewMeter("rpc/client/success/" + method).Mark(1)
+	} else {
+		metrics.NewMeter("rpc/client/failure/" + method).Mark(1)
+	}
+}
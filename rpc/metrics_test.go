@@ -0,0 +1,113 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wtc/go-wtc/log"
+)
+
+// captureAccessLog collects the access log records of the root logger until the
+// returned function is called.
+func captureAccessLog() (func() []map[string]interface{}, func()) {
+	var (
+		lock    sync.Mutex
+		records []map[string]interface{}
+	)
+	handler := log.Root().GetHandler()
+	log.Root().SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Msg != "Served RPC call" {
+			return nil
+		}
+		fields := make(map[string]interface{})
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			fields[r.Ctx[i].(string)] = r.Ctx[i+1]
+		}
+		lock.Lock()
+		records = append(records, fields)
+		lock.Unlock()
+		return nil
+	}))
+	get := func() []map[string]interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]map[string]interface{}{}, records...)
+	}
+	return get, func() { log.Root().SetHandler(handler) }
+}
+
+func TestAccessLog(t *testing.T) {
+	records, restore := captureAccessLog()
+	defer restore()
+
+	server := newTestServer("test", new(Service))
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	// Calls are not logged by default
+	postRPC(t, hs.URL, "", retsRequest)
+	if n := len(records()); n != 0 {
+		t.Fatalf("calls logged with access log disabled: %d", n)
+	}
+	// Sampled calls are logged with their outcome
+	server.SetAccessLog(&AccessLog{SampleRate: 1})
+	postRPC(t, hs.URL, "", retsRequest)
+	postRPC(t, hs.URL, "", `{"jsonrpc":"2.0","id":2,"method":"test_missing","params":[]}`)
+
+	logged := records()
+	if len(logged) != 2 {
+		t.Fatalf("logged call count mismatch: have %d, want 2", len(logged))
+	}
+	if logged[0]["method"] != "test_rets" || logged[0]["err"] != nil {
+		t.Errorf("successful call logged incorrectly: %v", logged[0])
+	}
+	if size, _ := logged[0]["size"].(int); size == 0 {
+		t.Errorf("response size not logged: %v", logged[0])
+	}
+	if client, _ := logged[0]["client"].(string); client == "" || client == "local" {
+		t.Errorf("remote client not logged: %v", logged[0])
+	}
+	if logged[1]["method"] != "invalid" || logged[1]["err"] == nil {
+		t.Errorf("failed call logged incorrectly: %v", logged[1])
+	}
+	// Disabling the access log stops logging
+	server.SetAccessLog(&AccessLog{})
+	postRPC(t, hs.URL, "", retsRequest)
+	if n := len(records()); n != 2 {
+		t.Fatalf("calls logged after disabling the access log: %d", n-2)
+	}
+}
+
+func TestAccessLogSlowCalls(t *testing.T) {
+	server := newTestServer("test", new(Service))
+	server.SetAccessLog(&AccessLog{SlowThreshold: time.Second})
+
+	if server.logged(time.Millisecond) {
+		t.Errorf("fast call logged without sampling")
+	}
+	if !server.logged(2 * time.Second) {
+		t.Errorf("slow call not logged")
+	}
+	server.SetAccessLog(nil)
+	if server.logged(2 * time.Second) {
+		t.Errorf("slow call logged with access log disabled")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/wtc/go-wtc/log"
 	set "gopkg.in/fatih/set.v0"
@@ -125,7 +126,11 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+//
+// If authentication is enabled, the context carries the session of the client
+// and requests the client isn't permitted to make are rejected. Requests above
+// the resource limits of the server are rejected too.
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -140,14 +145,16 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
 	if options&OptionSubscriptions == OptionSubscriptions {
-		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
+		notifier := newNotifier(codec)
+		defer notifier.close()
+		ctx = context.WithValue(ctx, notifierKey{}, notifier)
 	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
@@ -186,6 +193,23 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 			}
 			return nil
 		}
+		// Reject oversized batches as a whole
+		if err := s.checkBatch(reqs); err != nil {
+			codec.Write(codec.CreateErrorResponse(nil, err))
+			if singleShot {
+				return nil
+			}
+			continue
+		}
+		// Reject the requests the client isn't permitted to make or that exceed
+		// its rate limit
+		for _, req := range reqs {
+			if req.err == nil && req.method != "" {
+				if req.err = s.authorize(ctx, req.method); req.err == nil {
+					req.err = s.checkRate(ctx)
+				}
+			}
+		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
 			if batch {
@@ -213,16 +237,21 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes the
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
+//
+// Clients served this way are considered local, e.g. IPC clients, and have full
+// access unless the server's auth config restricts IPC.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	ctx, _ := s.authContext(context.Background(), "local", "", true)
+	s.serveRequest(ctx, codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	ctx, _ := s.authContext(context.Background(), "local", "", true)
+	s.serveRequest(ctx, codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -253,42 +282,53 @@ func (s *Server) createSubscription(ctx context.Context, c ServerCodec, req *ser
 	return reply[0].Interface().(*Subscription).ID, nil
 }
 
-// handle executes a request and returns the response from the callback.
-func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverRequest) (interface{}, func()) {
+// handle executes a request and returns the response from the callback, along
+// with the error the response reports, if any. For subscriptions a callback is
+// returned too, to be called with whether the response was sent.
+func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverRequest) (interface{}, func(bool), error) {
 	if req.err != nil {
-		return codec.CreateErrorResponse(&req.id, req.err), nil
+		return codec.CreateErrorResponse(&req.id, req.err), nil, req.err
 	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
 			if !supported { // interface doesn't support subscriptions (e.g. http)
-				return codec.CreateErrorResponse(&req.id, &callbackError{ErrNotificationsUnsupported.Error()}), nil
+				err := &callbackError{ErrNotificationsUnsupported.Error()}
+				return codec.CreateErrorResponse(&req.id, err), nil, err
 			}
 
 			subid := ID(req.args[0].String())
 			if err := notifier.unsubscribe(subid); err != nil {
-				return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+				rpcErr := &callbackError{err.Error()}
+				return codec.CreateErrorResponse(&req.id, rpcErr), nil, rpcErr
 			}
 
-			return codec.CreateResponse(req.id, true), nil
+			return codec.CreateResponse(req.id, true), nil, nil
 		}
-		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
+		rpcErr := &invalidParamsError{"Expected subscription id as first argument"}
+		return codec.CreateErrorResponse(&req.id, rpcErr), nil, rpcErr
 	}
 
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
-			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+			rpcErr := &callbackError{err.Error()}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil, rpcErr
 		}
 
-		// active the subscription after the sub id was successfully sent to the client
-		activateSub := func() {
+		// active the subscription after the sub id was successfully sent to the client,
+		// drop it if the response was replaced
+		activateSub := func(sent bool) {
 			notifier, _ := NotifierFromContext(ctx)
-			notifier.activate(subid, req.svcname)
+			if sent {
+				notifier.activate(subid, req.svcname)
+			} else {
+				notifier.unsubscribe(subid)
+			}
 		}
 
-		return codec.CreateResponse(req.id, subid), activateSub
+		return codec.CreateResponse(req.id, subid), activateSub, nil
 	}
 
 	// regular RPC call, prepare arguments
@@ -296,12 +336,16 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		rpcErr := &invalidParamsError{fmt.Sprintf("%s%s%s expects %d parameters, got %d",
 			req.svcname, serviceMethodSeparator, req.callb.method.Name,
 			len(req.callb.argTypes), len(req.args))}
-		return codec.CreateErrorResponse(&req.id, rpcErr), nil
+		return codec.CreateErrorResponse(&req.id, rpcErr), nil, rpcErr
 	}
 
+	// bound the call by its execution deadline, if any
+	callCtx, cancel := s.callContext(ctx, req.method)
+	defer cancel()
+
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
-		arguments = append(arguments, reflect.ValueOf(ctx))
+		arguments = append(arguments, reflect.ValueOf(callCtx))
 	}
 	if len(req.args) > 0 {
 		arguments = append(arguments, req.args...)
@@ -309,29 +353,39 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 
 	// execute RPC method and return result
 	reply := req.callb.method.Func.Call(arguments)
+	if callCtx.Err() == context.DeadlineExceeded {
+		rejectedTimeoutMeter.Mark(1)
+		rpcErr := &timeoutError{req.method}
+		return codec.CreateErrorResponse(&req.id, rpcErr), nil, rpcErr
+	}
 	if len(reply) == 0 {
-		return codec.CreateResponse(req.id, nil), nil
+		return codec.CreateResponse(req.id, nil), nil, nil
 	}
 
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			rpcErr := &callbackError{e.Error()}
+			res := codec.CreateErrorResponse(&req.id, rpcErr)
+			return res, nil, rpcErr
 		}
 	}
-	return codec.CreateResponse(req.id, reply[0].Interface()), nil
+	return codec.CreateResponse(req.id, reply[0].Interface()), nil, nil
 }
 
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
-	var response interface{}
-	var callback func()
-	if req.err != nil {
-		response = codec.CreateErrorResponse(&req.id, req.err)
+	start := time.Now()
+	response, callback, err := s.handle(ctx, codec, req)
+	if checked, rejected := s.newResponseBudget().check(codec, req.id, response); rejected != nil {
+		if callback != nil {
+			callback(false)
+		}
+		response, callback, err = checked, nil, rejected
 	} else {
-		response, callback = s.handle(ctx, codec, req)
+		response = checked
 	}
+	s.observe(ctx, req, time.Since(start), response, err)
 
 	if err := codec.Write(response); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
@@ -340,7 +394,7 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 
 	// when request was a subscribe request this allows these subscriptions to be actived
 	if callback != nil {
-		callback()
+		callback(true)
 	}
 }
 
@@ -348,15 +402,29 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 // It will only write the response back when the last request is processed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
 	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	budget := s.newResponseBudget()
+	var callbacks []func(bool)
 	for i, req := range requests {
+		start := time.Now()
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
-		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
+			s.observe(ctx, req, time.Since(start), responses[i], req.err)
+			continue
+		}
+		response, callback, err := s.handle(ctx, codec, req)
+		if checked, rejected := budget.check(codec, req.id, response); rejected != nil {
+			if callback != nil {
+				callback(false)
 			}
+			response, callback, err = checked, nil, rejected
+		} else {
+			response = checked
+		}
+		s.observe(ctx, req, time.Since(start), response, err)
+
+		responses[i] = response
+		if callback != nil {
+			callbacks = append(callbacks, callback)
 		}
 	}
 
@@ -367,7 +435,7 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 
 	// when request holds one of more subscribe requests this allows these subscriptions to be activated
 	for _, c := range callbacks {
-		c()
+		c(true)
 	}
 }
 
@@ -410,7 +478,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + subscribeMethodSuffix, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -427,7 +495,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
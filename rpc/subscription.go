@@ -107,7 +107,7 @@ func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
 }
 
-// unsubscribe a subscription.
+// unsubscribe a subscription, active or not yet activated.
 // If the subscription could not be found ErrSubscriptionNotFound is returned.
 func (n *Notifier) unsubscribe(id ID) error {
 	n.subMu.Lock()
@@ -115,6 +115,12 @@ func (n *Notifier) unsubscribe(id ID) error {
 	if s, found := n.active[id]; found {
 		close(s.err)
 		delete(n.active, id)
+		subscriptionGauge.Dec(1)
+		return nil
+	}
+	if s, found := n.inactive[id]; found {
+		close(s.err)
+		delete(n.inactive, id)
 		return nil
 	}
 	return ErrSubscriptionNotFound
@@ -131,5 +137,15 @@ func (n *Notifier) activate(id ID, namespace string) {
 		sub.namespace = namespace
 		n.active[id] = sub
 		delete(n.inactive, id)
+		subscriptionGauge.Inc(1)
 	}
 }
+
+// close drops the subscriptions of the connection when it is torn down.
+func (n *Notifier) close() {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+	subscriptionGauge.Dec(int64(len(n.active)))
+	n.active = make(map[ID]*Subscription)
+	n.inactive = make(map[ID]*Subscription)
+}
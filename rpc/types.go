@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/wtc/go-wtc/common/hexutil"
 	"gopkg.in/fatih/set.v0"
@@ -57,6 +58,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // Full name of the called method, e.g. eth_subscribe
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +76,12 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	auth    *authenticator // Client authentication, nil if disabled
+	limits  *Limits        // Resource limits of requests, nil if unlimited
+	limiter *rateLimiter   // Per client rate limiting, nil if disabled
+
+	accessLog atomic.Value // Access log config (accessLogConfig), unset if disabled
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -0,0 +1,197 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package signer implements a signing service owning the keys of the accounts,
+// signing the requests of remote nodes over JSON-RPC once approved.
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/rlp"
+)
+
+// Namespace is the JSON-RPC namespace of the signer API.
+const Namespace = "account"
+
+// ErrRequestDenied is returned for signing requests rejected by the user.
+var ErrRequestDenied = errors.New("request denied")
+
+// Approval is the decision on a signing request.
+type Approval struct {
+	Approved   bool
+	Passphrase string // Passphrase to decrypt the key with, empty if unlocked
+}
+
+// TxRequest is a request to sign a transaction.
+type TxRequest struct {
+	Account accounts.Account
+	Tx      *types.Transaction
+	ChainID *big.Int // Chain ID of EIP155 signatures, nil for unprotected ones
+}
+
+// DataRequest is a request to sign data.
+type DataRequest struct {
+	Account accounts.Account
+	Data    []byte
+}
+
+// UI approves signing requests, e.g. by prompting the user or by evaluating
+// rules. The requests are passed one at a time.
+type UI interface {
+	// ApproveTx decides on a transaction signing request.
+	ApproveTx(req *TxRequest) (Approval, error)
+
+	// ApproveData decides on a data signing request.
+	ApproveData(req *DataRequest) (Approval, error)
+}
+
+// SignTxArgs are the fields of a transaction to sign.
+type SignTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Big     `json:"gas"`
+	GasPrice hexutil.Big     `json:"gasPrice"`
+	Value    hexutil.Big     `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId"`
+}
+
+// toTransaction assembles the unsigned transaction.
+func (args *SignTxArgs) toTransaction() *types.Transaction {
+	if args.To == nil {
+		return types.NewContractCreation(uint64(args.Nonce), (*big.Int)(&args.Value), (*big.Int)(&args.Gas), (*big.Int)(&args.GasPrice), args.Data)
+	}
+	return types.NewTransaction(uint64(args.Nonce), *args.To, (*big.Int)(&args.Value), (*big.Int)(&args.Gas), (*big.Int)(&args.GasPrice), args.Data)
+}
+
+// SignTxResult is a signed transaction.
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// API is the JSON-RPC API of the signer, signing with the wallets of an
+// account manager.
+type API struct {
+	am   *accounts.Manager
+	ui   UI
+	lock sync.Mutex // Serialises the requests passed to the UI
+}
+
+// NewAPI creates a signer API signing with the given wallets once approved by
+// the UI.
+func NewAPI(am *accounts.Manager, ui UI) *API {
+	return &API{am: am, ui: ui}
+}
+
+// List returns the addresses of the accounts available for signing.
+func (api *API) List() []common.Address {
+	addresses := []common.Address{}
+	for _, wallet := range api.am.Wallets() {
+		for _, account := range wallet.Accounts() {
+			addresses = append(addresses, account.Address)
+		}
+	}
+	return addresses
+}
+
+// SignTransaction signs a transaction if approved, returning it RLP encoded.
+func (api *API) SignTransaction(ctx context.Context, args SignTxArgs) (*SignTxResult, error) {
+	account := accounts.Account{Address: args.From}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	req := &TxRequest{Account: account, Tx: args.toTransaction(), ChainID: (*big.Int)(args.ChainID)}
+
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	approval, err := api.ui.ApproveTx(req)
+	if err != nil {
+		return nil, err
+	}
+	if !approval.Approved {
+		log.Info("Transaction signing denied", "from", args.From, "nonce", req.Tx.Nonce())
+		return nil, ErrRequestDenied
+	}
+	var signed *types.Transaction
+	if approval.Passphrase != "" {
+		signed, err = wallet.SignTxWithPassphrase(account, approval.Passphrase, req.Tx, req.ChainID)
+	} else {
+		signed, err = wallet.SignTx(account, req.Tx, req.ChainID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Signed transaction", "from", args.From, "hash", signed.Hash())
+	return &SignTxResult{Raw: raw, Tx: signed}, nil
+}
+
+// SignData signs the hash of the given data prefixed like personal_sign does,
+// if approved. The V value of the signature is 27 or 28.
+func (api *API) SignData(ctx context.Context, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: addr}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	approval, err := api.ui.ApproveData(&DataRequest{Account: account, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if !approval.Approved {
+		log.Info("Data signing denied", "addr", addr)
+		return nil, ErrRequestDenied
+	}
+	var signature []byte
+	if approval.Passphrase != "" {
+		signature, err = wallet.SignHashWithPassphrase(account, approval.Passphrase, SignHash(data))
+	} else {
+		signature, err = wallet.SignHash(account, SignHash(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
+// SignHash calculates the hash of data signed by SignData, as
+//   keccak256("\x19Wtc Signed Message:\n"${message length}${message}).
+func SignHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Wtc Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
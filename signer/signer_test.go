@@ -0,0 +1,81 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/wtc/go-wtc/accounts"
+	"github.com/wtc/go-wtc/accounts/keystore"
+	"github.com/wtc/go-wtc/crypto"
+)
+
+// passphraseUI approves all requests with a passphrase.
+type passphraseUI string
+
+func (ui passphraseUI) ApproveTx(req *TxRequest) (Approval, error) {
+	return Approval{Approved: true, Passphrase: string(ui)}, nil
+}
+
+func (ui passphraseUI) ApproveData(req *DataRequest) (Approval, error) {
+	return Approval{Approved: ui != "", Passphrase: string(ui)}, nil
+}
+
+func TestSignData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := accounts.NewManager(ks)
+	defer am.Close()
+
+	api := NewAPI(am, passphraseUI("secret"))
+	if list := api.List(); len(list) != 1 || list[0] != account.Address {
+		t.Fatalf("account list mismatch: have %x, want %x", list, account.Address)
+	}
+	data := []byte("hello")
+	sig, err := api.SignData(context.Background(), account.Address, data)
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		t.Fatalf("invalid V value %d", sig[64])
+	}
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(SignHash(data), sig)
+	if err != nil {
+		t.Fatalf("failed to recover signer: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(*pub); addr != account.Address {
+		t.Errorf("signer mismatch: have %x, want %x", addr, account.Address)
+	}
+	// Denied requests are never signed
+	api = NewAPI(am, passphraseUI(""))
+	if _, err := api.SignData(context.Background(), account.Address, data); err != ErrRequestDenied {
+		t.Errorf("denied request: have error %v, want %v", err, ErrRequestDenied)
+	}
+}
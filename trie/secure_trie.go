@@ -21,6 +21,7 @@ import (
 
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/rlp"
 )
 
 var secureKeyPrefix = []byte("secure-key-")
@@ -161,6 +162,12 @@ func (t *SecureTrie) Copy() *SecureTrie {
 	return &cpy
 }
 
+// Prove constructs a merkle proof for key, see Trie.Prove. The proof is built
+// for the hashed key, so it has to be verified against keccak256(key).
+func (t *SecureTrie) Prove(key []byte) []rlp.RawValue {
+	return t.trie.Prove(t.hashKey(key))
+}
+
 // NodeIterator returns an iterator that returns nodes of the underlying trie. Iteration
 // starts at the key after the given start key.
 func (t *SecureTrie) NodeIterator(start []byte) NodeIterator {
@@ -34,6 +34,7 @@ import (
 	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/core/vm"
+	"github.com/wtc/go-wtc/core/vm/profiler"
 	"github.com/wtc/go-wtc/internal/ethapi"
 	"github.com/wtc/go-wtc/log"
 	"github.com/wtc/go-wtc/miner"
@@ -192,11 +193,7 @@ func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
 
 // SetGasPrice sets the minimum accepted gas price for the miner.
 func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
-	api.e.lock.Lock()
-	api.e.gasPrice = (*big.Int)(&gasPrice)
-	api.e.lock.Unlock()
-
-	api.e.txPool.SetGasPrice((*big.Int)(&gasPrice))
+	api.e.SetGasPrice((*big.Int)(&gasPrice))
 	return true
 }
 
@@ -548,6 +545,105 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 	}
 }
 
+// ProfileArgs are the arguments for the opcode level profiling of a transaction.
+type ProfileArgs struct {
+	RangeSize  *uint64                              `json:"rangeSize"`  // Size of the aggregated pc ranges
+	Format     *string                              `json:"format"`     // Exported profile format (pprof, folded)
+	Metric     *string                              `json:"metric"`     // Metric of folded profiles (gas, time)
+	SourceMaps map[common.Address]*ProfileSourceMap `json:"sourceMaps"` // Source maps of the executed contracts
+}
+
+// ProfileSourceMap is the solc source map of the code deployed at an address,
+// along with the source files it references in solc index order.
+type ProfileSourceMap struct {
+	SourceMap string   `json:"srcmap"`
+	Files     []string `json:"files"`
+	Sources   []string `json:"sources"`
+}
+
+// ProfileResult is the aggregated opcode level profile of a transaction.
+type ProfileResult struct {
+	Gas         uint64                  `json:"gas"`
+	Failed      bool                    `json:"failed"`
+	ReturnValue string                  `json:"returnValue"`
+	Opcodes     []profiler.OpcodeStat   `json:"opcodes"`
+	Contracts   []profiler.ContractStat `json:"contracts"`
+	Ranges      []profiler.RangeStat    `json:"ranges"`
+	Pprof       hexutil.Bytes           `json:"pprof,omitempty"`
+	Folded      string                  `json:"folded,omitempty"`
+}
+
+// ProfileTransaction re-executes a transaction and returns the gas and time
+// spent per opcode, per contract and per program counter range, optionally
+// exported as a pprof or flame graph profile.
+func (api *PrivateDebugAPI) ProfileTransaction(ctx context.Context, txHash common.Hash, config *ProfileArgs) (*ProfileResult, error) {
+	if config == nil {
+		config = new(ProfileArgs)
+	}
+	cfg := new(profiler.Config)
+	if config.RangeSize != nil {
+		cfg.RangeSize = *config.RangeSize
+	}
+	prof := profiler.New(cfg)
+
+	// Retrieve the tx from the chain and the containing block
+	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", txHash)
+	}
+	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	for addr, src := range config.SourceMaps {
+		sources := make([][]byte, len(src.Sources))
+		for i, source := range src.Sources {
+			sources[i] = []byte(source)
+		}
+		sourceMap, err := profiler.NewSourceMap(statedb.GetCode(addr), src.SourceMap, src.Files, sources)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source map for %x: %v", addr, err)
+		}
+		prof.AddSourceMap(statedb.GetCodeHash(addr), sourceMap)
+	}
+	// Run the transaction with profiling enabled.
+	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: prof})
+	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("profiling failed: %v", err)
+	}
+	result := &ProfileResult{
+		Gas:         gas.Uint64(),
+		Failed:      failed,
+		ReturnValue: fmt.Sprintf("%x", ret),
+		Opcodes:     prof.Opcodes(),
+		Contracts:   prof.Contracts(),
+		Ranges:      prof.Ranges(),
+	}
+	if config.Format != nil {
+		buf := new(bytes.Buffer)
+		switch *config.Format {
+		case "pprof":
+			if err := prof.WritePprof(buf); err != nil {
+				return nil, err
+			}
+			result.Pprof = buf.Bytes()
+		case "folded":
+			metric := profiler.MetricGas
+			if config.Metric != nil {
+				metric = profiler.Metric(*config.Metric)
+			}
+			if err := prof.WriteFolded(buf, metric); err != nil {
+				return nil, err
+			}
+			result.Folded = buf.String()
+		default:
+			return nil, fmt.Errorf("unknown profile format %q", *config.Format)
+		}
+	}
+	return result, nil
+}
+
 // computeTxEnv returns the execution environment of a certain transaction.
 func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
 	// Create the parent state.
@@ -143,6 +143,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Wtc, error) {
 	}
 
 	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+	if config.EVMInterpreter != "" {
+		if vmConfig.External, err = vm.LoadExternalInterpreter(ctx.ResolvePath(config.EVMInterpreter)); err != nil {
+			return nil, fmt.Errorf("failed to load external EVM interpreter: %v", err)
+		}
+		log.Info("Loaded external EVM interpreter", "name", vmConfig.External.Name(), "path", config.EVMInterpreter)
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
@@ -163,6 +169,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Wtc, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.downloader.SetCheckpoint(params.TrustedCheckpoints[genesisHash])
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
@@ -312,6 +319,35 @@ func (self *Wtc) SetEtherbase(etherbase common.Address) {
 	self.miner.SetEtherbase(etherbase)
 }
 
+// SetGasPrice sets the minimum gas price accepted by the miner and propagates
+// it to the transaction pool.
+func (s *Wtc) SetGasPrice(price *big.Int) {
+	s.lock.Lock()
+	s.gasPrice = price
+	s.lock.Unlock()
+
+	s.txPool.SetGasPrice(price)
+}
+
+// SetMaxPeers updates the number of Wtc protocol peers after the peer limit of
+// the p2p server was changed to the given total.
+func (s *Wtc) SetMaxPeers(total int) {
+	s.protocolManager.setMaxPeers(s.protocolPeers(total))
+}
+
+// protocolPeers calculates the number of Wtc protocol peers permitted out of the
+// total peer limit, reserving room for light clients if serving them.
+func (s *Wtc) protocolPeers(total int) int {
+	maxPeers := total
+	if s.config.LightServ > 0 {
+		maxPeers -= s.config.LightPeers
+		if maxPeers < total/2 {
+			maxPeers = total / 2
+		}
+	}
+	return maxPeers
+}
+
 func (s *Wtc) StartMining(local bool) error {
 	eb, err := s.Etherbase()
 	if err != nil {
@@ -362,16 +398,9 @@ func (s *Wtc) Start(srvr *p2p.Server) error {
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
 
-	// Figure out a max peers count based on the server limits
-	maxPeers := srvr.MaxPeers
-	if s.config.LightServ > 0 {
-		maxPeers -= s.config.LightPeers
-		if maxPeers < srvr.MaxPeers/2 {
-			maxPeers = srvr.MaxPeers / 2
-		}
-	}
 	// Start the networking layer and the light server if requested
-	s.protocolManager.Start(maxPeers)
+	s.protocolManager.Start(s.protocolPeers(srvr.MaxPeers))
+	s.startEthEntryUpdate(srvr)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
@@ -113,6 +113,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Go plugin providing an external EVM interpreter (empty = built-in only)
+	EVMInterpreter string `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowGPU    bool   `toml:"-"`
@@ -92,8 +92,15 @@ var (
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
+	errUnsyncedPeer            = errors.New("unsynced peer below the trusted checkpoint")
 )
 
+// IsStalling reports whether a peer was dropped for being unresponsive rather
+// than for serving invalid data.
+func IsStalling(reason error) bool {
+	return reason == errTimeout || reason == errStallingPeer
+}
+
 type Downloader struct {
 	mode SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
@@ -105,6 +112,11 @@ type Downloader struct {
 	fsPivotLock  *types.Header // Pivot header on critical section entry (cannot change between retries)
 	fsPivotFails uint32        // Number of subsequent fast sync failures in the critical section
 
+	checkpoint       *params.TrustedCheckpoint // Trusted checkpoint to challenge peers with (nil = no checkpoint)
+	checkpointHead   *types.Header             // Checkpoint header challenged in the current sync cycle (nil = none)
+	checkpointLinked int32                     // Flag whether the scheduled headers are linked to the checkpoint header (atomic)
+	checkpointLock   sync.RWMutex              // Lock protecting the trusted checkpoint
+
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
@@ -112,6 +124,8 @@ type Downloader struct {
 	syncStatsChainOrigin uint64 // Origin block number where syncing started at
 	syncStatsChainHeight uint64 // Highest block number known when syncing started
 	syncStatsState       stateSyncStats
+	syncStatsStart       time.Time    // Time the current sync cycle started at
+	syncStatsStartBlock  uint64       // Local block number the current sync cycle started at
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
 	lightchain LightChain
@@ -198,6 +212,10 @@ type BlockChain interface {
 
 	// InsertReceiptChain inserts a batch of receipts into the local chain.
 	InsertReceiptChain(types.Blocks, []types.Receipts) (int, error)
+
+	// InsertTrustedChain inserts a batch of blocks linked to a trusted checkpoint
+	// header into the local chain, skipping the seal checks of the blocks up to it.
+	InsertTrustedChain(types.Blocks, *types.Header) (int, error)
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
@@ -240,27 +258,92 @@ func New(mode SyncMode, stateDb wtcdb.Database, mux *event.TypeMux, chain BlockC
 // In addition, during the state download phase of fast synchronisation the number
 // of processed and the total number of known states are also returned. Otherwise
 // these are zero.
+//
+// While a sync is running, the phase it is waiting on is reported too, together
+// with the rate items of that phase are processed at and the estimated time left
+// until the phase completes.
 func (d *Downloader) Progress() wtc.SyncProgress {
 	// Lock the current stats and return the progress
 	d.syncStatsLock.RLock()
 	defer d.syncStatsLock.RUnlock()
 
-	current := uint64(0)
-	switch d.mode {
-	case FullSync:
-		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
-		current = d.blockchain.CurrentFastBlock().NumberU64()
-	case LightSync:
-		current = d.lightchain.CurrentHeader().Number.Uint64()
-	}
-	return wtc.SyncProgress{
+	current := d.currentBlock()
+	progress := wtc.SyncProgress{
 		StartingBlock: d.syncStatsChainOrigin,
 		CurrentBlock:  current,
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
 	}
+	if !d.Synchronising() {
+		return progress
+	}
+	switch {
+	case d.queue.PendingReceipts() > 0:
+		progress.Phase = PhaseReceipts
+	case d.queue.PendingBlocks() > 0:
+		progress.Phase = PhaseBodies
+	default:
+		progress.Phase = PhaseHeaders
+	}
+	done, left := uint64(0), uint64(0)
+	if current > d.syncStatsStartBlock {
+		done = current - d.syncStatsStartBlock
+	}
+	if d.syncStatsChainHeight > current {
+		left = d.syncStatsChainHeight - current
+	}
+	progress.Throughput, progress.ETA = estimateRate(done, left, time.Since(d.syncStatsStart))
+	return progress
+}
+
+// currentBlock retrieves the head the current sync mode is advancing.
+func (d *Downloader) currentBlock() uint64 {
+	switch d.mode {
+	case FullSync:
+		return d.blockchain.CurrentBlock().NumberU64()
+	case FastSync:
+		return d.blockchain.CurrentFastBlock().NumberU64()
+	case LightSync:
+		return d.lightchain.CurrentHeader().Number.Uint64()
+	}
+	return 0
+}
+
+// estimateRate calculates the number of items processed per second, and the time
+// needed to process the remaining items at that rate.
+func estimateRate(done, left uint64, elapsed time.Duration) (uint64, time.Duration) {
+	if done == 0 || elapsed <= 0 {
+		return 0, 0
+	}
+	rate := float64(done) / elapsed.Seconds()
+	return uint64(rate), time.Duration(float64(left) / rate * float64(time.Second))
+}
+
+// SetCheckpoint updates the trusted checkpoint of the downloader. Peers whose
+// chain doesn't contain the checkpoint's section head are rejected while the
+// local chain is below it. Checkpoints without a section head or older than the
+// one already in use are ignored.
+func (d *Downloader) SetCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	if checkpoint == nil || checkpoint.SectionHead == (common.Hash{}) {
+		return
+	}
+	d.checkpointLock.Lock()
+	defer d.checkpointLock.Unlock()
+
+	if d.checkpoint != nil && d.checkpoint.SectionIndex > checkpoint.SectionIndex {
+		return
+	}
+	d.checkpoint = checkpoint
+	log.Debug("Updated trusted checkpoint", "section", checkpoint.SectionIndex, "head", checkpoint.SectionHead)
+}
+
+// Checkpoint retrieves the trusted checkpoint of the downloader, if any.
+func (d *Downloader) Checkpoint() *params.TrustedCheckpoint {
+	d.checkpointLock.RLock()
+	defer d.checkpointLock.RUnlock()
+
+	return d.checkpoint
 }
 
 // Synchronising returns whether the downloader is currently retrieving blocks.
@@ -324,7 +407,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
 		errInvalidAncestor, errInvalidChain:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
-		d.dropPeer(id)
+		d.dropPeer(id, err)
 
 	default:
 		log.Warn("Synchronisation failed, retrying", "err", err)
@@ -425,6 +508,11 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
+	// Make sure the peer is on the chain of the trusted checkpoint, if below it
+	atomic.StoreInt32(&d.checkpointLinked, 0)
+	if d.checkpointHead, err = d.fetchCheckpoint(p, height); err != nil {
+		return err
+	}
 	origin, err := d.findAncestor(p, height)
 	if err != nil {
 		return err
@@ -434,6 +522,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		d.syncStatsChainOrigin = origin
 	}
 	d.syncStatsChainHeight = height
+	d.syncStatsStart, d.syncStatsStartBlock = time.Now(), d.currentBlock()
 	d.syncStatsLock.Unlock()
 
 	// Initiate the sync using a concurrent header and content retrieval algorithm
@@ -593,6 +682,60 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// fetchCheckpoint challenges the remote peer with the trusted checkpoint if the
+// local chain is still below it, retrieving the peer's header at the height of
+// the checkpoint. Peers not having reached the checkpoint and peers on a chain
+// forking off before it are rejected.
+func (d *Downloader) fetchCheckpoint(p *peerConnection, height uint64) (*types.Header, error) {
+	checkpoint := d.Checkpoint()
+	if checkpoint == nil || d.lightchain.CurrentHeader().Number.Uint64() >= checkpoint.HeadNumber() {
+		return nil, nil
+	}
+	number := checkpoint.HeadNumber()
+	if height < number {
+		p.log.Debug("Remote head below trusted checkpoint", "number", height, "checkpoint", number)
+		return nil, errUnsyncedPeer
+	}
+	p.log.Debug("Retrieving remote checkpoint header", "number", number)
+	go p.peer.RequestHeadersByNumber(number, 1, 0, false)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			// Make sure the peer's chain contains the checkpoint
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				p.log.Debug("Multiple headers for single request", "headers", len(headers))
+				return nil, errBadPeer
+			}
+			header := headers[0]
+			if header.Number.Uint64() != number || header.Hash() != checkpoint.SectionHead {
+				p.log.Warn("Remote chain forks before trusted checkpoint", "number", header.Number, "hash", header.Hash(), "checkpoint", checkpoint.SectionHead)
+				return nil, errInvalidChain
+			}
+			return header, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint header timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -856,7 +999,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 			// Header retrieval timed out, consider the peer bad and drop
 			p.log.Debug("Header request timed out", "elapsed", ttl)
 			headerTimeoutMeter.Mark(1)
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, errTimeout)
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh} {
@@ -1071,7 +1214,7 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 						setIdle(peer, 0)
 					} else {
 						peer.log.Debug("Stalling delivery, dropping", "type", kind)
-						d.dropPeer(pid)
+						d.dropPeer(pid, errStallingPeer)
 					}
 				}
 			}
@@ -1187,6 +1330,9 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 		}
 	}()
 
+	// Keep the last header leading up to the trusted checkpoint
+	var linked *types.Header
+
 	// Wait for batches of headers to process
 	gotHeaders := false
 
@@ -1255,6 +1401,28 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 				}
 				chunk := headers[:limit]
 
+				// While below the trusted checkpoint, ensure the chain leads up to it
+				if head := d.checkpointHead; head != nil && atomic.LoadInt32(&d.checkpointLinked) == 0 {
+					for _, header := range chunk {
+						if header.Number.Cmp(head.Number) > 0 {
+							break
+						}
+						if linked != nil && header.ParentHash != linked.Hash() {
+							log.Warn("Chain broke ancestry below trusted checkpoint", "number", header.Number, "hash", header.Hash(), "parent", header.ParentHash)
+							return errInvalidChain
+						}
+						linked = header
+					}
+					if linked != nil && linked.Number.Cmp(head.Number) == 0 {
+						if linked.Hash() != head.Hash() {
+							log.Warn("Chain doesn't match trusted checkpoint", "number", linked.Number, "hash", linked.Hash(), "checkpoint", head.Hash())
+							return errInvalidChain
+						}
+						// Blocks scheduled so far are linked to the checkpoint, skip their seal checks
+						atomic.StoreInt32(&d.checkpointLinked, 1)
+					}
+				}
+
 				// In case of header only syncing, validate the chunk immediately
 // 				if d.mode == FastSync || d.mode == LightSync {
 // 					// Collect the yet unknown headers to mark them as uncertain
@@ -1365,7 +1533,16 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 		for i, result := range results[:items] {
 			blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
 		}
-		if index, err := d.blockchain.InsertChain(blocks); err != nil {
+		var (
+			index int
+			err   error
+		)
+		if atomic.LoadInt32(&d.checkpointLinked) == 1 {
+			index, err = d.blockchain.InsertTrustedChain(blocks, d.checkpointHead)
+		} else {
+			index, err = d.blockchain.InsertChain(blocks)
+		}
+		if err != nil {
 			log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
 			return errInvalidChain
 		}
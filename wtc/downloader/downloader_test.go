@@ -61,6 +61,7 @@ type downloadTester struct {
 	ownBlocks   map[common.Hash]*types.Block   // Blocks belonging to the tester
 	ownReceipts map[common.Hash]types.Receipts // Receipts belonging to the tester
 	ownChainTd  map[common.Hash]*big.Int       // Total difficulties of the blocks in the local chain
+	ownTrusted  []*types.Header                // Headers of the blocks imported without seal checks
 
 	peerHashes   map[string][]common.Hash                  // Hash chain belonging to different test peers
 	peerHeaders  map[string]map[common.Hash]*types.Header  // Headers belonging to different test peers
@@ -307,10 +308,15 @@ func (dl *downloadTester) GetTdByHash(hash common.Hash) *big.Int {
 }
 
 // InsertHeaderChain injects a new batch of headers into the simulated chain.
-func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq int) (int, error) {
+func (dl *downloadTester) InsertHeaderChain(blocks types.Blocks) (int, error) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+
 	// Do a quick check, as the blockchain.InsertHeaderChain doesn't insert anything in case of errors
 	if _, ok := dl.ownHeaders[headers[0].ParentHash]; !ok {
 		return 0, errors.New("unknown parent")
@@ -375,6 +381,20 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 	return len(blocks), nil
 }
 
+// InsertTrustedChain injects a new batch of blocks into the simulated chain,
+// recording the ones whose seal checks are skipped due to the checkpoint.
+func (dl *downloadTester) InsertTrustedChain(blocks types.Blocks, checkpoint *types.Header) (int, error) {
+	dl.lock.Lock()
+	for _, block := range blocks {
+		if block.NumberU64() <= checkpoint.Number.Uint64() {
+			dl.ownTrusted = append(dl.ownTrusted, block.Header())
+		}
+	}
+	dl.lock.Unlock()
+
+	return dl.InsertChain(blocks)
+}
+
 // Rollback removes some recently added elements from the chain.
 func (dl *downloadTester) Rollback(hashes []common.Hash) {
 	dl.lock.Lock()
@@ -449,7 +469,7 @@ func (dl *downloadTester) newSlowPeer(id string, version int, hashes []common.Ha
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, reason error) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -1405,6 +1425,9 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != 0 || progress.CurrentBlock != 0 || progress.HighestBlock != uint64(targetBlocks/2+1) {
 		t.Fatalf("Initial progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, 0, 0, targetBlocks/2+1)
 	}
+	if progress := tester.downloader.Progress(); progress.Phase != PhaseHeaders {
+		t.Fatalf("Initial phase mismatch: have %q, want %q", progress.Phase, PhaseHeaders)
+	}
 	progress <- struct{}{}
 	pending.Wait()
 
@@ -1429,6 +1452,9 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != uint64(targetBlocks/2+1) || progress.CurrentBlock != uint64(targetBlocks) || progress.HighestBlock != uint64(targetBlocks) {
 		t.Fatalf("Final progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, targetBlocks/2+1, targetBlocks, targetBlocks)
 	}
+	if progress := tester.downloader.Progress(); progress.Phase != "" || progress.ETA != 0 {
+		t.Fatalf("Final phase mismatch: have %q (eta %v), want none", progress.Phase, progress.ETA)
+	}
 }
 
 // Tests that synchronisation progress (origin block number and highest block
@@ -1758,7 +1784,7 @@ func testFastCriticalRestarts(t *testing.T, protocol int, progress bool) {
 	// Create a tester peer with a critical section header missing (force failures)
 	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
 	delete(tester.peerHeaders["peer"], hashes[fsMinFullBlocks-1])
-	tester.downloader.dropPeer = func(id string) {} // We reuse the same "faulty" peer throughout the test
+	tester.downloader.dropPeer = func(id string, reason error) {} // We reuse the same "faulty" peer throughout the test
 
 	// Remove all possible pivot state roots and slow down replies (test failure resets later)
 	for i := 0; i < fsPivotInterval; i++ {
@@ -1820,3 +1846,86 @@ func testFastCriticalRestarts(t *testing.T, protocol int, progress bool) {
 	// completed using a single mode of operation, whereas fast-then-slow can result
 	// in arbitrary intermediate state that's not cleanly verifiable.
 }
+
+// makeCheckpoint creates a trusted checkpoint whose section head is the block at
+// the checkpoint height in the given hash chain (ordered head->parent).
+func makeCheckpoint(hashes []common.Hash) *params.TrustedCheckpoint {
+	checkpoint := &params.TrustedCheckpoint{CHTRoot: common.HexToHash("0x01")}
+	checkpoint.SectionHead = hashes[uint64(len(hashes)-1)-checkpoint.HeadNumber()]
+	return checkpoint
+}
+
+// Tests that syncing against a peer on the chain of the trusted checkpoint
+// succeeds, with only the blocks linked to the checkpoint skipping their seal
+// checks.
+func TestCheckpointSynchronisation(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := int(params.CHTFrequency) + 64
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	checkpoint := makeCheckpoint(hashes)
+	tester.downloader.SetCheckpoint(checkpoint)
+
+	if err := tester.sync("peer", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+
+	trusted := make(map[common.Hash]bool)
+	for _, header := range tester.ownTrusted {
+		if header.Number.Uint64() > checkpoint.HeadNumber() {
+			t.Errorf("block #%d above checkpoint #%d trusted", header.Number, checkpoint.HeadNumber())
+		}
+		trusted[header.Hash()] = true
+	}
+	if !trusted[checkpoint.SectionHead] {
+		t.Errorf("checkpoint block not trusted")
+	}
+}
+
+// Tests that peers on chains forking off before the trusted checkpoint, as well
+// as peers not having reached it yet, are rejected while the local chain is
+// below the checkpoint, but not challenged any more once above it.
+func TestCheckpointChallenge(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := int(params.CHTFrequency) + 64
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	tester.newPeer("short", 64, hashes[128:], headers, blocks, receipts)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	checkpoint := makeCheckpoint(hashes)
+	forged := *checkpoint
+	forged.SectionHead = common.HexToHash("0xdeadbeef")
+
+	// A peer below the checkpoint cannot prove its chain
+	tester.downloader.SetCheckpoint(checkpoint)
+	if err := tester.sync("short", nil, FullSync); err != errUnsyncedPeer {
+		t.Fatalf("unsynced peer error mismatch: have %v, want %v", err, errUnsyncedPeer)
+	}
+	// A peer on a different chain at the checkpoint height must be rejected
+	tester.downloader.SetCheckpoint(&forged)
+	if err := tester.sync("peer", nil, FullSync); err != errInvalidChain {
+		t.Fatalf("forked peer error mismatch: have %v, want %v", err, errInvalidChain)
+	}
+	assertOwnChain(t, tester, 1)
+
+	// Once synced past the checkpoint, peers are not challenged any more
+	tester.downloader.SetCheckpoint(checkpoint)
+	if err := tester.sync("peer", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	tester.downloader.SetCheckpoint(&forged)
+	if err := tester.sync("peer", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise synced chain: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
@@ -27,6 +27,13 @@ const (
 	LightSync                 // Download only the headers and terminate afterwards
 )
 
+// Phases of a synchronisation cycle, as reported by the downloader progress.
+const (
+	PhaseHeaders  = "headers"  // Waiting on the header chain
+	PhaseBodies   = "bodies"   // Waiting on block bodies
+	PhaseReceipts = "receipts" // Waiting on block receipts
+)
+
 func (mode SyncMode) IsValid() bool {
 	return mode >= FullSync && mode <= LightSync
 }
@@ -29,6 +29,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/golang-lru"
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/event"
 	"github.com/wtc/go-wtc/log"
@@ -37,6 +38,7 @@ import (
 const (
 	maxLackingHashes  = 4096 // Maximum number of entries allowed on the list or lacking items
 	measurementImpact = 0.1  // The impact a single measurement has on a peer's final throughput value.
+	maxDepartedPeers  = 256  // Maximum number of departed peers to remember the throughput measurements of
 )
 
 var (
@@ -346,10 +348,18 @@ func (p *peerConnection) Lacks(hash common.Hash) bool {
 	return ok
 }
 
+// peerMeasurements are the throughput and latency estimates of a departed peer,
+// kept to avoid re-measuring it from scratch should it reconnect.
+type peerMeasurements struct {
+	headers, blocks, receipts, states float64
+	rtt                               time.Duration
+}
+
 // peerSet represents the collection of active peer participating in the chain
 // download procedure.
 type peerSet struct {
 	peers        map[string]*peerConnection
+	departed     *lru.Cache // Measurements of recently departed peers, restored on reconnect
 	newPeerFeed  event.Feed
 	peerDropFeed event.Feed
 	lock         sync.RWMutex
@@ -357,8 +367,10 @@ type peerSet struct {
 
 // newPeerSet creates a new peer set top track the active download sources.
 func newPeerSet() *peerSet {
+	departed, _ := lru.New(maxDepartedPeers)
 	return &peerSet{
-		peers: make(map[string]*peerConnection),
+		peers:    make(map[string]*peerConnection),
+		departed: departed,
 	}
 }
 
@@ -387,8 +399,9 @@ func (ps *peerSet) Reset() {
 // peer is already known.
 //
 // The method also sets the starting throughput values of the new peer to the
-// average of all existing peers, to give it a realistic chance of being used
-// for data retrievals.
+// ones measured during its previous connection, or failing that to the average
+// of all existing peers, to give it a realistic chance of being used for data
+// retrievals.
 func (ps *peerSet) Register(p *peerConnection) error {
 	// Retrieve the current median RTT as a sane default
 	p.rtt = ps.medianRTT()
@@ -399,7 +412,11 @@ func (ps *peerSet) Register(p *peerConnection) error {
 		ps.lock.Unlock()
 		return errAlreadyRegistered
 	}
-	if len(ps.peers) > 0 {
+	if m, ok := ps.departed.Get(p.id); ok {
+		m := m.(peerMeasurements)
+		p.headerThroughput, p.blockThroughput, p.receiptThroughput, p.stateThroughput = m.headers, m.blocks, m.receipts, m.states
+		p.rtt = m.rtt
+	} else if len(ps.peers) > 0 {
 		p.headerThroughput, p.blockThroughput, p.receiptThroughput, p.stateThroughput = 0, 0, 0, 0
 
 		for _, peer := range ps.peers {
@@ -434,6 +451,16 @@ func (ps *peerSet) Unregister(id string) error {
 	delete(ps.peers, id)
 	ps.lock.Unlock()
 
+	p.lock.RLock()
+	ps.departed.Add(id, peerMeasurements{
+		headers:  p.headerThroughput,
+		blocks:   p.blockThroughput,
+		receipts: p.receiptThroughput,
+		states:   p.stateThroughput,
+		rtt:      p.rtt,
+	})
+	p.lock.RUnlock()
+
 	ps.peerDropFeed.Send(p)
 	return nil
 }
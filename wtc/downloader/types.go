@@ -22,8 +22,9 @@ import (
 	"github.com/wtc/go-wtc/core/types"
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious, with
+// the reason being the misbehaviour it was caught at.
+type peerDropFn func(id string, reason error)
 
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
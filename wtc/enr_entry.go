@@ -0,0 +1,86 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/forkid"
+	"github.com/wtc/go-wtc/log"
+	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/rlp"
+)
+
+// ethEntryKey is the key of the Wtc protocol entry in the node record.
+const ethEntryKey = "eth"
+
+// ethEntry is the node record entry advertising the fork ID of the chain the node
+// is on, allowing dialers to skip incompatible nodes without connecting.
+type ethEntry struct {
+	ForkID forkid.ID
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// currentEthEntry constructs the eth entry of the current chain head.
+func currentEthEntry(chain forkid.Blockchain) *ethEntry {
+	return &ethEntry{ForkID: forkid.NewIDFromChain(chain)}
+}
+
+// startEthEntryUpdate publishes the eth entry in the local node record and keeps
+// it current as the chain head passes fork blocks. The updater terminates when
+// the blockchain is stopped.
+func (s *Wtc) startEthEntryUpdate(srvr *p2p.Server) {
+	entry := currentEthEntry(s.blockchain)
+	if err := srvr.SetRecordEntry(ethEntryKey, entry); err != nil {
+		log.Warn("Failed to publish fork ID in node record", "err", err)
+	}
+	newHead := make(chan core.ChainHeadEvent, 10)
+	sub := s.blockchain.SubscribeChainHeadEvent(newHead)
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-newHead:
+				next := currentEthEntry(s.blockchain)
+				if next.ForkID == entry.ForkID {
+					continue
+				}
+				entry = next
+				if err := srvr.SetRecordEntry(ethEntryKey, entry); err != nil {
+					log.Warn("Failed to update fork ID in node record", "err", err)
+				}
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// newEthDialFilter creates a dial filter accepting the nodes whose record carries
+// an eth entry with a fork ID passing the given filter.
+func newEthDialFilter(forkFilter forkid.Filter) func(*discover.Record) bool {
+	return func(record *discover.Record) bool {
+		var entry ethEntry
+		if err := record.Load(ethEntryKey, &entry); err != nil {
+			return false
+		}
+		return forkFilter(entry.ForkID) == nil
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wtc/go-wtc/core/forkid"
+	"github.com/wtc/go-wtc/p2p/discover"
+	"github.com/wtc/go-wtc/rlp"
+	"github.com/wtc/go-wtc/wtc/downloader"
+)
+
+// Tests that the eth dial filter only accepts node records advertising a
+// compatible fork ID.
+func TestEthDialFilter(t *testing.T) {
+	local := forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}}
+	filter := newEthDialFilter(func(id forkid.ID) error {
+		if id != local {
+			return errors.New("incompatible fork ID")
+		}
+		return nil
+	})
+	// Entries with additional fields must be accepted for forward compatibility
+	extended := struct {
+		ForkID forkid.ID
+		Extra  uint64
+	}{local, 42}
+
+	tests := []struct {
+		name  string
+		entry interface{}
+		want  bool
+	}{
+		{"compatible", &ethEntry{ForkID: local}, true},
+		{"extended", &extended, true},
+		{"incompatible", &ethEntry{ForkID: forkid.ID{Hash: [4]byte{1, 2, 3, 4}}}, false},
+		{"missing", nil, false},
+		{"malformed", []byte{0x01}, false},
+	}
+	for _, tt := range tests {
+		record := new(discover.Record)
+		if tt.entry != nil {
+			if err := record.Set(ethEntryKey, tt.entry); err != nil {
+				t.Fatalf("%s: failed to set entry: %v", tt.name, err)
+			}
+		}
+		if ok := filter(record); ok != tt.want {
+			t.Errorf("%s: filter result mismatch: have %v, want %v", tt.name, ok, tt.want)
+		}
+	}
+}
+
+// Tests that the eth entry carries the fork ID used in the status handshake.
+func TestEthEntryForkID(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 16, nil, nil)
+	defer pm.Stop()
+
+	entry := currentEthEntry(pm.blockchain)
+	if want := forkid.NewIDFromChain(pm.blockchain); entry.ForkID != want {
+		t.Fatalf("fork ID mismatch: have %v, want %v", entry.ForkID, want)
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		t.Fatalf("failed to encode entry: %v", err)
+	}
+	var decoded ethEntry
+	if err := rlp.DecodeBytes(blob, &decoded); err != nil || decoded.ForkID != entry.ForkID {
+		t.Fatalf("entry round trip failed: have %v, %v, want %v", decoded.ForkID, err, entry.ForkID)
+	}
+}
@@ -41,3 +41,13 @@ var (
 	bodyFilterInMeter    = metrics.NewMeter("eth/fetcher/filter/bodies/in")
 	bodyFilterOutMeter   = metrics.NewMeter("eth/fetcher/filter/bodies/out")
 )
+
+var (
+	txAnnounceInMeter     = metrics.NewMeter("eth/fetcher/transaction/announces/in")
+	txAnnounceKnownMeter  = metrics.NewMeter("eth/fetcher/transaction/announces/known")
+	txAnnounceDOSMeter    = metrics.NewMeter("eth/fetcher/transaction/announces/dos")
+	txBroadcastInMeter    = metrics.NewMeter("eth/fetcher/transaction/broadcasts/in")
+	txReplyInMeter        = metrics.NewMeter("eth/fetcher/transaction/replies/in")
+	txRequestOutMeter     = metrics.NewMeter("eth/fetcher/transaction/request/out")
+	txRequestTimeoutMeter = metrics.NewMeter("eth/fetcher/transaction/request/timeout")
+)
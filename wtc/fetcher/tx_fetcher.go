@@ -0,0 +1,473 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/log"
+)
+
+const (
+	txArriveTimeout = 500 * time.Millisecond // Time allowance before an announced transaction is explicitly requested
+	txGatherSlack   = 100 * time.Millisecond // Interval used to collate almost-expired announces with fetches
+	txFetchTimeout  = 5 * time.Second        // Maximum allotted time to return an explicitly requested transaction
+	maxTxAnnounces  = 4096                   // Maximum number of unique transactions a peer may have announced
+	maxTxRetrievals = 256                    // Maximum number of transactions to retrieve in a single request
+)
+
+// txRetrievalFn is a callback type for checking whether a transaction is
+// already known to the local pool.
+type txRetrievalFn func(common.Hash) bool
+
+// txAdderFn is a callback type for injecting a batch of transactions into the
+// local pool.
+type txAdderFn func([]*types.Transaction) error
+
+// txRequesterFn is a callback type for requesting a batch of transactions from
+// a remote peer.
+type txRequesterFn func(peer string, hashes []common.Hash) error
+
+// txAnnounce is the notification of the availability of a batch of new
+// transactions in the network.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes being announced
+}
+
+// txRequest represents an in-flight transaction retrieval request.
+type txRequest struct {
+	hashes  []common.Hash // Transactions having been requested
+	time    time.Time     // Timestamp of the request
+	expired bool          // Whether the request timed out, still blocking the peer until it replies
+}
+
+// txDelivery is the notification that a batch of transactions have been added
+// to the pool and should be untracked.
+type txDelivery struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes having been delivered
+	direct bool          // Whether this is a direct reply or a broadcast
+}
+
+// TxFetcher is responsible for retrieving new transactions based on
+// announcements. Announced transactions first wait a short while for a
+// broadcast to deliver them anyway, after which they are requested from one
+// of the announcing peers at a time, moving on to the next if a request
+// times out or comes back without the transaction.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	cleanup chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Stage 1: Announced transactions waiting for a broadcast to arrive
+	waitlist  map[common.Hash]map[string]struct{} // Transactions waiting for an arrival timeout, with their announcers
+	waittime  map[common.Hash]time.Time           // Timestamps of the first announcement of the waiting transactions
+	waitslots map[string]map[common.Hash]struct{} // Waiting announcements grouped by peer (DOS protection)
+
+	// Stage 2: Announced transactions scheduled for retrieval
+	announces map[string]map[common.Hash]struct{} // Retrievable announcements grouped by peer
+	announced map[common.Hash]map[string]struct{} // Peers able to deliver each announced transaction
+	fetching  map[common.Hash]string              // Transactions currently being retrieved, with the peer asked
+	requests  map[string]*txRequest               // In-flight retrieval requests, one at most per peer
+
+	// Callbacks
+	hasTx    txRetrievalFn // Checks whether a transaction is already in the pool
+	addTxs   txAdderFn     // Inserts a batch of transactions into the pool
+	fetchTxs txRequesterFn // Requests a batch of transactions from a peer
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon starting a transaction retrieval
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txRetrievalFn, addTxs txAdderFn, fetchTxs txRequesterFn) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txAnnounce),
+		cleanup:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		waitlist:  make(map[common.Hash]map[string]struct{}),
+		waittime:  make(map[common.Hash]time.Time),
+		waitslots: make(map[string]map[common.Hash]struct{}),
+		announces: make(map[string]map[common.Hash]struct{}),
+		announced: make(map[common.Hash]map[string]struct{}),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+	}
+}
+
+// Start boots up the announcement based transaction retrieval, processing the
+// notifications and deliveries until termination requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retrieval, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a batch of
+// transactions in the network.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	// Skip the transactions already known, no need to bother the loop with them
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if !f.hasTx(hash) {
+			unknown = append(unknown, hash)
+		}
+	}
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+	txAnnounceKnownMeter.Mark(int64(len(hashes) - len(unknown)))
+
+	if len(unknown) == 0 {
+		return nil
+	}
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: unknown}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue injects a batch of transactions received from a peer into the pool,
+// either broadcast or a reply to a retrieval, and stops tracking them.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) error {
+	if direct {
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	if err := f.addTxs(txs); err != nil {
+		log.Trace("Failed to add fetched transactions", "peer", peer, "err", err)
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	select {
+	case f.cleanup <- &txDelivery{origin: peer, hashes: hashes, direct: direct}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop should be called when a peer disconnects, cleaning up all its tracked
+// announcements and rescheduling its in-flight retrievals.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main transaction fetcher loop, checking and processing various
+// notification events.
+func (f *TxFetcher) loop() {
+	var (
+		waitTimer    *time.Timer
+		waitTrigger  <-chan time.Time
+		fetchTimer   *time.Timer
+		fetchTrigger <-chan time.Time
+	)
+	// rescheduleWait arms the wait timer for the earliest waiting announcement
+	rescheduleWait := func() {
+		if waitTimer != nil {
+			waitTimer.Stop()
+		}
+		waitTimer, waitTrigger = nil, nil
+		if len(f.waittime) == 0 {
+			return
+		}
+		earliest := time.Now()
+		for _, instance := range f.waittime {
+			if instance.Before(earliest) {
+				earliest = instance
+			}
+		}
+		waitTimer = time.NewTimer(txArriveTimeout - time.Since(earliest))
+		waitTrigger = waitTimer.C
+	}
+	// rescheduleFetch arms the timeout timer for the earliest in-flight request
+	rescheduleFetch := func() {
+		if fetchTimer != nil {
+			fetchTimer.Stop()
+		}
+		fetchTimer, fetchTrigger = nil, nil
+		earliest, active := time.Now(), false
+		for _, req := range f.requests {
+			if !req.expired && !req.time.After(earliest) {
+				earliest, active = req.time, true
+			}
+		}
+		if !active {
+			return
+		}
+		fetchTimer = time.NewTimer(txFetchTimeout - time.Since(earliest))
+		fetchTrigger = fetchTimer.C
+	}
+	defer func() {
+		if waitTimer != nil {
+			waitTimer.Stop()
+		}
+		if fetchTimer != nil {
+			fetchTimer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-f.quit:
+			// Fetcher terminating, abort all operations
+			return
+
+		case ann := <-f.notify:
+			// Transactions were announced, make sure the peer isn't DOSing us
+			used := len(f.waitslots[ann.origin]) + len(f.announces[ann.origin])
+			if used+len(ann.hashes) > maxTxAnnounces {
+				log.Debug("Peer exceeded outstanding transaction announces", "peer", ann.origin, "limit", maxTxAnnounces)
+				txAnnounceDOSMeter.Mark(int64(used + len(ann.hashes) - maxTxAnnounces))
+				if used >= maxTxAnnounces {
+					break
+				}
+				ann.hashes = ann.hashes[:maxTxAnnounces-used]
+			}
+			idleWait, schedule := len(f.waittime) == 0, false
+			for _, hash := range ann.hashes {
+				// If the transaction is already scheduled for retrieval, add an alternate source
+				if peers := f.announced[hash]; peers != nil {
+					peers[ann.origin] = struct{}{}
+					f.addAnnounce(ann.origin, hash)
+					schedule = true
+					continue
+				}
+				// Otherwise wait a bit for a broadcast to deliver it
+				if f.waitlist[hash] == nil {
+					f.waitlist[hash] = make(map[string]struct{})
+					f.waittime[hash] = time.Now()
+				}
+				f.waitlist[hash][ann.origin] = struct{}{}
+				if f.waitslots[ann.origin] == nil {
+					f.waitslots[ann.origin] = make(map[common.Hash]struct{})
+				}
+				f.waitslots[ann.origin][hash] = struct{}{}
+			}
+			if idleWait && len(f.waittime) > 0 {
+				rescheduleWait()
+			}
+			if schedule {
+				f.scheduleFetches()
+				rescheduleFetch()
+			}
+
+		case <-waitTrigger:
+			// Move the announcements waited out (or almost) to the retrieval stage
+			for hash, instance := range f.waittime {
+				if time.Since(instance)+txGatherSlack < txArriveTimeout {
+					continue
+				}
+				f.announced[hash] = make(map[string]struct{})
+				for peer := range f.waitlist[hash] {
+					f.announced[hash][peer] = struct{}{}
+					f.addAnnounce(peer, hash)
+					f.removeWaitSlot(peer, hash)
+				}
+				delete(f.waitlist, hash)
+				delete(f.waittime, hash)
+			}
+			rescheduleWait()
+			f.scheduleFetches()
+			rescheduleFetch()
+
+		case <-fetchTrigger:
+			// Retrieval requests timed out, move to the alternate sources
+			for peer, req := range f.requests {
+				if req.expired || time.Since(req.time) < txFetchTimeout {
+					continue
+				}
+				log.Trace("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+				txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+
+				// Keep the request around so a late reply isn't mistaken for
+				// the reply of a new request to the same peer
+				f.release(peer, req)
+				req.expired = true
+			}
+			f.scheduleFetches()
+			rescheduleFetch()
+
+		case delivery := <-f.cleanup:
+			// Transactions arrived, stop tracking them from all sources
+			for _, hash := range delivery.hashes {
+				if peers, ok := f.waitlist[hash]; ok {
+					for peer := range peers {
+						f.removeWaitSlot(peer, hash)
+					}
+					delete(f.waitlist, hash)
+					delete(f.waittime, hash)
+				}
+				f.forgetTx(hash)
+			}
+			// If it was a reply to our request, the missing transactions are not
+			// available from the peer, retrieve them from alternate sources
+			if req := f.requests[delivery.origin]; req != nil && delivery.direct {
+				f.unrequest(delivery.origin, req)
+			}
+			rescheduleWait()
+			f.scheduleFetches()
+			rescheduleFetch()
+
+		case peer := <-f.drop:
+			// A peer disconnected, forget all its announcements
+			for hash := range f.waitslots[peer] {
+				delete(f.waitlist[hash], peer)
+				if len(f.waitlist[hash]) == 0 {
+					delete(f.waitlist, hash)
+					delete(f.waittime, hash)
+				}
+			}
+			delete(f.waitslots, peer)
+
+			if req := f.requests[peer]; req != nil {
+				f.unrequest(peer, req)
+			}
+			for hash := range f.announces[peer] {
+				f.removeAnnounce(peer, hash)
+			}
+			rescheduleWait()
+			f.scheduleFetches()
+			rescheduleFetch()
+		}
+	}
+}
+
+// scheduleFetches requests the retrievable transactions not being retrieved
+// yet from the idle peers having announced them.
+func (f *TxFetcher) scheduleFetches() {
+	for peer, hashes := range f.announces {
+		if f.requests[peer] != nil {
+			continue
+		}
+		var fetch []common.Hash
+		for hash := range hashes {
+			if _, ok := f.fetching[hash]; ok {
+				continue
+			}
+			fetch = append(fetch, hash)
+			if len(fetch) == maxTxRetrievals {
+				break
+			}
+		}
+		if len(fetch) == 0 {
+			continue
+		}
+		for _, hash := range fetch {
+			f.fetching[hash] = peer
+		}
+		f.requests[peer] = &txRequest{hashes: fetch, time: time.Now()}
+		txRequestOutMeter.Mark(int64(len(fetch)))
+
+		if f.fetchingHook != nil {
+			f.fetchingHook(peer, fetch)
+		}
+		go func(peer string, hashes []common.Hash) {
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				log.Debug("Failed to request transactions", "peer", peer, "err", err)
+				f.Drop(peer)
+			}
+		}(peer, fetch)
+	}
+}
+
+// unrequest removes an in-flight request, releasing its transactions.
+func (f *TxFetcher) unrequest(peer string, req *txRequest) {
+	f.release(peer, req)
+	delete(f.requests, peer)
+}
+
+// release marks the requested transactions still not delivered unavailable
+// from the peer, so they are rescheduled to alternate sources.
+func (f *TxFetcher) release(peer string, req *txRequest) {
+	for _, hash := range req.hashes {
+		if f.fetching[hash] == peer {
+			delete(f.fetching, hash)
+			f.removeAnnounce(peer, hash)
+		}
+	}
+}
+
+// addAnnounce tracks a retrievable transaction announced by a peer.
+func (f *TxFetcher) addAnnounce(peer string, hash common.Hash) {
+	if f.announces[peer] == nil {
+		f.announces[peer] = make(map[common.Hash]struct{})
+	}
+	f.announces[peer][hash] = struct{}{}
+}
+
+// removeAnnounce untracks a retrievable transaction announced by a peer,
+// forgetting the transaction if no other peer can deliver it.
+func (f *TxFetcher) removeAnnounce(peer string, hash common.Hash) {
+	if hashes := f.announces[peer]; hashes != nil {
+		delete(hashes, hash)
+		if len(hashes) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+	if peers := f.announced[hash]; peers != nil {
+		delete(peers, peer)
+		if len(peers) == 0 {
+			delete(f.announced, hash)
+		}
+	}
+}
+
+// removeWaitSlot untracks a waiting transaction announced by a peer.
+func (f *TxFetcher) removeWaitSlot(peer string, hash common.Hash) {
+	if hashes := f.waitslots[peer]; hashes != nil {
+		delete(hashes, hash)
+		if len(hashes) == 0 {
+			delete(f.waitslots, peer)
+		}
+	}
+}
+
+// forgetTx removes all traces of a retrievable transaction from the fetcher.
+func (f *TxFetcher) forgetTx(hash common.Hash) {
+	for peer := range f.announced[hash] {
+		if hashes := f.announces[peer]; hashes != nil {
+			delete(hashes, hash)
+			if len(hashes) == 0 {
+				delete(f.announces, peer)
+			}
+		}
+	}
+	delete(f.announced, hash)
+	delete(f.fetching, hash)
+}
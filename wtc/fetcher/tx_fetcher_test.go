@@ -0,0 +1,247 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/core/types"
+)
+
+// txFetchEvent is a retrieval request issued by the transaction fetcher.
+type txFetchEvent struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// txFetcherTester is a test simulator for mocking out the transaction pool and
+// the retrieval requests.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+
+	pool map[common.Hash]*types.Transaction
+	lock sync.RWMutex
+
+	requests chan txFetchEvent
+}
+
+// newTxTester creates a new transaction fetcher test mocker.
+func newTxTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		pool:     make(map[common.Hash]*types.Transaction),
+		requests: make(chan txFetchEvent, 16),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTxs, tester.fetchTxs)
+	tester.fetcher.Start()
+	return tester
+}
+
+func (f *txFetcherTester) hasTx(hash common.Hash) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.pool[hash] != nil
+}
+
+func (f *txFetcherTester) addTxs(txs []*types.Transaction) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, tx := range txs {
+		f.pool[tx.Hash()] = tx
+	}
+	return nil
+}
+
+func (f *txFetcherTester) fetchTxs(peer string, hashes []common.Hash) error {
+	f.requests <- txFetchEvent{peer: peer, hashes: hashes}
+	return nil
+}
+
+// makeTxs creates a batch of distinct transactions.
+func makeTxs(n int) []*types.Transaction {
+	txs := make([]*types.Transaction, n)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+	}
+	return txs
+}
+
+func txHashes(txs []*types.Transaction) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
+// verifyTxFetch checks that a retrieval of the given transactions is issued to
+// the given peer.
+func verifyTxFetch(t *testing.T, requests chan txFetchEvent, peer string, hashes []common.Hash) {
+	select {
+	case req := <-requests:
+		if req.peer != peer {
+			t.Fatalf("request peer mismatch: have %s, want %s", req.peer, peer)
+		}
+		have := append([]common.Hash{}, req.hashes...)
+		want := append([]common.Hash{}, hashes...)
+		sort.Slice(have, func(i, j int) bool { return have[i].Big().Cmp(have[j].Big()) < 0 })
+		sort.Slice(want, func(i, j int) bool { return want[i].Big().Cmp(want[j].Big()) < 0 })
+		if len(have) != len(want) {
+			t.Fatalf("request size mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range have {
+			if have[i] != want[i] {
+				t.Fatalf("request hash %d mismatch: have %x, want %x", i, have[i], want[i])
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("retrieval timeout")
+	}
+}
+
+// verifyNoTxFetch checks that no retrieval is issued in the arrival window.
+func verifyNoTxFetch(t *testing.T, requests chan txFetchEvent) {
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected retrieval from %s: %x", req.peer, req.hashes)
+	case <-time.After(2 * txArriveTimeout):
+	}
+}
+
+// Tests that announced transactions are retrieved after the arrival timeout,
+// and that known ones are never requested.
+func TestTxAnnouncementRetrieval(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs := makeTxs(3)
+	tester.addTxs(txs[:1])
+
+	if err := tester.fetcher.Notify("A", txHashes(txs)); err != nil {
+		t.Fatalf("failed to announce transactions: %v", err)
+	}
+	verifyTxFetch(t, tester.requests, "A", txHashes(txs[1:]))
+
+	if err := tester.fetcher.Enqueue("A", txs[1:], true); err != nil {
+		t.Fatalf("failed to deliver transactions: %v", err)
+	}
+	for _, tx := range txs {
+		if !tester.hasTx(tx.Hash()) {
+			t.Errorf("transaction %x not added to the pool", tx.Hash())
+		}
+	}
+	verifyNoTxFetch(t, tester.requests)
+}
+
+// Tests that transactions broadcast while waiting for the arrival timeout are
+// not retrieved any more.
+func TestTxBroadcastCancelsRetrieval(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs := makeTxs(2)
+	tester.fetcher.Notify("A", txHashes(txs))
+	tester.fetcher.Enqueue("B", txs[:1], false)
+
+	verifyTxFetch(t, tester.requests, "A", txHashes(txs[1:]))
+}
+
+// Tests that transactions announced by multiple peers are only retrieved once,
+// moving to an alternate source if the first one doesn't deliver them.
+func TestTxRetrievalDeduplication(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs := makeTxs(2)
+	tester.fetcher.Notify("A", txHashes(txs))
+	verifyTxFetch(t, tester.requests, "A", txHashes(txs))
+
+	// A late announcement is only an alternate source, not requested again
+	tester.fetcher.Notify("B", txHashes(txs))
+	verifyNoTxFetch(t, tester.requests)
+
+	// A reply missing a transaction moves its retrieval to the alternate
+	tester.fetcher.Enqueue("A", txs[:1], true)
+	verifyTxFetch(t, tester.requests, "B", txHashes(txs[1:]))
+
+	tester.fetcher.Enqueue("B", txs[1:], true)
+	verifyNoTxFetch(t, tester.requests)
+}
+
+// Tests that the in-flight retrievals of a dropped peer are rescheduled to an
+// alternate source, and that transactions no one else announced are forgotten.
+func TestTxRetrievalPeerDrop(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	txs := makeTxs(2)
+	tester.fetcher.Notify("A", txHashes(txs))
+	tester.fetcher.Notify("B", txHashes(txs[:1]))
+
+	req := <-tester.requests
+	alternate := "B"
+	if req.peer == "B" {
+		alternate = "A"
+	}
+	tester.fetcher.Drop(req.peer)
+
+	if alternate == "A" {
+		verifyTxFetch(t, tester.requests, "A", txHashes(txs))
+	} else {
+		verifyTxFetch(t, tester.requests, "B", txHashes(txs[:1]))
+	}
+	tester.fetcher.Drop(alternate)
+	verifyNoTxFetch(t, tester.requests)
+}
+
+// Tests that peers can't make the fetcher track an unbounded number of
+// announcements.
+func TestTxAnnouncementDOSProtection(t *testing.T) {
+	tester := newTxTester()
+	defer tester.fetcher.Stop()
+
+	hashes := make([]common.Hash, maxTxAnnounces+16)
+	for i := range hashes {
+		hashes[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	tester.fetcher.Notify("A", hashes)
+
+	// Reply to every request empty handed, until all tracked announcements are exhausted
+	requested := 0
+	for done := false; !done; {
+		select {
+		case req := <-tester.requests:
+			if len(req.hashes) > maxTxRetrievals {
+				t.Fatalf("request size mismatch: have %d, want at most %d", len(req.hashes), maxTxRetrievals)
+			}
+			requested += len(req.hashes)
+			tester.fetcher.Enqueue("A", nil, true)
+
+		case <-time.After(2 * txArriveTimeout):
+			done = true
+		}
+	}
+	if requested != maxTxAnnounces {
+		t.Fatalf("retrieved announcement count mismatch: have %d, want %d", requested, maxTxAnnounces)
+	}
+}
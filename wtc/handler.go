@@ -29,6 +29,7 @@ import (
 	"github.com/wtc/go-wtc/common"
 	"github.com/wtc/go-wtc/consensus"
 	"github.com/wtc/go-wtc/core"
+	"github.com/wtc/go-wtc/core/forkid"
 	"github.com/wtc/go-wtc/core/types"
 	"github.com/wtc/go-wtc/wtc/downloader"
 	"github.com/wtc/go-wtc/wtc/fetcher"
@@ -44,6 +45,7 @@ import (
 const (
 	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned blocks, headers or node data.
 	estHeaderRlpSize  = 500             // Approximate size of an RLP encoded block header
+	maxPooledTxFetch  = 256             // Amount of pooled transactions to allow serving per request
 
 	// txChanSize is the size of channel listening to TxPreEvent.
 	// The number is referenced from the size of tx pool.
@@ -58,8 +60,22 @@ var (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// errInvalidBlock is the reason peers propagating invalid blocks are dropped for.
+var errInvalidBlock = errors.New("invalid block propagated")
+
+// protocolError is returned for messages breaching the protocol, allowing them
+// to be told apart from plain networking failures when scoring peers.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -72,10 +88,12 @@ type ProtocolManager struct {
 	blockchain  *core.BlockChain
 	chaindb     wtcdb.Database
 	chainconfig *params.ChainConfig
-	maxPeers    int
+	forkFilter  forkid.Filter // Fork ID filter, constant across the lifetime of the node
+	maxPeers    int32         // Accessed atomically, updated when the p2p server limit changes
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
+	txFetcher  *fetcher.TxFetcher
 	peers      *peerSet
 
 	SubProtocols []p2p.Protocol
@@ -107,6 +125,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chaindb:     chaindb,
 		chainconfig: config,
+		forkFilter:  forkid.NewFilter(blockchain),
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
@@ -123,6 +142,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
+	dialFilter := newEthDialFilter(manager.forkFilter)
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
 		if mode == downloader.FastSync && version < eth63 {
@@ -131,9 +151,10 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		// Compatible; initialise the sub-protocol
 		version := version // Closure for the run
 		manager.SubProtocols = append(manager.SubProtocols, p2p.Protocol{
-			Name:    ProtocolName,
-			Version: version,
-			Length:  ProtocolLengths[i],
+			Name:       ProtocolName,
+			Version:    version,
+			Length:     ProtocolLengths[i],
+			DialFilter: dialFilter,
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 				peer := manager.newPeer(int(version), p, rw)
 				select {
@@ -160,7 +181,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.dropPeer)
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -177,11 +198,40 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	dropper := func(id string) {
+		manager.dropPeer(id, errInvalidBlock)
+	}
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, dropper)
+
+	hasTx := func(hash common.Hash) bool {
+		return manager.txpool.Get(hash) != nil
+	}
+	fetchTxs := func(id string, hashes []common.Hash) error {
+		p := manager.peers.Peer(id)
+		if p == nil {
+			return errNotRegistered
+		}
+		return p.RequestTxs(hashes)
+	}
+	manager.txFetcher = fetcher.NewTxFetcher(hasTx, manager.txpool.AddRemotes, fetchTxs)
 
 	return manager, nil
 }
 
+// dropPeer penalises a peer caught misbehaving by the downloader or the block
+// fetcher, then disconnects it. Stalling peers lose less reputation than the
+// ones serving invalid data.
+func (pm *ProtocolManager) dropPeer(id string, reason error) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		if downloader.IsStalling(reason) {
+			peer.AdjustScore(p2p.ScoreTimeout)
+		} else {
+			peer.AdjustScore(p2p.ScoreInvalidData)
+		}
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -192,6 +242,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 	// Unregister the peer from the downloader and Wtc peer set
 	pm.downloader.UnregisterPeer(id)
+	pm.txFetcher.Drop(id)
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
@@ -202,9 +253,10 @@ func (pm *ProtocolManager) removePeer(id string) {
 }
 
 func (pm *ProtocolManager) Start(maxPeers int) {
-	pm.maxPeers = maxPeers
+	pm.setMaxPeers(maxPeers)
 
-	// broadcast transactions
+	// broadcast and retrieve transactions
+	pm.txFetcher.Start()
 	pm.txCh = make(chan core.TxPreEvent, txChanSize)
 	pm.txSub = pm.txpool.SubscribeTxPreEvent(pm.txCh)
 	go pm.txBroadcastLoop()
@@ -230,6 +282,7 @@ func (pm *ProtocolManager) Stop() {
 
 	// Quit fetcher, txsyncLoop.
 	close(pm.quitSync)
+	pm.txFetcher.Stop()
 
 	// Disconnect existing sessions.
 	// This also closes the gate for any new registrations on the peer set.
@@ -243,6 +296,11 @@ func (pm *ProtocolManager) Stop() {
 	log.Info("Wtc protocol stopped")
 }
 
+// setMaxPeers updates the maximum number of Wtc peers, applying to new peers.
+func (pm *ProtocolManager) setMaxPeers(n int) {
+	atomic.StoreInt32(&pm.maxPeers, int32(n))
+}
+
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 	return newPeer(pv, p, newMeteredMsgWriter(rw))
 }
@@ -250,14 +308,15 @@ func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *p
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	if pm.peers.Len() >= pm.maxPeers {
+	if pm.peers.Len() >= int(atomic.LoadInt32(&pm.maxPeers)) {
 		return p2p.DiscTooManyPeers
 	}
 	p.Log().Debug("Wtc peer connected", "name", p.Name())
 
 	// Execute the Wtc handshake
 	td, head, genesis := pm.blockchain.Status()
-	if err := p.Handshake(pm.networkId, td, head, genesis); err != nil {
+	forkID := forkid.NewIDFromChain(pm.blockchain)
+	if err := p.Handshake(pm.networkId, td, head, genesis, forkID, pm.forkFilter); err != nil {
 		p.Log().Debug("Wtc handshake failed", "err", err)
 		return err
 	}
@@ -283,6 +342,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Wtc message handling failed", "err", err)
+			if _, ok := err.(*protocolError); ok {
+				p.AdjustScore(p2p.ScoreProtocolViolation)
+			}
 			return err
 		}
 	}
@@ -392,6 +454,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&headers); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		// Only reward deliveries answering one of our requests, not unsolicited data
+		solicited := p.solicited(BlockHeadersMsg)
 
 		// Filter out any explicitly requested headers, deliver the rest to the downloader
 		filter := len(headers) == 1
@@ -404,6 +468,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			err := pm.downloader.DeliverHeaders(p.id, headers)
 			if err != nil {
 				log.Debug("Failed to deliver headers", "err", err)
+			} else if solicited && len(headers) > 0 {
+				p.AdjustScore(p2p.ScoreUsefulData)
 			}
 		}
 
@@ -440,6 +506,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&request); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		solicited := p.solicited(BlockBodiesMsg)
+
 		// Deliver them all to the downloader for queuing
 		trasactions := make([][]*types.Transaction, len(request))
 		uncles := make([][]*types.Header, len(request))
@@ -457,6 +525,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			err := pm.downloader.DeliverBodies(p.id, trasactions, uncles)
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
+			} else if solicited && len(trasactions) > 0 {
+				p.AdjustScore(p2p.ScoreUsefulData)
 			}
 		}
 
@@ -493,9 +563,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&data); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		solicited := p.solicited(NodeDataMsg)
+
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
+		} else if solicited && len(data) > 0 {
+			p.AdjustScore(p2p.ScoreUsefulData)
 		}
 
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
@@ -540,9 +614,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&receipts); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		solicited := p.solicited(ReceiptsMsg)
+
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
+		} else if solicited && len(receipts) > 0 {
+			p.AdjustScore(p2p.ScoreUsefulData)
 		}
 
 	case msg.Code == NewBlockHashesMsg:
@@ -614,7 +692,74 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		return pm.txFetcher.Enqueue(p.id, txs, false)
+
+	case p.version >= eth64 && msg.Code == NewPooledTransactionHashesMsg:
+		// New transactions were announced, schedule the unknown ones for retrieval
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, hash := range hashes {
+			p.MarkTransaction(hash)
+		}
+		return pm.txFetcher.Notify(p.id, hashes)
+
+	case p.version >= eth64 && msg.Code == GetPooledTransactionsMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather transactions until the fetch or network limits is reached
+		var (
+			hash   common.Hash
+			bytes  int
+			hashes []common.Hash
+			txs    []rlp.RawValue
+		)
+		for bytes < softResponseLimit && len(txs) < maxPooledTxFetch {
+			// Retrieve the hash of the next transaction
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Retrieve the requested transaction, skipping if unknown
+			tx := pm.txpool.Get(hash)
+			if tx == nil {
+				continue
+			}
+			encoded, err := rlp.EncodeToBytes(tx)
+			if err != nil {
+				log.Error("Failed to encode transaction", "err", err)
+				continue
+			}
+			hashes = append(hashes, hash)
+			txs = append(txs, encoded)
+			bytes += len(encoded)
+		}
+		return p.SendPooledTransactionsRLP(hashes, txs)
+
+	case p.version >= eth64 && msg.Code == PooledTransactionsMsg:
+		// Transactions arrived in reply to one of our retrievals
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for i, tx := range txs {
+			if tx == nil {
+				return errResp(ErrDecode, "transaction %d is nil", i)
+			}
+			p.MarkTransaction(tx.Hash())
+		}
+		return pm.txFetcher.Enqueue(p.id, txs, true)
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -655,16 +800,24 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 }
 
-// BroadcastTx will propagate a transaction to all peers which are not known to
-// already have the given transaction.
+// BroadcastTx will propagate a transaction to a square root subset of the peers
+// not known to already have it, announcing only its hash to the rest. Peers not
+// supporting announcements (eth/63 and older) are always sent the transaction.
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
-	// Broadcast transaction to a batch of peers not knowing about it
 	peers := pm.peers.PeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
-	for _, peer := range peers {
-		peer.SendTransactions(types.Transactions{tx})
+
+	transfer := int(math.Sqrt(float64(len(peers))))
+	var sent, announced int
+	for i, peer := range peers {
+		if i < transfer || peer.version < eth64 {
+			peer.SendTransactions(types.Transactions{tx})
+			sent++
+		} else {
+			peer.SendPooledTransactionHashes([]common.Hash{hash})
+			announced++
+		}
 	}
-	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
+	log.Trace("Broadcast transaction", "hash", hash, "recipients", sent, "announced", announced)
 }
 
 // Mined broadcast loop
@@ -21,19 +21,16 @@ import (
 	"math/big"
 	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/wtc/go-wtc/common"
-	"github.com/wtc/go-wtc/consensus/ethash"
 	"github.com/wtc/go-wtc/core"
 	"github.com/wtc/go-wtc/core/state"
 	"github.com/wtc/go-wtc/core/types"
-	"github.com/wtc/go-wtc/core/vm"
 	"github.com/wtc/go-wtc/crypto"
 	"github.com/wtc/go-wtc/wtc/downloader"
 	"github.com/wtc/go-wtc/wtcdb"
-	"github.com/wtc/go-wtc/event"
 	"github.com/wtc/go-wtc/p2p"
+	"github.com/wtc/go-wtc/p2p/discover"
 	"github.com/wtc/go-wtc/params"
 )
 
@@ -377,7 +374,7 @@ func testGetNodeData(t *testing.T, protocol int) {
 		trie, _ := state.New(pm.blockchain.GetBlockByNumber(i).Root(), state.NewDatabase(statedb))
 
 		for j, acc := range accounts {
-			state, _ := pm.blockchain.State()
+			state, _, _, _ := pm.blockchain.State()
 			bw := state.GetBalance(acc)
 			bh := trie.GetBalance(acc)
 
@@ -449,3 +446,41 @@ func testGetReceipt(t *testing.T, protocol int) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that responses are only considered solicited if they answer an
+// outstanding request of the same kind, so unsolicited data isn't rewarded.
+func TestSolicitedResponses(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	p := newPeer(eth63, p2p.NewPeer(discover.NodeID{}, "peer", nil), net)
+	go func() {
+		for {
+			msg, err := app.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+		}
+	}()
+	// Unrequested responses must not be solicited
+	if p.solicited(BlockBodiesMsg) {
+		t.Fatalf("unrequested bodies reported solicited")
+	}
+	// Each request should solicit exactly one response of its kind
+	if err := p.RequestBodies([]common.Hash{{}}); err != nil {
+		t.Fatalf("failed to request bodies: %v", err)
+	}
+	if err := p.RequestHeadersByNumber(0, 1, 0, false); err != nil {
+		t.Fatalf("failed to request headers: %v", err)
+	}
+	if p.solicited(ReceiptsMsg) {
+		t.Fatalf("receipts reported solicited by other requests")
+	}
+	if !p.solicited(BlockBodiesMsg) || !p.solicited(BlockHeadersMsg) {
+		t.Fatalf("requested responses not reported solicited")
+	}
+	if p.solicited(BlockBodiesMsg) || p.solicited(BlockHeadersMsg) {
+		t.Fatalf("repeated responses reported solicited")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wtc/go-wtc/node"
+	"github.com/wtc/go-wtc/wtcdb"
+)
+
+const (
+	// healthCheckTimeout is the time after which an unresponsive subsystem, e.g.
+	// a deadlocked transaction pool, is considered unhealthy.
+	healthCheckTimeout = 3 * time.Second
+
+	// dbCheckInterval is the minimum time between two writes verifying database
+	// writability, the result being reused by the health requests in between.
+	dbCheckInterval = 30 * time.Second
+)
+
+// healthCheckKey is the database key written to verify database writability.
+var healthCheckKey = []byte("health-check")
+
+// HealthChecks implements node.HealthService, reporting on the liveness of the
+// transaction pool and chain database, and on the sync status of the node.
+func (s *Wtc) HealthChecks() []node.HealthCheck {
+	db := &dbHealth{db: s.chainDb}
+	pool := &poolHealth{stats: s.txPool.Stats}
+
+	return []node.HealthCheck{
+		{Name: "database", Check: db.check},
+		{Name: "txpool", Check: pool.check},
+		{Name: "sync", Readiness: true, Check: s.checkSync},
+		{Name: "head", Readiness: true, Check: s.checkHead},
+	}
+}
+
+// dbHealth verifies that the chain database accepts writes. As health requests
+// are unauthenticated, it writes at most once per dbCheckInterval.
+type dbHealth struct {
+	db wtcdb.Putter
+
+	checked time.Time // Time of the last write, zero if none yet
+	err     error     // Result of the last write
+
+	lock sync.Mutex // Protects the cached result
+}
+
+// check returns the result of the last write, writing anew if it is outdated.
+func (h *dbHealth) check(t *node.HealthConfig) (string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.checked.IsZero() || time.Since(h.checked) >= dbCheckInterval {
+		stamp := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+		h.err, h.checked = h.db.Put(healthCheckKey, stamp), time.Now()
+	}
+	if h.err != nil {
+		return "", fmt.Errorf("database not writable: %v", h.err)
+	}
+	return "writable", nil
+}
+
+// poolHealth verifies that the transaction pool responds in time. At most one
+// probe is outstanding at once, so a deadlocked pool doesn't pile up a blocked
+// goroutine with every health request.
+type poolHealth struct {
+	stats func() (int, int) // Retrieves the pending and queued transaction counts
+
+	probe  chan struct{} // Closed when the outstanding probe finishes, nil if none
+	status string        // Result of the last finished probe
+
+	lock sync.Mutex // Protects the probe and its result
+}
+
+// check waits for the outstanding probe of the pool, starting one if none is.
+func (h *poolHealth) check(t *node.HealthConfig) (string, error) {
+	h.lock.Lock()
+	if h.probe == nil {
+		probe := make(chan struct{})
+		h.probe = probe
+		go func() {
+			pending, queued := h.stats()
+
+			h.lock.Lock()
+			h.status, h.probe = fmt.Sprintf("%d pending, %d queued", pending, queued), nil
+			h.lock.Unlock()
+			close(probe)
+		}()
+	}
+	probe := h.probe
+	h.lock.Unlock()
+
+	timeout := time.NewTimer(healthCheckTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-probe:
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return h.status, nil
+	case <-timeout.C:
+		return "", errors.New("transaction pool unresponsive")
+	}
+}
+
+// checkSync verifies that the initial sync completed and that the node is not
+// lagging behind its best peer.
+func (s *Wtc) checkSync(t *node.HealthConfig) (string, error) {
+	if atomic.LoadUint32(&s.protocolManager.acceptTxs) == 0 {
+		return "", errors.New("initial sync not completed")
+	}
+	downloader := s.protocolManager.downloader
+	if !downloader.Synchronising() {
+		return "synced", nil
+	}
+	progress := downloader.Progress()
+	var lag uint64
+	if progress.HighestBlock > progress.CurrentBlock {
+		lag = progress.HighestBlock - progress.CurrentBlock
+	}
+	if lag > t.MaxBlockLag {
+		return "", fmt.Errorf("%d blocks behind, max %d", lag, t.MaxBlockLag)
+	}
+	return fmt.Sprintf("syncing, %d blocks behind", lag), nil
+}
+
+// checkHead verifies that the head block is recent.
+func (s *Wtc) checkHead(t *node.HealthConfig) (string, error) {
+	head := s.blockchain.CurrentBlock()
+	age := time.Since(time.Unix(head.Time().Int64(), 0))
+	age -= age % time.Second
+	if t.MaxHeadAge > 0 && age > t.MaxHeadAge {
+		return "", fmt.Errorf("head block #%d is %v old, max %v", head.NumberU64(), age, t.MaxHeadAge)
+	}
+	return fmt.Sprintf("head block #%d is %v old", head.NumberU64(), age), nil
+}
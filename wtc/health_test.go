@@ -0,0 +1,100 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingPutter is a database writer counting its writes, failing them if
+// an error is set.
+type countingPutter struct {
+	puts int
+	err  error
+}
+
+func (p *countingPutter) Put(key []byte, value []byte) error {
+	p.puts++
+	return p.err
+}
+
+// Tests that the database writability is checked at most once per interval,
+// with the cached result served in between.
+func TestDatabaseHealthCached(t *testing.T) {
+	db := &countingPutter{err: errors.New("disk full")}
+	h := &dbHealth{db: db}
+
+	for i := 0; i < 3; i++ {
+		if _, err := h.check(nil); err == nil {
+			t.Fatalf("check %d: failed write reported healthy", i)
+		}
+	}
+	if db.puts != 1 {
+		t.Fatalf("database writes within interval: got %d, want 1", db.puts)
+	}
+	// Expire the cached result and ensure the database is written again
+	db.err = nil
+	h.checked = h.checked.Add(-dbCheckInterval)
+	if msg, err := h.check(nil); err != nil {
+		t.Fatalf("writable database reported unhealthy: %v", err)
+	} else if msg != "writable" {
+		t.Fatalf("status mismatch: got %q, want %q", msg, "writable")
+	}
+	if db.puts != 2 {
+		t.Fatalf("database writes after interval: got %d, want 2", db.puts)
+	}
+}
+
+// Tests that concurrent checks of an unresponsive transaction pool share one
+// outstanding probe instead of leaking one goroutine each.
+func TestPoolHealthSingleProbe(t *testing.T) {
+	var (
+		probes  int32
+		release = make(chan struct{})
+	)
+	h := &poolHealth{stats: func() (int, int) {
+		atomic.AddInt32(&probes, 1)
+		<-release
+		return 1, 2
+	}}
+	// Check the deadlocked pool concurrently, all checks should time out
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := h.check(nil); err == nil {
+				t.Errorf("check %d: unresponsive pool reported healthy", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&probes); n != 1 {
+		t.Fatalf("outstanding probes: got %d, want 1", n)
+	}
+	// Unblock the pool and ensure the next check gets the probe result
+	close(release)
+	if msg, err := h.check(nil); err != nil {
+		t.Fatalf("responsive pool reported unhealthy: %v", err)
+	} else if msg != "1 pending, 2 queued" {
+		t.Fatalf("status mismatch: got %q, want %q", msg, "1 pending, 2 queued")
+	}
+}
@@ -26,6 +26,10 @@ var (
 	propTxnInTrafficMeter     = metrics.NewMeter("eth/prop/txns/in/traffic")
 	propTxnOutPacketsMeter    = metrics.NewMeter("eth/prop/txns/out/packets")
 	propTxnOutTrafficMeter    = metrics.NewMeter("eth/prop/txns/out/traffic")
+	propTxHashInPacketsMeter  = metrics.NewMeter("eth/prop/txhashes/in/packets")
+	propTxHashInTrafficMeter  = metrics.NewMeter("eth/prop/txhashes/in/traffic")
+	propTxHashOutPacketsMeter = metrics.NewMeter("eth/prop/txhashes/out/packets")
+	propTxHashOutTrafficMeter = metrics.NewMeter("eth/prop/txhashes/out/traffic")
 	propHashInPacketsMeter    = metrics.NewMeter("eth/prop/hashes/in/packets")
 	propHashInTrafficMeter    = metrics.NewMeter("eth/prop/hashes/in/traffic")
 	propHashOutPacketsMeter   = metrics.NewMeter("eth/prop/hashes/out/packets")
@@ -50,6 +54,10 @@ var (
 	reqReceiptInTrafficMeter  = metrics.NewMeter("eth/req/receipts/in/traffic")
 	reqReceiptOutPacketsMeter = metrics.NewMeter("eth/req/receipts/out/packets")
 	reqReceiptOutTrafficMeter = metrics.NewMeter("eth/req/receipts/out/traffic")
+	reqTxnInPacketsMeter      = metrics.NewMeter("eth/req/txns/in/packets")
+	reqTxnInTrafficMeter      = metrics.NewMeter("eth/req/txns/in/traffic")
+	reqTxnOutPacketsMeter     = metrics.NewMeter("eth/req/txns/out/packets")
+	reqTxnOutTrafficMeter     = metrics.NewMeter("eth/req/txns/out/traffic")
 	miscInPacketsMeter        = metrics.NewMeter("eth/misc/in/packets")
 	miscInTrafficMeter        = metrics.NewMeter("eth/misc/in/traffic")
 	miscOutPacketsMeter       = metrics.NewMeter("eth/misc/out/packets")
@@ -97,6 +105,11 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptInPacketsMeter, reqReceiptInTrafficMeter
 
+	case rw.version >= eth64 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxnInPacketsMeter, reqTxnInTrafficMeter
+	case rw.version >= eth64 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashInPacketsMeter, propTxHashInTrafficMeter
+
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashInPacketsMeter, propHashInTrafficMeter
 	case msg.Code == NewBlockMsg:
@@ -124,6 +137,11 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptOutPacketsMeter, reqReceiptOutTrafficMeter
 
+	case rw.version >= eth64 && msg.Code == PooledTransactionsMsg:
+		packets, traffic = reqTxnOutPacketsMeter, reqTxnOutTrafficMeter
+	case rw.version >= eth64 && msg.Code == NewPooledTransactionHashesMsg:
+		packets, traffic = propTxHashOutPacketsMeter, propTxHashOutTrafficMeter
+
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashOutPacketsMeter, propHashOutTrafficMeter
 	case msg.Code == NewBlockMsg:
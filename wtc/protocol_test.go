@@ -18,6 +18,7 @@ package eth
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -174,6 +175,199 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// This test checks that a broadcast transaction is sent in full to a square
+// root subset of the peers and only announced to the rest, while peers not
+// supporting announcements always receive it in full.
+func TestBroadcastTx64(t *testing.T)     { testBroadcastTx(t, 0, 9) }
+func TestBroadcastTxMixed(t *testing.T)  { testBroadcastTx(t, 4, 4) }
+func TestBroadcastTxLegacy(t *testing.T) { testBroadcastTx(t, 4, 0) }
+
+func testBroadcastTx(t *testing.T, legacy int, announcing int) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	// Connect all the peers and wait until they are registered
+	var peers []*testPeer
+	for i := 0; i < legacy+announcing; i++ {
+		version := eth64
+		if i < legacy {
+			version = eth63
+		}
+		p, _ := newTestPeer(fmt.Sprintf("peer #%d", i), version, pm, true)
+		defer p.close()
+		peers = append(peers, p)
+	}
+	for start := time.Now(); pm.peers.Len() < len(peers); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("peers not registered: have %d, want %d", pm.peers.Len(), len(peers))
+		}
+	}
+	// Broadcast a transaction and collect what each peer received
+	tx := newTestTransaction(testAccount, 0, 0)
+	go pm.BroadcastTx(tx.Hash(), tx)
+
+	codes := make([]uint64, len(peers))
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func(i int, p *testPeer) {
+			defer wg.Done()
+
+			msg, err := p.app.ReadMsg()
+			if err != nil {
+				t.Errorf("%v: read error: %v", p.Peer, err)
+				return
+			}
+			var hashes []common.Hash
+			switch msg.Code {
+			case TxMsg:
+				var txs []*types.Transaction
+				if err := msg.Decode(&txs); err != nil {
+					t.Errorf("%v: %v", p.Peer, err)
+				}
+				for _, tx := range txs {
+					hashes = append(hashes, tx.Hash())
+				}
+			case NewPooledTransactionHashesMsg:
+				if err := msg.Decode(&hashes); err != nil {
+					t.Errorf("%v: %v", p.Peer, err)
+				}
+			default:
+				t.Errorf("%v: got code %d, want TxMsg or NewPooledTransactionHashesMsg", p.Peer, msg.Code)
+			}
+			if len(hashes) != 1 || hashes[0] != tx.Hash() {
+				t.Errorf("%v: got hashes %x, want [%x]", p.Peer, hashes, tx.Hash())
+			}
+			codes[i] = msg.Code
+		}(i, p)
+	}
+	wg.Wait()
+
+	// Legacy peers must get the transaction, the rest is split by the square root
+	var sent int
+	for i, code := range codes {
+		if i < legacy {
+			if code != TxMsg {
+				t.Errorf("eth/63 peer #%d: got code %d, want TxMsg", i, code)
+			}
+			continue
+		}
+		if code == TxMsg {
+			sent++
+		}
+	}
+	if transfer := int(math.Sqrt(float64(len(peers)))); legacy == 0 && sent != transfer {
+		t.Errorf("eth/64 peers sent the transaction: got %d, want %d", sent, transfer)
+	} else if sent > transfer {
+		t.Errorf("eth/64 peers sent the transaction: got %d, want at most %d", sent, transfer)
+	}
+}
+
+// This test checks that pooled transactions are served by hash, skipping the
+// unknown ones.
+func TestGetPooledTransactions64(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	txs := []*types.Transaction{
+		newTestTransaction(testAccount, 0, 0),
+		newTestTransaction(testAccount, 1, 0),
+	}
+	pm.txpool.AddRemotes(txs)
+
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer p.close()
+
+	// The initial transaction sync and the reply can arrive in either order
+	go p2p.Send(p.app, GetPooledTransactionsMsg, []common.Hash{txs[1].Hash(), {0x01}, txs[0].Hash()})
+
+	for synced, served := false, false; !synced || !served; {
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		var got []*types.Transaction
+		if err := msg.Decode(&got); err != nil {
+			t.Fatalf("failed to decode message %d: %v", msg.Code, err)
+		}
+		switch msg.Code {
+		case TxMsg:
+			synced = true
+		case PooledTransactionsMsg:
+			if len(got) != 2 || got[0].Hash() != txs[1].Hash() || got[1].Hash() != txs[0].Hash() {
+				t.Errorf("pooled transactions mismatch: got %d, want [%x %x]", len(got), txs[1].Hash(), txs[0].Hash())
+			}
+			served = true
+		default:
+			t.Fatalf("got code %d, want TxMsg or PooledTransactionsMsg", msg.Code)
+		}
+	}
+}
+
+// This test checks that announced transactions are retrieved from the
+// announcing peer and added to the local pool.
+func TestNewPooledTransactionHashes64(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("retrieval mismatch: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTransactionsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 {
+			t.Errorf("wrong number of added transactions: got %d, want 1", len(added))
+		} else if added[0].Hash() != tx.Hash() {
+			t.Errorf("added wrong tx hash: got %v, want %v", added[0].Hash(), tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("no transaction added within 2 seconds")
+	}
+}
+
+// This test checks that eth/63 peers using the announcement messages are
+// disconnected, as those messages don't exist in their protocol version.
+func TestPooledTransactionMsgs63(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	defer pm.Stop()
+
+	tests := []struct {
+		code uint64
+		data interface{}
+	}{
+		{NewPooledTransactionHashesMsg, []common.Hash{{0x01}}},
+		{GetPooledTransactionsMsg, []common.Hash{{0x01}}},
+		{PooledTransactionsMsg, []*types.Transaction{newTestTransaction(testAccount, 0, 0)}},
+	}
+	for i, test := range tests {
+		p, errc := newTestPeer("peer", eth63, pm, true)
+		go p2p.Send(p.app, test.code, test.data)
+
+		want := errResp(ErrInvalidMsgCode, "%v", test.code)
+		select {
+		case err := <-errc:
+			if err == nil || err.Error() != want.Error() {
+				t.Errorf("test %d: wrong error: got %v, want %q", i, err, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("test %d: protocol did not shut down within 2 seconds", i)
+		}
+		p.close()
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/wtc/go-wtc"
 	"github.com/wtc/go-wtc/common"
@@ -277,6 +278,9 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+	Phase         string
+	Throughput    hexutil.Uint64
+	ETA           hexutil.Uint64
 }
 
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
@@ -301,6 +305,9 @@ func (ec *Client) SyncProgress(ctx context.Context) (*wtc.SyncProgress, error) {
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+		Phase:         progress.Phase,
+		Throughput:    uint64(progress.Throughput),
+		ETA:           time.Duration(progress.ETA) * time.Second,
 	}, nil
 }
 
@@ -0,0 +1,187 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/rlp"
+	"github.com/wtc/go-wtc/trie"
+)
+
+// AccountProof is the merkle proof of an account in the state trie of a block,
+// along with the account fields and storage values it claims to prove. None of
+// the fields can be trusted before the proof is verified against a state root.
+type AccountProof struct {
+	Address      common.Address
+	Proof        []rlp.RawValue
+	Balance      *big.Int
+	CoinAge      *big.Int
+	FUBlockTime  *big.Int
+	CodeHash     common.Hash
+	Nonce        uint64
+	StorageHash  common.Hash
+	StorageProof []StorageProof
+}
+
+// StorageProof is the merkle proof of a storage slot in the storage trie of an
+// account.
+type StorageProof struct {
+	Key   common.Hash
+	Value *big.Int
+	Proof []rlp.RawValue
+}
+
+type rpcAccountProof struct {
+	Address      common.Address    `json:"address"`
+	AccountProof []hexutil.Bytes   `json:"accountProof"`
+	Balance      *hexutil.Big      `json:"balance"`
+	CoinAge      *hexutil.Big      `json:"coinAge"`
+	FUBlockTime  *hexutil.Big      `json:"fuBlockTime"`
+	CodeHash     common.Hash       `json:"codeHash"`
+	Nonce        hexutil.Uint64    `json:"nonce"`
+	StorageHash  common.Hash       `json:"storageHash"`
+	StorageProof []rpcStorageProof `json:"storageProof"`
+}
+
+type rpcStorageProof struct {
+	Key   common.Hash     `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// ProofAt returns the merkle proof of the given account and storage slots.
+// The block number can be nil, in which case the proof is taken from the latest
+// known block. The returned proof should be verified against the state root of
+// the block before use. Proofs of a different account or of storage slots other
+// than the requested ones, in the requested order, are rejected.
+func (ec *Client) ProofAt(ctx context.Context, account common.Address, keys []common.Hash, blockNumber *big.Int) (*AccountProof, error) {
+	if keys == nil {
+		keys = []common.Hash{}
+	}
+	var res *rpcAccountProof
+	if err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if res == nil || res.Balance == nil || res.CoinAge == nil || res.FUBlockTime == nil {
+		return nil, fmt.Errorf("incomplete proof of account %x", account)
+	}
+	if res.Address != account {
+		return nil, fmt.Errorf("proof of account %x returned for %x", res.Address, account)
+	}
+	if len(res.StorageProof) != len(keys) {
+		return nil, fmt.Errorf("storage proof count mismatch: have %d, want %d", len(res.StorageProof), len(keys))
+	}
+	proof := &AccountProof{
+		Address:      res.Address,
+		Proof:        toRawProof(res.AccountProof),
+		Balance:      (*big.Int)(res.Balance),
+		CoinAge:      (*big.Int)(res.CoinAge),
+		FUBlockTime:  (*big.Int)(res.FUBlockTime),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]StorageProof, len(res.StorageProof)),
+	}
+	for i, slot := range res.StorageProof {
+		if slot.Key != keys[i] {
+			return nil, fmt.Errorf("storage proof %d key mismatch: have %x, want %x", i, slot.Key, keys[i])
+		}
+		if slot.Value == nil {
+			return nil, fmt.Errorf("incomplete proof of storage slot %x", slot.Key)
+		}
+		proof.StorageProof[i] = StorageProof{
+			Key:   slot.Key,
+			Value: (*big.Int)(slot.Value),
+			Proof: toRawProof(slot.Proof),
+		}
+	}
+	return proof, nil
+}
+
+func toRawProof(nodes []hexutil.Bytes) []rlp.RawValue {
+	proof := make([]rlp.RawValue, len(nodes))
+	for i, node := range nodes {
+		proof[i] = rlp.RawValue(node)
+	}
+	return proof
+}
+
+// Verify checks the account proof against the given state root, as well as the
+// storage proofs against the proven storage root. It returns an error if any of
+// the proofs is invalid or if any field of the proof differs from the proven
+// value, after which the proven fields can be trusted.
+func (p *AccountProof) Verify(root common.Hash) error {
+	enc, err := trie.VerifyProof(root, crypto.Keccak256(p.Address[:]), p.Proof)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	// Accounts missing from the state trie need to be reported empty
+	account := state.Account{
+		Balance:     new(big.Int),
+		CoinAge:     new(big.Int),
+		FUBlockTime: new(big.Int),
+		Root:        types.EmptyRootHash,
+		CodeHash:    crypto.Keccak256(nil),
+	}
+	if enc != nil {
+		if err := rlp.DecodeBytes(enc, &account); err != nil {
+			return fmt.Errorf("invalid account in proof: %v", err)
+		}
+	}
+	switch {
+	case account.Nonce != p.Nonce:
+		return fmt.Errorf("nonce mismatch: proven %d, have %d", account.Nonce, p.Nonce)
+	case account.Balance.Cmp(p.Balance) != 0:
+		return fmt.Errorf("balance mismatch: proven %v, have %v", account.Balance, p.Balance)
+	case account.CoinAge.Cmp(p.CoinAge) != 0:
+		return fmt.Errorf("coin age mismatch: proven %v, have %v", account.CoinAge, p.CoinAge)
+	case account.FUBlockTime.Cmp(p.FUBlockTime) != 0:
+		return fmt.Errorf("final update time mismatch: proven %v, have %v", account.FUBlockTime, p.FUBlockTime)
+	case common.BytesToHash(account.CodeHash) != p.CodeHash:
+		return fmt.Errorf("code hash mismatch: proven %x, have %x", account.CodeHash, p.CodeHash)
+	case account.Root != p.StorageHash:
+		return fmt.Errorf("storage hash mismatch: proven %x, have %x", account.Root, p.StorageHash)
+	}
+	for _, slot := range p.StorageProof {
+		value := new(big.Int)
+		if account.Root != types.EmptyRootHash {
+			enc, err := trie.VerifyProof(account.Root, crypto.Keccak256(slot.Key[:]), slot.Proof)
+			if err != nil {
+				return fmt.Errorf("invalid proof of storage slot %x: %v", slot.Key, err)
+			}
+			if enc != nil {
+				_, content, _, err := rlp.Split(enc)
+				if err != nil {
+					return fmt.Errorf("invalid value of storage slot %x: %v", slot.Key, err)
+				}
+				value.SetBytes(content)
+			}
+		}
+		if value.Cmp(slot.Value) != 0 {
+			return fmt.Errorf("storage slot %x mismatch: proven %v, have %v", slot.Key, value, slot.Value)
+		}
+	}
+	return nil
+}
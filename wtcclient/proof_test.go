@@ -0,0 +1,224 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-wtc library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wtc library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package wtcclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/wtc/go-wtc/common"
+	"github.com/wtc/go-wtc/common/hexutil"
+	"github.com/wtc/go-wtc/core/state"
+	"github.com/wtc/go-wtc/core/types"
+	"github.com/wtc/go-wtc/crypto"
+	"github.com/wtc/go-wtc/rpc"
+	"github.com/wtc/go-wtc/wtcdb"
+)
+
+var (
+	proofAddr  = common.HexToAddress("0x0a")
+	proofSlot  = common.HexToHash("0x01")
+	proofValue = common.HexToHash("0x2a")
+)
+
+// proofState creates a committed state with a single account holding balance,
+// coin age and a storage slot.
+func proofState(t *testing.T) (*state.StateDB, common.Hash) {
+	db, _ := wtcdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(proofAddr, big.NewInt(1000), big.NewInt(1), big.NewInt(10))
+	statedb.SetCoinAge(proofAddr, big.NewInt(500))
+	statedb.SetNonce(proofAddr, 3)
+	statedb.SetState(proofAddr, proofSlot, proofValue)
+
+	root, err := statedb.CommitTo(db, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, state.NewDatabase(db))
+	return statedb, root
+}
+
+// makeProof assembles an account proof from the state, the way the RPC API
+// reports it.
+func makeProof(t *testing.T, statedb *state.StateDB, addr common.Address, slots ...common.Hash) *AccountProof {
+	accountProof, err := statedb.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	proof := &AccountProof{
+		Address:     addr,
+		Proof:       accountProof,
+		Balance:     statedb.GetBalance(addr),
+		CoinAge:     new(big.Int),
+		FUBlockTime: new(big.Int),
+		CodeHash:    common.BytesToHash(crypto.Keccak256(nil)),
+		Nonce:       statedb.GetNonce(addr),
+		StorageHash: types.EmptyRootHash,
+	}
+	if tr := statedb.StorageTrie(addr); tr != nil {
+		proof.StorageHash = tr.Hash()
+	}
+	for _, slot := range slots {
+		storageProof, err := statedb.GetStorageProof(addr, slot)
+		if err != nil {
+			t.Fatalf("failed to prove storage slot %x: %v", slot, err)
+		}
+		value := statedb.GetState(addr, slot)
+		proof.StorageProof = append(proof.StorageProof, StorageProof{
+			Key:   slot,
+			Value: new(big.Int).SetBytes(value[:]),
+			Proof: storageProof,
+		})
+	}
+	return proof
+}
+
+// Tests that valid account and storage proofs verify, and that proofs with
+// tampered fields are rejected.
+func TestAccountProofVerify(t *testing.T) {
+	statedb, root := proofState(t)
+
+	proof := makeProof(t, statedb, proofAddr, proofSlot, common.HexToHash("0x02"))
+	proof.CoinAge.SetInt64(500)
+	if err := proof.Verify(root); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	proof.Balance = big.NewInt(1001)
+	if err := proof.Verify(root); err == nil {
+		t.Errorf("tampered balance accepted")
+	}
+	proof.Balance = big.NewInt(1000)
+	proof.FUBlockTime = big.NewInt(10)
+	if err := proof.Verify(root); err == nil {
+		t.Errorf("tampered final update time accepted")
+	}
+	proof.FUBlockTime = new(big.Int)
+	proof.CoinAge = big.NewInt(501)
+	if err := proof.Verify(root); err == nil {
+		t.Errorf("tampered coin age accepted")
+	}
+	proof.CoinAge = big.NewInt(500)
+	proof.StorageProof[0].Value = big.NewInt(43)
+	if err := proof.Verify(root); err == nil {
+		t.Errorf("tampered storage value accepted")
+	}
+	proof.StorageProof[0].Value = big.NewInt(42)
+	if err := proof.Verify(common.Hash{1}); err == nil {
+		t.Errorf("proof accepted against wrong root")
+	}
+}
+
+// Tests that the absence of an account can be proven.
+func TestAccountProofMissing(t *testing.T) {
+	statedb, root := proofState(t)
+
+	missing := common.HexToAddress("0x0b")
+	proof := makeProof(t, statedb, missing, proofSlot)
+	if err := proof.Verify(root); err != nil {
+		t.Fatalf("valid absence proof rejected: %v", err)
+	}
+	proof.Balance = big.NewInt(1)
+	if err := proof.Verify(root); err == nil {
+		t.Errorf("balance of missing account accepted")
+	}
+}
+
+// ProofAPI serves a fixed account proof over eth_getProof, tampered with before
+// being returned.
+type ProofAPI struct {
+	proof  *AccountProof
+	tamper func(*rpcAccountProof)
+}
+
+func (api *ProofAPI) GetProof(address common.Address, keys []common.Hash, blockNr string) (json.RawMessage, error) {
+	res := &rpcAccountProof{
+		Address:     api.proof.Address,
+		Balance:     (*hexutil.Big)(api.proof.Balance),
+		CoinAge:     (*hexutil.Big)(api.proof.CoinAge),
+		FUBlockTime: (*hexutil.Big)(api.proof.FUBlockTime),
+		CodeHash:    api.proof.CodeHash,
+		Nonce:       hexutil.Uint64(api.proof.Nonce),
+		StorageHash: api.proof.StorageHash,
+	}
+	for _, node := range api.proof.Proof {
+		res.AccountProof = append(res.AccountProof, hexutil.Bytes(node))
+	}
+	for _, slot := range api.proof.StorageProof {
+		storage := rpcStorageProof{Key: slot.Key, Value: (*hexutil.Big)(slot.Value)}
+		for _, node := range slot.Proof {
+			storage.Proof = append(storage.Proof, hexutil.Bytes(node))
+		}
+		res.StorageProof = append(res.StorageProof, storage)
+	}
+	if api.tamper != nil {
+		api.tamper(res)
+	}
+	return json.Marshal(res)
+}
+
+// Tests that proofs of a different account or of storage slots other than the
+// requested ones are rejected when retrieved.
+func TestProofAtMismatch(t *testing.T) {
+	statedb, root := proofState(t)
+
+	api := &ProofAPI{proof: makeProof(t, statedb, proofAddr, proofSlot, common.HexToHash("0x02"))}
+	api.proof.CoinAge.SetInt64(500)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register proof API: %v", err)
+	}
+	defer server.Stop()
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	keys := []common.Hash{proofSlot, common.HexToHash("0x02")}
+	proof, err := client.ProofAt(context.Background(), proofAddr, keys, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve proof: %v", err)
+	}
+	if err := proof.Verify(root); err != nil {
+		t.Fatalf("retrieved proof rejected: %v", err)
+	}
+	tampers := map[string]func(*rpcAccountProof){
+		"address": func(res *rpcAccountProof) {
+			res.Address = common.HexToAddress("0x0b")
+		},
+		"missing slot": func(res *rpcAccountProof) {
+			res.StorageProof = res.StorageProof[:1]
+		},
+		"extra slot": func(res *rpcAccountProof) {
+			res.StorageProof = append(res.StorageProof, res.StorageProof[0])
+		},
+		"reordered slots": func(res *rpcAccountProof) {
+			res.StorageProof[0], res.StorageProof[1] = res.StorageProof[1], res.StorageProof[0]
+		},
+		"replaced slot": func(res *rpcAccountProof) {
+			res.StorageProof[1].Key = common.HexToHash("0x03")
+		},
+	}
+	for name, tamper := range tampers {
+		api.tamper = tamper
+		if _, err := client.ProofAt(context.Background(), proofAddr, keys, nil); err == nil {
+			t.Errorf("%s: tampered proof accepted", name)
+		}
+	}
+}